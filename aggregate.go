@@ -0,0 +1,182 @@
+package main
+
+import (
+	"math"
+	"net/url"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+var idSegmentPattern = regexp.MustCompile(`^(\d+|[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}|[0-9a-fA-F]{16,}|[A-Za-z0-9_-]*\d[A-Za-z0-9_-]*[A-Za-z][A-Za-z0-9_-]*)$`)
+
+// TemplatePath replaces the segments of a path which look like identifiers with {id} so that calls to the same
+// endpoint for different resources are grouped together
+func TemplatePath(path string) string {
+	segments := strings.Split(path, "/")
+	for i, segment := range segments {
+		if segment != "" && IsIdSegment(segment) {
+			segments[i] = "{id}"
+		}
+	}
+	return strings.Join(segments, "/")
+}
+
+func IsIdSegment(segment string) bool {
+	if !idSegmentPattern.MatchString(segment) {
+		// Long opaque tokens without any separators are treated as identifiers too
+		return len(segment) >= 24 && !strings.ContainsAny(segment, ".-_")
+	}
+	// Mixed letters and digits only count as an identifier if it is long enough not to be a word like v2 or utf8
+	digits := strings.IndexFunc(segment, func(r rune) bool { return r < '0' || r > '9' }) < 0
+	return digits || len(segment) >= 8
+}
+
+func Endpoint(entry Entry) string {
+	requestUrl, err := url.Parse(entry.Request.Url)
+	if err != nil {
+		return entry.Request.Method + " " + entry.Request.Url
+	}
+	return strings.ToUpper(entry.Request.Method) + " " + requestUrl.Host + TemplatePath(requestUrl.Path)
+}
+
+func MimeType(entry Entry) string {
+	if entry.Response.Content == nil || entry.Response.Content.MimeType == "" {
+		return "[none]"
+	}
+	mime, _, _ := strings.Cut(entry.Response.Content.MimeType, ";")
+	return strings.ToLower(strings.TrimSpace(mime))
+}
+
+func GroupKey(entry Entry, by string) string {
+	switch by {
+	case "domain":
+		requestUrl, err := url.Parse(entry.Request.Url)
+		if err != nil {
+			return "[invalid url]"
+		}
+		return strings.ToLower(requestUrl.Host)
+	case "status":
+		return strconv.Itoa(entry.Response.Status)
+	case "mime":
+		return MimeType(entry)
+	case "method":
+		return strings.ToUpper(entry.Request.Method)
+	case "endpoint":
+		return Endpoint(entry)
+	case "graphql-operation":
+		if operations := GraphqlOperations(entry); len(operations) > 0 {
+			return strings.Join(operations, ", ")
+		}
+		return "[not graphql]"
+	case "provider":
+		if provider := EntryProvider(entry); provider != "" {
+			return provider
+		}
+		return "[unknown]"
+	case "country", "asn":
+		location := EntryGeoLocation(entry)
+		switch {
+		case location == nil:
+			return "[unknown]"
+		case by == "country" && location.Country != "":
+			return location.Country
+		case by == "asn" && location.Asn != 0:
+			return "AS" + strconv.FormatUint(location.Asn, 10) + " " + location.Organization
+		}
+		return "[unknown]"
+	}
+	return ""
+}
+
+// Percentile uses the nearest rank method on an already sorted slice
+func Percentile(sorted []float64, percentile float64) float64 {
+	if len(sorted) == 0 {
+		return 0
+	}
+	rank := int(math.Ceil(percentile/100*float64(len(sorted)))) - 1
+	return sorted[max(0, min(rank, len(sorted)-1))]
+}
+
+type Aggregate struct {
+	Key       string
+	Count     int
+	Bytes     int
+	Durations []float64
+	// ServerDurations are the server's own timings from the Server-Timing header, for the entries which had one
+	ServerDurations []float64
+}
+
+func AggregateEntries(entries []Entry, key func(entry Entry) string) []*Aggregate {
+	groups := make([]*Aggregate, 0)
+	byKey := make(map[string]*Aggregate)
+	for _, entry := range entries {
+		name := key(entry)
+		group, ok := byKey[name]
+		if !ok {
+			group = &Aggregate{Key: name}
+			byKey[name] = group
+			groups = append(groups, group)
+		}
+		group.Count++
+		group.Bytes += TransferSize(entry)
+		group.Durations = append(group.Durations, entry.TimeMs)
+		if server, ok := ServerTime(ServerTimings(entry)); ok {
+			group.ServerDurations = append(group.ServerDurations, server)
+		}
+	}
+
+	for _, group := range groups {
+		sort.Float64s(group.Durations)
+		sort.Float64s(group.ServerDurations)
+	}
+	sort.SliceStable(groups, func(i, j int) bool {
+		return groups[i].Count > groups[j].Count
+	})
+	return groups
+}
+
+// FormatAggregates adds the percentiles of the Server-Timing durations when any of the entries reported them, so the
+// time spent on the server can be compared to the total
+func FormatAggregates(groups []*Aggregate, by string) string {
+	serverTiming := false
+	for _, group := range groups {
+		serverTiming = serverTiming || len(group.ServerDurations) > 0
+	}
+
+	rows := make([][]string, 0, len(groups))
+	for _, group := range groups {
+		row := []string{
+			group.Key,
+			strconv.Itoa(group.Count),
+			FormatBytes(group.Bytes),
+			FormatDuration(Percentile(group.Durations, 50)),
+			FormatDuration(Percentile(group.Durations, 95)),
+		}
+		if serverTiming {
+			row = append(row, Tertiary(len(group.ServerDurations) > 0, FormatDuration(Percentile(group.ServerDurations, 50)), "-"))
+			row = append(row, Tertiary(len(group.ServerDurations) > 0, FormatDuration(Percentile(group.ServerDurations, 95)), "-"))
+		}
+		rows = append(rows, row)
+	}
+
+	name := strings.ToUpper(by[:1]) + by[1:]
+	switch by {
+	case "asn":
+		name = "ASN"
+	case "graphql-operation":
+		name = "GraphQL operation"
+	}
+	columns := []Column{
+		{Name: name},
+		{Name: "Count", Right: true},
+		{Name: "Bytes", Right: true},
+		{Name: "p50", Right: true},
+		{Name: "p95", Right: true},
+	}
+	if serverTiming {
+		columns = append(columns, Column{Name: "Server p50", Right: true}, Column{Name: "Server p95", Right: true})
+	}
+	return FormatTable(columns, rows)
+}
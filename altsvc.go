@@ -0,0 +1,204 @@
+package main
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/fatih/color"
+)
+
+type AuditAltSvcCmd struct {
+	File string `arg:"" help:"The HAR file to parse, or - to read it from stdin" type:"existingfile"`
+}
+
+type AltService struct {
+	Protocol  string
+	Authority string
+	MaxAge    int
+}
+
+// ParseAltSvc reads an Alt-Svc header such as `h3=":443"; ma=86400, h3-29=":443"`, returning nothing for `clear`
+func ParseAltSvc(value string) []AltService {
+	services := make([]AltService, 0)
+	for _, part := range strings.Split(value, ",") {
+		fields := strings.Split(part, ";")
+		protocol, authority, found := strings.Cut(strings.TrimSpace(fields[0]), "=")
+		if !found {
+			continue
+		}
+		service := AltService{Protocol: strings.ToLower(protocol), Authority: strings.Trim(authority, `"`), MaxAge: 86400}
+		for _, parameter := range fields[1:] {
+			name, value, _ := strings.Cut(strings.TrimSpace(parameter), "=")
+			if strings.EqualFold(name, "ma") {
+				if maxAge, err := strconv.Atoi(value); err == nil {
+					service.MaxAge = maxAge
+				}
+			}
+		}
+		services = append(services, service)
+	}
+	return services
+}
+
+// AltSvcReport follows one origin from before it advertised HTTP/3 to after, to see whether the browser switched
+type AltSvcReport struct {
+	Origin     string
+	Advertised []string
+	// AdvertisedAt and UpgradedAt are entry indexes, or -1 if it never happened
+	AdvertisedAt int
+	UpgradedAt   int
+	Before       []Entry
+	After        []Entry
+	Upgraded     int
+	AuditGroup
+}
+
+func AnalyzeAltSvc(entries []Entry, indexes []int) []*AltSvcReport {
+	reports := make([]*AltSvcReport, 0)
+	byOrigin := make(map[string]*AltSvcReport)
+	for i, entry := range entries {
+		origin := Origin(entry.Request.Url)
+		report, ok := byOrigin[origin]
+		if !ok {
+			report = &AltSvcReport{Origin: origin, AdvertisedAt: -1, UpgradedAt: -1}
+			report.Name = origin
+			byOrigin[origin] = report
+			reports = append(reports, report)
+		}
+		report.Entries++
+
+		version := EntryHttpVersion(entry)
+		if report.AdvertisedAt == -1 {
+			report.Before = append(report.Before, entry)
+		} else {
+			report.After = append(report.After, entry)
+			if version == "h3" {
+				report.Upgraded++
+			}
+		}
+		if version == "h3" && report.UpgradedAt == -1 {
+			report.UpgradedAt = indexes[i]
+		}
+
+		if header := FindHeader(entry.Response.Headers, "alt-svc"); header != nil && report.AdvertisedAt == -1 {
+			for _, service := range ParseAltSvc(header.Value) {
+				if NormalizeHttpVersion(service.Protocol) == "h3" {
+					report.Advertised = append(report.Advertised, service.Protocol+"="+service.Authority+" ma="+strconv.Itoa(service.MaxAge))
+				}
+			}
+			if len(report.Advertised) > 0 {
+				report.AdvertisedAt = indexes[i]
+			}
+		}
+	}
+
+	reports = Filter(reports, func(report *AltSvcReport) bool {
+		return report.AdvertisedAt != -1 || report.UpgradedAt != -1
+	})
+	for _, report := range reports {
+		switch {
+		case report.AdvertisedAt == -1:
+			report.Add(SeverityWarning, "used HTTP/3 without an Alt-Svc header in the capture, it was probably advertised earlier or by DNS")
+		case len(report.After) == 0:
+			report.Add(SeverityWarning, "advertised HTTP/3 on its last request, so there was no chance to upgrade")
+		case report.Upgraded == 0:
+			report.Add(SeverityError, "advertised HTTP/3 but none of the "+strconv.Itoa(len(report.After))+" later requests used it, UDP may be blocked")
+		case report.Upgraded < len(report.After):
+			report.Add(SeverityWarning, strconv.Itoa(len(report.After)-report.Upgraded)+" of "+strconv.Itoa(len(report.After))+" requests after the advertisement still used an older version")
+		}
+	}
+	return reports
+}
+
+// MedianMs is the median of a timing over the entries which measured it
+func MedianMs(entries []Entry, timing func(entry Entry) float64) (float64, bool) {
+	values := make([]float64, 0, len(entries))
+	for _, entry := range entries {
+		if value := timing(entry); value >= 0 {
+			values = append(values, value)
+		}
+	}
+	if len(values) == 0 {
+		return 0, false
+	}
+	sort.Float64s(values)
+	return Percentile(values, 50), true
+}
+
+// ConnectionSetupMs is the time spent on DNS, connecting and the TLS handshake, or -1 if the connection was reused
+func ConnectionSetupMs(entry Entry) float64 {
+	connect := OrUnknown(entry.Timings.Connect)
+	if connect <= 0 {
+		return Unknown
+	}
+	return max(0, OrUnknown(entry.Timings.Dns)) + connect
+}
+
+func FormatMedian(entries []Entry, timing func(entry Entry) float64) string {
+	if median, ok := MedianMs(entries, timing); ok {
+		return FormatDuration(median)
+	}
+	return "-"
+}
+
+func FormatAltSvcReports(reports []*AltSvcReport) string {
+	if len(reports) == 0 {
+		return color.HiBlackString("No origins advertised or used HTTP/3")
+	}
+
+	total := func(entry Entry) float64 { return entry.TimeMs }
+	rows := make([][]string, 0, len(reports))
+	for _, report := range reports {
+		rows = append(rows, []string{
+			report.Origin,
+			Tertiary(report.AdvertisedAt != -1, "#"+strconv.Itoa(report.AdvertisedAt), "-"),
+			Tertiary(report.UpgradedAt != -1, "#"+strconv.Itoa(report.UpgradedAt), "-"),
+			strconv.Itoa(report.Upgraded) + "/" + strconv.Itoa(len(report.After)),
+			FormatMedian(report.Before, total),
+			FormatMedian(report.After, total),
+			FormatMedian(report.Before, ConnectionSetupMs),
+			FormatMedian(report.After, ConnectionSetupMs),
+		})
+	}
+	output := []string{FormatTable([]Column{
+		{Name: "Origin"},
+		{Name: "Advertised", Right: true},
+		{Name: "First h3", Right: true},
+		{Name: "h3 after", Right: true},
+		{Name: "p50 before", Right: true},
+		{Name: "p50 after", Right: true},
+		{Name: "Setup before", Right: true},
+		{Name: "Setup after", Right: true},
+	}, rows)}
+
+	for _, report := range reports {
+		if len(report.Findings) == 0 && len(report.Advertised) == 0 {
+			continue
+		}
+		output = append(output, "", color.YellowString(report.Origin))
+		if len(report.Advertised) > 0 {
+			output = append(output, color.HiBlackString("  Alt-Svc: ")+strings.Join(report.Advertised, ", "))
+		}
+		for _, finding := range report.Findings {
+			output = append(output, "  "+FormatFinding(finding))
+		}
+	}
+	return strings.Join(output, "\n")
+}
+
+func (cmd *AuditAltSvcCmd) Run() error {
+	har, err := ReadHar(cmd.File, false)
+	if err != nil {
+		return err
+	}
+	indexes, err := SelectEntryIndexes(har.Log)
+	if err != nil {
+		return err
+	}
+	entries := make([]Entry, len(indexes))
+	for i, index := range indexes {
+		entries[i] = har.Log.Entries[index]
+	}
+	return WriteOutput(FormatAltSvcReports(AnalyzeAltSvc(entries, indexes)))
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+type AnnotateCmd struct {
+	Entry   []int  `name:"entry" required:"" help:"The index of the entry to comment on, as shown by #N in other commands. Can be given more than once"`
+	Comment string `name:"comment" required:"" help:"The comment to write into the entries, an empty comment removes the existing one"`
+	Append  bool   `name:"append-comment" help:"Add the comment on a new line after any existing comment rather than replacing it"`
+	File    string `arg:"" help:"The HAR file to annotate in place, or - to read it from stdin and write it to stdout" type:"existingfile"`
+}
+
+// AnnotateEntry sets the comment of an entry, which is the field the HAR spec gives users for their own notes
+func AnnotateEntry(entry *Entry, comment string, keep bool) {
+	if keep && entry.Comment != nil && *entry.Comment != "" && comment != "" {
+		comment = *entry.Comment + "\n" + comment
+	}
+	if comment == "" {
+		entry.Comment = nil
+		return
+	}
+	entry.Comment = &comment
+}
+
+func (cmd *AnnotateCmd) Run() error {
+	har, err := ReadHarFile(cmd.File)
+	if err != nil {
+		return err
+	}
+	for _, index := range cmd.Entry {
+		if index < 0 || index >= len(har.Log.Entries) {
+			return errors.New("there is no entry #" + strconv.Itoa(index) + ", the file has " + strconv.Itoa(len(har.Log.Entries)) + " entries")
+		}
+	}
+	for _, index := range cmd.Entry {
+		AnnotateEntry(&har.Log.Entries[index], cmd.Comment, cmd.Append)
+	}
+
+	// Everything harv doesn't model, such as the fields browsers add with an underscore, is kept by the extensions
+	if CLI.Out != nil || cmd.File == "-" {
+		content, err := MarshalHar(har)
+		if err != nil {
+			return err
+		}
+		return WriteOutput(string(content))
+	}
+	if err := WriteHarFile(cmd.File, har); err != nil {
+		return err
+	}
+	indexes := make([]string, len(cmd.Entry))
+	for i, index := range cmd.Entry {
+		indexes[i] = "#" + strconv.Itoa(index)
+	}
+	fmt.Fprintln(os.Stderr, Tertiary(cmd.Comment == "", "Removed the comment from ", "Commented on ")+strings.Join(indexes, ", ")+" in "+cmd.File)
+	return nil
+}
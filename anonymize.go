@@ -0,0 +1,400 @@
+package main
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"net"
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+type AnonymizeCmd struct {
+	Key  *string `name:"key" help:"Secret the pseudonyms are derived from, use the same key to get the same pseudonyms across files. Defaults to a random key so they are only consistent within the file"`
+	File string  `arg:"" help:"The HAR file to anonymize, or - to read it from stdin" type:"existingfile"`
+}
+
+var (
+	emailPattern = regexp.MustCompile(`[A-Za-z0-9._%+-]+@([A-Za-z0-9-]+\.)+[A-Za-z]{2,}`)
+	ipv4Pattern  = regexp.MustCompile(`\b(\d{1,3}\.){3}\d{1,3}\b`)
+	jwtPattern   = regexp.MustCompile(`eyJ[A-Za-z0-9_-]+\.[A-Za-z0-9_-]+\.[A-Za-z0-9_-]*`)
+)
+
+// sensitiveHeaders have values which are credentials in their entirety
+var sensitiveHeaders = []string{"authorization", "proxy-authorization", "x-api-key", "x-auth-token", "x-csrf-token", "x-xsrf-token"}
+
+// sensitiveParameters are query and form parameter names whose values are treated as tokens
+var sensitiveParameters = []string{"token", "key", "secret", "password", "passwd", "session", "auth", "code", "state", "nonce", "signature", "sig", "credential"}
+
+// Pseudonymizer replaces identifying values with pseudonyms derived from an HMAC of the original, so the same value
+// always gets the same pseudonym and requests can still be correlated
+type Pseudonymizer struct {
+	key   []byte
+	hosts *regexp.Regexp
+}
+
+func NewPseudonymizer(key []byte, hosts []string) *Pseudonymizer {
+	pseudonymizer := &Pseudonymizer{key: key}
+	if len(hosts) > 0 {
+		// Longest first so a host is never partly replaced by one of its parent domains
+		sort.Slice(hosts, func(i, j int) bool { return len(hosts[i]) > len(hosts[j]) })
+		quoted := make([]string, len(hosts))
+		for i, host := range hosts {
+			quoted[i] = regexp.QuoteMeta(host)
+		}
+		pseudonymizer.hosts = regexp.MustCompile(`(?i)(^|[^A-Za-z0-9.-])(` + strings.Join(quoted, "|") + `)\b`)
+	}
+	return pseudonymizer
+}
+
+func (pseudonymizer *Pseudonymizer) Hash(kind string, value string) []byte {
+	mac := hmac.New(sha256.New, pseudonymizer.key)
+	mac.Write([]byte(kind + "\x00" + value))
+	return mac.Sum(nil)
+}
+
+// Like replaces every letter and digit with another of the same kind, so IDs and tokens keep their length and format
+func (pseudonymizer *Pseudonymizer) Like(kind string, value string) string {
+	if value == "" {
+		return value
+	}
+	hash := pseudonymizer.Hash(kind, value)
+	output := []rune(value)
+	for i, r := range output {
+		b := int(hash[i%len(hash)]) + i/len(hash)
+		switch {
+		case r >= '0' && r <= '9':
+			output[i] = rune('0' + b%10)
+		case r >= 'a' && r <= 'z':
+			output[i] = rune('a' + b%26)
+		case r >= 'A' && r <= 'Z':
+			output[i] = rune('A' + b%26)
+		}
+	}
+	return string(output)
+}
+
+// Host replaces each label of the host apart from the top level domain, hashing it along with its parents so hosts
+// under the same domain still share a suffix
+func (pseudonymizer *Pseudonymizer) Host(host string) string {
+	name, port, err := net.SplitHostPort(host)
+	if err != nil {
+		name, port = host, ""
+	}
+	if net.ParseIP(strings.Trim(name, "[]")) != nil {
+		name = pseudonymizer.Ip(name)
+	} else if !strings.EqualFold(name, "localhost") {
+		labels := strings.Split(strings.ToLower(name), ".")
+		// The top level domain is kept unless it is the only label
+		last := max(len(labels)-1, 1)
+		for i := 0; i < last; i++ {
+			if labels[i] == "*" || labels[i] == "" {
+				continue
+			}
+			labels[i] = "h" + hex.EncodeToString(pseudonymizer.Hash("host", strings.Join(labels[i:], ".")))[:8]
+		}
+		name = strings.Join(labels, ".")
+	}
+	if port != "" {
+		return net.JoinHostPort(strings.Trim(name, "[]"), port)
+	}
+	return name
+}
+
+// Ip maps IPv4 addresses into 10.0.0.0/8 and IPv6 addresses into fd00::/8 so they are obviously not real
+func (pseudonymizer *Pseudonymizer) Ip(ip string) string {
+	bracketed := strings.HasPrefix(ip, "[")
+	parsed := net.ParseIP(strings.Trim(ip, "[]"))
+	if parsed == nil {
+		return pseudonymizer.Like("ip", ip)
+	}
+	hash := pseudonymizer.Hash("ip", parsed.String())
+	if parsed.To4() != nil {
+		return net.IPv4(10, hash[0], hash[1], hash[2]).String()
+	}
+	pseudonym := append(net.IP{0xfd}, hash[:15]...).String()
+	return Tertiary(bracketed, "["+pseudonym+"]", pseudonym)
+}
+
+func (pseudonymizer *Pseudonymizer) Email(email string) string {
+	_, domain, _ := strings.Cut(email, "@")
+	return "user-" + hex.EncodeToString(pseudonymizer.Hash("email", strings.ToLower(email)))[:8] + "@" + pseudonymizer.Host(domain)
+}
+
+// Text replaces anything recognisable in free text, such as bodies and header values
+func (pseudonymizer *Pseudonymizer) Text(v string) string {
+	v = emailPattern.ReplaceAllStringFunc(v, pseudonymizer.Email)
+	v = jwtPattern.ReplaceAllStringFunc(v, func(token string) string {
+		return pseudonymizer.Like("token", token)
+	})
+	v = ipv4Pattern.ReplaceAllStringFunc(v, func(ip string) string {
+		if net.ParseIP(ip) == nil {
+			return ip
+		}
+		return pseudonymizer.Ip(ip)
+	})
+	if pseudonymizer.hosts != nil {
+		v = pseudonymizer.hosts.ReplaceAllStringFunc(v, func(match string) string {
+			groups := pseudonymizer.hosts.FindStringSubmatch(match)
+			return groups[1] + pseudonymizer.Host(groups[2])
+		})
+	}
+	return v
+}
+
+// Parameter pseudonymizes a query or form value, treating it as a token if the name suggests it is one or as an
+// identifier if it looks like one
+func (pseudonymizer *Pseudonymizer) Parameter(name string, value string) string {
+	lower := strings.ToLower(name)
+	for _, sensitive := range sensitiveParameters {
+		if strings.Contains(lower, sensitive) {
+			return pseudonymizer.Like("token", value)
+		}
+	}
+	if strings.HasSuffix(lower, "id") || IsIdSegment(value) {
+		return pseudonymizer.Like("id", value)
+	}
+	return pseudonymizer.Text(value)
+}
+
+func (pseudonymizer *Pseudonymizer) Query(raw string) string {
+	if raw == "" {
+		return raw
+	}
+	pairs := strings.Split(raw, "&")
+	for i, pair := range pairs {
+		name, value, found := strings.Cut(pair, "=")
+		decoded, err := url.QueryUnescape(value)
+		if !found || err != nil {
+			continue
+		}
+		pairs[i] = name + "=" + url.QueryEscape(pseudonymizer.Parameter(DecodeQueryComponent(name), decoded))
+	}
+	return strings.Join(pairs, "&")
+}
+
+func (pseudonymizer *Pseudonymizer) Url(raw string) string {
+	parsed, err := url.Parse(raw)
+	if err != nil || parsed.Host == "" {
+		return pseudonymizer.Text(raw)
+	}
+	parsed.Host = pseudonymizer.Host(parsed.Host)
+	if parsed.User != nil {
+		parsed.User = url.User(pseudonymizer.Like("user", parsed.User.Username()))
+	}
+
+	segments := strings.Split(parsed.Path, "/")
+	for i, segment := range segments {
+		if IsIdSegment(segment) {
+			segments[i] = pseudonymizer.Like("id", segment)
+		} else if strings.Contains(segment, "@") {
+			segments[i] = pseudonymizer.Text(segment)
+		}
+	}
+	parsed.Path = strings.Join(segments, "/")
+	parsed.RawPath = ""
+	parsed.RawQuery = pseudonymizer.Query(parsed.RawQuery)
+	parsed.Fragment = pseudonymizer.Text(parsed.Fragment)
+	return parsed.String()
+}
+
+func (pseudonymizer *Pseudonymizer) Header(header Header) string {
+	name := strings.ToLower(header.Name)
+	switch {
+	case name == "host" || name == ":authority":
+		return pseudonymizer.Host(header.Value)
+	case name == "origin" || name == "referer" || name == "location" || name == "content-location":
+		return pseudonymizer.Url(header.Value)
+	case name == "cookie" || name == "set-cookie":
+		return pseudonymizer.CookieHeader(header.Value)
+	}
+	for _, sensitive := range sensitiveHeaders {
+		if name == sensitive {
+			// Keep the scheme, eg Bearer, so it is still clear what kind of credential was sent
+			if scheme, credentials, found := strings.Cut(header.Value, " "); found {
+				return scheme + " " + pseudonymizer.Like("token", credentials)
+			}
+			return pseudonymizer.Like("token", header.Value)
+		}
+	}
+	return pseudonymizer.Text(header.Value)
+}
+
+// CookieHeader keeps the cookie names and attributes but replaces every value
+func (pseudonymizer *Pseudonymizer) CookieHeader(v string) string {
+	parts := strings.Split(v, ";")
+	for i, part := range parts {
+		name, value, found := strings.Cut(part, "=")
+		if !found {
+			continue
+		}
+		switch strings.ToLower(strings.TrimSpace(name)) {
+		case "domain":
+			parts[i] = name + "=" + pseudonymizer.Host(value)
+		case "path", "expires", "max-age", "samesite", "priority":
+		default:
+			parts[i] = name + "=" + pseudonymizer.Like("cookie", value)
+		}
+	}
+	return strings.Join(parts, ";")
+}
+
+func (pseudonymizer *Pseudonymizer) Cookies(cookies []Cookie) {
+	for i := range cookies {
+		cookies[i].Value = pseudonymizer.Like("cookie", cookies[i].Value)
+		if cookies[i].Domain != nil {
+			domain := pseudonymizer.Host(strings.TrimPrefix(*cookies[i].Domain, "."))
+			if strings.HasPrefix(*cookies[i].Domain, ".") {
+				domain = "." + domain
+			}
+			cookies[i].Domain = &domain
+		}
+	}
+}
+
+func (pseudonymizer *Pseudonymizer) Headers(headers []Header) {
+	for i := range headers {
+		headers[i].Value = pseudonymizer.Header(headers[i])
+	}
+}
+
+func (pseudonymizer *Pseudonymizer) Body(content *Content) {
+	text, ok := DecodedBody(*content)
+	if !ok {
+		return
+	}
+	text = pseudonymizer.Text(text)
+	if content.Encoding != nil && strings.EqualFold(*content.Encoding, "base64") {
+		text = base64.StdEncoding.EncodeToString([]byte(text))
+	}
+	content.Text = &text
+}
+
+// Extensions are free form, so the best that can be done is replacing anything recognisable in the raw JSON
+func (pseudonymizer *Pseudonymizer) Extensions(extensions Extensions) {
+	for name, raw := range extensions.Fields {
+		extensions.Fields[name] = json.RawMessage(pseudonymizer.Text(string(raw)))
+	}
+}
+
+func (pseudonymizer *Pseudonymizer) StackTrace(stack *StackTrace) {
+	for ; stack != nil; stack = stack.Parent {
+		for i := range stack.CallFrames {
+			stack.CallFrames[i].Url = pseudonymizer.Url(stack.CallFrames[i].Url)
+		}
+	}
+}
+
+func (pseudonymizer *Pseudonymizer) Entry(entry *Entry) {
+	request := &entry.Request
+	request.Url = pseudonymizer.Url(request.Url)
+	pseudonymizer.Headers(request.Headers)
+	pseudonymizer.Cookies(request.Cookies)
+	for i := range request.QueryString {
+		request.QueryString[i].Value = pseudonymizer.Parameter(request.QueryString[i].Name, request.QueryString[i].Value)
+	}
+	if request.PostData != nil {
+		if strings.Contains(request.PostData.MimeType, "x-www-form-urlencoded") {
+			request.PostData.Text = pseudonymizer.Query(request.PostData.Text)
+		} else {
+			request.PostData.Text = pseudonymizer.Text(request.PostData.Text)
+		}
+		for i, param := range request.PostData.Params {
+			if param.Value != nil {
+				value := pseudonymizer.Parameter(param.Name, *param.Value)
+				request.PostData.Params[i].Value = &value
+			}
+		}
+	}
+
+	response := &entry.Response
+	pseudonymizer.Headers(response.Headers)
+	pseudonymizer.Cookies(response.Cookies)
+	if response.RedirectUrl != nil && *response.RedirectUrl != "" {
+		redirect := pseudonymizer.Url(*response.RedirectUrl)
+		response.RedirectUrl = &redirect
+	}
+	if response.Content != nil {
+		pseudonymizer.Body(response.Content)
+	}
+
+	if entry.ServerIP != nil {
+		ip := pseudonymizer.Ip(*entry.ServerIP)
+		entry.ServerIP = &ip
+	}
+	if entry.Initiator != nil {
+		if entry.Initiator.Url != nil {
+			initiator := pseudonymizer.Url(*entry.Initiator.Url)
+			entry.Initiator.Url = &initiator
+		}
+		pseudonymizer.StackTrace(entry.Initiator.Stack)
+	}
+	if entry.SecurityDetails != nil {
+		entry.SecurityDetails.SubjectName = pseudonymizer.Host(entry.SecurityDetails.SubjectName)
+		for i, san := range entry.SecurityDetails.SanList {
+			entry.SecurityDetails.SanList[i] = pseudonymizer.Host(san)
+		}
+	}
+	pseudonymizer.Extensions(entry.Extensions)
+}
+
+// Hostnames collects every host requested in the file, so they can also be found and replaced in free text
+func Hostnames(entries []Entry) []string {
+	seen := make(map[string]bool)
+	hosts := make([]string, 0)
+	for _, entry := range entries {
+		parsed, err := url.Parse(entry.Request.Url)
+		if err != nil || parsed.Hostname() == "" || net.ParseIP(parsed.Hostname()) != nil {
+			continue
+		}
+		host := strings.ToLower(parsed.Hostname())
+		if !seen[host] && strings.Contains(host, ".") {
+			seen[host] = true
+			hosts = append(hosts, host)
+		}
+	}
+	return hosts
+}
+
+func Anonymize(har *HarFile, key []byte) {
+	pseudonymizer := NewPseudonymizer(key, Hostnames(har.Log.Entries))
+	if har.Log.Pages != nil {
+		for i := range *har.Log.Pages {
+			page := &(*har.Log.Pages)[i]
+			page.Title = pseudonymizer.Url(page.Title)
+		}
+	}
+	for i := range har.Log.Entries {
+		pseudonymizer.Entry(&har.Log.Entries[i])
+	}
+}
+
+func (cmd *AnonymizeCmd) Run() error {
+	har, err := ReadHarFile(cmd.File)
+	if err != nil {
+		return err
+	}
+	har.Log.Entries, err = SelectEntries(har.Log)
+	if err != nil {
+		return err
+	}
+
+	key := make([]byte, 32)
+	if cmd.Key != nil {
+		key = []byte(*cmd.Key)
+	} else if _, err := rand.Read(key); err != nil {
+		return err
+	}
+	Anonymize(&har, key)
+
+	content, err := MarshalHar(har)
+	if err != nil {
+		return err
+	}
+	return WriteOutput(string(content))
+}
@@ -0,0 +1,127 @@
+package main
+
+import (
+	"net"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func TestPseudonymizerIsConsistent(t *testing.T) {
+	// Hosts are only replaced in free text when the pseudonymizer was given them
+	first := NewPseudonymizer([]byte("key"), []string{"shop.example.com"})
+	again := NewPseudonymizer([]byte("key"), []string{"shop.example.com"})
+	other := NewPseudonymizer([]byte("other key"), []string{"shop.example.com"})
+	for _, value := range []string{"see shop.example.com", "user@example.com", "93.184.216.34"} {
+		pseudonym := first.Text(value)
+		if pseudonym == value {
+			t.Errorf("%s wasn't replaced", value)
+		}
+		if again.Text(value) != pseudonym {
+			t.Errorf("%s got different pseudonyms with the same key", value)
+		}
+		if other.Text(value) == pseudonym {
+			t.Errorf("%s got the same pseudonym with a different key", value)
+		}
+	}
+}
+
+func TestPseudonymizerLikeKeepsFormat(t *testing.T) {
+	pseudonymizer := NewPseudonymizer([]byte("key"), nil)
+	value := "AbC-123_xyz.Q9"
+	pseudonym := pseudonymizer.Like("token", value)
+	if len(pseudonym) != len(value) || pseudonym == value {
+		t.Fatalf("Like(%q) = %q", value, pseudonym)
+	}
+	for i := range value {
+		kind := func(b byte) string {
+			switch {
+			case b >= '0' && b <= '9':
+				return "digit"
+			case b >= 'a' && b <= 'z':
+				return "lower"
+			case b >= 'A' && b <= 'Z':
+				return "upper"
+			}
+			return string(b)
+		}
+		if kind(value[i]) != kind(pseudonym[i]) {
+			t.Errorf("Like(%q) = %q, character %d changed from %s to %s", value, pseudonym, i, kind(value[i]), kind(pseudonym[i]))
+		}
+	}
+}
+
+func TestPseudonymizerHost(t *testing.T) {
+	pseudonymizer := NewPseudonymizer([]byte("key"), nil)
+	shop := pseudonymizer.Host("shop.example.com")
+	api := pseudonymizer.Host("api.example.com:8443")
+	if !strings.HasSuffix(shop, ".com") || strings.Contains(shop, "example") {
+		t.Errorf("Host(shop.example.com) = %s, expected the top level domain alone to be kept", shop)
+	}
+	// Hosts under the same domain still share it
+	_, domain, _ := strings.Cut(shop, ".")
+	if !strings.HasSuffix(api, "."+domain+":8443") {
+		t.Errorf("Host(api.example.com:8443) = %s, expected it to end in %s:8443", api, domain)
+	}
+	if host := pseudonymizer.Host("localhost:3000"); host != "localhost:3000" {
+		t.Errorf("Host(localhost:3000) = %s", host)
+	}
+}
+
+func TestPseudonymizerIp(t *testing.T) {
+	pseudonymizer := NewPseudonymizer([]byte("key"), nil)
+	if ip := net.ParseIP(pseudonymizer.Ip("93.184.216.34")); ip == nil || !ip.IsPrivate() || ip.To4()[0] != 10 {
+		t.Errorf("expected an IPv4 address in 10.0.0.0/8, got %s", ip)
+	}
+	ip := pseudonymizer.Ip("[2606:2800:220:1:248:1893:25c8:1946]")
+	if !strings.HasPrefix(ip, "[fd") || !strings.HasSuffix(ip, "]") {
+		t.Errorf("expected a bracketed IPv6 address in fd00::/8, got %s", ip)
+	}
+}
+
+func TestPseudonymizerUrl(t *testing.T) {
+	pseudonymizer := NewPseudonymizer([]byte("key"), nil)
+	raw := "https://alice@shop.example.com/users/12345/orders?token=s3cr3tvalue&sort=asc"
+	parsed, err := url.Parse(pseudonymizer.Url(raw))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if parsed.Scheme != "https" || parsed.Host == "shop.example.com" || parsed.User.Username() == "alice" {
+		t.Errorf("expected the host and user of %s to be replaced", parsed)
+	}
+	segments := strings.Split(parsed.Path, "/")
+	if len(segments) != 4 || segments[1] != "users" || segments[3] != "orders" || segments[2] == "12345" || len(segments[2]) != 5 {
+		t.Errorf("expected only the ID in the path to be replaced, got %s", parsed.Path)
+	}
+	query := parsed.Query()
+	if token := query.Get("token"); token == "s3cr3tvalue" || len(token) != len("s3cr3tvalue") {
+		t.Errorf("expected the token to be replaced with one of the same length, got %s", token)
+	}
+	if query.Get("sort") != "asc" {
+		t.Errorf("expected sort=asc to be kept, got %s", query.Get("sort"))
+	}
+}
+
+func TestAnonymizeChromeHar(t *testing.T) {
+	har := readTestHar(t, "testdata/chrome.har")
+	hosts := Hostnames(har.Log.Entries)
+	if len(hosts) == 0 {
+		t.Fatal("expected the capture to have hosts")
+	}
+	Anonymize(&har, []byte("key"))
+	content, err := MarshalHar(har)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, host := range hosts {
+		if strings.Contains(strings.ToLower(string(content)), host) {
+			t.Errorf("%s is still in the anonymized file", host)
+		}
+	}
+	first, _ := url.Parse(har.Log.Entries[0].Request.Url)
+	for _, entry := range har.Log.Entries[1:] {
+		if parsed, _ := url.Parse(entry.Request.Url); parsed.Host != first.Host {
+			t.Errorf("requests to the same host were given different pseudonyms, %s and %s", first.Host, parsed.Host)
+		}
+	}
+}
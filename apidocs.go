@@ -0,0 +1,233 @@
+package main
+
+import (
+	"crypto/rand"
+	"encoding/json"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+type ExportApidocsCmd struct {
+	MaxBody    int    `name:"max-body" default:"2000" help:"The most characters of each example body to include"`
+	NoSanitize bool   `name:"no-sanitize" help:"Show the examples as they were captured instead of replacing tokens, identifiers and emails with pseudonyms"`
+	File       string `arg:"" help:"The HAR file to parse, or - to read it from stdin" type:"existingfile"`
+}
+
+// ApiEndpoint is everything seen of one method and path template, with the entry used as its example
+type ApiEndpoint struct {
+	Method   string
+	Host     string
+	Path     string
+	Calls    int
+	Statuses map[int]int
+	// Params are the query parameters sent on any of the calls, with how many calls sent them and the first value
+	Params      []string
+	ParamCounts map[string]int
+	ParamValues map[string]string
+	Example     *Entry
+}
+
+// isBetterExample prefers a successful call with a response body, since it documents what the endpoint returns
+func isBetterExample(current *Entry, candidate Entry) bool {
+	score := func(entry Entry) int {
+		score := 0
+		if entry.Response.Status >= 200 && entry.Response.Status < 300 {
+			score += 2
+		}
+		if body, ok := EntryBodyText(entry, false); ok && body != "" {
+			score++
+		}
+		return score
+	}
+	return current == nil || score(candidate) > score(*current)
+}
+
+func CollectApiEndpoints(entries []Entry) []*ApiEndpoint {
+	endpoints := make([]*ApiEndpoint, 0)
+	byKey := make(map[string]*ApiEndpoint)
+	for i, entry := range entries {
+		requestUrl, err := url.Parse(entry.Request.Url)
+		if err != nil {
+			continue
+		}
+		key := Endpoint(entry)
+		endpoint, ok := byKey[key]
+		if !ok {
+			endpoint = &ApiEndpoint{
+				Method:      strings.ToUpper(entry.Request.Method),
+				Host:        strings.ToLower(requestUrl.Host),
+				Path:        Tertiary(requestUrl.Path == "", "/", TemplatePath(requestUrl.Path)),
+				Statuses:    make(map[int]int),
+				ParamCounts: make(map[string]int),
+				ParamValues: make(map[string]string),
+			}
+			byKey[key] = endpoint
+			endpoints = append(endpoints, endpoint)
+		}
+		endpoint.Calls++
+		endpoint.Statuses[entry.Response.Status]++
+		seen := make(map[string]bool)
+		for name, values := range requestUrl.Query() {
+			if seen[name] {
+				continue
+			}
+			seen[name] = true
+			if endpoint.ParamCounts[name] == 0 {
+				endpoint.Params = append(endpoint.Params, name)
+				endpoint.ParamValues[name] = values[0]
+			}
+			endpoint.ParamCounts[name]++
+		}
+		if isBetterExample(endpoint.Example, entry) {
+			endpoint.Example = &entries[i]
+		}
+	}
+	for _, endpoint := range endpoints {
+		sort.Strings(endpoint.Params)
+	}
+	sort.SliceStable(endpoints, func(i, j int) bool {
+		if endpoints[i].Host != endpoints[j].Host {
+			return endpoints[i].Host < endpoints[j].Host
+		}
+		if endpoints[i].Path != endpoints[j].Path {
+			return endpoints[i].Path < endpoints[j].Path
+		}
+		return endpoints[i].Method < endpoints[j].Method
+	})
+	return endpoints
+}
+
+// sanitizeJson pseudonymizes the strings of a document, treating each value as a parameter named by its key so fields
+// like password and token are replaced entirely
+func sanitizeJson(pseudonymizer *Pseudonymizer, name string, value any) any {
+	switch typed := value.(type) {
+	case map[string]any:
+		for key, item := range typed {
+			typed[key] = sanitizeJson(pseudonymizer, key, item)
+		}
+	case []any:
+		for i, item := range typed {
+			typed[i] = sanitizeJson(pseudonymizer, name, item)
+		}
+	case string:
+		return pseudonymizer.Parameter(name, typed)
+	}
+	return value
+}
+
+// ExampleBody is the body pretty printed if it is JSON, with the language for its code block
+func ExampleBody(pseudonymizer *Pseudonymizer, body string, mime string, maxBody int) (string, string) {
+	language := ""
+	if value, ok := ParseJsonBody(body); ok {
+		if pseudonymizer != nil {
+			value = sanitizeJson(pseudonymizer, "", value)
+		}
+		if content, err := json.MarshalIndent(value, "", "  "); err == nil {
+			body = string(content)
+			language = "json"
+		}
+	} else {
+		if pseudonymizer != nil {
+			body = Tertiary(strings.Contains(mime, "x-www-form-urlencoded"), pseudonymizer.Query(body), pseudonymizer.Text(body))
+		}
+		if strings.Contains(mime, "xml") {
+			language = "xml"
+		} else if strings.Contains(mime, "html") {
+			language = "html"
+		}
+	}
+
+	if runes := []rune(body); len(runes) > maxBody {
+		body = string(runes[:maxBody]) + "\n... " + strconv.Itoa(len(runes)-maxBody) + " more characters"
+	}
+	// A body containing a fence would end the code block early
+	return strings.ReplaceAll(body, "```", "` ` `"), language
+}
+
+// markdownCode puts a value in inline code which is safe to use in a table cell
+func markdownCode(text string) string {
+	text = strings.NewReplacer("|", "\\|", "`", "'", "\n", " ").Replace(MiddleEllipsis(text, maxSequenceLabel))
+	if text == "" {
+		return ""
+	}
+	return "`" + text + "`"
+}
+
+func FormatApiDocs(endpoints []*ApiEndpoint, pseudonymizer *Pseudonymizer, maxBody int) string {
+	calls := 0
+	for _, endpoint := range endpoints {
+		calls += endpoint.Calls
+	}
+	lines := []string{"# API documentation", "", "Generated from " + strconv.Itoa(calls) + " captured requests to " + strconv.Itoa(len(endpoints)) + " endpoints."}
+	if pseudonymizer != nil {
+		lines = append(lines, "Tokens, identifiers and emails in the examples have been replaced with pseudonyms.")
+	}
+
+	host := ""
+	for _, endpoint := range endpoints {
+		if endpoint.Host != host {
+			host = endpoint.Host
+			lines = append(lines, "", "## "+host)
+		}
+		lines = append(lines, "", "### "+endpoint.Method+" "+strings.ReplaceAll(endpoint.Path, "_", "\\_"), "")
+
+		statuses := make([]int, 0, len(endpoint.Statuses))
+		for status := range endpoint.Statuses {
+			statuses = append(statuses, status)
+		}
+		sort.Ints(statuses)
+		seen := make([]string, len(statuses))
+		for i, status := range statuses {
+			seen[i] = Tertiary(status == 0, "failed", strconv.Itoa(status)) + " (" + strconv.Itoa(endpoint.Statuses[status]) + ")"
+		}
+		lines = append(lines, "Calls: "+strconv.Itoa(endpoint.Calls)+". Status codes: "+strings.Join(seen, ", ")+".")
+
+		if len(endpoint.Params) > 0 {
+			lines = append(lines, "", "| Query parameter | Sent on | Example |", "| --- | --- | --- |")
+			for _, name := range endpoint.Params {
+				value := endpoint.ParamValues[name]
+				if pseudonymizer != nil {
+					value = pseudonymizer.Parameter(name, value)
+				}
+				lines = append(lines, "| "+markdownCode(name)+" | "+strconv.Itoa(endpoint.ParamCounts[name])+" of "+
+					strconv.Itoa(endpoint.Calls)+" | "+markdownCode(value)+" |")
+			}
+		}
+
+		example := endpoint.Example
+		if postData := example.Request.PostData; postData != nil && postData.Text != "" {
+			body, language := ExampleBody(pseudonymizer, postData.Text, postData.MimeType, maxBody)
+			mime := Tertiary(postData.MimeType == "", "[none]", postData.MimeType)
+			lines = append(lines, "", "Request body ("+markdownCode(mime)+"):", "", "```"+language, body, "```")
+		}
+		if body, ok := EntryBodyText(*example, false); ok && body != "" {
+			mime := MimeType(*example)
+			body, language := ExampleBody(pseudonymizer, body, mime, maxBody)
+			lines = append(lines, "", "Response "+strconv.Itoa(example.Response.Status)+" ("+markdownCode(mime)+"):", "", "```"+language, body, "```")
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+func (cmd *ExportApidocsCmd) Run() error {
+	har, err := ReadHarFile(cmd.File)
+	if err != nil {
+		return err
+	}
+	entries, err := SelectEntries(har.Log)
+	if err != nil {
+		return err
+	}
+
+	var pseudonymizer *Pseudonymizer
+	if !cmd.NoSanitize {
+		key := make([]byte, 32)
+		if _, err := rand.Read(key); err != nil {
+			return err
+		}
+		pseudonymizer = NewPseudonymizer(key, nil)
+	}
+	return WriteOutput(FormatApiDocs(CollectApiEndpoints(entries), pseudonymizer, cmd.MaxBody))
+}
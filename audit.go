@@ -0,0 +1,191 @@
+package main
+
+import (
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/fatih/color"
+)
+
+type AuditCmd struct {
+	Tls          AuditTlsCmd          `cmd:"" name:"tls" help:"Flag old TLS versions, weak ciphers and expiring certificates (requires Chrome _securityDetails)"`
+	AltSvc       AuditAltSvcCmd       `cmd:"" name:"alt-svc" help:"Check whether origins advertising HTTP/3 with Alt-Svc were upgraded to it, and compare timings before and after"`
+	Certs        AuditCertsCmd        `cmd:"" name:"certs" help:"Summarise the certificate of each origin with its issuer, validity, days to expiry and whether its names cover the host (requires Chrome _securityDetails)"`
+	Revalidation AuditRevalidationCmd `cmd:"" name:"revalidation" help:"Pair If-None-Match and If-Modified-Since requests with their 304 or 200 responses and flag assets re-downloaded with an unchanged ETag"`
+	Dns          AuditDnsCmd          `cmd:"" name:"dns" help:"Total the DNS lookup time of each hostname and flag hosts resolved repeatedly or slowly"`
+	SchemaDrift  AuditSchemaDriftCmd  `cmd:"" name:"schema-drift" help:"Flag fields of JSON responses which appear, disappear or change type part way through the capture, or compared to a --baseline capture"`
+	Vary         AuditVaryCmd         `cmd:"" name:"vary" help:"List the Vary headers of each endpoint and flag high cardinality cache keys and cache busting"`
+	Hsts         AuditHstsCmd         `cmd:"" name:"hsts" help:"List hosts served over HTTPS without Strict-Transport-Security or with a short max-age, and plain HTTP requests to hosts which advertise it"`
+	Csp          AuditCspCmd          `cmd:"" name:"csp" help:"Check the subresources of each page against its Content-Security-Policy and list the sources it would need"`
+}
+
+type AuditTlsCmd struct {
+	ExpiryDays int    `name:"expiry-days" default:"30" help:"Certificates expiring within this many days will be flagged"`
+	File       string `arg:"" help:"The HAR file to parse, or - to read it from stdin" type:"existingfile"`
+}
+
+const (
+	SeverityError   = "error"
+	SeverityWarning = "warning"
+)
+
+type Finding struct {
+	Severity string
+	Message  string
+}
+
+// AuditGroup collects the findings for a single origin, keeping them in the order they were first raised
+type AuditGroup struct {
+	Name     string
+	Summary  string
+	Entries  int
+	Findings []Finding
+	seen     map[string]bool
+}
+
+func (group *AuditGroup) Add(severity string, message string) {
+	if group.seen == nil {
+		group.seen = make(map[string]bool)
+	}
+	if group.seen[message] {
+		return
+	}
+	group.seen[message] = true
+	group.Findings = append(group.Findings, Finding{Severity: severity, Message: message})
+}
+
+func FormatFinding(finding Finding) string {
+	if finding.Severity == SeverityError {
+		return color.RedString("[error] ") + finding.Message
+	}
+	return color.YellowString("[warning] ") + finding.Message
+}
+
+func FormatAuditGroups(groups []*AuditGroup) string {
+	if len(groups) == 0 {
+		return color.HiBlackString("No matching entries to audit")
+	}
+
+	output := make([]string, 0)
+	for _, group := range groups {
+		header := color.YellowString(group.Name)
+		if group.Summary != "" {
+			header += color.HiBlackString(" (" + group.Summary + ")")
+		}
+		header += color.HiBlackString(" " + strconv.Itoa(group.Entries) + Tertiary(group.Entries == 1, " entry", " entries"))
+		output = append(output, header)
+
+		if len(group.Findings) == 0 {
+			output = append(output, "  "+color.GreenString("no issues found"))
+		}
+		for _, finding := range group.Findings {
+			output = append(output, "  "+FormatFinding(finding))
+		}
+	}
+
+	return strings.Join(output, "\n")
+}
+
+func Origin(requestUrl string) string {
+	parsed, err := url.Parse(requestUrl)
+	if err != nil {
+		return requestUrl
+	}
+	return parsed.Scheme + "://" + parsed.Host
+}
+
+func CipherWeakness(cipher string, mac string) (string, string) {
+	upper := strings.ToUpper(cipher + " " + mac)
+	for _, weak := range []string{"NULL", "EXPORT", "ANON", "RC4", "3DES", "DES_", "IDEA", "MD5"} {
+		if strings.Contains(upper, weak) {
+			return SeverityError, "weak cipher " + cipher + Tertiary(mac != "", " with "+mac, "")
+		}
+	}
+	if strings.Contains(upper, "CBC") || strings.Contains(upper, "SHA1") {
+		return SeverityWarning, "obsolete cipher " + cipher + Tertiary(mac != "", " with "+mac, "")
+	}
+	return "", ""
+}
+
+func AuditTls(entries []Entry, expiryWindow time.Duration, now time.Time) []*AuditGroup {
+	groups := make([]*AuditGroup, 0)
+	byOrigin := make(map[string]*AuditGroup)
+
+	for _, entry := range entries {
+		details := entry.SecurityDetails
+		if details == nil {
+			continue
+		}
+
+		origin := Origin(entry.Request.Url)
+		group, ok := byOrigin[origin]
+		if !ok {
+			group = &AuditGroup{Name: origin, Summary: strings.Join(Filter([]string{details.Protocol, details.Cipher}, func(part string) bool {
+				return part != ""
+			}), ", ")}
+			byOrigin[origin] = group
+			groups = append(groups, group)
+		}
+		group.Entries++
+
+		switch strings.ToUpper(details.Protocol) {
+		case "SSL 2.0", "SSL 3.0", "TLS 1.0":
+			group.Add(SeverityError, "deprecated protocol "+details.Protocol)
+		case "TLS 1.1":
+			group.Add(SeverityWarning, "deprecated protocol "+details.Protocol)
+		}
+
+		mac := ""
+		if details.Mac != nil {
+			mac = *details.Mac
+		}
+		if severity, message := CipherWeakness(details.Cipher, mac); message != "" {
+			group.Add(severity, message)
+		}
+		if strings.EqualFold(details.KeyExchange, "RSA") {
+			group.Add(SeverityWarning, "RSA key exchange does not provide forward secrecy")
+		}
+
+		// Chrome leaves out the certificate when the connection was reused, which would read as the epoch
+		certificate := CertificateName(*details)
+		if details.ValidTo != 0 {
+			validTo := UnixSeconds(details.ValidTo)
+			if validTo.Before(now) {
+				group.Add(SeverityError, certificate+" expired on "+validTo.Format(time.DateOnly))
+			} else if validTo.Before(now.Add(expiryWindow)) {
+				days := int(validTo.Sub(now).Hours() / 24)
+				group.Add(SeverityWarning, certificate+" expires in "+strconv.Itoa(days)+" days ("+validTo.Format(time.DateOnly)+")")
+			}
+		}
+		if details.ValidFrom != 0 && UnixSeconds(details.ValidFrom).After(now) {
+			group.Add(SeverityError, certificate+" is not valid until "+UnixSeconds(details.ValidFrom).Format(time.DateOnly))
+		}
+
+		if entry.SecurityState != nil && *entry.SecurityState == "insecure" {
+			group.Add(SeverityError, "browser reported the connection as insecure")
+		}
+	}
+
+	return groups
+}
+
+// CertificateName is how findings refer to a certificate, which only has a subject when the HAR recorded one
+func CertificateName(details SecurityDetails) string {
+	return Tertiary(details.SubjectName == "", "certificate", "certificate for "+details.SubjectName)
+}
+
+func (cmd *AuditTlsCmd) Run() error {
+	har, err := ReadHar(cmd.File, false)
+	if err != nil {
+		return err
+	}
+	validEntries, err := SelectEntries(har.Log)
+	if err != nil {
+		return err
+	}
+
+	groups := AuditTls(validEntries, time.Duration(cmd.ExpiryDays)*24*time.Hour, time.Now())
+	return WriteOutput(FormatAuditGroups(groups))
+}
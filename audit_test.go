@@ -0,0 +1,47 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/fatih/color"
+)
+
+func TestAuditTlsWithoutCertificate(t *testing.T) {
+	har := readTestHar(t, "testdata/chrome.har")
+	groups := AuditTls(har.Log.Entries, 30*24*time.Hour, time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC))
+	if len(groups) != 1 {
+		t.Fatalf("found %d origins, expected 1", len(groups))
+	}
+	if len(groups[0].Findings) != 0 {
+		t.Errorf("a connection without certificate dates was reported as %+v", groups[0].Findings)
+	}
+	if groups[0].Summary != "TLS 1.3" {
+		t.Errorf("summary is %q, expected TLS 1.3", groups[0].Summary)
+	}
+
+	color.NoColor = true
+	output := FormatSecurityDetails(*har.Log.Entries[0].SecurityDetails, nil)
+	if strings.Contains(output, "Valid:") || strings.Contains(output, "1970") || strings.Contains(output, "Subject:") {
+		t.Errorf("printed the missing certificate fields:\n%s", output)
+	}
+}
+
+func TestAuditTlsExpiredCertificate(t *testing.T) {
+	now := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	entries := []Entry{{
+		Request: Request{Method: "GET", Url: "https://example.com/"},
+		SecurityDetails: &SecurityDetails{
+			Protocol:    "TLS 1.3",
+			Cipher:      "AES_128_GCM",
+			SubjectName: "example.com",
+			ValidFrom:   float64(now.AddDate(-1, 0, 0).Unix()),
+			ValidTo:     float64(now.AddDate(0, 0, -1).Unix()),
+		},
+	}}
+	groups := AuditTls(entries, 30*24*time.Hour, now)
+	if len(groups) != 1 || len(groups[0].Findings) != 1 || groups[0].Findings[0].Message != "certificate for example.com expired on 2024-02-29" {
+		t.Errorf("unexpected findings %+v", groups[0].Findings)
+	}
+}
@@ -0,0 +1,261 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/fatih/color"
+)
+
+type BodyDiffCmd struct {
+	Request bool   `name:"request" help:"Compare the request bodies instead of the response bodies"`
+	Context int    `name:"context" default:"3" help:"How many unchanged lines to show around each change in a text diff"`
+	File    string `arg:"" help:"The HAR file to parse, or - to read it from stdin" type:"existingfile"`
+	First   int    `arg:"" help:"The index of the first entry, as shown by #N in other commands"`
+	Second  int    `arg:"" help:"The index of the entry to compare it with"`
+}
+
+// maxDiffCells bounds the table used to line up two texts, bodies larger than this after removing the lines they share
+// at the start and end are only reported as different
+const maxDiffCells = 16_000_000
+
+// CompactJson shortens a value for showing on one line of a diff
+func CompactJson(value any) string {
+	content, err := json.Marshal(value)
+	if err != nil {
+		return "?"
+	}
+	text := string(content)
+	if len(text) > 80 {
+		text = text[:77] + "..."
+	}
+	return text
+}
+
+// DiffJson walks two documents together and describes each value which was removed, added or changed, by path
+func DiffJson(path string, before any, after any) []string {
+	beforeObject, beforeIsObject := before.(map[string]any)
+	afterObject, afterIsObject := after.(map[string]any)
+	if beforeIsObject && afterIsObject {
+		keys := make([]string, 0, len(beforeObject)+len(afterObject))
+		for key := range beforeObject {
+			keys = append(keys, key)
+		}
+		for key := range afterObject {
+			if _, ok := beforeObject[key]; !ok {
+				keys = append(keys, key)
+			}
+		}
+		sort.Strings(keys)
+
+		lines := make([]string, 0)
+		for _, key := range keys {
+			beforeValue, inBefore := beforeObject[key]
+			afterValue, inAfter := afterObject[key]
+			switch {
+			case !inAfter:
+				lines = append(lines, color.RedString("- "+path+"."+key+": "+CompactJson(beforeValue)))
+			case !inBefore:
+				lines = append(lines, color.GreenString("+ "+path+"."+key+": "+CompactJson(afterValue)))
+			default:
+				lines = append(lines, DiffJson(path+"."+key, beforeValue, afterValue)...)
+			}
+		}
+		return lines
+	}
+
+	beforeArray, beforeIsArray := before.([]any)
+	afterArray, afterIsArray := after.([]any)
+	if beforeIsArray && afterIsArray {
+		lines := make([]string, 0)
+		for i := 0; i < max(len(beforeArray), len(afterArray)); i++ {
+			itemPath := path + "[" + strconv.Itoa(i) + "]"
+			switch {
+			case i >= len(afterArray):
+				lines = append(lines, color.RedString("- "+itemPath+": "+CompactJson(beforeArray[i])))
+			case i >= len(beforeArray):
+				lines = append(lines, color.GreenString("+ "+itemPath+": "+CompactJson(afterArray[i])))
+			default:
+				lines = append(lines, DiffJson(itemPath, beforeArray[i], afterArray[i])...)
+			}
+		}
+		return lines
+	}
+
+	beforeContent, _ := json.Marshal(before)
+	afterContent, _ := json.Marshal(after)
+	if string(beforeContent) == string(afterContent) {
+		return nil
+	}
+	return []string{color.YellowString("~ "+path+": ") + CompactJson(before) + color.YellowString(" -> ") + CompactJson(after)}
+}
+
+// DiffLines produces a unified diff of two texts, lining them up with the longest common subsequence of their lines
+func DiffLines(before string, after string, context int) ([]string, error) {
+	a := strings.Split(before, "\n")
+	b := strings.Split(after, "\n")
+
+	prefix := 0
+	for prefix < len(a) && prefix < len(b) && a[prefix] == b[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(a)-prefix && suffix < len(b)-prefix && a[len(a)-1-suffix] == b[len(b)-1-suffix] {
+		suffix++
+	}
+	middleA := a[prefix : len(a)-suffix]
+	middleB := b[prefix : len(b)-suffix]
+	if (len(middleA)+1)*(len(middleB)+1) > maxDiffCells {
+		return nil, errors.New("the bodies are too large to compare line by line, they differ from line " + strconv.Itoa(prefix+1))
+	}
+
+	// lengths[i][j] is the length of the longest common subsequence of middleA[i:] and middleB[j:]
+	lengths := make([][]int32, len(middleA)+1)
+	for i := range lengths {
+		lengths[i] = make([]int32, len(middleB)+1)
+	}
+	for i := len(middleA) - 1; i >= 0; i-- {
+		for j := len(middleB) - 1; j >= 0; j-- {
+			if middleA[i] == middleB[j] {
+				lengths[i][j] = lengths[i+1][j+1] + 1
+			} else {
+				lengths[i][j] = max(lengths[i+1][j], lengths[i][j+1])
+			}
+		}
+	}
+
+	type op struct {
+		kind byte
+		text string
+		a, b int
+	}
+	ops := make([]op, 0, len(a)+len(b))
+	for i := 0; i < prefix; i++ {
+		ops = append(ops, op{' ', a[i], i, i})
+	}
+	i, j := 0, 0
+	for i < len(middleA) || j < len(middleB) {
+		switch {
+		case i < len(middleA) && j < len(middleB) && middleA[i] == middleB[j]:
+			ops = append(ops, op{' ', middleA[i], prefix + i, prefix + j})
+			i++
+			j++
+		case i < len(middleA) && (j == len(middleB) || lengths[i+1][j] >= lengths[i][j+1]):
+			ops = append(ops, op{'-', middleA[i], prefix + i, prefix + j})
+			i++
+		default:
+			ops = append(ops, op{'+', middleB[j], prefix + i, prefix + j})
+			j++
+		}
+	}
+	for k := 0; k < suffix; k++ {
+		ops = append(ops, op{' ', a[len(a)-suffix+k], len(a) - suffix + k, len(b) - suffix + k})
+	}
+
+	// Group the changes into hunks, merging changes whose context would overlap
+	changes := make([]int, 0)
+	for k, o := range ops {
+		if o.kind != ' ' {
+			changes = append(changes, k)
+		}
+	}
+	lines := make([]string, 0)
+	for c := 0; c < len(changes); {
+		last := c
+		for last+1 < len(changes) && changes[last+1]-changes[last] <= 2*context+1 {
+			last++
+		}
+		from := max(0, changes[c]-context)
+		to := min(len(ops), changes[last]+context+1)
+		c = last + 1
+
+		countA, countB := 0, 0
+		for _, o := range ops[from:to] {
+			if o.kind != '+' {
+				countA++
+			}
+			if o.kind != '-' {
+				countB++
+			}
+		}
+		lines = append(lines, color.CyanString("@@ -"+strconv.Itoa(ops[from].a+1)+","+strconv.Itoa(countA)+
+			" +"+strconv.Itoa(ops[from].b+1)+","+strconv.Itoa(countB)+" @@"))
+		for _, o := range ops[from:to] {
+			switch o.kind {
+			case '-':
+				lines = append(lines, color.RedString("-"+o.text))
+			case '+':
+				lines = append(lines, color.GreenString("+"+o.text))
+			default:
+				lines = append(lines, " "+o.text)
+			}
+		}
+	}
+	return lines, nil
+}
+
+// EntryBodyText is the decoded request or response body, and whether there was one
+func EntryBodyText(entry Entry, request bool) (string, bool) {
+	if request {
+		if entry.Request.PostData == nil {
+			return "", false
+		}
+		return entry.Request.PostData.Text, true
+	}
+	if entry.Response.Content == nil {
+		return "", false
+	}
+	return DecodedBody(*entry.Response.Content)
+}
+
+func FormatBodyDiffHeader(marker string, index int, entry Entry) string {
+	return color.HiBlackString(marker+" #"+strconv.Itoa(index)+" ") + MethodColor(entry.Request.Method)(entry.Request.Method) + " " +
+		StatusColor(entry.Response.Status)(strconv.Itoa(entry.Response.Status)) + " " + entry.Request.Url
+}
+
+// DiffBodies compares the bodies structurally when both are JSON, and line by line otherwise
+func DiffBodies(before string, after string, context int) ([]string, error) {
+	beforeJson, beforeOk := ParseJsonBody(before)
+	afterJson, afterOk := ParseJsonBody(after)
+	if beforeOk && afterOk {
+		return DiffJson("$", beforeJson, afterJson), nil
+	}
+	return DiffLines(before, after, context)
+}
+
+func (cmd *BodyDiffCmd) Run() error {
+	har, err := ReadHarFile(cmd.File)
+	if err != nil {
+		return err
+	}
+	entries := har.Log.Entries
+	for _, index := range []int{cmd.First, cmd.Second} {
+		if index < 0 || index >= len(entries) {
+			return errors.New("there is no entry #" + strconv.Itoa(index) + ", the file has " + strconv.Itoa(len(entries)) + " entries")
+		}
+	}
+	first := entries[cmd.First]
+	second := entries[cmd.Second]
+	part := Tertiary(cmd.Request, "request", "response")
+	before, ok := EntryBodyText(first, cmd.Request)
+	if !ok {
+		return errors.New("entry #" + strconv.Itoa(cmd.First) + " has no " + part + " body, or it is binary")
+	}
+	after, ok := EntryBodyText(second, cmd.Request)
+	if !ok {
+		return errors.New("entry #" + strconv.Itoa(cmd.Second) + " has no " + part + " body, or it is binary")
+	}
+
+	lines, err := DiffBodies(before, after, cmd.Context)
+	if err != nil {
+		return err
+	}
+	output := []string{FormatBodyDiffHeader("---", cmd.First, first), FormatBodyDiffHeader("+++", cmd.Second, second)}
+	if len(lines) == 0 {
+		output = append(output, color.GreenString("The "+part+" bodies are the same"))
+	}
+	return WriteOutput(strings.Join(append(output, lines...), "\n"))
+}
@@ -0,0 +1,186 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+type ExportBrunoCmd struct {
+	OutDir string  `name:"out-dir" required:"" help:"The directory to write the collection to, it is created if it doesn't exist"`
+	Name   *string `name:"name" help:"The name of the collection, defaults to the name of the file"`
+	File   string  `arg:"" help:"The HAR file to parse, or - to read it from stdin" type:"existingfile"`
+}
+
+// brunoMethods are the methods Bruno can send, requests using anything else are left out of the collection
+var brunoMethods = []string{"get", "post", "put", "delete", "patch", "options", "head"}
+
+// brunoBlock writes a named block of the .bru format, whose content is indented by two spaces
+func brunoBlock(name string, lines []string) string {
+	for i, line := range lines {
+		lines[i] = "  " + line
+	}
+	return name + " {\n" + strings.Join(lines, "\n") + "\n}\n"
+}
+
+// brunoPairs writes headers and parameters as the name: value lines of a block. Bruno reads a value to the end of the
+// line, so newlines are replaced with spaces
+func brunoPairs(pairs []QueryParameter) []string {
+	lines := make([]string, len(pairs))
+	for i, pair := range pairs {
+		lines[i] = strings.ReplaceAll(pair.Name, ":", "%3A") + ": " + strings.NewReplacer("\r", "", "\n", " ").Replace(pair.Value)
+	}
+	return lines
+}
+
+// BrunoBodyMode is the body type Bruno should edit the body as, and the name of the block it is written in
+func BrunoBodyMode(postData PostData) (mode string, block string) {
+	mime := strings.ToLower(postData.MimeType)
+	switch {
+	case strings.Contains(mime, "x-www-form-urlencoded"):
+		return "formUrlEncoded", "body:form-urlencoded"
+	case strings.Contains(mime, "json"):
+		return "json", "body:json"
+	case strings.Contains(mime, "xml"):
+		return "xml", "body:xml"
+	}
+	return "text", "body:text"
+}
+
+// FormatBruRequest writes an entry in Bruno's .bru format
+func FormatBruRequest(seq int, entry Entry) string {
+	request := entry.Request
+	name := strings.ToUpper(request.Method) + " " + request.Url
+	query := make([]QueryParameter, 0)
+	if requestUrl, err := url.Parse(request.Url); err == nil {
+		name = strings.ToUpper(request.Method) + " " + MiddleEllipsis(Tertiary(requestUrl.Path == "", "/", requestUrl.Path), maxSequenceLabel)
+		for _, pair := range strings.Split(requestUrl.RawQuery, "&") {
+			if pair != "" {
+				key, value, _ := strings.Cut(pair, "=")
+				query = append(query, QueryParameter{Name: DecodeQueryComponent(key), Value: DecodeQueryComponent(value)})
+			}
+		}
+	}
+
+	auth := RequestAuth(request)
+	hasBody := request.PostData != nil && (request.PostData.Text != "" || len(request.PostData.Params) > 0)
+	bodyMode, bodyBlock := "none", ""
+	if hasBody {
+		bodyMode, bodyBlock = BrunoBodyMode(*request.PostData)
+	}
+	authMode := "none"
+	if auth != nil {
+		authMode = auth.Type
+	}
+
+	blocks := []string{
+		brunoBlock("meta", []string{"name: " + strings.ReplaceAll(name, "\n", " "), "type: http", "seq: " + strconv.Itoa(seq)}),
+		brunoBlock(strings.ToLower(request.Method), []string{"url: " + request.Url, "body: " + bodyMode, "auth: " + authMode}),
+	}
+	if len(query) > 0 {
+		blocks = append(blocks, brunoBlock("params:query", brunoPairs(query)))
+	}
+	if headers := ReplayHeaders(request, auth != nil); len(headers) > 0 {
+		pairs := make([]QueryParameter, len(headers))
+		for i, header := range headers {
+			pairs[i] = QueryParameter{Name: header.Name, Value: header.Value}
+		}
+		blocks = append(blocks, brunoBlock("headers", brunoPairs(pairs)))
+	}
+	switch {
+	case auth == nil:
+	case auth.Type == "bearer":
+		blocks = append(blocks, brunoBlock("auth:bearer", []string{"token: " + auth.Token}))
+	case auth.Type == "basic":
+		blocks = append(blocks, brunoBlock("auth:basic", []string{"username: " + auth.Username, "password: " + auth.Password}))
+	}
+	if hasBody {
+		if fields, ok := FormFields(*request.PostData); ok {
+			blocks = append(blocks, brunoBlock(bodyBlock, brunoPairs(fields)))
+		} else {
+			blocks = append(blocks, brunoBlock(bodyBlock, strings.Split(ReplayBody(*request.PostData), "\n")))
+		}
+	}
+	return strings.Join(blocks, "\n")
+}
+
+// WriteBrunoCollection writes the collection file and a folder of requests for each host, returning how many of the
+// entries used a method Bruno can't send
+func WriteBrunoCollection(dir string, name string, entries []Entry) (int, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return 0, err
+	}
+	collection, err := json.MarshalIndent(map[string]any{"version": "1", "name": name, "type": "collection", "ignore": []string{"node_modules", ".git"}}, "", "  ")
+	if err != nil {
+		return 0, err
+	}
+	if err := os.WriteFile(filepath.Join(dir, "bruno.json"), append(collection, '\n'), 0644); err != nil {
+		return 0, err
+	}
+
+	skipped := 0
+	seqs := make(map[string]int)
+	for _, entry := range entries {
+		method := strings.ToLower(entry.Request.Method)
+		if !slices.Contains(brunoMethods, method) {
+			skipped++
+			continue
+		}
+		host, path := "invalid-url", entry.Request.Url
+		if requestUrl, err := url.Parse(entry.Request.Url); err == nil {
+			host, path = strings.ToLower(requestUrl.Host), requestUrl.Path
+		}
+		folder := filepath.Join(dir, BrunoFolderName(host))
+		if seqs[folder] == 0 {
+			if err := os.MkdirAll(folder, 0755); err != nil {
+				return skipped, err
+			}
+		}
+		seqs[folder]++
+		file := filepath.Join(folder, ExportFileName(seqs[folder], method, path, ".bru"))
+		if err := os.WriteFile(file, []byte(FormatBruRequest(seqs[folder], entry)), 0644); err != nil {
+			return skipped, err
+		}
+	}
+	return skipped, nil
+}
+
+// BrunoFolderName is the folder of a host's requests, cleaned so a hostile host like .. can't write outside the
+// collection. Ports are written with an underscore since Windows doesn't allow colons in directory names
+func BrunoFolderName(host string) string {
+	name := strings.Trim(unsafeFileName.ReplaceAllString(strings.ReplaceAll(host, ":", "_"), "-"), "-")
+	if strings.Trim(name, ".") == "" {
+		return "invalid-host"
+	}
+	return name
+}
+
+func (cmd *ExportBrunoCmd) Run() error {
+	har, err := ReadHarFile(cmd.File)
+	if err != nil {
+		return err
+	}
+	entries, err := SelectEntries(har.Log)
+	if err != nil {
+		return err
+	}
+	name := CollectionName(cmd.File)
+	if cmd.Name != nil {
+		name = *cmd.Name
+	}
+	skipped, err := WriteBrunoCollection(cmd.OutDir, name, entries)
+	if err != nil {
+		return err
+	}
+	message := "Wrote " + strconv.Itoa(len(entries)-skipped) + " requests to " + cmd.OutDir
+	if skipped > 0 {
+		message += ", leaving out " + strconv.Itoa(skipped) + " using methods Bruno doesn't support"
+	}
+	fmt.Fprintln(os.Stderr, message)
+	return nil
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"io/fs"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestBrunoFolderName(t *testing.T) {
+	cases := map[string]string{
+		"example.com":      "example.com",
+		"example.com:8443": "example.com_8443",
+		"..":               "invalid-host",
+		".":                "invalid-host",
+		"":                 "invalid-host",
+		"../../etc":        "..-..-etc",
+		`a\..\b`:           "a-..-b",
+	}
+	for host, expected := range cases {
+		if name := BrunoFolderName(host); name != expected {
+			t.Errorf("BrunoFolderName(%q) = %q, expected %q", host, name, expected)
+		}
+	}
+}
+
+func TestWriteBrunoCollectionStaysInDir(t *testing.T) {
+	root := t.TempDir()
+	dir := filepath.Join(root, "collection")
+	entries := []Entry{
+		{Request: Request{Method: "GET", Url: "http://../../etc/passwd"}},
+		{Request: Request{Method: "GET", Url: "http://./x"}},
+		{Request: Request{Method: "GET", Url: "https://example.com/ok"}},
+	}
+	if _, err := WriteBrunoCollection(dir, "test", entries); err != nil {
+		t.Fatal(err)
+	}
+
+	written := 0
+	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		if !strings.HasPrefix(path, dir+string(filepath.Separator)) {
+			t.Errorf("wrote %s outside of %s", path, dir)
+		}
+		if strings.HasSuffix(path, ".bru") {
+			written++
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if written != len(entries) {
+		t.Errorf("wrote %d requests, expected %d", written, len(entries))
+	}
+}
@@ -0,0 +1,370 @@
+package main
+
+import (
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"encoding/base64"
+	"encoding/xml"
+	"errors"
+	"io"
+	"net/http/httputil"
+	"net/url"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+type ExportBurpCmd struct {
+	File string `arg:"" help:"The HAR file to parse, or - to read it from stdin" type:"existingfile"`
+}
+
+type ImportBurpCmd struct {
+	File string `arg:"" help:"The XML file written by Burp's Save items, or - to read it from stdin" type:"existingfile"`
+}
+
+// burpTimeLayout is how Burp writes the time of each item, eg Mon Jan 02 15:04:05 UTC 2006
+const burpTimeLayout = "Mon Jan 02 15:04:05 MST 2006"
+
+// BurpData is a value Burp wraps in CDATA, with requests and responses base64 encoded
+type BurpData struct {
+	Base64 bool   `xml:"base64,attr,omitempty"`
+	Text   string `xml:",cdata"`
+}
+
+func (data BurpData) Bytes() ([]byte, error) {
+	if data.Base64 {
+		return base64.StdEncoding.DecodeString(strings.TrimSpace(data.Text))
+	}
+	return []byte(data.Text), nil
+}
+
+type BurpHost struct {
+	Ip   string `xml:"ip,attr"`
+	Name string `xml:",chardata"`
+}
+
+type BurpItem struct {
+	Time           string   `xml:"time"`
+	Url            BurpData `xml:"url"`
+	Host           BurpHost `xml:"host"`
+	Port           string   `xml:"port"`
+	Protocol       string   `xml:"protocol"`
+	Method         BurpData `xml:"method"`
+	Path           BurpData `xml:"path"`
+	Extension      string   `xml:"extension"`
+	Request        BurpData `xml:"request"`
+	Status         string   `xml:"status"`
+	ResponseLength string   `xml:"responselength"`
+	MimeType       string   `xml:"mimetype"`
+	Response       BurpData `xml:"response"`
+	Comment        string   `xml:"comment"`
+}
+
+// BurpItems is the document written by Save items in Burp's proxy history and site map
+type BurpItems struct {
+	XMLName     xml.Name   `xml:"items"`
+	BurpVersion string     `xml:"burpVersion,attr"`
+	ExportTime  string     `xml:"exportTime,attr"`
+	Items       []BurpItem `xml:"item"`
+}
+
+// burpMimeTypes are the names Burp gives the common response types in its mimetype column
+var burpMimeTypes = map[string]string{
+	"text/html":              "HTML",
+	"application/json":       "JSON",
+	"text/javascript":        "script",
+	"application/javascript": "script",
+	"text/css":               "CSS",
+	"application/xml":        "XML",
+	"text/xml":               "XML",
+	"text/plain":             "text",
+	"image/png":              "PNG",
+	"image/jpeg":             "JPEG",
+	"image/gif":              "GIF",
+	"image/svg+xml":          "XML",
+}
+
+// RawHttpMessage splits a request or response as it was sent on the wire into its first line, headers and body
+func RawHttpMessage(raw []byte) (string, []Header, []byte) {
+	head, body, found := bytes.Cut(raw, []byte("\r\n\r\n"))
+	if !found {
+		head, body, _ = bytes.Cut(raw, []byte("\n\n"))
+	}
+	lines := strings.Split(strings.ReplaceAll(string(head), "\r\n", "\n"), "\n")
+	headers := make([]Header, 0, len(lines)-1)
+	for _, line := range lines[1:] {
+		if name, value, ok := strings.Cut(line, ":"); ok {
+			headers = append(headers, Header{Name: strings.TrimSpace(name), Value: strings.TrimSpace(value)})
+		}
+	}
+	return lines[0], headers, body
+}
+
+// decodeBurpBody undoes the chunked transfer and content encodings Burp keeps in the raw response, since a HAR holds
+// the decoded body. Encodings Go can't decode, such as br, are left as they are
+func decodeBurpBody(headers []Header, body []byte) []byte {
+	if strings.Contains(strings.ToLower(HeaderValue(headers, "transfer-encoding")), "chunked") {
+		if decoded, err := io.ReadAll(httputil.NewChunkedReader(bytes.NewReader(body))); err == nil {
+			body = decoded
+		}
+	}
+	var reader io.Reader
+	switch strings.ToLower(strings.TrimSpace(HeaderValue(headers, "content-encoding"))) {
+	case "gzip":
+		if gzipReader, err := gzip.NewReader(bytes.NewReader(body)); err == nil {
+			reader = gzipReader
+		}
+	case "deflate":
+		reader = flate.NewReader(bytes.NewReader(body))
+	}
+	if reader != nil {
+		if decoded, err := io.ReadAll(reader); err == nil {
+			return decoded
+		}
+	}
+	return body
+}
+
+// NewBurpEntry converts a Burp item to an entry. Burp doesn't record how long requests took, so the entries have no
+// timings
+func NewBurpEntry(item BurpItem) (Entry, error) {
+	rawRequest, err := item.Request.Bytes()
+	if err != nil {
+		return Entry{}, err
+	}
+	requestLine, requestHeaders, requestBody := RawHttpMessage(rawRequest)
+	version := "HTTP/1.1"
+	if fields := strings.Fields(requestLine); len(fields) == 3 {
+		version = fields[2]
+	}
+	started, err := time.Parse(burpTimeLayout, strings.TrimSpace(item.Time))
+	if err != nil {
+		started = time.Unix(0, 0)
+	}
+	entry := Entry{
+		StartedDateTime: started.UTC().Format(time.RFC3339Nano),
+		Request: Request{
+			Method:      strings.TrimSpace(item.Method.Text),
+			Url:         strings.TrimSpace(item.Url.Text),
+			HttpVersion: version,
+			Cookies:     headerCookies(requestHeaders, "cookie"),
+			Headers:     requestHeaders,
+			QueryString: make([]QueryParameter, 0),
+			HeadersSize: len(rawRequest) - len(requestBody),
+			BodySize:    len(requestBody),
+		},
+		Response: Response{
+			Cookies:     make([]Cookie, 0),
+			Headers:     make([]Header, 0),
+			Content:     &Content{MimeType: "x-unknown"},
+			HeadersSize: Unknown,
+			BodySize:    Unknown,
+		},
+		Timings: EntryTimings{Blocked: Pointer(float64(Unknown)), Dns: Pointer(float64(Unknown)), Connect: Pointer(float64(Unknown)), Ssl: Pointer(float64(Unknown))},
+	}
+	if requestUrl, err := url.Parse(entry.Request.Url); err == nil {
+		for name, values := range requestUrl.Query() {
+			for _, value := range values {
+				entry.Request.QueryString = append(entry.Request.QueryString, QueryParameter{Name: name, Value: value})
+			}
+		}
+	}
+	if len(requestBody) > 0 {
+		entry.Request.PostData = &PostData{MimeType: HeaderValue(requestHeaders, "content-type"), Text: string(requestBody)}
+	}
+	if item.Host.Ip != "" {
+		entry.ServerIP = Pointer(item.Host.Ip)
+	}
+	if strings.TrimSpace(item.Comment) != "" {
+		entry.Comment = Pointer(strings.TrimSpace(item.Comment))
+	}
+
+	rawResponse, err := item.Response.Bytes()
+	if err != nil {
+		return Entry{}, err
+	}
+	if len(rawResponse) == 0 {
+		// Requests which never got a response are saved without one
+		return entry, nil
+	}
+	statusLine, responseHeaders, rawBody := RawHttpMessage(rawResponse)
+	responseVersion, status, _ := strings.Cut(statusLine, " ")
+	code, reason, _ := strings.Cut(status, " ")
+	entry.Response.Status, _ = strconv.Atoi(code)
+	entry.Response.StatusText = reason
+	entry.Response.HttpVersion = responseVersion
+	entry.Response.Headers = responseHeaders
+	entry.Response.Cookies = headerCookies(responseHeaders, "set-cookie")
+	entry.Response.HeadersSize = len(rawResponse) - len(rawBody)
+	entry.Response.BodySize = len(rawBody)
+	if location := HeaderValue(responseHeaders, "location"); location != "" {
+		entry.Response.RedirectUrl = Pointer(location)
+	}
+	body := decodeBurpBody(responseHeaders, rawBody)
+	entry.Response.Content = &Content{Size: len(body), MimeType: Tertiary(HeaderValue(responseHeaders, "content-type") == "", "x-unknown", HeaderValue(responseHeaders, "content-type"))}
+	if len(body) > 0 {
+		if utf8.Valid(body) {
+			entry.Response.Content.Text = Pointer(string(body))
+		} else {
+			entry.Response.Content.Text = Pointer(base64.StdEncoding.EncodeToString(body))
+			entry.Response.Content.Encoding = Pointer("base64")
+		}
+	}
+	return entry, nil
+}
+
+// ReadBurpItems converts the XML written by Burp's Save items to a HAR
+func ReadBurpItems(content []byte) (HarFile, error) {
+	var items BurpItems
+	if err := xml.Unmarshal(content, &items); err != nil {
+		return HarFile{}, errors.New("failed to parse the Burp items, " + err.Error())
+	}
+	har := HarFile{Log: Log{Version: "1.2", Creator: Creator{Name: "harv", Version: "Burp " + items.BurpVersion}, Entries: make([]Entry, 0, len(items.Items))}}
+	for i, item := range items.Items {
+		entry, err := NewBurpEntry(item)
+		if err != nil {
+			return HarFile{}, errors.New("failed to decode Burp item " + strconv.Itoa(i+1) + ", " + err.Error())
+		}
+		har.Log.Entries = append(har.Log.Entries, entry)
+	}
+	return har, nil
+}
+
+// burpHttpVersion is the version on the request line Burp shows, which is HTTP/2 for h2 rather than HTTP/2.0
+func burpHttpVersion(version string) string {
+	switch strings.ToLower(version) {
+	case "h2", "http/2", "http/2.0":
+		return "HTTP/2"
+	case "http/1.0":
+		return "HTTP/1.0"
+	}
+	return "HTTP/1.1"
+}
+
+// RawHttpRequest writes a request as it would be sent on the wire, with target as the path on the request line
+func RawHttpRequest(entry Entry, requestUrl *url.URL, target string, version string) string {
+	var request strings.Builder
+	request.WriteString(strings.ToUpper(entry.Request.Method) + " " + target + " " + version + "\r\n")
+	if FindHeader(entry.Request.Headers, "host") == nil {
+		request.WriteString("Host: " + requestUrl.Host + "\r\n")
+	}
+	for _, header := range entry.Request.Headers {
+		// HTTP/2 captures name the host :authority, which the Host header above stands in for
+		if !strings.HasPrefix(header.Name, ":") {
+			request.WriteString(header.Name + ": " + header.Value + "\r\n")
+		}
+	}
+	request.WriteString("\r\n" + string(RequestBodyBytes(entry.Request)))
+	return request.String()
+}
+
+// NewBurpItem writes an entry back out as the raw request and response Burp keeps. The HAR holds the decoded response
+// body, so it is written without its content or transfer encoding and with a matching length
+func NewBurpItem(entry Entry) (BurpItem, bool) {
+	requestUrl, err := url.Parse(entry.Request.Url)
+	if err != nil || requestUrl.Host == "" {
+		return BurpItem{}, false
+	}
+	port := requestUrl.Port()
+	if port == "" {
+		port = Tertiary(requestUrl.Scheme == "https", "443", "80")
+	}
+	item := BurpItem{
+		Url:       BurpData{Text: entry.Request.Url},
+		Host:      BurpHost{Name: requestUrl.Hostname()},
+		Port:      port,
+		Protocol:  requestUrl.Scheme,
+		Method:    BurpData{Text: strings.ToUpper(entry.Request.Method)},
+		Path:      BurpData{Text: requestUrl.RequestURI()},
+		Extension: strings.TrimPrefix(path.Ext(requestUrl.Path), "."),
+		MimeType:  burpMimeTypes[MimeType(entry)],
+	}
+	if item.Extension == "" {
+		item.Extension = "null"
+	}
+	if started, ok := ParseStartedDateTime(entry.StartedDateTime); ok {
+		item.Time = started.UTC().Format(burpTimeLayout)
+	}
+	if entry.ServerIP != nil {
+		item.Host.Ip = strings.Trim(*entry.ServerIP, "[]")
+	}
+	if entry.Comment != nil {
+		item.Comment = *entry.Comment
+	}
+
+	request := RawHttpRequest(entry, requestUrl, item.Path.Text, burpHttpVersion(entry.Request.HttpVersion))
+	item.Request = BurpData{Base64: true, Text: base64.StdEncoding.EncodeToString([]byte(request))}
+
+	if entry.Response.Status > 0 {
+		body := ""
+		if entry.Response.Content != nil {
+			body, _ = DecodedBody(*entry.Response.Content)
+		}
+		var response strings.Builder
+		response.WriteString(burpHttpVersion(entry.Response.HttpVersion) + " " + strconv.Itoa(entry.Response.Status) + " " + entry.Response.StatusText + "\r\n")
+		for _, header := range entry.Response.Headers {
+			name := strings.ToLower(header.Name)
+			if !strings.HasPrefix(name, ":") && name != "content-encoding" && name != "transfer-encoding" && name != "content-length" {
+				response.WriteString(header.Name + ": " + header.Value + "\r\n")
+			}
+		}
+		response.WriteString("Content-Length: " + strconv.Itoa(len(body)) + "\r\n\r\n" + body)
+		item.Status = strconv.Itoa(entry.Response.Status)
+		item.ResponseLength = strconv.Itoa(response.Len())
+		item.Response = BurpData{Base64: true, Text: base64.StdEncoding.EncodeToString([]byte(response.String()))}
+	}
+	return item, true
+}
+
+func FormatBurpItems(entries []Entry) (string, error) {
+	items := BurpItems{BurpVersion: "harv", ExportTime: time.Now().UTC().Format(burpTimeLayout), Items: make([]BurpItem, 0, len(entries))}
+	for _, entry := range entries {
+		if item, ok := NewBurpItem(entry); ok {
+			items.Items = append(items.Items, item)
+		}
+	}
+	content, err := xml.MarshalIndent(items, "", "  ")
+	if err != nil {
+		return "", err
+	}
+	return xml.Header + string(content), nil
+}
+
+func (cmd *ExportBurpCmd) Run() error {
+	har, err := ReadHarFile(cmd.File)
+	if err != nil {
+		return err
+	}
+	entries, err := SelectEntries(har.Log)
+	if err != nil {
+		return err
+	}
+	output, err := FormatBurpItems(entries)
+	if err != nil {
+		return err
+	}
+	return WriteOutput(output)
+}
+
+func (cmd *ImportBurpCmd) Run() error {
+	content, err := ReadInput(cmd.File)
+	if err != nil {
+		return err
+	}
+	har, err := ReadBurpItems(content)
+	if err != nil {
+		return err
+	}
+	har.Log.Entries, err = SelectEntries(har.Log)
+	if err != nil {
+		return err
+	}
+	output, err := MarshalHar(har)
+	if err != nil {
+		return err
+	}
+	return WriteOutput(string(output))
+}
@@ -0,0 +1,157 @@
+package main
+
+import (
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/fatih/color"
+)
+
+type AuditCertsCmd struct {
+	ExpiryDays int    `name:"expiry-days" default:"30" help:"Certificates expiring within this many days will be flagged"`
+	File       string `arg:"" help:"The HAR file to parse, or - to read it from stdin" type:"existingfile"`
+}
+
+// SanCovers checks host against a certificate's subject alternative names, where a wildcard covers exactly one label
+func SanCovers(sans []string, host string) bool {
+	host = strings.ToLower(host)
+	for _, san := range sans {
+		san = strings.ToLower(san)
+		if san == host {
+			return true
+		}
+		if strings.HasPrefix(san, "*.") {
+			label, parent, found := strings.Cut(host, ".")
+			if found && label != "" && parent == san[2:] {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+type CertificateReport struct {
+	Origin  string
+	Host    string
+	Details SecurityDetails
+	AuditGroup
+}
+
+func AuditCerts(entries []Entry, expiryWindow time.Duration, now time.Time) []*CertificateReport {
+	reports := make([]*CertificateReport, 0)
+	byOrigin := make(map[string]*CertificateReport)
+	for _, entry := range entries {
+		if entry.SecurityDetails == nil {
+			continue
+		}
+		origin := Origin(entry.Request.Url)
+		report, ok := byOrigin[origin]
+		if ok {
+			report.Entries++
+			continue
+		}
+		requestUrl, err := url.Parse(entry.Request.Url)
+		if err != nil {
+			continue
+		}
+		report = &CertificateReport{Origin: origin, Host: requestUrl.Hostname(), Details: *entry.SecurityDetails}
+		report.Name = origin
+		report.Entries = 1
+		byOrigin[origin] = report
+		reports = append(reports, report)
+
+		details := entry.SecurityDetails
+		// Dates of 0 are a certificate the HAR doesn't have, such as on a reused connection
+		certificate := CertificateName(*details)
+		validTo := UnixSeconds(details.ValidTo)
+		validFrom := UnixSeconds(details.ValidFrom)
+		switch {
+		case details.ValidTo == 0:
+		case validTo.Before(now):
+			report.Add(SeverityError, certificate+" expired on "+validTo.Format(time.DateOnly))
+		case validTo.Before(now.Add(expiryWindow)):
+			report.Add(SeverityWarning, certificate+" expires in "+strconv.Itoa(int(validTo.Sub(now).Hours()/24))+" days")
+		}
+		if details.ValidFrom != 0 && validFrom.After(now) {
+			report.Add(SeverityError, certificate+" is not valid until "+validFrom.Format(time.DateOnly))
+		}
+		if len(details.SanList) > 0 && !SanCovers(details.SanList, report.Host) {
+			report.Add(SeverityError, report.Host+" is not in the certificate's names: "+strings.Join(details.SanList, ", "))
+		}
+		if len(details.SanList) == 0 {
+			report.Add(SeverityWarning, "certificate has no subject alternative names")
+		}
+		if details.CertificateTransparencyCompliance != nil && *details.CertificateTransparencyCompliance == "not-compliant" {
+			report.Add(SeverityWarning, "certificate is not certificate transparency compliant")
+		}
+	}
+	return reports
+}
+
+func FormatCertificateReports(reports []*CertificateReport, now time.Time) string {
+	if len(reports) == 0 {
+		return color.HiBlackString("No entries with certificate details (Chrome _securityDetails)")
+	}
+
+	rows := make([][]string, 0, len(reports))
+	for _, report := range reports {
+		expires := "-"
+		if report.Details.ValidTo != 0 {
+			days := int(UnixSeconds(report.Details.ValidTo).Sub(now).Hours() / 24)
+			expires = Tertiary(days < 0, "expired", strconv.Itoa(days)+"d")
+		}
+		covers := "-"
+		if len(report.Details.SanList) > 0 {
+			covers = Tertiary(SanCovers(report.Details.SanList, report.Host), "yes", "no")
+		}
+		rows = append(rows, []string{
+			report.Origin,
+			report.Details.SubjectName,
+			report.Details.Issuer,
+			CertificateDate(report.Details.ValidFrom),
+			CertificateDate(report.Details.ValidTo),
+			expires,
+			covers,
+		})
+	}
+	output := []string{FormatTable([]Column{
+		{Name: "Origin"},
+		{Name: "Subject"},
+		{Name: "Issuer"},
+		{Name: "Valid from"},
+		{Name: "Valid to"},
+		{Name: "Expires", Right: true},
+		{Name: "SAN covers"},
+	}, rows)}
+
+	for _, report := range reports {
+		if len(report.Findings) == 0 {
+			continue
+		}
+		output = append(output, "", color.YellowString(report.Origin))
+		for _, finding := range report.Findings {
+			output = append(output, "  "+FormatFinding(finding))
+		}
+	}
+	return strings.Join(output, "\n")
+}
+
+// CertificateDate is a table cell for a certificate date, "-" when the HAR doesn't have it
+func CertificateDate(seconds float64) string {
+	return Tertiary(seconds == 0, "-", UnixSeconds(seconds).Format(time.DateOnly))
+}
+
+func (cmd *AuditCertsCmd) Run() error {
+	har, err := ReadHar(cmd.File, false)
+	if err != nil {
+		return err
+	}
+	validEntries, err := SelectEntries(har.Log)
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	return WriteOutput(FormatCertificateReports(AuditCerts(validEntries, time.Duration(cmd.ExpiryDays)*24*time.Hour, now), now))
+}
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/fatih/color"
+)
+
+func TestAuditCertsWithoutCertificate(t *testing.T) {
+	color.NoColor = true
+	now := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	har := readTestHar(t, "testdata/chrome.har")
+	reports := AuditCerts(har.Log.Entries, 30*24*time.Hour, now)
+	if len(reports) != 1 {
+		t.Fatalf("found %d origins, expected 1", len(reports))
+	}
+	for _, finding := range reports[0].Findings {
+		if finding.Severity == SeverityError {
+			t.Errorf("a connection without certificate dates was reported as %q", finding.Message)
+		}
+	}
+
+	output := FormatCertificateReports(reports, now)
+	row := strings.Split(output, "\n")[1]
+	if strings.Contains(output, "1970") || strings.Contains(row, "expired") || strings.Contains(row, "yes") {
+		t.Errorf("printed the missing certificate as expired or covered:\n%s", output)
+	}
+}
+
+func TestAuditCertsSanCovers(t *testing.T) {
+	color.NoColor = true
+	now := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	details := SecurityDetails{SubjectName: "example.com", ValidFrom: float64(now.AddDate(-1, 0, 0).Unix()), ValidTo: float64(now.AddDate(1, 0, 0).Unix())}
+	cases := map[string][]string{
+		"yes": {"example.com", "*.example.com"},
+		"no":  {"other.example"},
+	}
+	for expected, sans := range cases {
+		details.SanList = sans
+		entries := []Entry{{Request: Request{Method: "GET", Url: "https://www.example.com/"}, SecurityDetails: &details}}
+		row := strings.Fields(strings.Split(FormatCertificateReports(AuditCerts(entries, 0, now), now), "\n")[1])
+		if covers := row[len(row)-1]; covers != expected {
+			t.Errorf("SAN covers is %q for %v, expected %q", covers, sans, expected)
+		}
+	}
+}
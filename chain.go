@@ -0,0 +1,90 @@
+package main
+
+import (
+	"net/url"
+	"sort"
+)
+
+// RedirectTarget is the absolute URL an entry redirected to, as redirectURL is often the relative Location header
+func RedirectTarget(entry Entry) string {
+	if entry.Response.RedirectUrl == nil || *entry.Response.RedirectUrl == "" {
+		return ""
+	}
+	base, err := url.Parse(entry.Request.Url)
+	if err != nil {
+		return *entry.Response.RedirectUrl
+	}
+	target, err := base.Parse(*entry.Response.RedirectUrl)
+	if err != nil {
+		return *entry.Response.RedirectUrl
+	}
+	return target.String()
+}
+
+// ChainIndexes adds the context of each matched entry for --with-chain: every redirect which led to it or followed
+// from it, and the request which initiated it. Redirects are matched to the nearest request for the target URL, the
+// next one for where it went and the last one before for what came to it
+func ChainIndexes(entries []Entry, matched []int) []int {
+	byUrl := make(map[string][]int)
+	for i, entry := range entries {
+		byUrl[entry.Request.Url] = append(byUrl[entry.Request.Url], i)
+	}
+	redirectedTo := make(map[string][]int)
+	for i, entry := range entries {
+		if target := RedirectTarget(entry); target != "" {
+			redirectedTo[target] = append(redirectedTo[target], i)
+		}
+	}
+	// lastBefore is the last of the indexes which comes before i, they are always in file order
+	lastBefore := func(indexes []int, i int) int {
+		found := -1
+		for _, index := range indexes {
+			if index >= i {
+				break
+			}
+			found = index
+		}
+		return found
+	}
+	firstAfter := func(indexes []int, i int) int {
+		for _, index := range indexes {
+			if index > i {
+				return index
+			}
+		}
+		return -1
+	}
+
+	included := make(map[int]bool)
+	pending := make([]int, 0, len(matched))
+	for _, i := range matched {
+		included[i] = true
+		pending = append(pending, i)
+	}
+	for _, i := range matched {
+		if initiator := lastBefore(byUrl[InitiatorUrl(entries[i])], i); initiator >= 0 {
+			included[initiator] = true
+		}
+	}
+	for len(pending) > 0 {
+		i := pending[len(pending)-1]
+		pending = pending[:len(pending)-1]
+		linked := []int{lastBefore(redirectedTo[entries[i].Request.Url], i)}
+		if target := RedirectTarget(entries[i]); target != "" {
+			linked = append(linked, firstAfter(byUrl[target], i))
+		}
+		for _, index := range linked {
+			if index >= 0 && !included[index] {
+				included[index] = true
+				pending = append(pending, index)
+			}
+		}
+	}
+
+	indexes := make([]int, 0, len(included))
+	for i := range included {
+		indexes = append(indexes, i)
+	}
+	sort.Ints(indexes)
+	return indexes
+}
@@ -0,0 +1,191 @@
+package main
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+)
+
+// replayHeaderSkip are set by the client sending a request, so copying them into a collection would send them twice or
+// with the wrong value once the body is edited
+var replayHeaderSkip = []string{"host", "content-length", "connection", "keep-alive", "transfer-encoding", "upgrade", "te", "trailer", "proxy-connection"}
+
+// ReplayHeaders are the request headers worth keeping when the request is sent again by another tool, leaving out the
+// HTTP/2 pseudo headers and the Authorization header when it is exported as the auth of the request instead
+func ReplayHeaders(request Request, withoutAuth bool) []Header {
+	headers := make([]Header, 0, len(request.Headers))
+	for _, header := range request.Headers {
+		name := strings.ToLower(header.Name)
+		if strings.HasPrefix(name, ":") || slices.Contains(replayHeaderSkip, name) || withoutAuth && name == "authorization" {
+			continue
+		}
+		headers = append(headers, header)
+	}
+	return headers
+}
+
+// HttpAuth is the credentials of the Authorization header, for the tools which keep them apart from the headers
+type HttpAuth struct {
+	// Type is bearer or basic
+	Type     string
+	Token    string
+	Username string
+	Password string
+}
+
+// RequestAuth parses bearer and basic credentials, other schemes are left as an Authorization header
+func RequestAuth(request Request) *HttpAuth {
+	scheme, credentials, found := strings.Cut(strings.TrimSpace(HeaderValue(request.Headers, "authorization")), " ")
+	if !found {
+		return nil
+	}
+	credentials = strings.TrimSpace(credentials)
+	switch strings.ToLower(scheme) {
+	case "bearer":
+		return &HttpAuth{Type: "bearer", Token: credentials}
+	case "basic":
+		decoded, err := base64.StdEncoding.DecodeString(credentials)
+		if err != nil {
+			return nil
+		}
+		username, password, _ := strings.Cut(string(decoded), ":")
+		return &HttpAuth{Type: "basic", Username: username, Password: password}
+	}
+	return nil
+}
+
+// FormFields are the fields of a URL encoded body in the order they were sent, or false for any other body
+func FormFields(postData PostData) ([]QueryParameter, bool) {
+	if !strings.Contains(strings.ToLower(postData.MimeType), "x-www-form-urlencoded") {
+		return nil, false
+	}
+	fields := make([]QueryParameter, 0)
+	if len(postData.Params) > 0 {
+		for _, param := range postData.Params {
+			value := ""
+			if param.Value != nil {
+				value = *param.Value
+			}
+			fields = append(fields, QueryParameter{Name: param.Name, Value: value})
+		}
+		return fields, true
+	}
+	for _, pair := range strings.Split(postData.Text, "&") {
+		if pair == "" {
+			continue
+		}
+		name, value, _ := strings.Cut(pair, "=")
+		fields = append(fields, QueryParameter{Name: DecodeQueryComponent(name), Value: DecodeQueryComponent(value)})
+	}
+	return fields, true
+}
+
+// ReplayBody is the text of the request body, pretty printed if it is JSON so it is easier to edit before sending again
+func ReplayBody(postData PostData) string {
+	text := strings.ReplaceAll(postData.Text, "\r\n", "\n")
+	if strings.Contains(strings.ToLower(postData.MimeType), "json") {
+		// Indenting the text rather than the decoded value keeps the fields in the order they were sent
+		var indented bytes.Buffer
+		if json.Indent(&indented, []byte(text), "", "  ") == nil {
+			return indented.String()
+		}
+	}
+	return text
+}
+
+// mockHeaderSkip describe how the response was sent rather than the response, and would be wrong once a mock is
+// served by another server with the body already decoded
+var mockHeaderSkip = []string{"content-length", "content-encoding", "transfer-encoding", "connection", "keep-alive"}
+
+// MockResponse is a recorded response to be served again by a mock, with a binary body kept as base64
+type MockResponse struct {
+	Status  int
+	Headers []Header
+	Body    string
+	Base64  bool
+}
+
+func NewMockResponse(entry Entry) MockResponse {
+	response := MockResponse{Status: entry.Response.Status}
+	for _, header := range entry.Response.Headers {
+		name := strings.ToLower(header.Name)
+		if !strings.HasPrefix(name, ":") && !slices.Contains(mockHeaderSkip, name) {
+			response.Headers = append(response.Headers, header)
+		}
+	}
+	if entry.Response.Content == nil || entry.Response.Content.Text == nil {
+		return response
+	}
+	if text, ok := DecodedBody(*entry.Response.Content); ok {
+		response.Body = text
+	} else if content := entry.Response.Content; content.Encoding != nil && strings.EqualFold(*content.Encoding, "base64") {
+		response.Body = *content.Text
+		response.Base64 = true
+	}
+	return response
+}
+
+// HeaderMap joins repeated headers into one value, except Set-Cookie whose values are joined by newlines as
+// comma separated cookies can't be told apart from the commas in their expiry dates
+func HeaderMap(headers []Header) map[string]string {
+	values := make(map[string]string, len(headers))
+	for _, header := range headers {
+		name := strings.ToLower(header.Name)
+		if existing, ok := values[name]; ok {
+			values[name] = existing + Tertiary(name == "set-cookie", "\n", ", ") + header.Value
+		} else {
+			values[name] = header.Value
+		}
+	}
+	return values
+}
+
+var unsafeFileName = regexp.MustCompile(`[^A-Za-z0-9._]+`)
+
+// ExportFileName makes a readable file name from the method and path, prefixed with its position so names never clash
+func ExportFileName(seq int, method string, path string, extension string) string {
+	name := strings.Trim(unsafeFileName.ReplaceAllString(strings.ToLower(method)+"-"+path, "-"), "-")
+	if len(name) > 60 {
+		name = strings.TrimRight(name[:60], "-")
+	}
+	return fmt.Sprintf("%03d-%s%s", seq, name, extension)
+}
+
+// CollectionName names a collection after the file it came from
+func CollectionName(file string) string {
+	if file == "-" {
+		return "harv"
+	}
+	return strings.TrimSuffix(filepath.Base(file), filepath.Ext(file))
+}
+
+// HarCookies converts cookies parsed by net/http to the cookies of a HAR request or response
+func HarCookies(list []*http.Cookie) []Cookie {
+	cookies := make([]Cookie, len(list))
+	for i, cookie := range list {
+		cookies[i] = Cookie{Name: cookie.Name, Value: cookie.Value}
+		if cookie.Path != "" {
+			cookies[i].Path = Pointer(cookie.Path)
+		}
+		if cookie.Domain != "" {
+			cookies[i].Domain = Pointer(cookie.Domain)
+		}
+		if !cookie.Expires.IsZero() {
+			cookies[i].Expires = Pointer(cookie.Expires.UTC().Format(time.RFC3339))
+		}
+		if cookie.HttpOnly {
+			cookies[i].HttpOnly = Pointer(true)
+		}
+		if cookie.Secure {
+			cookies[i].Secure = Pointer(true)
+		}
+	}
+	return cookies
+}
@@ -0,0 +1,123 @@
+package main
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/fatih/color"
+)
+
+type CompareCmd struct {
+	By     string `name:"by" default:"endpoint" enum:"endpoint,domain,status,mime,method,protocol,provider" help:"What to group the entries by before comparing them"`
+	Before string `arg:"" help:"The earlier HAR file" type:"existingfile"`
+	After  string `arg:"" help:"The later HAR file" type:"existingfile"`
+}
+
+// FormatDurationChange is the difference between two durations with its sign, eg +12 ms
+func FormatDurationChange(before float64, after float64) string {
+	change := after - before
+	if change < 0 {
+		return "-" + FormatDuration(-change)
+	}
+	return "+" + FormatDuration(change)
+}
+
+// CompareAggregates lines up the groups of two captures, with groups only in one of them compared against nothing
+func CompareAggregates(before []*Aggregate, after []*Aggregate, by string) string {
+	type pair struct {
+		key    string
+		before *Aggregate
+		after  *Aggregate
+	}
+	pairs := make([]*pair, 0)
+	byKey := make(map[string]*pair)
+	for _, group := range before {
+		byKey[group.Key] = &pair{key: group.Key, before: group}
+		pairs = append(pairs, byKey[group.Key])
+	}
+	for _, group := range after {
+		if existing, ok := byKey[group.Key]; ok {
+			existing.after = group
+		} else {
+			pairs = append(pairs, &pair{key: group.Key, after: group})
+		}
+	}
+	count := func(group *Aggregate) int {
+		if group == nil {
+			return 0
+		}
+		return group.Count
+	}
+	sort.SliceStable(pairs, func(i, j int) bool {
+		return count(pairs[i].before)+count(pairs[i].after) > count(pairs[j].before)+count(pairs[j].after)
+	})
+
+	stats := func(group *Aggregate) (p50 float64, p95 float64, size float64) {
+		return Percentile(group.Durations, 50), Percentile(group.Durations, 95), float64(group.Bytes) / float64(group.Count)
+	}
+	rows := make([][]string, 0, len(pairs))
+	for _, pair := range pairs {
+		row := []string{pair.key, strconv.Itoa(count(pair.before)), strconv.Itoa(count(pair.after))}
+		switch {
+		case pair.before == nil:
+			p50, p95, size := stats(pair.after)
+			row = append(row, "-", FormatDuration(p50), color.GreenString("added"), "-", FormatDuration(p95), "", "-", FormatBytes(int(size)), "")
+		case pair.after == nil:
+			p50, p95, size := stats(pair.before)
+			row = append(row, FormatDuration(p50), "-", color.RedString("removed"), FormatDuration(p95), "-", "", FormatBytes(int(size)), "-", "")
+		default:
+			p50Before, p95Before, sizeBefore := stats(pair.before)
+			p50After, p95After, sizeAfter := stats(pair.after)
+			row = append(row,
+				FormatDuration(p50Before), FormatDuration(p50After), FormatDurationChange(p50Before, p50After)+" "+FormatDelta(p50Before, p50After),
+				FormatDuration(p95Before), FormatDuration(p95After), FormatDurationChange(p95Before, p95After)+" "+FormatDelta(p95Before, p95After),
+				FormatBytes(int(sizeBefore)), FormatBytes(int(sizeAfter)), FormatDelta(sizeBefore, sizeAfter),
+			)
+		}
+		rows = append(rows, row)
+	}
+
+	return FormatTable([]Column{
+		{Name: strings.ToUpper(by[:1]) + by[1:]},
+		{Name: "Calls before", Right: true},
+		{Name: "Calls after", Right: true},
+		{Name: "p50 before", Right: true},
+		{Name: "p50 after", Right: true},
+		{Name: "Change", Right: true},
+		{Name: "p95 before", Right: true},
+		{Name: "p95 after", Right: true},
+		{Name: "Change", Right: true},
+		{Name: "Size before", Right: true},
+		{Name: "Size after", Right: true},
+		{Name: "Change", Right: true},
+	}, rows)
+}
+
+func (cmd *CompareCmd) Run() error {
+	aggregate := func(file string) ([]*Aggregate, error) {
+		har, err := ReadHar(file, false)
+		if err != nil {
+			return nil, err
+		}
+		entries, err := SelectEntries(har.Log)
+		if err != nil {
+			return nil, err
+		}
+		return AggregateEntries(entries, func(entry Entry) string {
+			if cmd.By == "protocol" {
+				return EntryHttpVersion(entry)
+			}
+			return GroupKey(entry, cmd.By)
+		}), nil
+	}
+	before, err := aggregate(cmd.Before)
+	if err != nil {
+		return err
+	}
+	after, err := aggregate(cmd.After)
+	if err != nil {
+		return err
+	}
+	return WriteOutput(CompareAggregates(before, after, cmd.By))
+}
@@ -0,0 +1,314 @@
+package main
+
+import (
+	"encoding/json"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+type ExportContractsCmd struct {
+	Package    string `name:"package" default:"contract" help:"The package of the Go test file"`
+	MaxEnum    int    `name:"max-enum" default:"5" help:"Strings with at most this many distinct values, each seen more than once, must be one of them. 0 accepts any string"`
+	AllMethods bool   `name:"all-methods" help:"Also test endpoints which change data, such as POST and DELETE, which are left out by default as the tests send them again"`
+	File       string `arg:"" help:"The HAR file to parse, or - to read it from stdin" type:"existingfile"`
+}
+
+// contractSafeMethods can be sent again without changing anything on the server
+var contractSafeMethods = []string{"GET", "HEAD", "OPTIONS"}
+
+// contractHeaderSkip are request headers which would be stale or wrong by the time the tests run. The credentials are
+// given by CONTRACT_AUTHORIZATION instead
+var contractHeaderSkip = []string{"authorization", "cookie", "accept-encoding"}
+
+// Contract is the request the tests send to an endpoint and the status and schema its response should have
+type Contract struct {
+	Name    string
+	Entry   Entry
+	Schema  map[string]any
+	Bodies  int
+	Headers map[string]string
+}
+
+// CollectContracts pairs the schema inferred from the successful JSON responses of each endpoint with the first of
+// those requests, which is the one the test sends again
+func CollectContracts(entries []Entry, maxEnum int, allMethods bool) []Contract {
+	selected := Filter(entries, func(entry Entry) bool {
+		return allMethods || slices.Contains(contractSafeMethods, strings.ToUpper(entry.Request.Method))
+	})
+	first := make(map[string]Entry)
+	for _, entry := range selected {
+		if _, ok := first[Endpoint(entry)]; ok || entry.Response.Status < 200 || entry.Response.Status >= 300 {
+			continue
+		}
+		if _, ok := EntryJsonBody(entry, false); ok {
+			first[Endpoint(entry)] = entry
+		}
+	}
+
+	contracts := make([]Contract, 0)
+	for _, schema := range InferSchemas(selected, false) {
+		entry := first[schema.Endpoint]
+		headers := make(map[string]string)
+		for name, value := range HeaderMap(ReplayHeaders(entry.Request, false)) {
+			if !slices.Contains(contractHeaderSkip, strings.ToLower(name)) {
+				headers[name] = value
+			}
+		}
+		contracts = append(contracts, Contract{
+			Name:    schema.Endpoint,
+			Entry:   entry,
+			Schema:  schema.Root.Schema(maxEnum),
+			Bodies:  schema.Bodies,
+			Headers: headers,
+		})
+	}
+	return contracts
+}
+
+const contractTestsTemplate = `// Generated by harv from FILE. Set CONTRACT_BASE_URL, eg https://staging.example.com, to test another server and
+// CONTRACT_AUTHORIZATION to send an Authorization header
+package PACKAGE
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+)
+
+type contract struct {
+	name    string
+	method  string
+	url     string
+	headers map[string]string
+	body    string
+	status  int
+	// schema is the JSON Schema the response body was inferred to have, which can be edited to loosen or tighten it
+	schema string
+}
+
+var contracts = []contract{
+CONTRACTS
+}
+
+var contractClient = &http.Client{
+	Timeout:       30 * time.Second,
+	CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse },
+}
+
+func contractUrl(t *testing.T, recorded string) string {
+	base := os.Getenv("CONTRACT_BASE_URL")
+	if base == "" {
+		return recorded
+	}
+	target, err := url.Parse(recorded)
+	if err != nil {
+		t.Fatal(err)
+	}
+	server, err := url.Parse(base)
+	if err != nil {
+		t.Fatal(err)
+	}
+	target.Scheme, target.Host = server.Scheme, server.Host
+	return target.String()
+}
+
+func jsonType(value any) string {
+	switch typed := value.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case json.Number:
+		if _, err := typed.Int64(); err == nil {
+			return "integer"
+		}
+		return "number"
+	case string:
+		return "string"
+	case []any:
+		return "array"
+	case map[string]any:
+		return "object"
+	}
+	return "unknown"
+}
+
+// validate checks a value against the parts of JSON Schema harv infers: type, properties, required, items, maxItems
+// and enum. Properties which aren't in the schema are allowed
+func validate(schema map[string]any, value any, path string) []string {
+	actual := jsonType(value)
+	allowed := make([]string, 0)
+	switch types := schema["type"].(type) {
+	case string:
+		allowed = append(allowed, types)
+	case []any:
+		for _, name := range types {
+			allowed = append(allowed, fmt.Sprint(name))
+		}
+	}
+	matched := len(allowed) == 0
+	for _, name := range allowed {
+		matched = matched || name == actual || name == "number" && actual == "integer"
+	}
+	if !matched {
+		return []string{fmt.Sprintf("%s is %s, expected %s", path, actual, strings.Join(allowed, " or "))}
+	}
+
+	problems := make([]string, 0)
+	if values, ok := schema["enum"].([]any); ok {
+		if text, ok := value.(string); ok {
+			found := false
+			for _, allowed := range values {
+				found = found || allowed == text
+			}
+			if !found {
+				problems = append(problems, fmt.Sprintf("%s is %q, expected one of %v", path, text, values))
+			}
+		}
+	}
+	switch typed := value.(type) {
+	case map[string]any:
+		required, _ := schema["required"].([]any)
+		for _, name := range required {
+			if _, ok := typed[fmt.Sprint(name)]; !ok {
+				problems = append(problems, fmt.Sprintf("%s.%s is missing", path, name))
+			}
+		}
+		properties, _ := schema["properties"].(map[string]any)
+		names := make([]string, 0, len(properties))
+		for name := range properties {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			property, ok := properties[name].(map[string]any)
+			if field, present := typed[name]; ok && present {
+				problems = append(problems, validate(property, field, path+"."+name)...)
+			}
+		}
+	case []any:
+		if maxItems, ok := schema["maxItems"].(float64); ok && float64(len(typed)) > maxItems {
+			problems = append(problems, fmt.Sprintf("%s has %d items, expected at most %v", path, len(typed), maxItems))
+		}
+		if items, ok := schema["items"].(map[string]any); ok {
+			for i, item := range typed {
+				problems = append(problems, validate(items, item, fmt.Sprintf("%s[%d]", path, i))...)
+			}
+		}
+	}
+	return problems
+}
+
+func TestContracts(t *testing.T) {
+	for _, c := range contracts {
+		c := c
+		t.Run(c.name, func(t *testing.T) {
+			var schema map[string]any
+			if err := json.Unmarshal([]byte(c.schema), &schema); err != nil {
+				t.Fatalf("the schema isn't valid JSON: %v", err)
+			}
+			var body io.Reader
+			if c.body != "" {
+				body = strings.NewReader(c.body)
+			}
+			request, err := http.NewRequest(c.method, contractUrl(t, c.url), body)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for name, value := range c.headers {
+				request.Header.Set(name, value)
+			}
+			if authorization := os.Getenv("CONTRACT_AUTHORIZATION"); authorization != "" {
+				request.Header.Set("Authorization", authorization)
+			}
+			response, err := contractClient.Do(request)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer response.Body.Close()
+			if response.StatusCode != c.status {
+				t.Fatalf("%s %s responded with %d, expected %d", c.method, request.URL, response.StatusCode, c.status)
+			}
+			decoder := json.NewDecoder(response.Body)
+			decoder.UseNumber()
+			var document any
+			if err := decoder.Decode(&document); err != nil {
+				t.Fatalf("the response isn't JSON: %v", err)
+			}
+			for _, problem := range validate(schema, document, "$") {
+				t.Error(problem)
+			}
+		})
+	}
+}`
+
+// goString writes a string as a Go literal, preferring a raw string so schemas and bodies stay readable
+func goString(value string) string {
+	if strings.ContainsAny(value, "`\r") || !strings.Contains(value, "\n") {
+		return strconv.Quote(value)
+	}
+	return "`" + value + "`"
+}
+
+func FormatContractTests(file string, pkg string, contracts []Contract) (string, error) {
+	blocks := make([]string, 0, len(contracts))
+	for _, contract := range contracts {
+		schema, err := json.MarshalIndent(contract.Schema, "\t\t", "\t")
+		if err != nil {
+			return "", err
+		}
+		names := make([]string, 0, len(contract.Headers))
+		for name := range contract.Headers {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		headers := make([]string, len(names))
+		for i, name := range names {
+			headers[i] = strconv.Quote(name) + ": " + strconv.Quote(contract.Headers[name])
+		}
+		request := contract.Entry.Request
+		lines := []string{
+			"\t{",
+			"\t\tname:    " + strconv.Quote(contract.Name) + ",",
+			"\t\tmethod:  " + strconv.Quote(strings.ToUpper(request.Method)) + ",",
+			"\t\turl:     " + strconv.Quote(request.Url) + ",",
+			"\t\theaders: map[string]string{" + strings.Join(headers, ", ") + "},",
+		}
+		if request.PostData != nil && request.PostData.Text != "" {
+			lines = append(lines, "\t\tbody:    "+goString(request.PostData.Text)+",")
+		}
+		lines = append(lines,
+			"\t\tstatus:  "+strconv.Itoa(contract.Entry.Response.Status)+",",
+			"\t\t// Inferred from "+strconv.Itoa(contract.Bodies)+Tertiary(contract.Bodies == 1, " response", " responses"),
+			"\t\tschema: "+goString(string(schema))+",",
+			"\t},",
+		)
+		blocks = append(blocks, strings.Join(lines, "\n"))
+	}
+	return strings.NewReplacer("FILE", Tertiary(file == "-", "stdin", file), "PACKAGE", pkg, "CONTRACTS", strings.Join(blocks, "\n")).Replace(contractTestsTemplate), nil
+}
+
+func (cmd *ExportContractsCmd) Run() error {
+	har, err := ReadHarFile(cmd.File)
+	if err != nil {
+		return err
+	}
+	entries, err := SelectEntries(har.Log)
+	if err != nil {
+		return err
+	}
+	output, err := FormatContractTests(cmd.File, cmd.Package, CollectContracts(entries, cmd.MaxEnum, cmd.AllMethods))
+	if err != nil {
+		return err
+	}
+	return WriteOutput(output)
+}
@@ -0,0 +1,374 @@
+package main
+
+import (
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/fatih/color"
+)
+
+type AuditCspCmd struct {
+	File string `arg:"" help:"The HAR file to parse, or - to read it from stdin" type:"existingfile"`
+}
+
+// cspDirectives are the directives which govern each kind of subresource, most specific first, ending in the
+// default-src fallback
+var cspDirectives = map[string][]string{
+	"script":   {"script-src-elem", "script-src", "default-src"},
+	"style":    {"style-src-elem", "style-src", "default-src"},
+	"img":      {"img-src", "default-src"},
+	"font":     {"font-src", "default-src"},
+	"connect":  {"connect-src", "default-src"},
+	"media":    {"media-src", "default-src"},
+	"frame":    {"frame-src", "child-src", "default-src"},
+	"worker":   {"worker-src", "child-src", "script-src", "default-src"},
+	"manifest": {"manifest-src", "default-src"},
+	"object":   {"object-src", "default-src"},
+}
+
+// ContentSecurityPolicy maps each directive to its source list, ReportOnly policies are reported on but not enforced
+type ContentSecurityPolicy struct {
+	Directives map[string][]string
+	ReportOnly bool
+}
+
+func ParseCsp(value string, reportOnly bool) ContentSecurityPolicy {
+	policy := ContentSecurityPolicy{Directives: make(map[string][]string), ReportOnly: reportOnly}
+	for _, directive := range strings.Split(value, ";") {
+		fields := strings.Fields(directive)
+		if len(fields) == 0 {
+			continue
+		}
+		name := strings.ToLower(fields[0])
+		// Only the first occurrence of a directive counts
+		if _, ok := policy.Directives[name]; !ok {
+			policy.Directives[name] = fields[1:]
+		}
+	}
+	return policy
+}
+
+func EntryPolicies(entry Entry) []ContentSecurityPolicy {
+	policies := make([]ContentSecurityPolicy, 0)
+	for _, header := range entry.Response.Headers {
+		switch strings.ToLower(header.Name) {
+		case "content-security-policy":
+			policies = append(policies, ParseCsp(header.Value, false))
+		case "content-security-policy-report-only":
+			policies = append(policies, ParseCsp(header.Value, true))
+		}
+	}
+	return policies
+}
+
+// CspResourceKind uses the browser's resource type when the HAR has it and otherwise guesses from the MIME type
+func CspResourceKind(entry Entry) string {
+	resourceType := ""
+	if entry.ResourceType != nil {
+		resourceType = strings.ToLower(*entry.ResourceType)
+	}
+	switch resourceType {
+	case "script":
+		return "script"
+	case "stylesheet":
+		return "style"
+	case "image":
+		return "img"
+	case "font":
+		return "font"
+	case "xhr", "fetch", "websocket", "eventsource", "ping", "beacon":
+		return "connect"
+	case "media":
+		return "media"
+	case "document", "subdocument":
+		return "frame"
+	case "manifest":
+		return "manifest"
+	}
+
+	mime := MimeType(entry)
+	switch {
+	case strings.Contains(mime, "javascript") || strings.Contains(mime, "ecmascript"):
+		return "script"
+	case mime == "text/css":
+		return "style"
+	case strings.HasPrefix(mime, "image/"):
+		return "img"
+	case strings.HasPrefix(mime, "font/") || strings.Contains(mime, "font"):
+		return "font"
+	case strings.HasPrefix(mime, "video/") || strings.HasPrefix(mime, "audio/"):
+		return "media"
+	case mime == "text/html":
+		return "frame"
+	case strings.Contains(mime, "manifest"):
+		return "manifest"
+	}
+	return "connect"
+}
+
+func IsDocument(entry Entry) bool {
+	if entry.ResourceType != nil {
+		return strings.EqualFold(*entry.ResourceType, "document")
+	}
+	return MimeType(entry) == "text/html"
+}
+
+// IsBroadSource is true for sources which allow whole schemes or any host, which a stricter policy would replace
+func IsBroadSource(source string) bool {
+	return source == "*" || strings.HasSuffix(source, ":") && !strings.Contains(source, "/")
+}
+
+// CspSourceMatches checks a URL against one source expression of a policy served with document
+func CspSourceMatches(source string, target *url.URL, document *url.URL) bool {
+	lower := strings.ToLower(source)
+	switch {
+	case lower == "'none'":
+		return false
+	case lower == "'self'":
+		return target.Host == document.Host && (target.Scheme == document.Scheme || document.Scheme == "http" && target.Scheme == "https" ||
+			document.Scheme == "https" && target.Scheme == "wss" || document.Scheme == "http" && target.Scheme == "ws")
+	case strings.HasPrefix(lower, "'"):
+		// Nonces, hashes and keywords like 'unsafe-inline' don't allow URLs
+		return false
+	case lower == "*":
+		return target.Scheme == "http" || target.Scheme == "https" || target.Scheme == "ws" || target.Scheme == "wss"
+	case strings.HasSuffix(lower, ":") && !strings.Contains(lower, "/"):
+		scheme := strings.TrimSuffix(lower, ":")
+		return target.Scheme == scheme || scheme == "http" && target.Scheme == "https" || scheme == "ws" && target.Scheme == "wss"
+	}
+
+	scheme := ""
+	rest := lower
+	if before, after, found := strings.Cut(lower, "://"); found {
+		scheme = before
+		rest = after
+	}
+	hostPort, path, _ := strings.Cut(rest, "/")
+	host, port, _ := strings.Cut(hostPort, ":")
+
+	switch {
+	case scheme == "" && document.Scheme == "http":
+		if target.Scheme != "http" && target.Scheme != "https" {
+			return false
+		}
+	case scheme == "":
+		if target.Scheme != document.Scheme {
+			return false
+		}
+	case target.Scheme != scheme && !(scheme == "http" && target.Scheme == "https") && !(scheme == "ws" && target.Scheme == "wss"):
+		return false
+	}
+
+	targetHost := strings.ToLower(target.Hostname())
+	if strings.HasPrefix(host, "*.") {
+		if !strings.HasSuffix(targetHost, host[1:]) {
+			return false
+		}
+	} else if host != targetHost {
+		return false
+	}
+
+	if port != "" && port != "*" && port != target.Port() {
+		defaultPort := map[string]string{"http": "80", "https": "443", "ws": "80", "wss": "443"}[target.Scheme]
+		if target.Port() != "" || port != defaultPort {
+			return false
+		}
+	}
+
+	if path != "" {
+		path = "/" + path
+		if strings.HasSuffix(path, "/") {
+			return strings.HasPrefix(target.Path, path)
+		}
+		return target.Path == path
+	}
+	return true
+}
+
+// Check returns the directive governing the URL and the source which allows it, with allowed false if it is
+// blocked. The directive is empty when the policy doesn't restrict this kind of resource
+func (policy ContentSecurityPolicy) Check(kind string, target *url.URL, document *url.URL) (directive string, source string, allowed bool) {
+	for _, name := range cspDirectives[kind] {
+		sources, ok := policy.Directives[name]
+		if !ok {
+			continue
+		}
+		for _, source := range sources {
+			if CspSourceMatches(source, target, document) {
+				return name, source, true
+			}
+		}
+		return name, "", false
+	}
+	return "", "", true
+}
+
+// CspSource is how an origin would be written in a policy for document
+func CspSource(target *url.URL, document *url.URL) string {
+	if target.Host == document.Host && target.Scheme == document.Scheme {
+		return "'self'"
+	}
+	if target.Scheme == "data" || target.Scheme == "blob" {
+		return target.Scheme + ":"
+	}
+	return target.Scheme + "://" + target.Host
+}
+
+// CspPage is a document and the subresources loaded by it
+type CspPage struct {
+	Document  Entry
+	Resources []Entry
+	AuditGroup
+	// Observed is the sources each directive would need to allow everything the page loaded
+	Observed map[string][]string
+}
+
+// GroupCspPages uses the page references to find which document loaded each resource, falling back to each document
+// owning the entries after it when the HAR doesn't have pages
+func GroupCspPages(entries []Entry) []*CspPage {
+	pages := make([]*CspPage, 0)
+	byRef := make(map[string]*CspPage)
+	var current *CspPage
+	for _, entry := range entries {
+		if entry.PageRef != nil {
+			page, ok := byRef[*entry.PageRef]
+			if !ok && IsDocument(entry) && entry.Response.Status >= 200 && entry.Response.Status < 300 {
+				page = &CspPage{Document: entry}
+				byRef[*entry.PageRef] = page
+				pages = append(pages, page)
+				continue
+			}
+			if ok {
+				page.Resources = append(page.Resources, entry)
+			}
+			continue
+		}
+
+		if IsDocument(entry) && entry.Response.Status >= 200 && entry.Response.Status < 300 {
+			current = &CspPage{Document: entry}
+			pages = append(pages, current)
+		} else if current != nil {
+			current.Resources = append(current.Resources, entry)
+		}
+	}
+	return pages
+}
+
+type cspViolation struct {
+	message string
+	count   int
+	example string
+}
+
+func AuditCsp(entries []Entry) []*CspPage {
+	pages := GroupCspPages(entries)
+	for _, page := range pages {
+		document, err := url.Parse(page.Document.Request.Url)
+		if err != nil {
+			continue
+		}
+		page.Name = page.Document.Request.Url
+		page.Entries = len(page.Resources)
+		page.Observed = make(map[string][]string)
+		policies := EntryPolicies(page.Document)
+		switch {
+		case len(policies) == 0:
+			page.Summary = "no policy"
+			page.Add(SeverityWarning, "document has no Content-Security-Policy")
+		case policies[0].ReportOnly:
+			page.Summary = "report only"
+		default:
+			page.Summary = "enforced"
+		}
+
+		violations := make([]*cspViolation, 0)
+		byMessage := make(map[string]*cspViolation)
+		violate := func(severity string, message string, example string) {
+			key := severity + message
+			if violation, ok := byMessage[key]; ok {
+				violation.count++
+				return
+			}
+			byMessage[key] = &cspViolation{message: severity + "\x00" + message, count: 1, example: example}
+			violations = append(violations, byMessage[key])
+		}
+
+		seenSources := make(map[string]bool)
+		for _, resource := range page.Resources {
+			target, err := url.Parse(resource.Request.Url)
+			if err != nil {
+				continue
+			}
+			kind := CspResourceKind(resource)
+			directive := kind + "-src"
+			source := CspSource(target, document)
+			if !seenSources[directive+" "+source] {
+				seenSources[directive+" "+source] = true
+				page.Observed[directive] = append(page.Observed[directive], source)
+			}
+
+			for _, policy := range policies {
+				governing, allowedBy, allowed := policy.Check(kind, target, document)
+				switch {
+				case !allowed:
+					violate(Tertiary(policy.ReportOnly, SeverityWarning, SeverityError),
+						governing+Tertiary(policy.ReportOnly, " would block ", " blocked ")+source, resource.Request.Url)
+				case IsBroadSource(allowedBy):
+					violate(SeverityWarning, governing+" only allows "+target.Scheme+"://"+target.Host+" through "+allowedBy+", a stricter policy would list "+source, resource.Request.Url)
+				}
+			}
+		}
+
+		for _, violation := range violations {
+			severity, message, _ := strings.Cut(violation.message, "\x00")
+			if violation.count > 1 {
+				message += " (" + strconv.Itoa(violation.count) + " requests, eg " + violation.example + ")"
+			} else {
+				message += " (" + violation.example + ")"
+			}
+			page.Add(severity, message)
+		}
+	}
+	return pages
+}
+
+// SuggestedCsp builds a policy allowing exactly the sources the page was seen loading
+func SuggestedCsp(observed map[string][]string) string {
+	directives := make([]string, 0, len(observed))
+	for directive, sources := range observed {
+		sorted := append([]string{}, sources...)
+		sort.Strings(sorted)
+		directives = append(directives, directive+" "+strings.Join(sorted, " "))
+	}
+	sort.Strings(directives)
+	return strings.Join(append([]string{"default-src 'self'"}, directives...), "; ")
+}
+
+func FormatCspPages(pages []*CspPage) string {
+	if len(pages) == 0 {
+		return color.HiBlackString("No documents found")
+	}
+	output := make([]string, 0)
+	for _, page := range pages {
+		output = append(output, FormatAuditGroups([]*AuditGroup{&page.AuditGroup}))
+		if len(page.Observed) > 0 {
+			output = append(output, color.HiBlackString("  Suggested: ")+SuggestedCsp(page.Observed))
+		}
+		output = append(output, "")
+	}
+	return strings.TrimRight(strings.Join(output, "\n"), "\n")
+}
+
+func (cmd *AuditCspCmd) Run() error {
+	har, err := ReadHar(cmd.File, false)
+	if err != nil {
+		return err
+	}
+	validEntries, err := SelectEntries(har.Log)
+	if err != nil {
+		return err
+	}
+	return WriteOutput(FormatCspPages(AuditCsp(validEntries)))
+}
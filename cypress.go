@@ -0,0 +1,149 @@
+package main
+
+import (
+	"encoding/base64"
+	"log/slog"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+type ExportCypressCmd struct {
+	OutDir string `name:"out-dir" required:"" help:"The fixtures directory to write the response bodies to, eg cypress/fixtures"`
+	File   string `arg:"" help:"The HAR file to parse, or - to read it from stdin" type:"existingfile"`
+}
+
+// bodyExtensions are the file extensions of the common response types, so fixtures open in the right editor and
+// Cypress parses the JSON ones
+var bodyExtensions = map[string]string{
+	"application/json":       ".json",
+	"text/html":              ".html",
+	"text/css":               ".css",
+	"text/javascript":        ".js",
+	"application/javascript": ".js",
+	"application/xml":        ".xml",
+	"text/xml":               ".xml",
+	"text/csv":               ".csv",
+	"image/svg+xml":          ".svg",
+	"image/png":              ".png",
+	"image/jpeg":             ".jpg",
+	"image/gif":              ".gif",
+	"image/webp":             ".webp",
+	"image/x-icon":           ".ico",
+	"font/woff2":             ".woff2",
+	"application/pdf":        ".pdf",
+}
+
+// BodyExtension picks a file extension for a body from its MIME type
+func BodyExtension(mime string, binary bool) string {
+	if extension, ok := bodyExtensions[mime]; ok {
+		return extension
+	}
+	if strings.HasSuffix(mime, "+json") {
+		return ".json"
+	}
+	return Tertiary(binary, ".bin", ".txt")
+}
+
+// CypressIntercept is one recorded response to a method and path, and the fixture holding its body
+type CypressIntercept struct {
+	Method   string
+	Host     string
+	Path     string
+	Response MockResponse
+	Fixture  string
+}
+
+// WriteCypressFixtures writes the body of each response to a fixture file, returning the intercepts grouped by
+// method, host and path in the order they were first requested
+func WriteCypressFixtures(dir string, entries []Entry) ([][]CypressIntercept, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	groups := make([][]CypressIntercept, 0)
+	byKey := make(map[string]int)
+	fixtures := 0
+	for _, entry := range entries {
+		requestUrl, err := url.Parse(entry.Request.Url)
+		if err != nil || entry.Response.Status == 0 {
+			continue
+		}
+		intercept := CypressIntercept{
+			Method:   strings.ToUpper(entry.Request.Method),
+			Host:     strings.ToLower(requestUrl.Hostname()),
+			Path:     Tertiary(requestUrl.Path == "", "/", requestUrl.Path),
+			Response: NewMockResponse(entry),
+		}
+		if intercept.Response.Body != "" {
+			body := []byte(intercept.Response.Body)
+			if intercept.Response.Base64 {
+				if body, err = base64.StdEncoding.DecodeString(intercept.Response.Body); err != nil {
+					slog.Warn("Failed to decode the response body, skipping the intercept", "url", entry.Request.Url, "error", err)
+					continue
+				}
+			}
+			fixtures++
+			// The extension comes from the type of the body, so drop any the path has, eg /logo.png
+			name := strings.TrimSuffix(intercept.Path, filepath.Ext(intercept.Path))
+			intercept.Fixture = ExportFileName(fixtures, intercept.Method, name, BodyExtension(MimeType(entry), intercept.Response.Base64))
+			if err := os.WriteFile(filepath.Join(dir, intercept.Fixture), body, 0644); err != nil {
+				return nil, err
+			}
+		}
+
+		key := intercept.Method + " " + intercept.Host + intercept.Path
+		if index, ok := byKey[key]; ok {
+			groups[index] = append(groups[index], intercept)
+		} else {
+			byKey[key] = len(groups)
+			groups = append(groups, []CypressIntercept{intercept})
+		}
+	}
+	return groups, nil
+}
+
+// FormatCypressIntercepts writes a function setting up a cy.intercept() for each response. Cypress tries the most
+// recently defined intercept first, so the responses to a path are defined last to first, with all but the last
+// recorded one only used once, to serve them in the order they were recorded
+func FormatCypressIntercepts(file string, dir string, groups [][]CypressIntercept) string {
+	lines := []string{
+		"// Generated by harv from " + Tertiary(file == "-", "stdin", file) + ", with the response bodies in " + dir,
+		"// Call interceptRecorded() in a test or a beforeEach to serve the recorded responses",
+		"export function interceptRecorded() {",
+	}
+	for _, group := range groups {
+		alias := strings.TrimPrefix(ExportFileName(0, group[0].Method, group[0].Path, ""), "000-")
+		for i := len(group) - 1; i >= 0; i-- {
+			intercept := group[i]
+			matcher := "{ method: " + jsLiteral(intercept.Method) + ", hostname: " + jsLiteral(intercept.Host) + ", pathname: " + jsLiteral(intercept.Path)
+			if i < len(group)-1 {
+				matcher += ", times: 1"
+			}
+			response := "{ statusCode: " + strconv.Itoa(intercept.Response.Status) + ", headers: " + jsLiteral(HeaderMap(intercept.Response.Headers))
+			if intercept.Fixture != "" {
+				// Binary fixtures need the null encoding, otherwise Cypress reads them as text
+				response += ", fixture: " + jsLiteral(intercept.Fixture+Tertiary(intercept.Response.Base64, ",null", ""))
+			}
+			lines = append(lines, "  cy.intercept("+matcher+" }, "+response+" }).as("+jsLiteral(alias)+");")
+		}
+	}
+	return strings.Join(append(lines, "}"), "\n")
+}
+
+func (cmd *ExportCypressCmd) Run() error {
+	har, err := ReadHarFile(cmd.File)
+	if err != nil {
+		return err
+	}
+	entries, err := SelectEntries(har.Log)
+	if err != nil {
+		return err
+	}
+	groups, err := WriteCypressFixtures(cmd.OutDir, entries)
+	if err != nil {
+		return err
+	}
+	return WriteOutput(FormatCypressIntercepts(cmd.File, cmd.OutDir, groups))
+}
@@ -0,0 +1,275 @@
+package main
+
+import (
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/fatih/color"
+)
+
+type DiffCmd struct {
+	Endpoint *string  `name:"endpoint" help:"Line up the calls to this endpoint in both files and compare them, eg \"GET /api/users/{id}\" or a real path"`
+	Header   []string `name:"header" default:"content-type,content-encoding,cache-control,vary,access-control-allow-origin" help:"Response headers to compare with --endpoint"`
+	Before   string   `arg:"" help:"The earlier HAR file" type:"existingfile"`
+	After    string   `arg:"" help:"The later HAR file" type:"existingfile"`
+}
+
+// EndpointMatcher matches entries against what the user typed for --endpoint, which may have a method, a host and IDs
+// which haven't been replaced by {id}
+type EndpointMatcher struct {
+	Method string
+	Host   string
+	Path   string
+}
+
+func NewEndpointMatcher(pattern string) EndpointMatcher {
+	matcher := EndpointMatcher{}
+	pattern = strings.TrimSpace(pattern)
+	if method, rest, found := strings.Cut(pattern, " "); found {
+		matcher.Method = strings.ToUpper(method)
+		pattern = strings.TrimSpace(rest)
+	}
+	if strings.Contains(pattern, "://") {
+		if parsed, err := url.Parse(pattern); err == nil {
+			matcher.Host = strings.ToLower(parsed.Host)
+			pattern = parsed.Path
+		}
+	} else if !strings.HasPrefix(pattern, "/") {
+		host, path, _ := strings.Cut(pattern, "/")
+		matcher.Host = strings.ToLower(host)
+		pattern = "/" + path
+	}
+	matcher.Path = TemplatePath(pattern)
+	return matcher
+}
+
+func (matcher EndpointMatcher) Matches(entry Entry) bool {
+	requestUrl, err := url.Parse(entry.Request.Url)
+	if err != nil {
+		return false
+	}
+	return (matcher.Method == "" || strings.EqualFold(entry.Request.Method, matcher.Method)) &&
+		(matcher.Host == "" || strings.EqualFold(requestUrl.Host, matcher.Host)) &&
+		TemplatePath(requestUrl.Path) == matcher.Path
+}
+
+// indexedEntry keeps the position of an entry in its file alongside it
+type indexedEntry struct {
+	Index int
+	Entry Entry
+}
+
+func selectDiffEntries(file string) ([]indexedEntry, error) {
+	har, err := ReadHarFile(file)
+	if err != nil {
+		return nil, err
+	}
+	indexes, err := SelectEntryIndexes(har.Log)
+	if err != nil {
+		return nil, err
+	}
+	entries := make([]indexedEntry, len(indexes))
+	for i, index := range indexes {
+		entries[i] = indexedEntry{Index: index, Entry: har.Log.Entries[index]}
+	}
+	return entries, nil
+}
+
+// FormatChange shows a before and after value, highlighting it when they differ
+func FormatChange(before string, after string) string {
+	if before == after {
+		return before
+	}
+	return before + color.YellowString(" -> ") + after
+}
+
+// FormatDelta is the percentage change from before to after, red when it got bigger
+func FormatDelta(before float64, after float64) string {
+	if before <= 0 {
+		return "-"
+	}
+	change := (after - before) / before * 100
+	text := strconv.FormatFloat(change, 'f', 0, 64) + "%"
+	switch {
+	case change >= 0.5:
+		return color.RedString("+" + text)
+	case change <= -0.5:
+		return color.GreenString(text)
+	}
+	return "0%"
+}
+
+func entryDurations(entries []indexedEntry) []float64 {
+	durations := make([]float64, len(entries))
+	for i, entry := range entries {
+		durations[i] = entry.Entry.TimeMs
+	}
+	sort.Float64s(durations)
+	return durations
+}
+
+// DiffEndpoint pairs the nth call to the endpoint in each file and compares them, then compares the structure of all
+// of their JSON bodies
+func DiffEndpoint(name string, before []indexedEntry, after []indexedEntry, headers []string) string {
+	output := []string{color.YellowString(name) + color.HiBlackString(" "+strconv.Itoa(len(before))+" calls before, "+strconv.Itoa(len(after))+" after")}
+	if len(before) == 0 || len(after) == 0 {
+		return strings.Join(append(output, color.HiBlackString("  Only called in one of the files")), "\n")
+	}
+
+	beforeDurations := entryDurations(before)
+	afterDurations := entryDurations(after)
+	p50Before, p50After := Percentile(beforeDurations, 50), Percentile(afterDurations, 50)
+	p95Before, p95After := Percentile(beforeDurations, 95), Percentile(afterDurations, 95)
+	output = append(output,
+		color.HiBlackString("  p50: ")+FormatChange(FormatDuration(p50Before), FormatDuration(p50After))+" "+FormatDelta(p50Before, p50After),
+		color.HiBlackString("  p95: ")+FormatChange(FormatDuration(p95Before), FormatDuration(p95After))+" "+FormatDelta(p95Before, p95After),
+		"")
+
+	rows := make([][]string, 0)
+	headerChanges := make([]string, 0)
+	for i := 0; i < max(len(before), len(after)); i++ {
+		if i >= len(before) {
+			rows = append(rows, []string{"-", "#" + strconv.Itoa(after[i].Index), "only after", "", ""})
+			continue
+		}
+		if i >= len(after) {
+			rows = append(rows, []string{"#" + strconv.Itoa(before[i].Index), "-", "only before", "", ""})
+			continue
+		}
+		a, b := before[i].Entry, after[i].Entry
+		rows = append(rows, []string{
+			"#" + strconv.Itoa(before[i].Index),
+			"#" + strconv.Itoa(after[i].Index),
+			FormatChange(StatusColor(a.Response.Status)(strconv.Itoa(a.Response.Status)), StatusColor(b.Response.Status)(strconv.Itoa(b.Response.Status))),
+			FormatChange(FormatDuration(a.TimeMs), FormatDuration(b.TimeMs)) + " " + FormatDelta(a.TimeMs, b.TimeMs),
+			FormatChange(FormatBytes(TransferSize(a)), FormatBytes(TransferSize(b))),
+		})
+		for _, header := range headers {
+			was := HeaderValue(a.Response.Headers, header)
+			is := HeaderValue(b.Response.Headers, header)
+			if was != is {
+				headerChanges = append(headerChanges, "    "+color.HiBlackString("#"+strconv.Itoa(before[i].Index)+" -> #"+strconv.Itoa(after[i].Index)+" ")+
+					strings.ToLower(header)+": "+FormatChange(Tertiary(was == "", "[none]", was), Tertiary(is == "", "[none]", is)))
+			}
+		}
+	}
+	output = append(output, Indent(FormatTable([]Column{
+		{Name: "Before"},
+		{Name: "After"},
+		{Name: "Status"},
+		{Name: "Time"},
+		{Name: "Size"},
+	}, rows), 2))
+
+	if len(headerChanges) > 0 {
+		output = append(output, "", color.HiBlackString("  Headers:"))
+		output = append(output, headerChanges...)
+	}
+
+	// The body structure is compared across all of the calls, since the nth calls may be for different resources
+	beforeBodies := make([]Entry, len(before))
+	for i, entry := range before {
+		beforeBodies[i] = entry.Entry
+	}
+	afterBodies := make([]Entry, len(after))
+	afterIndexes := make([]int, len(after))
+	for i, entry := range after {
+		afterBodies[i] = entry.Entry
+		afterIndexes[i] = entry.Index
+	}
+	groups := AuditSchemaDrift(afterBodies, afterIndexes, beforeBodies)
+	output = append(output, "", color.HiBlackString("  Body:"))
+	changed := false
+	for _, group := range groups {
+		for _, finding := range group.Findings {
+			output = append(output, "    "+FormatFinding(finding))
+			changed = true
+		}
+	}
+	if !changed {
+		output = append(output, "    "+color.GreenString("no changes to the structure of the JSON bodies"))
+	}
+	return strings.Join(output, "\n")
+}
+
+func entryHosts(entries []indexedEntry) map[string]bool {
+	hosts := make(map[string]bool)
+	for _, entry := range entries {
+		if requestUrl, err := url.Parse(entry.Entry.Request.Url); err == nil {
+			hosts[strings.ToLower(requestUrl.Host)] = true
+		}
+	}
+	return hosts
+}
+
+// DiffOverview lists the endpoints which were only called in one of the files. When the files have no hosts in
+// common, such as a capture of production and one replayed against staging, the endpoints are compared by path alone
+func DiffOverview(before []indexedEntry, after []indexedEntry) string {
+	afterHosts := entryHosts(after)
+	sameHosts := false
+	for host := range entryHosts(before) {
+		sameHosts = sameHosts || afterHosts[host]
+	}
+	counts := func(entries []indexedEntry) (map[string]int, []string) {
+		count := make(map[string]int)
+		order := make([]string, 0)
+		for _, entry := range entries {
+			endpoint := Endpoint(entry.Entry)
+			if requestUrl, err := url.Parse(entry.Entry.Request.Url); err == nil && !sameHosts {
+				endpoint = strings.ToUpper(entry.Entry.Request.Method) + " " + TemplatePath(requestUrl.Path)
+			}
+			if count[endpoint] == 0 {
+				order = append(order, endpoint)
+			}
+			count[endpoint]++
+		}
+		return count, order
+	}
+	beforeCounts, beforeOrder := counts(before)
+	afterCounts, afterOrder := counts(after)
+
+	rows := make([][]string, 0)
+	for _, endpoint := range beforeOrder {
+		if afterCounts[endpoint] == 0 {
+			rows = append(rows, []string{color.RedString("removed"), endpoint, strconv.Itoa(beforeCounts[endpoint]), "0"})
+		}
+	}
+	for _, endpoint := range afterOrder {
+		if beforeCounts[endpoint] == 0 {
+			rows = append(rows, []string{color.GreenString("added"), endpoint, "0", strconv.Itoa(afterCounts[endpoint])})
+		}
+	}
+	for _, endpoint := range beforeOrder {
+		if afterCounts[endpoint] > 0 && afterCounts[endpoint] != beforeCounts[endpoint] {
+			rows = append(rows, []string{color.YellowString("count"), endpoint, strconv.Itoa(beforeCounts[endpoint]), strconv.Itoa(afterCounts[endpoint])})
+		}
+	}
+	if len(rows) == 0 {
+		return color.GreenString("Both files called the same endpoints the same number of times, use --endpoint to compare one")
+	}
+	return FormatTable([]Column{{Name: "Change"}, {Name: "Endpoint"}, {Name: "Before", Right: true}, {Name: "After", Right: true}}, rows)
+}
+
+func (cmd *DiffCmd) Run() error {
+	before, err := selectDiffEntries(cmd.Before)
+	if err != nil {
+		return err
+	}
+	after, err := selectDiffEntries(cmd.After)
+	if err != nil {
+		return err
+	}
+	if cmd.Endpoint == nil {
+		return WriteOutput(DiffOverview(before, after))
+	}
+
+	matcher := NewEndpointMatcher(*cmd.Endpoint)
+	matches := func(entries []indexedEntry) []indexedEntry {
+		return Filter(entries, func(entry indexedEntry) bool {
+			return matcher.Matches(entry.Entry)
+		})
+	}
+	return WriteOutput(DiffEndpoint(*cmd.Endpoint, matches(before), matches(after), cmd.Header))
+}
@@ -0,0 +1,138 @@
+package main
+
+import (
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/fatih/color"
+)
+
+type AuditDnsCmd struct {
+	SlowMs float64 `name:"slow-ms" default:"50" help:"Hosts whose slowest lookup took at least this many milliseconds will be suggested for dns-prefetch"`
+	File   string  `arg:"" help:"The HAR file to parse, or - to read it from stdin" type:"existingfile"`
+}
+
+// DnsReport is the time spent resolving one hostname, where a lookup is any entry with a dns phase above zero since
+// browsers report 0 when the address was already cached
+type DnsReport struct {
+	Host     string
+	Requests int
+	Lookups  int
+	TotalMs  float64
+	MaxMs    float64
+	// FirstLookup is the index of the entry which first resolved the host
+	FirstLookup int
+	AuditGroup
+}
+
+func AuditDns(entries []Entry, indexes []int, slowMs float64) []*DnsReport {
+	reports := make([]*DnsReport, 0)
+	byHost := make(map[string]*DnsReport)
+	for i, entry := range entries {
+		requestUrl, err := url.Parse(entry.Request.Url)
+		if err != nil || requestUrl.Hostname() == "" {
+			continue
+		}
+		host := strings.ToLower(requestUrl.Hostname())
+		report, ok := byHost[host]
+		if !ok {
+			report = &DnsReport{Host: host, FirstLookup: -1}
+			report.Name = host
+			byHost[host] = report
+			reports = append(reports, report)
+		}
+		report.Requests++
+
+		dns := OrUnknown(entry.Timings.Dns)
+		if dns <= 0 {
+			continue
+		}
+		report.Lookups++
+		report.TotalMs += dns
+		report.MaxMs = max(report.MaxMs, dns)
+		if report.FirstLookup == -1 {
+			report.FirstLookup = indexes[i]
+		}
+	}
+
+	reports = Filter(reports, func(report *DnsReport) bool {
+		return report.Lookups > 0
+	})
+	sort.SliceStable(reports, func(i, j int) bool {
+		return reports[i].TotalMs > reports[j].TotalMs
+	})
+	for _, report := range reports {
+		report.Entries = report.Requests
+		if report.Lookups > 1 {
+			report.Add(SeverityWarning, "resolved "+strconv.Itoa(report.Lookups)+" times, "+FormatDuration(report.TotalMs-report.MaxMs)+
+				" could be saved by a longer TTL or reusing the connection")
+		}
+		if report.MaxMs >= slowMs {
+			report.Add(SeverityWarning, "a lookup took "+FormatDuration(report.MaxMs)+", a <link rel=\"dns-prefetch\"> hint would start it earlier")
+		}
+	}
+	return reports
+}
+
+func FormatDnsReports(reports []*DnsReport) string {
+	if len(reports) == 0 {
+		return color.HiBlackString("No entries with a DNS lookup")
+	}
+
+	lookups := 0
+	total := 0.0
+	rows := make([][]string, 0, len(reports))
+	for _, report := range reports {
+		lookups += report.Lookups
+		total += report.TotalMs
+		rows = append(rows, []string{
+			report.Host,
+			strconv.Itoa(report.Requests),
+			strconv.Itoa(report.Lookups),
+			FormatDuration(report.TotalMs),
+			FormatDuration(report.TotalMs / float64(report.Lookups)),
+			FormatDuration(report.MaxMs),
+			"#" + strconv.Itoa(report.FirstLookup),
+		})
+	}
+	output := []string{FormatTable([]Column{
+		{Name: "Host"},
+		{Name: "Requests", Right: true},
+		{Name: "Lookups", Right: true},
+		{Name: "Total", Right: true},
+		{Name: "Average", Right: true},
+		{Name: "Max", Right: true},
+		{Name: "First", Right: true},
+	}, rows)}
+	output = append(output, "", color.HiBlackString("Total: ")+strconv.Itoa(lookups)+" lookups of "+strconv.Itoa(len(reports))+
+		" hosts taking "+FormatDuration(total))
+
+	for _, report := range reports {
+		if len(report.Findings) == 0 {
+			continue
+		}
+		output = append(output, "", color.YellowString(report.Host))
+		for _, finding := range report.Findings {
+			output = append(output, "  "+FormatFinding(finding))
+		}
+	}
+	return strings.Join(output, "\n")
+}
+
+func (cmd *AuditDnsCmd) Run() error {
+	har, err := ReadHar(cmd.File, false)
+	if err != nil {
+		return err
+	}
+	indexes, err := SelectEntryIndexes(har.Log)
+	if err != nil {
+		return err
+	}
+	entries := make([]Entry, len(indexes))
+	for i, index := range indexes {
+		entries[i] = har.Log.Entries[index]
+	}
+	return WriteOutput(FormatDnsReports(AuditDns(entries, indexes, cmd.SlowMs)))
+}
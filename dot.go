@@ -0,0 +1,129 @@
+package main
+
+import (
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+type ExportDotCmd struct {
+	Hosts bool   `name:"hosts" help:"Draw a node for each host rather than each URL, counting the requests along each edge"`
+	File  string `arg:"" help:"The HAR file to parse, or - to read it from stdin" type:"existingfile"`
+}
+
+// InitiatorUrl is the URL of the document or script which caused the request. Chrome gives the URL for requests made
+// by the parser, and the call stack for requests made by scripts, where the innermost frame with a URL is the script
+// which made it. Without either the Referer is used, which is the document for most subresources
+func InitiatorUrl(entry Entry) string {
+	if entry.Initiator != nil {
+		if entry.Initiator.Url != nil && *entry.Initiator.Url != "" {
+			return *entry.Initiator.Url
+		}
+		for stack := entry.Initiator.Stack; stack != nil; stack = stack.Parent {
+			for _, frame := range stack.CallFrames {
+				if frame.Url != "" {
+					return frame.Url
+				}
+			}
+		}
+	}
+	return HeaderValue(entry.Request.Headers, "referer")
+}
+
+// dotNodeName is how a URL is labelled in the graph, the host and path without the query
+func dotNodeName(raw string, hosts bool) string {
+	parsed, err := url.Parse(raw)
+	if err != nil || parsed.Host == "" {
+		return MiddleEllipsis(raw, maxSequenceLabel)
+	}
+	if hosts {
+		return strings.ToLower(parsed.Host)
+	}
+	return MiddleEllipsis(strings.ToLower(parsed.Host)+parsed.EscapedPath(), maxSequenceLabel)
+}
+
+// dotText escapes text for a quoted Graphviz string
+func dotText(text string) string {
+	return strings.NewReplacer("\\", "\\\\", "\"", "\\\"", "\n", " ").Replace(text)
+}
+
+// FormatDot draws an edge from the initiator of each request to the request, filling third parties in a different
+// colour to the site of the first document so their loading chains stand out
+func FormatDot(entries []Entry, hosts bool) string {
+	firstParty := ""
+	for _, entry := range entries {
+		if CspResourceKind(entry) == "frame" || strings.Contains(MimeType(entry), "html") {
+			firstParty = SiteOf(entry.Request.Url)
+			break
+		}
+	}
+	if firstParty == "" && len(entries) > 0 {
+		firstParty = SiteOf(entries[0].Request.Url)
+	}
+
+	type edge struct{ from, to string }
+	ids := make(map[string]string)
+	nodes := make([]string, 0)
+	kinds := make(map[string]string)
+	sites := make(map[string]string)
+	node := func(raw string) string {
+		name := dotNodeName(raw, hosts)
+		if _, ok := ids[name]; !ok {
+			ids[name] = "n" + strconv.Itoa(len(ids)+1)
+			nodes = append(nodes, name)
+			sites[name] = SiteOf(raw)
+		}
+		return name
+	}
+	edges := make([]edge, 0)
+	counts := make(map[edge]int)
+	for _, entry := range entries {
+		to := node(entry.Request.Url)
+		if kinds[to] == "" {
+			kinds[to] = CspResourceKind(entry)
+		}
+		initiator := InitiatorUrl(entry)
+		if initiator == "" {
+			continue
+		}
+		from := node(initiator)
+		if from == to {
+			continue
+		}
+		key := edge{from, to}
+		if counts[key] == 0 {
+			edges = append(edges, key)
+		}
+		counts[key]++
+	}
+
+	lines := []string{"digraph initiators {", "  rankdir=LR;", "  node [shape=box, style=\"rounded,filled\", fontname=\"Helvetica\", fontsize=10];"}
+	for _, name := range nodes {
+		label := dotText(name)
+		if !hosts && kinds[name] != "" {
+			label += "\\n" + kinds[name]
+		}
+		fill := Tertiary(sites[name] == firstParty, "#cfe2f3", "#f4cccc")
+		lines = append(lines, "  "+ids[name]+" [label=\""+label+"\", fillcolor=\""+fill+"\"];")
+	}
+	for _, key := range edges {
+		line := "  " + ids[key.from] + " -> " + ids[key.to]
+		if counts[key] > 1 {
+			line += " [label=\"" + strconv.Itoa(counts[key]) + "\"]"
+		}
+		lines = append(lines, line+";")
+	}
+	return strings.Join(append(lines, "}"), "\n")
+}
+
+func (cmd *ExportDotCmd) Run() error {
+	har, err := ReadHar(cmd.File, false)
+	if err != nil {
+		return err
+	}
+	entries, err := SelectEntries(har.Log)
+	if err != nil {
+		return err
+	}
+	return WriteOutput(FormatDot(entries, cmd.Hosts))
+}
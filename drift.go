@@ -0,0 +1,234 @@
+package main
+
+import (
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+type AuditSchemaDriftCmd struct {
+	Baseline *string `name:"baseline" help:"Compare each endpoint's response bodies to the ones in this earlier HAR file instead of within the capture"`
+	File     string  `arg:"" help:"The HAR file to parse, or - to read it from stdin" type:"existingfile"`
+}
+
+// JsonFieldTypes flattens a body into the types seen at each path, such as $.items[].id, where a field of an array of
+// objects counts as present if any of them had it
+func JsonFieldTypes(value any) map[string]string {
+	seen := make(map[string]map[string]bool)
+	var walk func(path string, value any)
+	walk = func(path string, value any) {
+		if seen[path] == nil {
+			seen[path] = make(map[string]bool)
+		}
+		seen[path][JsonType(value)] = true
+		switch typed := value.(type) {
+		case []any:
+			for _, item := range typed {
+				walk(path+"[]", item)
+			}
+		case map[string]any:
+			for key, item := range typed {
+				walk(path+"."+key, item)
+			}
+		}
+	}
+	walk("$", value)
+
+	fields := make(map[string]string, len(seen))
+	for path, types := range seen {
+		// null alongside a type is treated as optional rather than a change of type
+		if len(types) > 1 {
+			delete(types, "null")
+		}
+		// A float which happened to be whole isn't a change of type
+		if types["integer"] {
+			delete(types, "integer")
+			types["number"] = true
+		}
+		names := make([]string, 0, len(types))
+		for name := range types {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		fields[path] = strings.Join(names, "|")
+	}
+	return fields
+}
+
+// driftBody is one JSON response and the entry it came from
+type driftBody struct {
+	Index  int
+	Fields map[string]string
+}
+
+func collectDriftBodies(entries []Entry, indexes []int) ([]string, map[string][]driftBody) {
+	endpoints := make([]string, 0)
+	bodies := make(map[string][]driftBody)
+	for i, entry := range entries {
+		if entry.Response.Status < 200 || entry.Response.Status >= 300 {
+			continue
+		}
+		body, ok := EntryJsonBody(entry, false)
+		if !ok {
+			continue
+		}
+		endpoint := Endpoint(entry)
+		if _, ok := bodies[endpoint]; !ok {
+			endpoints = append(endpoints, endpoint)
+		}
+		bodies[endpoint] = append(bodies[endpoint], driftBody{Index: indexes[i], Fields: JsonFieldTypes(body)})
+	}
+	return endpoints, bodies
+}
+
+func sortedFieldPaths(bodies []driftBody) []string {
+	unique := make(map[string]bool)
+	for _, body := range bodies {
+		for path := range body.Fields {
+			unique[path] = true
+		}
+	}
+	paths := make([]string, 0, len(unique))
+	for path := range unique {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	return paths
+}
+
+// DetectDrift looks for a point in the calls to an endpoint where a field consistently appears, disappears or changes
+// type, which is what a deploy part way through the session looks like. Fields which come and go are just optional
+func DetectDrift(bodies []driftBody, group *AuditGroup) {
+	for _, path := range sortedFieldPaths(bodies) {
+		states := make([]string, len(bodies))
+		for i, body := range bodies {
+			states[i] = body.Fields[path]
+		}
+
+		// The switch point is where the state changes, as long as it never changes back
+		changes := 0
+		at := -1
+		for i := 1; i < len(states); i++ {
+			if states[i] != states[i-1] {
+				changes++
+				at = i
+			}
+		}
+		if changes != 1 {
+			if changes > 1 && !slices.Contains(states, "") {
+				group.Add(SeverityWarning, path+" alternates between "+strings.Join(UniqueStrings(states), " and "))
+			}
+			continue
+		}
+
+		before, after := states[at-1], states[at]
+		call := "from #" + strconv.Itoa(bodies[at].Index) + ", after " + strconv.Itoa(at) + " of " + strconv.Itoa(len(bodies)) + " calls"
+		switch {
+		case before == "":
+			group.Add(SeverityWarning, path+" appeared as "+after+" "+call)
+		case after == "":
+			group.Add(SeverityWarning, path+" disappeared "+call)
+		default:
+			group.Add(SeverityError, path+" changed from "+before+" to "+after+" "+call)
+		}
+	}
+}
+
+// CompareDrift reports the fields of an endpoint which are only in one capture or have different types in each
+func CompareDrift(baseline []driftBody, current []driftBody, group *AuditGroup) {
+	union := func(bodies []driftBody) map[string]string {
+		types := make(map[string][]string)
+		for _, body := range bodies {
+			for path, name := range body.Fields {
+				types[path] = append(types[path], name)
+			}
+		}
+		fields := make(map[string]string, len(types))
+		for path, names := range types {
+			fields[path] = strings.Join(UniqueStrings(names), " and ")
+		}
+		return fields
+	}
+	before := union(baseline)
+	after := union(current)
+	for _, path := range sortedFieldPaths(append(append([]driftBody{}, baseline...), current...)) {
+		was, inBaseline := before[path]
+		is, inCurrent := after[path]
+		switch {
+		case !inBaseline:
+			group.Add(SeverityWarning, path+" was added as "+is)
+		case !inCurrent:
+			group.Add(SeverityWarning, path+" was removed, it was "+was)
+		case was != is:
+			group.Add(SeverityError, path+" changed from "+was+" to "+is)
+		}
+	}
+}
+
+// UniqueStrings keeps the first occurrence of each value, skipping empty strings
+func UniqueStrings(values []string) []string {
+	unique := make([]string, 0)
+	for _, value := range values {
+		if value != "" && !slices.Contains(unique, value) {
+			unique = append(unique, value)
+		}
+	}
+	return unique
+}
+
+func AuditSchemaDrift(entries []Entry, indexes []int, baseline []Entry) []*AuditGroup {
+	endpoints, bodies := collectDriftBodies(entries, indexes)
+	var baselineBodies map[string][]driftBody
+	if baseline != nil {
+		baselineIndexes := make([]int, len(baseline))
+		for i := range baseline {
+			baselineIndexes[i] = i
+		}
+		_, baselineBodies = collectDriftBodies(baseline, baselineIndexes)
+	}
+
+	groups := make([]*AuditGroup, 0, len(endpoints))
+	for _, endpoint := range endpoints {
+		group := &AuditGroup{Name: endpoint, Entries: len(bodies[endpoint])}
+		if baseline == nil {
+			DetectDrift(bodies[endpoint], group)
+		} else if previous, ok := baselineBodies[endpoint]; ok {
+			group.Summary = strconv.Itoa(len(previous)) + " in baseline"
+			CompareDrift(previous, bodies[endpoint], group)
+		} else {
+			group.Summary = "not in baseline"
+		}
+		groups = append(groups, group)
+	}
+	return groups
+}
+
+func (cmd *AuditSchemaDriftCmd) Run() error {
+	// The baseline is selected first so the capture start used for the output is the current file's
+	var baseline []Entry
+	if cmd.Baseline != nil {
+		baselineHar, err := ReadHarFile(*cmd.Baseline)
+		if err != nil {
+			return err
+		}
+		baseline, err = SelectEntries(baselineHar.Log)
+		if err != nil {
+			return err
+		}
+	}
+
+	har, err := ReadHarFile(cmd.File)
+	if err != nil {
+		return err
+	}
+	indexes, err := SelectEntryIndexes(har.Log)
+	if err != nil {
+		return err
+	}
+	entries := make([]Entry, len(indexes))
+	for i, index := range indexes {
+		entries[i] = har.Log.Entries[index]
+	}
+	return WriteOutput(FormatAuditGroups(AuditSchemaDrift(entries, indexes, baseline)))
+}
@@ -0,0 +1,194 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"net"
+	"net/url"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type ExportEcsCmd struct {
+	Index *string `name:"index" help:"Put an Elasticsearch bulk create action for this index before each document, so the output can be posted to /_bulk as it is"`
+	File  string  `arg:"" help:"The HAR file to parse, or - to read it from stdin" type:"existingfile"`
+}
+
+// ecsVersion is the version of the Elastic Common Schema the documents follow
+const ecsVersion = "8.11.0"
+
+// ecsHttpVersions are the versions as ECS writes them in http.version
+var ecsHttpVersions = map[string]string{"h3": "3", "h2": "2", "http/1.1": "1.1", "http/1.0": "1.0"}
+
+// EcsDocument converts an entry to an event using the Elastic Common Schema fields for HTTP requests, with the
+// fields ECS has no place for, such as the page and timing phases, under harv
+func EcsDocument(entry Entry) map[string]any {
+	started, _ := ParseStartedDateTime(entry.StartedDateTime)
+	duration := time.Duration(entry.TimeMs * float64(time.Millisecond))
+	event := map[string]any{
+		"kind":     "event",
+		"category": []string{"web", "network"},
+		"type":     []string{"access", "connection"},
+		"duration": duration.Nanoseconds(),
+		"start":    started.UTC().Format(time.RFC3339Nano),
+		"end":      started.Add(duration).UTC().Format(time.RFC3339Nano),
+		"outcome":  Tertiary(IsErrorResponse(entry.Response), "failure", "success"),
+	}
+
+	request := map[string]any{
+		"method": strings.ToUpper(entry.Request.Method),
+	}
+	if entry.Request.BodySize >= 0 {
+		request["body"] = map[string]any{"bytes": entry.Request.BodySize}
+		if entry.Request.HeadersSize >= 0 {
+			request["bytes"] = entry.Request.HeadersSize + entry.Request.BodySize
+		}
+	}
+	if entry.Request.PostData != nil && entry.Request.PostData.MimeType != "" {
+		request["mime_type"] = entry.Request.PostData.MimeType
+	}
+	if referrer := HeaderValue(entry.Request.Headers, "referer"); referrer != "" {
+		request["referrer"] = referrer
+	}
+	response := map[string]any{
+		"status_code": entry.Response.Status,
+		"bytes":       TransferSize(entry),
+	}
+	if entry.Response.Content != nil && entry.Response.Content.Size >= 0 {
+		response["body"] = map[string]any{"bytes": entry.Response.Content.Size}
+	}
+	if mime := MimeType(entry); mime != "[none]" {
+		response["mime_type"] = mime
+	}
+	httpFields := map[string]any{"request": request, "response": response}
+	if version, ok := ecsHttpVersions[EntryHttpVersion(entry)]; ok {
+		httpFields["version"] = version
+	}
+
+	harvFields := map[string]any{
+		"timings": map[string]any{
+			"blocked": OrUnknown(entry.Timings.Blocked),
+			"dns":     OrUnknown(entry.Timings.Dns),
+			"connect": OrUnknown(entry.Timings.Connect),
+			"ssl":     OrUnknown(entry.Timings.Ssl),
+			"send":    entry.Timings.Send,
+			"wait":    entry.Timings.Wait,
+			"receive": entry.Timings.Receive,
+		},
+	}
+	if entry.PageRef != nil {
+		harvFields["page"] = *entry.PageRef
+	}
+	if entry.ResourceType != nil {
+		harvFields["resource_type"] = *entry.ResourceType
+	}
+	document := map[string]any{
+		"@timestamp": started.UTC().Format(time.RFC3339Nano),
+		"ecs":        map[string]any{"version": ecsVersion},
+		"event":      event,
+		"http":       httpFields,
+		"harv":       harvFields,
+	}
+	if agent := HeaderValue(entry.Request.Headers, "user-agent"); agent != "" {
+		document["user_agent"] = map[string]any{"original": agent}
+	}
+
+	if requestUrl, err := url.Parse(entry.Request.Url); err == nil {
+		urlFields := map[string]any{
+			"full":     entry.Request.Url,
+			"original": entry.Request.Url,
+			"scheme":   requestUrl.Scheme,
+			"domain":   requestUrl.Hostname(),
+			"path":     requestUrl.Path,
+		}
+		if requestUrl.RawQuery != "" {
+			urlFields["query"] = requestUrl.RawQuery
+		}
+		if requestUrl.Fragment != "" {
+			urlFields["fragment"] = requestUrl.Fragment
+		}
+		if extension := strings.TrimPrefix(path.Ext(requestUrl.Path), "."); extension != "" {
+			urlFields["extension"] = extension
+		}
+		port := requestUrl.Port()
+		if port == "" {
+			port = Tertiary(requestUrl.Scheme == "https", "443", "80")
+		}
+		if number, err := strconv.Atoi(port); err == nil {
+			urlFields["port"] = number
+		}
+		document["url"] = urlFields
+
+		destination := map[string]any{"domain": requestUrl.Hostname(), "address": requestUrl.Hostname()}
+		if number, ok := urlFields["port"]; ok {
+			destination["port"] = number
+		}
+		if entry.ServerIP != nil {
+			if ip := net.ParseIP(strings.Trim(*entry.ServerIP, "[]")); ip != nil {
+				destination["ip"] = ip.String()
+				destination["address"] = ip.String()
+			}
+		}
+		document["destination"] = destination
+	}
+
+	if entry.SecurityDetails != nil && entry.SecurityDetails.Protocol != "" {
+		tls := map[string]any{
+			"version_protocol": "tls",
+			"version":          NormalizeTlsVersion(entry.SecurityDetails.Protocol),
+			"cipher":           entry.SecurityDetails.Cipher,
+			"server": map[string]any{
+				"subject": entry.SecurityDetails.SubjectName,
+				"issuer":  entry.SecurityDetails.Issuer,
+			},
+		}
+		if protocol := EntryHttpVersion(entry); protocol != "unknown" {
+			tls["next_protocol"] = protocol
+		}
+		document["tls"] = tls
+	}
+	if entry.Comment != nil {
+		document["message"] = *entry.Comment
+	}
+	return document
+}
+
+// FormatEcs writes a document on each line, preceded by a bulk create action when index isn't empty
+func FormatEcs(entries []Entry, index string) (string, error) {
+	var buffer bytes.Buffer
+	encoder := json.NewEncoder(&buffer)
+	encoder.SetEscapeHTML(false)
+	for _, entry := range entries {
+		if index != "" {
+			if err := encoder.Encode(map[string]any{"create": map[string]any{"_index": index}}); err != nil {
+				return "", err
+			}
+		}
+		if err := encoder.Encode(EcsDocument(entry)); err != nil {
+			return "", err
+		}
+	}
+	return strings.TrimSuffix(buffer.String(), "\n"), nil
+}
+
+func (cmd *ExportEcsCmd) Run() error {
+	har, err := ReadHar(cmd.File, false)
+	if err != nil {
+		return err
+	}
+	entries, err := SelectEntries(har.Log)
+	if err != nil {
+		return err
+	}
+	index := ""
+	if cmd.Index != nil {
+		index = *cmd.Index
+	}
+	output, err := FormatEcs(entries, index)
+	if err != nil {
+		return err
+	}
+	return WriteOutput(output)
+}
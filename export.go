@@ -0,0 +1,307 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"net/url"
+	"path"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type ExportCmd struct {
+	Cookies    ExportCookiesCmd    `cmd:"" name:"cookies" help:"Write the final state of the cookies as a Netscape cookie jar for curl and wget"`
+	Mermaid    ExportMermaidCmd    `cmd:"" name:"mermaid" help:"Write the entries as a Mermaid sequence diagram between the client and each host"`
+	Plantuml   ExportPlantumlCmd   `cmd:"" name:"plantuml" help:"Write the entries as a PlantUML sequence diagram, optionally grouped by page and with static assets collapsed"`
+	Apidocs    ExportApidocsCmd    `cmd:"" name:"apidocs" help:"Write Markdown documentation of each endpoint with its parameters, status codes and example bodies"`
+	Insomnia   ExportInsomniaCmd   `cmd:"" name:"insomnia" help:"Write the requests as an Insomnia v4 export with a folder for each host"`
+	Bruno      ExportBrunoCmd      `cmd:"" name:"bruno" help:"Write the requests as a Bruno collection with a .bru file for each request"`
+	Http       ExportHttpCmd       `cmd:"" name:"http" help:"Write the requests as an .http file for the VS Code REST Client and JetBrains HTTP Client"`
+	Hurl       ExportHurlCmd       `cmd:"" name:"hurl" help:"Write the requests as a Hurl file asserting the recorded status, content type and JSON fields"`
+	Playwright ExportPlaywrightCmd `cmd:"" name:"playwright" help:"Write Playwright page.route() handlers serving the recorded responses, or a HAR for routeFromHAR"`
+	Cypress    ExportCypressCmd    `cmd:"" name:"cypress" help:"Write the response bodies as Cypress fixtures and the cy.intercept() calls which serve them"`
+	Msw        ExportMswCmd        `cmd:"" name:"msw" help:"Write Mock Service Worker handlers returning the recorded responses"`
+	Wiremock   ExportWiremockCmd   `cmd:"" name:"wiremock" help:"Write WireMock stub mappings and body files serving the recorded responses"`
+	Smoketest  ExportSmoketestCmd  `cmd:"" name:"smoketest" help:"Write a Go test or shell script checking each GET endpoint still responds with the recorded status"`
+	FuzzCorpus ExportFuzzCorpusCmd `cmd:"" name:"fuzz-corpus" help:"Write each distinct request body as a seed file in a directory for its endpoint, for go-fuzz, AFL or ffuf"`
+	Contracts  ExportContractsCmd  `cmd:"" name:"contract-tests" help:"Write a Go test sending a request to each JSON endpoint and validating the response against the inferred schema"`
+	Burp       ExportBurpCmd       `cmd:"" name:"burp" help:"Write the requests and responses as the XML of Burp's Save items"`
+	Zap        ExportZapCmd        `cmd:"" name:"zap" help:"Send the requests through a running OWASP ZAP instance, optionally starting an active scan"`
+	Ecs        ExportEcsCmd        `cmd:"" name:"ecs" help:"Write each entry as an Elastic Common Schema document on its own line, for indexing in Elasticsearch"`
+	Otlp       ExportOtlpCmd       `cmd:"" name:"otlp" help:"Convert each entry to an OpenTelemetry client span with events for its timing phases, written as OTLP JSON or sent to a collector"`
+	Prom       ExportPromCmd       `cmd:"" name:"prom" help:"Write request counts, error counts and duration histograms by endpoint and status in the Prometheus exposition format"`
+	Timeseries ExportTimeseriesCmd `cmd:"" name:"timeseries" help:"Write the request rate, error rate, p95 duration and bytes of each bucket of time over the capture as CSV or JSON"`
+	Jaeger     ExportJaegerCmd     `cmd:"" name:"jaeger" help:"Write each page as a Jaeger trace with the document as the root span and each request under the one which loaded its initiator"`
+	Dot        ExportDotCmd        `cmd:"" name:"dot" help:"Write a Graphviz graph of which documents and scripts triggered which requests"`
+	Flamegraph ExportFlamegraphCmd `cmd:"" name:"flamegraph" help:"Write the bytes or time of each domain and path as folded stacks or an SVG flamegraph"`
+}
+
+type ExportCookiesCmd struct {
+	File string `arg:"" help:"The HAR file to parse, or - to read it from stdin" type:"existingfile"`
+}
+
+// JarCookie is a cookie as a browser would store it, HostOnly cookies are only sent to the exact host which set them
+type JarCookie struct {
+	Domain   string
+	HostOnly bool
+	Path     string
+	Secure   bool
+	HttpOnly bool
+	// Expires is zero for session cookies
+	Expires time.Time
+	Name    string
+	Value   string
+}
+
+// CookieJar keeps the cookies in the order they were first seen so the exported file is stable
+type CookieJar struct {
+	cookies map[[3]string]*JarCookie
+	order   [][3]string
+}
+
+func NewCookieJar() *CookieJar {
+	return &CookieJar{cookies: make(map[[3]string]*JarCookie)}
+}
+
+func (jar *CookieJar) Set(cookie JarCookie) {
+	key := [3]string{cookie.Domain, cookie.Path, cookie.Name}
+	if _, ok := jar.cookies[key]; !ok {
+		jar.order = append(jar.order, key)
+	}
+	jar.cookies[key] = &cookie
+}
+
+func (jar *CookieJar) Delete(cookie JarCookie) {
+	key := [3]string{cookie.Domain, cookie.Path, cookie.Name}
+	if _, ok := jar.cookies[key]; !ok {
+		return
+	}
+	delete(jar.cookies, key)
+	jar.order = slices.DeleteFunc(jar.order, func(existing [3]string) bool { return existing == key })
+}
+
+// Find returns a stored cookie which would have been sent to host with this name
+func (jar *CookieJar) Find(host string, name string) *JarCookie {
+	for _, key := range jar.order {
+		cookie, ok := jar.cookies[key]
+		if ok && cookie.Name == name && (cookie.Domain == host || !cookie.HostOnly && strings.HasSuffix(host, "."+cookie.Domain)) {
+			return cookie
+		}
+	}
+	return nil
+}
+
+func (jar *CookieJar) Cookies() []JarCookie {
+	cookies := make([]JarCookie, 0, len(jar.cookies))
+	for _, key := range jar.order {
+		if cookie, ok := jar.cookies[key]; ok {
+			cookies = append(cookies, *cookie)
+		}
+	}
+	return cookies
+}
+
+// DefaultCookiePath is the directory of the request path, which is where a cookie without a Path attribute applies
+func DefaultCookiePath(requestPath string) string {
+	if !strings.HasPrefix(requestPath, "/") || strings.Count(requestPath, "/") == 1 {
+		return "/"
+	}
+	return path.Dir(requestPath)
+}
+
+func ParseCookieTime(v string) (time.Time, bool) {
+	if parsed, err := time.Parse(time.RFC3339, v); err == nil {
+		return parsed, true
+	}
+	if parsed, err := http.ParseTime(v); err == nil {
+		return parsed, true
+	}
+	return time.Time{}, false
+}
+
+// SetCookies are the cookies set by a response, from the parsed cookies when the HAR has them and otherwise from the
+// Set-Cookie headers
+func SetCookies(entry Entry) []http.Cookie {
+	cookies := make([]http.Cookie, 0)
+	if len(entry.Response.Cookies) > 0 {
+		for _, cookie := range entry.Response.Cookies {
+			parsed := http.Cookie{Name: cookie.Name, Value: cookie.Value}
+			if cookie.Path != nil {
+				parsed.Path = *cookie.Path
+			}
+			if cookie.Domain != nil {
+				parsed.Domain = *cookie.Domain
+			}
+			if cookie.Expires != nil {
+				parsed.Expires, _ = ParseCookieTime(*cookie.Expires)
+			}
+			parsed.Secure = cookie.Secure != nil && *cookie.Secure
+			parsed.HttpOnly = cookie.HttpOnly != nil && *cookie.HttpOnly
+			cookies = append(cookies, parsed)
+		}
+		return cookies
+	}
+
+	header := make(http.Header)
+	for _, h := range entry.Response.Headers {
+		if strings.EqualFold(h.Name, "set-cookie") {
+			header.Add("Set-Cookie", h.Value)
+		}
+	}
+	for _, cookie := range (&http.Response{Header: header}).Cookies() {
+		cookies = append(cookies, *cookie)
+	}
+	return cookies
+}
+
+// ToJarCookie resolves the domain, path and expiry of a cookie set by entry, returning false if it deletes the cookie
+func ToJarCookie(entry Entry, cookie http.Cookie) (JarCookie, bool) {
+	requestUrl, err := url.Parse(entry.Request.Url)
+	if err != nil {
+		return JarCookie{}, false
+	}
+	jarCookie := JarCookie{
+		Domain:   strings.ToLower(requestUrl.Hostname()),
+		HostOnly: true,
+		Path:     Tertiary(strings.HasPrefix(cookie.Path, "/"), cookie.Path, DefaultCookiePath(requestUrl.Path)),
+		Secure:   cookie.Secure,
+		HttpOnly: cookie.HttpOnly,
+		Expires:  cookie.Expires,
+		Name:     cookie.Name,
+		Value:    cookie.Value,
+	}
+	if cookie.Domain != "" {
+		jarCookie.Domain = strings.ToLower(strings.TrimPrefix(cookie.Domain, "."))
+		jarCookie.HostOnly = false
+	}
+
+	now := time.Now()
+	if started, ok := ParseStartedDateTime(entry.StartedDateTime); ok {
+		now = started
+	}
+	if cookie.MaxAge < 0 {
+		return jarCookie, false
+	}
+	if cookie.MaxAge > 0 {
+		jarCookie.Expires = now.Add(time.Duration(cookie.MaxAge) * time.Second)
+	}
+	return jarCookie, jarCookie.Expires.IsZero() || jarCookie.Expires.After(now)
+}
+
+// Apply stores or deletes a cookie set by entry, returning what happened to it: set, overwritten, refreshed when the
+// value didn't change, deleted, or expired when an expired cookie was set which wasn't stored
+func (jar *CookieJar) Apply(entry Entry, cookie http.Cookie) (JarCookie, string) {
+	jarCookie, keep := ToJarCookie(entry, cookie)
+	existing, exists := jar.cookies[[3]string{jarCookie.Domain, jarCookie.Path, jarCookie.Name}]
+	if !keep {
+		jar.Delete(jarCookie)
+		return jarCookie, Tertiary(exists, "deleted", "expired")
+	}
+	jar.Set(jarCookie)
+	switch {
+	case !exists:
+		return jarCookie, "set"
+	case existing.Value == jarCookie.Value:
+		return jarCookie, "refreshed"
+	}
+	return jarCookie, "overwritten"
+}
+
+// BuildCookieJar replays the cookies sent and set by each entry in order. Cookies seen in requests are only added if
+// they weren't set in the capture, as the request doesn't say which domain or path they belong to
+func BuildCookieJar(entries []Entry) *CookieJar {
+	jar := NewCookieJar()
+	for _, entry := range entries {
+		requestUrl, err := url.Parse(entry.Request.Url)
+		if err != nil {
+			continue
+		}
+		host := strings.ToLower(requestUrl.Hostname())
+		for _, cookie := range entry.Request.Cookies {
+			if existing := jar.Find(host, cookie.Name); existing != nil {
+				existing.Value = cookie.Value
+				continue
+			}
+			jar.Set(JarCookie{Domain: host, HostOnly: true, Path: "/", Name: cookie.Name, Value: cookie.Value})
+		}
+
+		for _, cookie := range SetCookies(entry) {
+			jar.Apply(entry, cookie)
+		}
+	}
+	return jar
+}
+
+func FormatNetscapeCookies(cookies []JarCookie) string {
+	lines := []string{"# Netscape HTTP Cookie File", "# Exported by harv", ""}
+	for _, cookie := range cookies {
+		domain := Tertiary(cookie.HostOnly, cookie.Domain, "."+cookie.Domain)
+		if cookie.HttpOnly {
+			// curl reads the prefix as the HttpOnly flag, other tools treat the line as a comment
+			domain = "#HttpOnly_" + domain
+		}
+		expires := int64(0)
+		if !cookie.Expires.IsZero() {
+			expires = cookie.Expires.Unix()
+		}
+		lines = append(lines, strings.Join([]string{
+			domain,
+			Tertiary(cookie.HostOnly, "FALSE", "TRUE"),
+			cookie.Path,
+			Tertiary(cookie.Secure, "TRUE", "FALSE"),
+			strconv.FormatInt(expires, 10),
+			cookie.Name,
+			cookie.Value,
+		}, "\t"))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// ParseNetscapeCookies reads a cookie jar written by curl, wget, browser extensions or export cookies
+func ParseNetscapeCookies(text string) ([]JarCookie, error) {
+	cookies := make([]JarCookie, 0)
+	for number, line := range strings.Split(text, "\n") {
+		line = strings.TrimRight(line, "\r")
+		httpOnly := strings.HasPrefix(line, "#HttpOnly_")
+		line = strings.TrimPrefix(line, "#HttpOnly_")
+		if strings.TrimSpace(line) == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		fields := strings.Split(line, "\t")
+		if len(fields) != 7 {
+			return nil, errors.New("line " + strconv.Itoa(number+1) + " of the cookie jar doesn't have the 7 tab separated fields of a Netscape cookie file")
+		}
+		expires, err := strconv.ParseInt(fields[4], 10, 64)
+		if err != nil {
+			return nil, errors.New("line " + strconv.Itoa(number+1) + " of the cookie jar has an invalid expiry: " + fields[4])
+		}
+		cookie := JarCookie{
+			Domain:   strings.TrimPrefix(strings.ToLower(fields[0]), "."),
+			HostOnly: strings.EqualFold(fields[1], "FALSE"),
+			Path:     fields[2],
+			Secure:   strings.EqualFold(fields[3], "TRUE"),
+			HttpOnly: httpOnly,
+			Name:     fields[5],
+			Value:    fields[6],
+		}
+		if expires > 0 {
+			cookie.Expires = time.Unix(expires, 0)
+		}
+		cookies = append(cookies, cookie)
+	}
+	return cookies, nil
+}
+
+func (cmd *ExportCookiesCmd) Run() error {
+	har, err := ReadHar(cmd.File, BodiesNeeded())
+	if err != nil {
+		return err
+	}
+	entries, err := SelectEntries(har.Log)
+	if err != nil {
+		return err
+	}
+	return WriteOutput(FormatNetscapeCookies(BuildCookieJar(entries).Cookies()))
+}
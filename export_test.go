@@ -0,0 +1,34 @@
+package main
+
+import "testing"
+
+func TestCookieJarSetExpireSet(t *testing.T) {
+	setCookie := func(value string) Entry {
+		return Entry{
+			StartedDateTime: "2024-01-01T00:00:00Z",
+			Request:         Request{Method: "GET", Url: "https://example.com/"},
+			Response:        Response{Headers: []Header{{Name: "Set-Cookie", Value: value}}},
+		}
+	}
+	jar := BuildCookieJar([]Entry{setCookie("sid=one"), setCookie("sid=; Max-Age=0"), setCookie("sid=two")})
+
+	cookies := jar.Cookies()
+	if len(cookies) != 1 {
+		t.Fatalf("jar has %d cookies, expected 1: %v", len(cookies), cookies)
+	}
+	if cookies[0].Value != "two" {
+		t.Errorf("cookie value is %q, expected two", cookies[0].Value)
+	}
+}
+
+func TestCookieJarDeleteMissing(t *testing.T) {
+	jar := NewCookieJar()
+	jar.Set(JarCookie{Domain: "example.com", Path: "/", Name: "a", Value: "1"})
+	jar.Delete(JarCookie{Domain: "example.com", Path: "/", Name: "b"})
+	jar.Set(JarCookie{Domain: "example.com", Path: "/", Name: "b", Value: "2"})
+
+	cookies := jar.Cookies()
+	if len(cookies) != 2 || cookies[0].Name != "a" || cookies[1].Name != "b" {
+		t.Errorf("jar cookies are %v, expected a then b", cookies)
+	}
+}
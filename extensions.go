@@ -0,0 +1,407 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// Extensions holds the fields of a HAR object which are not part of the typed model, such as the vendor specific
+// fields prefixed with an underscore, and the order of every key the object was read with. They are written back out
+// by MarshalJSON so exported HARs don't lose or add anything.
+type Extensions struct {
+	Fields map[string]json.RawMessage
+	// Keys is nil for objects which weren't read from a file
+	Keys []string
+}
+
+func UnmarshalWithExtensions(data []byte, v interface{}) error {
+	typedErr := json.Unmarshal(data, v)
+	var syntaxError *json.SyntaxError
+	if errors.As(typedErr, &syntaxError) {
+		return typedErr
+	}
+	CollectExtensions(reflect.ValueOf(v), data)
+
+	return typedErr
+}
+
+// skipSpace returns the index of the first character from i which isn't whitespace
+func skipSpace(data []byte, i int) int {
+	for i < len(data) && (data[i] == ' ' || data[i] == '\t' || data[i] == '\n' || data[i] == '\r') {
+		i++
+	}
+	return i
+}
+
+// skipValue returns the index just after the JSON value starting at i. The data has already been decoded so it is
+// known to be valid, which means only strings and nesting need following to find where the value ends
+func skipValue(data []byte, i int) int {
+	depth := 0
+	for ; i < len(data); i++ {
+		switch data[i] {
+		case '"':
+			for i++; i < len(data) && data[i] != '"'; i++ {
+				if data[i] == '\\' {
+					i++
+				}
+			}
+			if depth == 0 {
+				return i + 1
+			}
+		case '{', '[':
+			depth++
+		case '}', ']':
+			// At the top level this closes whatever the value is in
+			if depth == 0 {
+				return i
+			}
+			if depth--; depth == 0 {
+				return i + 1
+			}
+		case ',', ' ', '\t', '\n', '\r':
+			if depth == 0 {
+				return i
+			}
+		}
+	}
+	return i
+}
+
+// splitJson splits a JSON object into its keys and values, keeping the order of the keys, or an array into its values
+func splitJson(data []byte, open byte) ([]string, []json.RawMessage, bool) {
+	i := skipSpace(data, 0)
+	if i >= len(data) || data[i] != open {
+		return nil, nil, false
+	}
+	keys := make([]string, 0)
+	values := make([]json.RawMessage, 0)
+	for i = skipSpace(data, i+1); i < len(data) && data[i] != '}' && data[i] != ']'; {
+		if open == '{' {
+			end := skipValue(data, i)
+			if end-i < 2 || data[i] != '"' {
+				return nil, nil, false
+			}
+			key := string(data[i+1 : end-1])
+			if bytes.IndexByte(data[i:end], '\\') >= 0 && json.Unmarshal(data[i:end], &key) != nil {
+				return nil, nil, false
+			}
+			keys = append(keys, key)
+			if i = skipSpace(data, end); i >= len(data) || data[i] != ':' {
+				return nil, nil, false
+			}
+			i = skipSpace(data, i+1)
+		}
+		end := skipValue(data, i)
+		if end <= i {
+			return nil, nil, false
+		}
+		values = append(values, data[i:end])
+		if i = skipSpace(data, end); i < len(data) && data[i] == ',' {
+			i = skipSpace(data, i+1)
+		}
+	}
+	return keys, values, i < len(data)
+}
+
+// decodeObject splits a JSON object into its values, keeping the order of the keys
+func decodeObject(data json.RawMessage) ([]string, map[string]json.RawMessage, bool) {
+	names, values, ok := splitJson(data, '{')
+	if !ok {
+		return nil, nil, false
+	}
+	keys := make([]string, 0, len(names))
+	object := make(map[string]json.RawMessage, len(names))
+	for i, key := range names {
+		if _, seen := object[key]; !seen {
+			keys = append(keys, key)
+		}
+		object[key] = values[i]
+	}
+	return keys, object, true
+}
+
+// CollectExtensions fills in the Extensions of v and everything inside it from the raw JSON it was decoded from
+func CollectExtensions(v reflect.Value, raw json.RawMessage) {
+	switch v.Kind() {
+	case reflect.Pointer:
+		if !v.IsNil() {
+			CollectExtensions(v.Elem(), raw)
+		}
+	case reflect.Slice:
+		if !canHoldExtensions(v.Type().Elem()) {
+			return
+		}
+		_, values, ok := splitJson(raw, '[')
+		if !ok {
+			return
+		}
+		for i := 0; i < v.Len() && i < len(values); i++ {
+			CollectExtensions(v.Index(i), values[i])
+		}
+	case reflect.Struct:
+		field := v.FieldByName("Extensions")
+		if !field.IsValid() || !field.CanSet() {
+			return
+		}
+		keys, object, ok := decodeObject(raw)
+		if !ok {
+			return
+		}
+
+		known := make(map[string]bool)
+		for i := 0; i < v.NumField(); i++ {
+			name := JsonFieldName(v.Type().Field(i))
+			if name == "" {
+				continue
+			}
+			known[name] = true
+			if value, ok := object[name]; ok && canHoldExtensions(v.Type().Field(i).Type) {
+				CollectExtensions(v.Field(i), value)
+			}
+		}
+
+		extensions := Extensions{Keys: keys}
+		for key, value := range object {
+			if known[key] {
+				continue
+			}
+			if extensions.Fields == nil {
+				extensions.Fields = make(map[string]json.RawMessage)
+			}
+			extensions.Fields[key] = value
+		}
+		field.Set(reflect.ValueOf(extensions))
+	}
+}
+
+// canHoldExtensions is whether a type is, or contains, one of the model structs, so plain values aren't scanned
+func canHoldExtensions(t reflect.Type) bool {
+	for t.Kind() == reflect.Pointer || t.Kind() == reflect.Slice {
+		t = t.Elem()
+	}
+	return t.Kind() == reflect.Struct
+}
+
+func JsonFieldName(field reflect.StructField) string {
+	tag := field.Tag.Get("json")
+	if tag == "-" || !field.IsExported() {
+		return ""
+	}
+	name, _, _ := strings.Cut(tag, ",")
+	if name == "" {
+		return field.Name
+	}
+	return name
+}
+
+// marshalUnescaped encodes v like json.Marshal but leaves <, > and & as they are, as browsers write them
+func marshalUnescaped(v interface{}) ([]byte, error) {
+	var buffer bytes.Buffer
+	encoder := json.NewEncoder(&buffer)
+	encoder.SetEscapeHTML(false)
+	if err := encoder.Encode(v); err != nil {
+		return nil, err
+	}
+	return bytes.TrimRight(buffer.Bytes(), "\n"), nil
+}
+
+// isEmptyField is whether omitempty would leave out a value, counting structs with nothing set as empty as well
+func isEmptyField(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Slice, reflect.Map, reflect.String:
+		return v.Len() == 0
+	}
+	return v.IsZero()
+}
+
+// MarshalWithExtensions encodes the typed fields of v along with the extensions. An object which was read from a file
+// is written with the keys it had, in the same order, so a field which was missing isn't written out as its zero value
+// and one which was an empty list isn't left out by omitempty. Anything set since it was read is written after them.
+func MarshalWithExtensions(v interface{}, extensions Extensions) ([]byte, error) {
+	value := reflect.ValueOf(v)
+	read := make(map[string]bool, len(extensions.Keys))
+	for _, key := range extensions.Keys {
+		read[key] = true
+	}
+
+	fields := make(map[string]json.RawMessage)
+	order := make([]string, 0, value.NumField())
+	for i := 0; i < value.NumField(); i++ {
+		field := value.Type().Field(i)
+		name := JsonFieldName(field)
+		if name == "" {
+			continue
+		}
+		empty := isEmptyField(value.Field(i))
+		omitEmpty := strings.Contains(field.Tag.Get("json"), ",omitempty") || extensions.Keys != nil
+		if empty && omitEmpty && !read[name] {
+			continue
+		}
+		encoded, err := marshalUnescaped(value.Field(i).Interface())
+		if err != nil {
+			return nil, err
+		}
+		fields[name] = encoded
+		order = append(order, name)
+	}
+
+	keys := make([]string, 0, len(fields)+len(extensions.Fields))
+	for _, key := range extensions.Keys {
+		if _, ok := fields[key]; ok {
+			keys = append(keys, key)
+		} else if _, ok := extensions.Fields[key]; ok {
+			keys = append(keys, key)
+		}
+	}
+	for _, key := range order {
+		if !read[key] {
+			keys = append(keys, key)
+		}
+	}
+	added := make([]string, 0)
+	for key := range extensions.Fields {
+		if !read[key] {
+			added = append(added, key)
+		}
+	}
+	sort.Strings(added)
+	keys = append(keys, added...)
+
+	var buffer bytes.Buffer
+	buffer.WriteByte('{')
+	for i, key := range keys {
+		if i > 0 {
+			buffer.WriteByte(',')
+		}
+		name, _ := marshalUnescaped(key)
+		buffer.Write(name)
+		buffer.WriteByte(':')
+		if encoded, ok := fields[key]; ok {
+			buffer.Write(encoded)
+		} else {
+			buffer.Write(extensions.Fields[key])
+		}
+	}
+	buffer.WriteByte('}')
+
+	return buffer.Bytes(), nil
+}
+
+func (v Creator) MarshalJSON() ([]byte, error) {
+	type plain Creator
+	return MarshalWithExtensions(plain(v), v.Extensions)
+}
+
+func (v Browser) MarshalJSON() ([]byte, error) {
+	type plain Browser
+	return MarshalWithExtensions(plain(v), v.Extensions)
+}
+
+func (v PageTiming) MarshalJSON() ([]byte, error) {
+	type plain PageTiming
+	return MarshalWithExtensions(plain(v), v.Extensions)
+}
+
+func (v Page) MarshalJSON() ([]byte, error) {
+	type plain Page
+	return MarshalWithExtensions(plain(v), v.Extensions)
+}
+
+func (v Cookie) MarshalJSON() ([]byte, error) {
+	type plain Cookie
+	return MarshalWithExtensions(plain(v), v.Extensions)
+}
+
+func (v Header) MarshalJSON() ([]byte, error) {
+	type plain Header
+	return MarshalWithExtensions(plain(v), v.Extensions)
+}
+
+func (v QueryParameter) MarshalJSON() ([]byte, error) {
+	type plain QueryParameter
+	return MarshalWithExtensions(plain(v), v.Extensions)
+}
+
+func (v PostParameters) MarshalJSON() ([]byte, error) {
+	type plain PostParameters
+	return MarshalWithExtensions(plain(v), v.Extensions)
+}
+
+func (v PostData) MarshalJSON() ([]byte, error) {
+	type plain PostData
+	return MarshalWithExtensions(plain(v), v.Extensions)
+}
+
+func (v Content) MarshalJSON() ([]byte, error) {
+	type plain Content
+	return MarshalWithExtensions(plain(v), v.Extensions)
+}
+
+func (v Response) MarshalJSON() ([]byte, error) {
+	type plain Response
+	return MarshalWithExtensions(plain(v), v.Extensions)
+}
+
+func (v Request) MarshalJSON() ([]byte, error) {
+	type plain Request
+	return MarshalWithExtensions(plain(v), v.Extensions)
+}
+
+func (v CacheState) MarshalJSON() ([]byte, error) {
+	type plain CacheState
+	return MarshalWithExtensions(plain(v), v.Extensions)
+}
+
+func (v Cache) MarshalJSON() ([]byte, error) {
+	type plain Cache
+	return MarshalWithExtensions(plain(v), v.Extensions)
+}
+
+func (v EntryTimings) MarshalJSON() ([]byte, error) {
+	type plain EntryTimings
+	return MarshalWithExtensions(plain(v), v.Extensions)
+}
+
+func (v CallFrame) MarshalJSON() ([]byte, error) {
+	type plain CallFrame
+	return MarshalWithExtensions(plain(v), v.Extensions)
+}
+
+func (v StackTrace) MarshalJSON() ([]byte, error) {
+	type plain StackTrace
+	return MarshalWithExtensions(plain(v), v.Extensions)
+}
+
+func (v Initiator) MarshalJSON() ([]byte, error) {
+	type plain Initiator
+	return MarshalWithExtensions(plain(v), v.Extensions)
+}
+
+func (v SignedCertificateTimestamp) MarshalJSON() ([]byte, error) {
+	type plain SignedCertificateTimestamp
+	return MarshalWithExtensions(plain(v), v.Extensions)
+}
+
+func (v SecurityDetails) MarshalJSON() ([]byte, error) {
+	type plain SecurityDetails
+	return MarshalWithExtensions(plain(v), v.Extensions)
+}
+
+func (v Entry) MarshalJSON() ([]byte, error) {
+	type plain Entry
+	return MarshalWithExtensions(plain(v), v.Extensions)
+}
+
+func (v Log) MarshalJSON() ([]byte, error) {
+	type plain Log
+	return MarshalWithExtensions(plain(v), v.Extensions)
+}
+
+func (v HarFile) MarshalJSON() ([]byte, error) {
+	type plain HarFile
+	return MarshalWithExtensions(plain(v), v.Extensions)
+}
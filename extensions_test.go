@@ -0,0 +1,136 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"strings"
+	"testing"
+)
+
+// roundTripHar has its keys in Firefox's order, vendor fields at several levels and empty lists browsers write
+const roundTripHar = `{
+  "log": {
+    "version": "1.2",
+    "creator": {
+      "name": "Firefox",
+      "version": "123.0"
+    },
+    "_vendor": {
+      "build": "20240301"
+    },
+    "entries": [
+      {
+        "pageref": "page_1",
+        "startedDateTime": "2024-03-01T11:00:00.000+01:00",
+        "request": {
+          "bodySize": 0,
+          "method": "POST",
+          "url": "https://example.com/search?q=<b>",
+          "httpVersion": "HTTP/2",
+          "headers": [],
+          "cookies": [],
+          "queryString": [
+            {
+              "name": "q",
+              "value": "<b>"
+            }
+          ],
+          "headersSize": 120,
+          "postData": {
+            "mimeType": "application/json",
+            "params": [],
+            "text": "{}"
+          }
+        },
+        "response": {
+          "status": 200,
+          "statusText": "OK",
+          "httpVersion": "HTTP/2",
+          "headers": [],
+          "cookies": [],
+          "content": {
+            "mimeType": "text/html",
+            "size": 0,
+            "text": ""
+          },
+          "redirectURL": "",
+          "headersSize": 80,
+          "bodySize": 0
+        },
+        "cache": {},
+        "timings": {
+          "blocked": 0,
+          "dns": 0,
+          "connect": 0,
+          "ssl": 0,
+          "send": 0,
+          "wait": 12,
+          "receive": 0
+        },
+        "time": 12,
+        "_securityState": "secure"
+      }
+    ]
+  }
+}`
+
+func TestHarRoundTrip(t *testing.T) {
+	cases := map[string][]byte{"inline": []byte(roundTripHar)}
+	for _, file := range []string{"testdata/chrome.har", "testdata/firefox.har"} {
+		content, err := os.ReadFile(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		cases[file] = bytes.TrimRight(content, "\n")
+	}
+	for name, content := range cases {
+		t.Run(name, func(t *testing.T) {
+			var har HarFile
+			if err := UnmarshalWithExtensions(content, &har); err != nil {
+				t.Fatal(err)
+			}
+			encoded, err := MarshalHar(har)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(encoded, content) {
+				t.Errorf("round trip changed the file, first difference at byte %d:\n%s", firstDifference(encoded, content), encoded)
+			}
+		})
+	}
+}
+
+func firstDifference(a []byte, b []byte) int {
+	for i := 0; i < len(a) && i < len(b); i++ {
+		if a[i] != b[i] {
+			return i
+		}
+	}
+	return min(len(a), len(b))
+}
+
+func TestMarshalKeepsChangesToReadObjects(t *testing.T) {
+	var entry Entry
+	if err := UnmarshalWithExtensions([]byte(`{"_custom":1,"request":{"method":"GET","url":"https://example.com/"}}`), &entry); err != nil {
+		t.Fatal(err)
+	}
+	comment := "checked"
+	entry.Comment = &comment
+	encoded, err := MarshalWithExtensions(entry, entry.Extensions)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(string(encoded), `{"_custom":1,"request":{"method":"GET","url":"https://example.com/"},"comment":"checked"`) {
+		t.Errorf("unexpected encoding %s", encoded)
+	}
+}
+
+func TestMarshalNewObjects(t *testing.T) {
+	encoded, err := MarshalWithExtensions(PostData{MimeType: "text/plain"}, Extensions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(encoded) != `{"mimeType":"text/plain","text":""}` {
+		t.Errorf("unexpected encoding %s", encoded)
+	}
+}
@@ -0,0 +1,194 @@
+package main
+
+import (
+	"fmt"
+	"hash/fnv"
+	"html"
+	"math"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+type ExportFlamegraphCmd struct {
+	Value  string  `name:"value" default:"bytes" enum:"bytes,time" help:"Size each frame by the bytes transferred or by the time taken"`
+	Format *string `name:"format" enum:"folded,svg" help:"Write folded stacks for flamegraph.pl and speedscope, or an SVG. Defaults to SVG when --out ends in .svg"`
+	Width  int     `name:"svg-width" default:"1200" help:"The width of the SVG in pixels"`
+	File   string  `arg:"" help:"The HAR file to parse, or - to read it from stdin" type:"existingfile"`
+}
+
+// FlameNode is a frame of the flamegraph, the domain at the top followed by each segment of the path
+type FlameNode struct {
+	Name     string
+	Value    float64
+	Children map[string]*FlameNode
+}
+
+func (node *FlameNode) Add(stack []string, value float64) {
+	node.Value += value
+	if len(stack) == 0 {
+		return
+	}
+	if node.Children == nil {
+		node.Children = make(map[string]*FlameNode)
+	}
+	child, ok := node.Children[stack[0]]
+	if !ok {
+		child = &FlameNode{Name: stack[0]}
+		node.Children[stack[0]] = child
+	}
+	child.Add(stack[1:], value)
+}
+
+// SortedChildren orders frames by name, as flamegraphs do, so the same path is in the same place across files
+func (node *FlameNode) SortedChildren() []*FlameNode {
+	children := make([]*FlameNode, 0, len(node.Children))
+	for _, child := range node.Children {
+		children = append(children, child)
+	}
+	sort.Slice(children, func(i, j int) bool {
+		return children[i].Name < children[j].Name
+	})
+	return children
+}
+
+// FlameStack is the domain then each path segment of the request, where the last segment is the resource itself
+func FlameStack(entry Entry) []string {
+	requestUrl, err := url.Parse(entry.Request.Url)
+	if err != nil {
+		return []string{"[invalid url]"}
+	}
+	stack := []string{strings.ToLower(requestUrl.Host)}
+	for _, segment := range strings.Split(strings.Trim(requestUrl.Path, "/"), "/") {
+		if segment != "" {
+			// Semicolons separate frames in the folded format
+			stack = append(stack, strings.ReplaceAll(segment, ";", "%3B"))
+		}
+	}
+	if len(stack) == 1 {
+		stack = append(stack, "/")
+	}
+	return stack
+}
+
+func BuildFlameTree(entries []Entry, value string) *FlameNode {
+	root := &FlameNode{Name: "all"}
+	for _, entry := range entries {
+		if value == "time" {
+			root.Add(FlameStack(entry), math.Max(entry.TimeMs, 0))
+		} else {
+			root.Add(FlameStack(entry), float64(TransferSize(entry)))
+		}
+	}
+	return root
+}
+
+// FormatFoldedStacks writes a line for each leaf frame in the format read by flamegraph.pl, inferno and speedscope
+func FormatFoldedStacks(root *FlameNode) string {
+	lines := make([]string, 0)
+	var walk func(node *FlameNode, stack []string)
+	walk = func(node *FlameNode, stack []string) {
+		children := node.SortedChildren()
+		// Frames can have their own value as well as children, eg /api and /api/users
+		own := node.Value
+		for _, child := range children {
+			own -= child.Value
+			walk(child, append(stack, child.Name))
+		}
+		if len(stack) > 0 && math.Round(own) > 0 {
+			lines = append(lines, strings.Join(stack, ";")+" "+strconv.FormatFloat(math.Round(own), 'f', 0, 64))
+		}
+	}
+	walk(root, nil)
+	return strings.Join(lines, "\n")
+}
+
+// flameColor picks a warm colour from the name so the same frame keeps its colour between runs
+func flameColor(name string) string {
+	hash := fnv.New32a()
+	hash.Write([]byte(name))
+	value := hash.Sum32()
+	return fmt.Sprintf("rgb(%d,%d,%d)", 205+value%50, 80+(value>>8)%130, 40+(value>>16)%50)
+}
+
+// FormatFlamegraphSvg draws the tree as an icicle graph with the domains at the top, with a tooltip on each frame
+func FormatFlamegraphSvg(root *FlameNode, width int, value string) string {
+	const rowHeight = 18
+	depth := 0
+	var measure func(node *FlameNode, level int)
+	measure = func(node *FlameNode, level int) {
+		depth = max(depth, level+1)
+		for _, child := range node.Children {
+			measure(child, level+1)
+		}
+	}
+	measure(root, 0)
+
+	describe := func(amount float64) string {
+		if value == "time" {
+			return FormatDuration(amount)
+		}
+		return FormatBytes(int(amount))
+	}
+	height := depth*rowHeight + 10
+	frames := make([]string, 0)
+	var draw func(node *FlameNode, x float64, level int, path string)
+	draw = func(node *FlameNode, x float64, level int, path string) {
+		if root.Value <= 0 {
+			return
+		}
+		frameWidth := node.Value / root.Value * float64(width-20)
+		if frameWidth < 0.1 {
+			return
+		}
+		title := html.EscapeString(path) + " (" + describe(node.Value) + ", " + strconv.FormatFloat(node.Value/root.Value*100, 'f', 1, 64) + "%)"
+		frame := fmt.Sprintf(`<g><title>%s</title><rect x="%.1f" y="%d" width="%.1f" height="%d" fill="%s" rx="2"/>`,
+			title, x, level*rowHeight+5, frameWidth, rowHeight-1, flameColor(node.Name))
+		// Roughly 7 pixels per character at this font size
+		if characters := int((frameWidth - 6) / 7); characters >= 3 {
+			label := node.Name
+			if len(label) > characters {
+				label = label[:characters-2] + ".."
+			}
+			frame += fmt.Sprintf(`<text x="%.1f" y="%d">%s</text>`, x+3, level*rowHeight+18, html.EscapeString(label))
+		}
+		frames = append(frames, frame+"</g>")
+
+		childX := x
+		for _, child := range node.SortedChildren() {
+			childPath := Tertiary(path == "all", child.Name, path+"/"+child.Name)
+			draw(child, childX, level+1, childPath)
+			childX += child.Value / root.Value * float64(width-20)
+		}
+	}
+	draw(root, 10, 0, "all")
+
+	return fmt.Sprintf(`<?xml version="1.0" standalone="no"?>
+<svg version="1.1" width="%d" height="%d" xmlns="http://www.w3.org/2000/svg">
+<style>text { font-family: Verdana, sans-serif; font-size: 12px; fill: black; pointer-events: none; } rect:hover { stroke: black; }</style>
+<rect width="100%%" height="100%%" fill="white"/>
+%s
+</svg>`, width, height, strings.Join(frames, "\n"))
+}
+
+func (cmd *ExportFlamegraphCmd) Run() error {
+	har, err := ReadHar(cmd.File, false)
+	if err != nil {
+		return err
+	}
+	entries, err := SelectEntries(har.Log)
+	if err != nil {
+		return err
+	}
+	root := BuildFlameTree(entries, cmd.Value)
+
+	svg := CLI.Out != nil && strings.HasSuffix(strings.ToLower(*CLI.Out), ".svg")
+	if cmd.Format != nil {
+		svg = *cmd.Format == "svg"
+	}
+	if svg {
+		return WriteOutput(FormatFlamegraphSvg(root, cmd.Width, cmd.Value))
+	}
+	return WriteOutput(FormatFoldedStacks(root))
+}
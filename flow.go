@@ -0,0 +1,126 @@
+package main
+
+import (
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/fatih/color"
+)
+
+type FlowCmd struct {
+	Oauth FlowOauthCmd `cmd:"" name:"oauth" help:"Reconstruct OAuth and OpenID Connect flows and flag missing state, tokens in URLs and tokens sent to third parties"`
+	Saml  FlowSamlCmd  `cmd:"" name:"saml" help:"Decode the SAML requests and responses exchanged between the service and identity providers"`
+}
+
+// FlowStep is a request which took part in a flow along with the parameters worth showing for it
+type FlowStep struct {
+	Index  int
+	Kind   string
+	Entry  Entry
+	Params [][2]string
+	// Detail is printed below the parameters, such as a decoded message
+	Detail string
+}
+
+func (step *FlowStep) Param(name string, value string) {
+	if value != "" {
+		step.Params = append(step.Params, [2]string{name, value})
+	}
+}
+
+// Flow is a sequence of steps, the findings are kept in an AuditGroup so they are reported in the same way as audits
+type Flow struct {
+	Steps []FlowStep
+	AuditGroup
+}
+
+func FormatFlows(flows []*Flow, kind string) string {
+	if len(flows) == 0 {
+		return color.HiBlackString("No " + kind + " flows found")
+	}
+
+	output := make([]string, 0)
+	for i, flow := range flows {
+		header := color.YellowString("Flow "+strconv.Itoa(i+1)+": ") + flow.Name
+		if flow.Summary != "" {
+			header += color.HiBlackString(" (" + flow.Summary + ")")
+		}
+		output = append(output, header)
+
+		width := 0
+		for _, step := range flow.Steps {
+			width = max(width, len(step.Kind))
+		}
+		for _, step := range flow.Steps {
+			line := "  " + color.HiBlackString("#"+strconv.Itoa(step.Index)) + " " + color.CyanString(step.Kind+strings.Repeat(" ", width-len(step.Kind))) + " " +
+				MethodColor(step.Entry.Request.Method)(step.Entry.Request.Method) + " " + StatusColor(step.Entry.Response.Status)(strconv.Itoa(step.Entry.Response.Status)) + " " + WithoutQuery(step.Entry.Request.Url)
+			for _, param := range step.Params {
+				line += "\n      " + color.HiBlackString(param[0]+" = ") + param[1]
+			}
+			if step.Detail != "" {
+				line += "\n" + Indent(step.Detail, 6)
+			}
+			output = append(output, line)
+		}
+		for _, finding := range flow.Findings {
+			output = append(output, "  "+FormatFinding(finding))
+		}
+		if len(flow.Findings) == 0 {
+			output = append(output, "  "+color.GreenString("no issues found"))
+		}
+		output = append(output, "")
+	}
+	return strings.TrimRight(strings.Join(output, "\n"), "\n")
+}
+
+func WithoutQuery(raw string) string {
+	parsed, err := url.Parse(raw)
+	if err != nil {
+		return raw
+	}
+	parsed.RawQuery = ""
+	parsed.Fragment = ""
+	return parsed.String()
+}
+
+func UrlQuery(raw string) url.Values {
+	parsed, err := url.Parse(raw)
+	if err != nil {
+		return url.Values{}
+	}
+	return parsed.Query()
+}
+
+// FormValues are the parameters of a form encoded request body, from either the params or the text
+func FormValues(request Request) url.Values {
+	values := url.Values{}
+	if request.PostData == nil {
+		return values
+	}
+	for _, param := range request.PostData.Params {
+		if param.Value != nil {
+			values.Add(param.Name, *param.Value)
+		}
+	}
+	if len(values) == 0 && strings.Contains(request.PostData.MimeType, "x-www-form-urlencoded") {
+		if parsed, err := url.ParseQuery(request.PostData.Text); err == nil {
+			return parsed
+		}
+	}
+	return values
+}
+
+// SiteOf approximates the registrable domain of a URL by its last two labels, which is enough to tell first and third
+// parties apart in most captures without a public suffix list
+func SiteOf(raw string) string {
+	parsed, err := url.Parse(raw)
+	if err != nil {
+		return raw
+	}
+	labels := strings.Split(strings.ToLower(parsed.Hostname()), ".")
+	if len(labels) > 2 {
+		labels = labels[len(labels)-2:]
+	}
+	return strings.Join(labels, ".")
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+type ExportFuzzCorpusCmd struct {
+	OutDir string `name:"out-dir" required:"" help:"The directory to write a seed directory for each endpoint to"`
+	File   string `arg:"" help:"The HAR file to parse, or - to read it from stdin" type:"existingfile"`
+}
+
+// RequestBodyBytes is the body of a request as it was sent, encoding the form fields when only they were recorded
+func RequestBodyBytes(request Request) []byte {
+	if request.PostData == nil {
+		return nil
+	}
+	if fields, ok := FormFields(*request.PostData); ok && request.PostData.Text == "" {
+		values := make([]string, len(fields))
+		for i, field := range fields {
+			values[i] = url.QueryEscape(field.Name) + "=" + url.QueryEscape(field.Value)
+		}
+		return []byte(strings.Join(values, "&"))
+	}
+	return []byte(request.PostData.Text)
+}
+
+// WriteFuzzCorpus writes each distinct request body to the directory of its endpoint, named by its hash as go-fuzz
+// names its corpus so the same body is never written twice. It returns how many endpoints and seeds were written
+func WriteFuzzCorpus(dir string, entries []Entry) (int, int, error) {
+	endpoints := make(map[string]string)
+	written := make(map[string]bool)
+	for _, entry := range entries {
+		body := RequestBodyBytes(entry.Request)
+		if len(body) == 0 {
+			continue
+		}
+		endpoint := Endpoint(entry)
+		endpointDir, ok := endpoints[endpoint]
+		if !ok {
+			requestUrl, err := url.Parse(entry.Request.Url)
+			if err != nil {
+				continue
+			}
+			endpointDir = filepath.Join(dir, ExportFileName(len(endpoints)+1, entry.Request.Method, requestUrl.Host+TemplatePath(requestUrl.Path), ""))
+			if err := os.MkdirAll(endpointDir, 0755); err != nil {
+				return 0, 0, err
+			}
+			endpoints[endpoint] = endpointDir
+		}
+		sum := sha1.Sum(body)
+		mime, _, _ := strings.Cut(entry.Request.PostData.MimeType, ";")
+		file := filepath.Join(endpointDir, hex.EncodeToString(sum[:])+BodyExtension(strings.ToLower(strings.TrimSpace(mime)), false))
+		if written[file] {
+			continue
+		}
+		if err := os.WriteFile(file, body, 0644); err != nil {
+			return 0, 0, err
+		}
+		written[file] = true
+	}
+	return len(endpoints), len(written), nil
+}
+
+func (cmd *ExportFuzzCorpusCmd) Run() error {
+	har, err := ReadHarFile(cmd.File)
+	if err != nil {
+		return err
+	}
+	entries, err := SelectEntries(har.Log)
+	if err != nil {
+		return err
+	}
+	endpoints, seeds, err := WriteFuzzCorpus(cmd.OutDir, entries)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintln(os.Stderr, "Wrote "+strconv.Itoa(seeds)+" seeds for "+strconv.Itoa(endpoints)+" endpoints to "+cmd.OutDir)
+	return nil
+}
@@ -0,0 +1,122 @@
+package main
+
+import (
+	"log/slog"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// GeoLocation merges what the --geoip databases know about an address, as the City and ASN databases are separate
+type GeoLocation struct {
+	Country      string
+	City         string
+	Asn          uint64
+	Organization string
+}
+
+func (location GeoLocation) String() string {
+	parts := make([]string, 0, 3)
+	if location.Country != "" {
+		parts = append(parts, location.Country)
+	}
+	if location.City != "" {
+		parts = append(parts, location.City)
+	}
+	if location.Asn != 0 {
+		parts = append(parts, strings.TrimSpace("AS"+strconv.FormatUint(location.Asn, 10)+" "+location.Organization))
+	}
+	return strings.Join(parts, ", ")
+}
+
+var geoIpDatabases []*MaxMindDb
+
+// geoIpCache is shared by the --jobs workers, captures usually only contact a handful of addresses
+var geoIpCache = struct {
+	sync.Mutex
+	locations map[string]*GeoLocation
+}{locations: make(map[string]*GeoLocation)}
+
+func ConfigureGeoIp() error {
+	if CLI.GeoIp == nil {
+		return nil
+	}
+	for _, path := range *CLI.GeoIp {
+		db, err := OpenMaxMindDb(path)
+		if err != nil {
+			return err
+		}
+		slog.Info("Loaded GeoIP database", "path", path, "type", db.Type)
+		geoIpDatabases = append(geoIpDatabases, db)
+	}
+	return nil
+}
+
+// mmdbString follows a path of map keys through a record, such as country, iso_code
+func mmdbString(record any, path ...string) string {
+	for _, key := range path {
+		fields, ok := record.(map[string]any)
+		if !ok {
+			return ""
+		}
+		record = fields[key]
+	}
+	value, _ := record.(string)
+	return value
+}
+
+// LookupGeoIp returns nil when no databases were given or none of them know the address
+func LookupGeoIp(address string) *GeoLocation {
+	if len(geoIpDatabases) == 0 {
+		return nil
+	}
+	// Chrome writes IPv6 addresses in brackets
+	address = strings.Trim(address, "[]")
+	geoIpCache.Lock()
+	defer geoIpCache.Unlock()
+	if location, ok := geoIpCache.locations[address]; ok {
+		return location
+	}
+
+	var location *GeoLocation
+	if ip := net.ParseIP(address); ip != nil {
+		for _, db := range geoIpDatabases {
+			record, found, err := db.Lookup(ip)
+			if err != nil {
+				slog.Warn("Failed to look up address", "address", address, "database", db.Type, "error", err)
+				continue
+			}
+			if !found {
+				continue
+			}
+			if location == nil {
+				location = &GeoLocation{}
+			}
+			if country := mmdbString(record, "country", "iso_code"); country != "" {
+				location.Country = country
+			}
+			if city := mmdbString(record, "city", "names", "en"); city != "" {
+				location.City = city
+			}
+			if fields, ok := record.(map[string]any); ok {
+				if asn, ok := fields["autonomous_system_number"].(uint64); ok {
+					location.Asn = asn
+				}
+				if organization, ok := fields["autonomous_system_organization"].(string); ok {
+					location.Organization = organization
+				}
+			}
+		}
+	}
+	geoIpCache.locations[address] = location
+	return location
+}
+
+// EntryGeoLocation looks up the server address of the entry
+func EntryGeoLocation(entry Entry) *GeoLocation {
+	if entry.ServerIP == nil || *entry.ServerIP == "" {
+		return nil
+	}
+	return LookupGeoIp(*entry.ServerIP)
+}
@@ -6,6 +6,7 @@ require (
 	github.com/TylerBrock/colorjson v0.0.0-20200706003622-8a50f05110d2
 	github.com/alecthomas/kong v0.8.1
 	github.com/fatih/color v1.16.0
+	golang.org/x/term v0.14.0
 )
 
 require (
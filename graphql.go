@@ -0,0 +1,100 @@
+package main
+
+import (
+	"encoding/json"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+// GraphqlRequest is the body of a GraphQL request over HTTP, or the same fields sent in the query string of a GET
+type GraphqlRequest struct {
+	Query         string          `json:"query"`
+	OperationName string          `json:"operationName"`
+	Id            string          `json:"id"`
+	DocumentId    string          `json:"documentId"`
+	Extensions    json.RawMessage `json:"extensions"`
+}
+
+// graphqlOperationPattern finds the name of the first named operation in a document
+var graphqlOperationPattern = regexp.MustCompile(`\b(?:query|mutation|subscription)\s+([_A-Za-z][_0-9A-Za-z]*)`)
+
+// Name is the operation the request ran. Without an operationName it is taken from the document, and requests which
+// only send a persisted query are named by its hash, or by its ID when the URL is known to be a GraphQL endpoint as
+// plenty of other APIs take an id
+func (request GraphqlRequest) Name(endpoint bool) string {
+	if request.OperationName != "" {
+		return request.OperationName
+	}
+	// Search APIs take a query too, but theirs won't have a selection set
+	if strings.Contains(request.Query, "{") {
+		if match := graphqlOperationPattern.FindStringSubmatch(request.Query); match != nil {
+			return match[1]
+		}
+		return "[anonymous]"
+	}
+	if endpoint && request.Id != "" {
+		return "persisted:" + request.Id
+	}
+	if endpoint && request.DocumentId != "" {
+		return "persisted:" + request.DocumentId
+	}
+	var extensions struct {
+		PersistedQuery struct {
+			Sha256Hash string `json:"sha256Hash"`
+		} `json:"persistedQuery"`
+	}
+	if json.Unmarshal(request.Extensions, &extensions) == nil && extensions.PersistedQuery.Sha256Hash != "" {
+		hash := extensions.PersistedQuery.Sha256Hash
+		return "persisted:" + hash[:min(len(hash), 12)]
+	}
+	return ""
+}
+
+// GraphqlOperations are the names of the operations an entry ran, more than one for a batch, or none if it isn't a
+// GraphQL request
+func GraphqlOperations(entry Entry) []string {
+	requestUrl, err := url.Parse(entry.Request.Url)
+	if err != nil {
+		return nil
+	}
+	endpoint := strings.Contains(strings.ToLower(requestUrl.Path), "graphql")
+
+	requests := make([]GraphqlRequest, 0, 1)
+	if entry.Request.PostData != nil && strings.HasPrefix(strings.TrimSpace(entry.Request.PostData.Text), "[") {
+		_ = json.Unmarshal([]byte(entry.Request.PostData.Text), &requests)
+	} else if entry.Request.PostData != nil && entry.Request.PostData.Text != "" {
+		var request GraphqlRequest
+		if json.Unmarshal([]byte(entry.Request.PostData.Text), &request) == nil {
+			requests = append(requests, request)
+		}
+	} else {
+		query := requestUrl.Query()
+		requests = append(requests, GraphqlRequest{
+			Query:         query.Get("query"),
+			OperationName: query.Get("operationName"),
+			Id:            query.Get("id"),
+			DocumentId:    query.Get("documentId"),
+			Extensions:    json.RawMessage(query.Get("extensions")),
+		})
+	}
+
+	operations := make([]string, 0, len(requests))
+	for _, request := range requests {
+		if name := request.Name(endpoint); name != "" {
+			operations = append(operations, name)
+		}
+	}
+	return operations
+}
+
+func MatchesGraphqlOperation(entry Entry, wanted []string) bool {
+	for _, operation := range GraphqlOperations(entry) {
+		for _, name := range wanted {
+			if strings.EqualFold(operation, name) {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,147 @@
+package main
+
+import (
+	"encoding/base64"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode/utf8"
+
+	"github.com/fatih/color"
+)
+
+const grepContextWidth = 40
+const grepLinesPerBody = 5
+
+var grepPattern *regexp.Regexp
+var grepOnce sync.Once
+
+func GrepPattern() *regexp.Regexp {
+	grepOnce.Do(func() {
+		if CLI.Grep != nil && *CLI.Grep != "" {
+			grepPattern = regexp.MustCompile("(?i)" + regexp.QuoteMeta(*CLI.Grep))
+		}
+	})
+	return grepPattern
+}
+
+// GrepHighlight marks the --grep term wherever it appears in already formatted output
+func GrepHighlight(v string) string {
+	return HighlightPattern(v, GrepPattern())
+}
+
+type GrepMatch struct {
+	Location string
+	Text     string
+}
+
+// DecodedBody returns the response text, decoding it if it was stored as base64. Binary content isn't returned.
+func DecodedBody(content Content) (string, bool) {
+	if content.Text == nil {
+		return "", false
+	}
+	text := *content.Text
+	if content.Encoding != nil && strings.EqualFold(*content.Encoding, "base64") {
+		decoded, err := base64.StdEncoding.DecodeString(text)
+		if err != nil {
+			return "", false
+		}
+		text = string(decoded)
+	}
+	return text, utf8.ValidString(text)
+}
+
+// Snippet trims long lines, such as minified JSON, to the text around the first match
+func Snippet(line string, pattern *regexp.Regexp) string {
+	match := pattern.FindStringIndex(line)
+	if match == nil {
+		return line
+	}
+
+	start := max(0, match[0]-grepContextWidth)
+	end := min(len(line), match[1]+grepContextWidth)
+	for start > 0 && !utf8.RuneStart(line[start]) {
+		start--
+	}
+	for end < len(line) && !utf8.RuneStart(line[end]) {
+		end++
+	}
+
+	snippet := strings.TrimSpace(line[start:end])
+	if start > 0 {
+		snippet = "…" + snippet
+	}
+	if end < len(line) {
+		snippet += "…"
+	}
+	return snippet
+}
+
+func GrepBody(location string, body string, pattern *regexp.Regexp) []GrepMatch {
+	matches := make([]GrepMatch, 0)
+	lines := strings.Split(body, "\n")
+	hits := 0
+	for i, line := range lines {
+		if !pattern.MatchString(line) {
+			continue
+		}
+		hits++
+		if hits <= grepLinesPerBody {
+			matches = append(matches, GrepMatch{Location: location + " line " + strconv.Itoa(i+1), Text: Snippet(line, pattern)})
+		}
+	}
+	if hits > grepLinesPerBody {
+		matches = append(matches, GrepMatch{Location: location, Text: "and " + strconv.Itoa(hits-grepLinesPerBody) + " more matching lines"})
+	}
+	return matches
+}
+
+func GrepEntry(entry Entry, pattern *regexp.Regexp) []GrepMatch {
+	matches := make([]GrepMatch, 0)
+	check := func(location string, v string) {
+		if pattern.MatchString(v) {
+			matches = append(matches, GrepMatch{Location: location, Text: Snippet(v, pattern)})
+		}
+	}
+
+	check("URL", entry.Request.Url)
+	for _, parameter := range QueryParameters(entry.Request) {
+		check("Query "+DecodeQueryComponent(parameter.Name), DecodeQueryComponent(parameter.Name)+"="+DecodeQueryComponent(parameter.Value))
+	}
+	for _, header := range entry.Request.Headers {
+		check("Request Header", header.Name+": "+header.Value)
+	}
+	for _, cookie := range entry.Request.Cookies {
+		check("Request Cookie", cookie.Name+"="+cookie.Value)
+	}
+	if entry.Request.PostData != nil {
+		matches = append(matches, GrepBody("Request Body", entry.Request.PostData.Text, pattern)...)
+		for _, parameter := range entry.Request.PostData.Params {
+			if parameter.Value != nil {
+				check("Request Body Parameter", parameter.Name+"="+*parameter.Value)
+			}
+		}
+	}
+	for _, header := range entry.Response.Headers {
+		check("Response Header", header.Name+": "+header.Value)
+	}
+	for _, cookie := range entry.Response.Cookies {
+		check("Response Cookie", cookie.Name+"="+cookie.Value)
+	}
+	if entry.Response.Content != nil {
+		if body, ok := DecodedBody(*entry.Response.Content); ok {
+			matches = append(matches, GrepBody("Response Body", body, pattern)...)
+		}
+	}
+
+	return matches
+}
+
+func FormatGrepMatches(matches []GrepMatch) string {
+	lines := make([]string, 0, len(matches))
+	for _, match := range matches {
+		lines = append(lines, color.HiBlackString(match.Location+": ")+GrepHighlight(match.Text))
+	}
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,232 @@
+package main
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/TylerBrock/colorjson"
+	"github.com/fatih/color"
+)
+
+// grpcStatusNames are the names of the gRPC status codes, by code
+var grpcStatusNames = []string{
+	"OK", "CANCELLED", "UNKNOWN", "INVALID_ARGUMENT", "DEADLINE_EXCEEDED", "NOT_FOUND", "ALREADY_EXISTS",
+	"PERMISSION_DENIED", "RESOURCE_EXHAUSTED", "FAILED_PRECONDITION", "ABORTED", "OUT_OF_RANGE", "UNIMPLEMENTED",
+	"INTERNAL", "UNAVAILABLE", "DATA_LOSS", "UNAUTHENTICATED",
+}
+
+// GrpcWebFrame is one length prefixed frame of a gRPC-web body, either a message or the trailers at the end
+type GrpcWebFrame struct {
+	Trailer    bool
+	Compressed bool
+	Data       []byte
+}
+
+func IsGrpcWeb(mimeType string) bool {
+	return strings.HasPrefix(strings.ToLower(strings.TrimSpace(mimeType)), "application/grpc-web")
+}
+
+// GrpcWebBody is the bytes of a gRPC-web body. The -text variant base64 encodes each frame separately, so the text
+// can be several base64 strings run together
+func GrpcWebBody(mimeType string, text string, base64Encoded bool) ([]byte, error) {
+	body := []byte(text)
+	if base64Encoded {
+		decoded, err := base64.StdEncoding.DecodeString(text)
+		if err != nil {
+			return nil, err
+		}
+		body = decoded
+	}
+	if !strings.HasPrefix(strings.ToLower(mimeType), "application/grpc-web-text") {
+		return body, nil
+	}
+	decoded := make([]byte, 0, len(body))
+	for chunk := strings.TrimSpace(string(body)); chunk != ""; {
+		end := strings.Index(chunk, "=")
+		for end >= 0 && end+1 < len(chunk) && chunk[end+1] == '=' {
+			end++
+		}
+		part := chunk
+		if end >= 0 {
+			part, chunk = chunk[:end+1], chunk[end+1:]
+		} else {
+			chunk = ""
+		}
+		bytes, err := base64.StdEncoding.DecodeString(part)
+		if err != nil {
+			return nil, err
+		}
+		decoded = append(decoded, bytes...)
+	}
+	return decoded, nil
+}
+
+func ParseGrpcWebFrames(body []byte) ([]GrpcWebFrame, error) {
+	frames := make([]GrpcWebFrame, 0)
+	for len(body) > 0 {
+		if len(body) < 5 {
+			return frames, errors.New("the body ends part way through a frame header")
+		}
+		length := int(uint32(body[1])<<24 | uint32(body[2])<<16 | uint32(body[3])<<8 | uint32(body[4]))
+		if length > len(body)-5 {
+			return frames, errors.New("frame of " + strconv.Itoa(length) + " bytes is longer than the rest of the body")
+		}
+		frames = append(frames, GrpcWebFrame{Trailer: body[0]&0x80 != 0, Compressed: body[0]&0x01 != 0, Data: body[5 : 5+length]})
+		body = body[5+length:]
+	}
+	return frames, nil
+}
+
+// ParseGrpcWebTrailers reads the trailer frame, which is written as HTTP/1 header lines
+func ParseGrpcWebTrailers(data []byte) []Header {
+	trailers := make([]Header, 0)
+	for _, line := range strings.Split(string(data), "\n") {
+		name, value, found := strings.Cut(strings.TrimRight(line, "\r"), ":")
+		if found {
+			trailers = append(trailers, Header{Name: strings.ToLower(strings.TrimSpace(name)), Value: strings.TrimSpace(value)})
+		}
+	}
+	return trailers
+}
+
+func responseGrpcWebFrames(entry Entry) []GrpcWebFrame {
+	content := entry.Response.Content
+	if content == nil || content.Text == nil || !IsGrpcWeb(content.MimeType) {
+		return nil
+	}
+	body, err := GrpcWebBody(content.MimeType, *content.Text, content.Encoding != nil && strings.EqualFold(*content.Encoding, "base64"))
+	if err != nil {
+		return nil
+	}
+	frames, _ := ParseGrpcWebFrames(body)
+	return frames
+}
+
+// GrpcStatus is the status of a gRPC-web call from its trailers, or from the headers of a response which only had
+// trailers
+func GrpcStatus(entry Entry) (int, string, bool) {
+	headers := entry.Response.Headers
+	for _, frame := range responseGrpcWebFrames(entry) {
+		if frame.Trailer {
+			headers = ParseGrpcWebTrailers(frame.Data)
+		}
+	}
+	value := HeaderValue(headers, "grpc-status")
+	if value == "" {
+		return 0, "", false
+	}
+	status, err := strconv.Atoi(value)
+	if err != nil {
+		return 0, "", false
+	}
+	message, err := url.PathUnescape(HeaderValue(headers, "grpc-message"))
+	if err != nil {
+		message = HeaderValue(headers, "grpc-message")
+	}
+	return status, message, true
+}
+
+func FormatGrpcStatus(status int, message string) string {
+	text := "grpc-status " + strconv.Itoa(status)
+	if status >= 0 && status < len(grpcStatusNames) {
+		text += " " + grpcStatusNames[status]
+	}
+	if message != "" {
+		text += ": " + message
+	}
+	if status != 0 {
+		return color.RedString(text)
+	}
+	return color.GreenString(text)
+}
+
+// grpcMessageType is the type of the request or response messages of the method being called, when it is in the
+// --proto-descriptors
+func grpcMessageType(entry Entry, response bool) *ProtoMessageType {
+	if protoDescriptors == nil {
+		return nil
+	}
+	requestUrl, err := url.Parse(entry.Request.Url)
+	if err != nil {
+		return nil
+	}
+	for path, method := range protoDescriptors.Methods {
+		if strings.HasSuffix(requestUrl.Path, path) {
+			return protoDescriptors.Messages[method[Tertiary(response, 1, 0)]]
+		}
+	}
+	return nil
+}
+
+func FormatGrpcWebMessage(data []byte, mimeType string, messageType *ProtoMessageType) string {
+	var value any
+	if strings.Contains(strings.ToLower(mimeType), "+json") {
+		if err := json.Unmarshal(data, &value); err != nil {
+			return string(data)
+		}
+	} else {
+		decoded, err := DecodeProto(data, messageType)
+		if err != nil {
+			return color.HiBlackString("[not a protobuf message, "+err.Error()+"] ") + base64.StdEncoding.EncodeToString(data)
+		}
+		value = decoded
+	}
+	formatter := colorjson.NewFormatter()
+	formatter.Indent = 2
+	processed, err := formatter.Marshal(value)
+	if err != nil {
+		return string(data)
+	}
+	return string(processed)
+}
+
+// FormatGrpcWebBody prints each message of a gRPC-web body in place of the framing, followed by the trailers
+func FormatGrpcWebBody(entry Entry, response bool) string {
+	var mimeType, text string
+	base64Encoded := false
+	if response {
+		mimeType = entry.Response.Content.MimeType
+		if entry.Response.Content.Text != nil {
+			text = *entry.Response.Content.Text
+		}
+		base64Encoded = entry.Response.Content.Encoding != nil && strings.EqualFold(*entry.Response.Content.Encoding, "base64")
+	} else {
+		mimeType, text = entry.Request.PostData.MimeType, entry.Request.PostData.Text
+	}
+	output := color.HiBlackString("Mime Type: ") + TypeColor(mimeType)
+
+	body, err := GrpcWebBody(mimeType, text, base64Encoded)
+	if err != nil {
+		return output + "\n" + color.HiBlackString("[failed to decode the body, "+err.Error()+"]")
+	}
+	frames, err := ParseGrpcWebFrames(body)
+	messageType := grpcMessageType(entry, response)
+	if messageType != nil {
+		output += color.HiBlackString(" (" + messageType.Name + ")")
+	}
+	messages := 0
+	for _, frame := range frames {
+		if frame.Trailer {
+			output += color.YellowString("\nTrailers:")
+			for _, trailer := range ParseGrpcWebTrailers(frame.Data) {
+				output += "\n  " + color.HiBlackString(trailer.Name) + " = " + TypeColor(trailer.Value)
+			}
+			continue
+		}
+		messages++
+		output += color.YellowString("\nMessage "+strconv.Itoa(messages)) + color.HiBlackString(" ("+FormatBytes(len(frame.Data))+")")
+		if frame.Compressed {
+			output += "\n" + color.HiBlackString("[compressed] ") + base64.StdEncoding.EncodeToString(frame.Data)
+			continue
+		}
+		output += "\n" + FormatGrpcWebMessage(frame.Data, mimeType, messageType)
+	}
+	if err != nil {
+		output += "\n" + color.HiBlackString("["+err.Error()+"]")
+	}
+	return output
+}
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"net/url"
+	"regexp"
+	"strings"
+	"sync"
+
+	"github.com/fatih/color"
+)
+
+// These only toggle reverse video rather than resetting every attribute, so highlighting can be placed inside text
+// which has already been coloured
+const (
+	highlightStart = "\x1b[7m"
+	highlightEnd   = "\x1b[27m"
+)
+
+// TermsPattern matches every case-insensitive occurrence of the terms, like grep --color, or is nil without any terms
+func TermsPattern(terms []string) *regexp.Regexp {
+	quoted := make([]string, 0, len(terms))
+	for _, term := range terms {
+		if term != "" {
+			quoted = append(quoted, regexp.QuoteMeta(term))
+		}
+	}
+	if len(quoted) == 0 {
+		return nil
+	}
+	return regexp.MustCompile("(?i)" + strings.Join(quoted, "|"))
+}
+
+var escapeSequencePattern = regexp.MustCompile("\x1b\\[[0-9;]*m")
+
+// HighlightPattern skips over any colour escape codes already in v, so it is safe to use on formatted output. Matches
+// which span a change of colour won't be highlighted.
+func HighlightPattern(v string, pattern *regexp.Regexp) string {
+	if pattern == nil || color.NoColor {
+		return v
+	}
+
+	output := ""
+	last := 0
+	for _, escape := range escapeSequencePattern.FindAllStringIndex(v, -1) {
+		output += HighlightText(v[last:escape[0]], pattern) + v[escape[0]:escape[1]]
+		last = escape[1]
+	}
+	return output + HighlightText(v[last:], pattern)
+}
+
+func HighlightText(v string, pattern *regexp.Regexp) string {
+	return pattern.ReplaceAllStringFunc(v, func(match string) string {
+		return highlightStart + match + highlightEnd
+	})
+}
+
+func OptionalTerms(values ...*string) []string {
+	terms := make([]string, 0)
+	for _, value := range values {
+		if value != nil {
+			terms = append(terms, *value)
+		}
+	}
+	return terms
+}
+
+var urlHighlightOnce sync.Once
+var hostHighlight, pathHighlight *regexp.Regexp
+
+// UrlHighlightPatterns are the domain and path filters compiled once, as they don't change while the entries are printed
+func UrlHighlightPatterns() (*regexp.Regexp, *regexp.Regexp) {
+	urlHighlightOnce.Do(func() {
+		hostHighlight = TermsPattern(OptionalTerms(CLI.RequestDomain, CLI.RequestDomainIncludes, CLI.Grep))
+		pathHighlight = TermsPattern(OptionalTerms(CLI.RequestPath, CLI.RequestPathIncludes, CLI.Grep))
+	})
+	return hostHighlight, pathHighlight
+}
+
+// HighlightUrl marks the parts of the URL which matched the domain and path filters
+func HighlightUrl(raw string) string {
+	parsed, err := url.Parse(raw)
+	if err != nil || parsed.Host == "" {
+		return raw
+	}
+	hostStart := strings.Index(raw, parsed.Host)
+	if hostStart < 0 {
+		return raw
+	}
+	hostEnd := hostStart + len(parsed.Host)
+	pathEnd := hostEnd + len(raw[hostEnd:])
+	if index := strings.IndexAny(raw[hostEnd:], "?#"); index >= 0 {
+		pathEnd = hostEnd + index
+	}
+
+	hostPattern, pathPattern := UrlHighlightPatterns()
+	return HighlightPattern(raw[:hostStart], GrepPattern()) +
+		HighlightPattern(raw[hostStart:hostEnd], hostPattern) +
+		HighlightPattern(raw[hostEnd:pathEnd], pathPattern) +
+		HighlightPattern(raw[pathEnd:], GrepPattern())
+}
+
+// HighlightRedirect marks the part of the redirect URL which matched --redirect-to
+func HighlightRedirect(raw string) string {
+	if CLI.RedirectTo == nil {
+		return raw
+	}
+	return HighlightPattern(raw, CLI.RedirectTo.Regexp)
+}
@@ -0,0 +1,47 @@
+package main
+
+import (
+	"regexp"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/fatih/color"
+)
+
+func TestHighlightUrl(t *testing.T) {
+	color.NoColor = false
+	defer func() {
+		color.NoColor = true
+		CLI.RequestDomainIncludes, CLI.RequestPathIncludes = nil, nil
+		urlHighlightOnce = sync.Once{}
+	}()
+	domain, path := "example", "api"
+	CLI.RequestDomainIncludes, CLI.RequestPathIncludes = &domain, &path
+	urlHighlightOnce = sync.Once{}
+
+	highlighted := HighlightUrl("https://www.example.com/api/example?q=api")
+	expected := "https://www." + highlightStart + "example" + highlightEnd + ".com/" + highlightStart + "api" + highlightEnd + "/example?q=api"
+	if highlighted != expected {
+		t.Errorf("highlighted %q, expected %q", highlighted, expected)
+	}
+}
+
+func TestHighlightRedirect(t *testing.T) {
+	color.NoColor = false
+	defer func() {
+		color.NoColor = true
+		CLI.RedirectTo = nil
+	}()
+	CLI.RedirectTo = &Pattern{regexp.MustCompile(`(?i)/login\b`)}
+
+	location := "https://example.com/login?next=/account"
+	entry := Entry{
+		Request:  Request{Method: "GET", Url: "https://example.com/account"},
+		Response: Response{Status: 302, RedirectUrl: &location},
+	}
+	output := FormatEntry(entry)
+	if !strings.Contains(output, "https://example.com"+highlightStart+"/login"+highlightEnd+"?next=/account") {
+		t.Errorf("the redirect wasn't highlighted:\n%q", output)
+	}
+}
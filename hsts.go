@@ -0,0 +1,152 @@
+package main
+
+import (
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+type AuditHstsCmd struct {
+	MinMaxAge int    `name:"min-max-age" default:"31536000" help:"HSTS max-age values below this many seconds will be flagged, the default is a year"`
+	File      string `arg:"" help:"The HAR file to parse, or - to read it from stdin" type:"existingfile"`
+}
+
+type HstsPolicy struct {
+	MaxAge            int
+	IncludeSubDomains bool
+	Preload           bool
+}
+
+func ParseHsts(value string) (HstsPolicy, bool) {
+	var policy HstsPolicy
+	found := false
+	for _, directive := range strings.Split(value, ";") {
+		name, value, _ := strings.Cut(strings.TrimSpace(directive), "=")
+		switch strings.ToLower(strings.TrimSpace(name)) {
+		case "max-age":
+			maxAge, err := strconv.Atoi(strings.Trim(strings.TrimSpace(value), `"`))
+			if err != nil {
+				return policy, false
+			}
+			policy.MaxAge = maxAge
+			found = true
+		case "includesubdomains":
+			policy.IncludeSubDomains = true
+		case "preload":
+			policy.Preload = true
+		}
+	}
+	return policy, found
+}
+
+func (policy HstsPolicy) String() string {
+	output := "max-age=" + strconv.Itoa(policy.MaxAge)
+	if policy.IncludeSubDomains {
+		output += "; includeSubDomains"
+	}
+	if policy.Preload {
+		output += "; preload"
+	}
+	return output
+}
+
+// FormatSeconds describes a max-age in the largest whole unit
+func FormatSeconds(seconds int) string {
+	switch {
+	case seconds >= 86400:
+		return strconv.Itoa(seconds/86400) + " days"
+	case seconds >= 3600:
+		return strconv.Itoa(seconds/3600) + " hours"
+	}
+	return strconv.Itoa(seconds) + " seconds"
+}
+
+// AuditHsts checks the Strict-Transport-Security header of every host contacted over HTTPS, then flags plain HTTP
+// requests to hosts which a browser would have upgraded had it seen the header first
+func AuditHsts(entries []Entry, minMaxAge int) []*AuditGroup {
+	groups := make([]*AuditGroup, 0)
+	byHost := make(map[string]*AuditGroup)
+	policies := make(map[string]HstsPolicy)
+	group := func(host string) *AuditGroup {
+		if existing, ok := byHost[host]; ok {
+			return existing
+		}
+		created := &AuditGroup{Name: host}
+		byHost[host] = created
+		groups = append(groups, created)
+		return created
+	}
+
+	for _, entry := range entries {
+		requestUrl, err := url.Parse(entry.Request.Url)
+		if err != nil || requestUrl.Scheme != "https" || entry.Response.Status == 0 {
+			continue
+		}
+		host := strings.ToLower(requestUrl.Hostname())
+		hostGroup := group(host)
+		hostGroup.Entries++
+		if header := FindHeader(entry.Response.Headers, "strict-transport-security"); header != nil {
+			if policy, ok := ParseHsts(header.Value); ok {
+				policies[host] = policy
+			} else {
+				hostGroup.Add(SeverityError, "invalid Strict-Transport-Security header: "+header.Value)
+			}
+		}
+	}
+
+	for _, hostGroup := range groups {
+		policy, ok := policies[hostGroup.Name]
+		switch {
+		case !ok:
+			hostGroup.Summary = "no HSTS"
+			hostGroup.Add(SeverityWarning, "served over HTTPS without Strict-Transport-Security")
+		case policy.MaxAge == 0:
+			hostGroup.Summary = policy.String()
+			hostGroup.Add(SeverityError, "max-age=0 removes the HSTS policy")
+		case policy.MaxAge < minMaxAge:
+			hostGroup.Summary = policy.String()
+			hostGroup.Add(SeverityWarning, "max-age of "+FormatSeconds(policy.MaxAge)+" is shorter than "+FormatSeconds(minMaxAge))
+		default:
+			hostGroup.Summary = policy.String()
+		}
+		if ok && policy.Preload && (!policy.IncludeSubDomains || policy.MaxAge < 31536000) {
+			hostGroup.Add(SeverityWarning, "preload requires includeSubDomains and a max-age of at least a year")
+		}
+	}
+
+	for _, entry := range entries {
+		requestUrl, err := url.Parse(entry.Request.Url)
+		if err != nil || requestUrl.Scheme != "http" {
+			continue
+		}
+		host := strings.ToLower(requestUrl.Hostname())
+		// The host's own policy applies, as does the policy of any parent domain with includeSubDomains
+		for candidate := host; candidate != ""; {
+			if policy, ok := policies[candidate]; ok && policy.MaxAge > 0 && (candidate == host || policy.IncludeSubDomains) {
+				hostGroup := group(host)
+				if _, ok := policies[host]; !ok && hostGroup.Summary == "" {
+					hostGroup.Summary = "plain HTTP only"
+				}
+				if hostGroup.Summary == "plain HTTP only" {
+					hostGroup.Entries++
+				}
+				hostGroup.Add(SeverityError, "plain HTTP request to "+WithoutQuery(entry.Request.Url)+" although "+candidate+" advertises HSTS")
+				break
+			}
+			_, candidate, _ = strings.Cut(candidate, ".")
+		}
+	}
+	return groups
+}
+
+func (cmd *AuditHstsCmd) Run() error {
+	har, err := ReadHar(cmd.File, false)
+	if err != nil {
+		return err
+	}
+	validEntries, err := SelectEntries(har.Log)
+	if err != nil {
+		return err
+	}
+	return WriteOutput(FormatAuditGroups(AuditHsts(validEntries, cmd.MinMaxAge)))
+}
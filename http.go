@@ -0,0 +1,51 @@
+package main
+
+import (
+	"net/url"
+	"strings"
+)
+
+type ExportHttpCmd struct {
+	File string `arg:"" help:"The HAR file to parse, or - to read it from stdin" type:"existingfile"`
+}
+
+// FormatHttpRequest writes an entry as a request of an .http file, as read by the VS Code REST Client and JetBrains
+// HTTP Client, with a ### separator titled with the method and path above it
+func FormatHttpRequest(entry Entry) string {
+	request := entry.Request
+	title := strings.ToUpper(request.Method) + " " + request.Url
+	if requestUrl, err := url.Parse(request.Url); err == nil {
+		title = strings.ToUpper(request.Method) + " " + MiddleEllipsis(Tertiary(requestUrl.Path == "", "/", requestUrl.Path), maxSequenceLabel)
+	}
+	lines := []string{"### " + title, strings.ToUpper(request.Method) + " " + request.Url}
+	for _, header := range ReplayHeaders(request, false) {
+		lines = append(lines, header.Name+": "+strings.NewReplacer("\r", "", "\n", " ").Replace(header.Value))
+	}
+	if request.PostData != nil && request.PostData.Text != "" {
+		body := ReplayBody(*request.PostData)
+		// A line starting with ### would be read as the start of the next request
+		body = strings.ReplaceAll("\n"+body, "\n###", "\n ###")[1:]
+		lines = append(lines, "", body)
+	}
+	return strings.Join(lines, "\n")
+}
+
+func FormatHttpFile(entries []Entry) string {
+	requests := make([]string, len(entries))
+	for i, entry := range entries {
+		requests[i] = FormatHttpRequest(entry)
+	}
+	return strings.Join(requests, "\n\n")
+}
+
+func (cmd *ExportHttpCmd) Run() error {
+	har, err := ReadHarFile(cmd.File)
+	if err != nil {
+		return err
+	}
+	entries, err := SelectEntries(har.Log)
+	if err != nil {
+		return err
+	}
+	return WriteOutput(FormatHttpFile(entries))
+}
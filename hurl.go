@@ -0,0 +1,151 @@
+package main
+
+import (
+	"encoding/json"
+	"net/url"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+type ExportHurlCmd struct {
+	MaxAsserts int    `name:"max-asserts" default:"5" help:"The most fields of each JSON response to assert on"`
+	File       string `arg:"" help:"The HAR file to parse, or - to read it from stdin" type:"existingfile"`
+}
+
+// volatileFieldPattern matches the names of fields which are expected to change between runs, like timestamps and
+// tokens, which would make an assertion on them fail every time
+var volatileFieldPattern = regexp.MustCompile(`(?i:time|date|token|nonce|expires|expiry|session|csrf|signature|random|uuid|etag|request_?id|trace)|_at$|[a-z]At$`)
+
+var jsonPathIdentifier = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// JsonPathField appends a field to a JSONPath, quoting names which aren't identifiers
+func JsonPathField(path string, name string) string {
+	if jsonPathIdentifier.MatchString(name) {
+		return path + "." + name
+	}
+	quoted, _ := json.Marshal(name)
+	return path + "['" + strings.ReplaceAll(strings.Trim(string(quoted), "\""), "'", "\\'") + "']"
+}
+
+// hurlString quotes a value for Hurl, which reads JSON style escapes
+func hurlString(value string) string {
+	quoted, _ := json.Marshal(value)
+	return string(quoted)
+}
+
+// JsonAsserts picks up to max fields of the response to compare, leaving out volatile fields and only checking nested
+// objects and arrays exist. An array response is checked by its length and the fields of its first item
+func JsonAsserts(value any, max int) []string {
+	asserts := make([]string, 0)
+	path := "$"
+	if array, ok := value.([]any); ok {
+		asserts = append(asserts, "jsonpath \"$\" count == "+strconv.Itoa(len(array)))
+		if len(array) == 0 {
+			return asserts
+		}
+		path = "$[0]"
+		value = array[0]
+	}
+	object, ok := value.(map[string]any)
+	if !ok {
+		return asserts
+	}
+	names := make([]string, 0, len(object))
+	for name := range object {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	fields := 0
+	for _, name := range names {
+		if fields >= max {
+			break
+		}
+		if volatileFieldPattern.MatchString(name) {
+			continue
+		}
+		field := "jsonpath " + hurlString(JsonPathField(path, name))
+		switch typed := object[name].(type) {
+		case nil:
+			asserts = append(asserts, field+" == null")
+		case bool:
+			asserts = append(asserts, field+" == "+strconv.FormatBool(typed))
+		case json.Number:
+			asserts = append(asserts, field+" == "+typed.String())
+		case string:
+			asserts = append(asserts, field+" == "+hurlString(typed))
+		default:
+			asserts = append(asserts, field+" exists")
+		}
+		fields++
+	}
+	return asserts
+}
+
+// FormatHurlEntry writes the request and asserts that the response has the same status, content type and JSON fields
+// as the one recorded
+func FormatHurlEntry(entry Entry, maxAsserts int) string {
+	request := entry.Request
+	lines := make([]string, 0)
+	if requestUrl, err := url.Parse(request.Url); err == nil {
+		lines = append(lines, "# "+strings.ToUpper(request.Method)+" "+Tertiary(requestUrl.Path == "", "/", requestUrl.Path))
+	}
+	lines = append(lines, strings.ToUpper(request.Method)+" "+request.Url)
+	for _, header := range ReplayHeaders(request, false) {
+		lines = append(lines, header.Name+": "+strings.NewReplacer("\r", "", "\n", " ").Replace(header.Value))
+	}
+
+	if request.PostData != nil && (request.PostData.Text != "" || len(request.PostData.Params) > 0) {
+		if fields, ok := FormFields(*request.PostData); ok {
+			lines = append(lines, "[FormParams]")
+			for _, field := range fields {
+				lines = append(lines, field.Name+": "+hurlString(field.Value))
+			}
+		} else if _, ok := ParseJsonBody(request.PostData.Text); ok {
+			// Hurl reads a JSON body written directly after the headers
+			lines = append(lines, ReplayBody(*request.PostData))
+		} else {
+			lines = append(lines, "```", ReplayBody(*request.PostData), "```")
+		}
+	}
+
+	if entry.Response.Status == 0 {
+		return strings.Join(append(lines, "# The request failed when it was recorded", "HTTP *"), "\n")
+	}
+	lines = append(lines, "HTTP "+strconv.Itoa(entry.Response.Status))
+	asserts := make([]string, 0)
+	if contentType := HeaderValue(entry.Response.Headers, "content-type"); contentType != "" {
+		mime, _, _ := strings.Cut(contentType, ";")
+		asserts = append(asserts, "header \"Content-Type\" contains "+hurlString(strings.TrimSpace(mime)))
+	}
+	if value, ok := EntryJsonBody(entry, false); ok {
+		asserts = append(asserts, JsonAsserts(value, maxAsserts)...)
+	}
+	if len(asserts) > 0 {
+		lines = append(lines, "[Asserts]")
+		lines = append(lines, asserts...)
+	}
+	return strings.Join(lines, "\n")
+}
+
+func FormatHurlFile(entries []Entry, maxAsserts int) string {
+	requests := make([]string, len(entries))
+	for i, entry := range entries {
+		requests[i] = FormatHurlEntry(entry, maxAsserts)
+	}
+	return strings.Join(requests, "\n\n")
+}
+
+func (cmd *ExportHurlCmd) Run() error {
+	har, err := ReadHarFile(cmd.File)
+	if err != nil {
+		return err
+	}
+	entries, err := SelectEntries(har.Log)
+	if err != nil {
+		return err
+	}
+	return WriteOutput(FormatHurlFile(entries, cmd.MaxAsserts))
+}
@@ -0,0 +1,143 @@
+package main
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/binary"
+	"image"
+	_ "image/gif"
+	_ "image/jpeg"
+	_ "image/png"
+	"strconv"
+	"strings"
+
+	"github.com/fatih/color"
+)
+
+// maxPreviewColumns and maxPreviewRows bound --image-preview, each row of characters is two rows of pixels
+const (
+	maxPreviewColumns = 48
+	maxPreviewRows    = 16
+)
+
+type ImageInfo struct {
+	Format string
+	Width  int
+	Height int
+}
+
+// ContentBytes is the body of a response as bytes, decoding it if it was stored as base64
+func ContentBytes(content Content) ([]byte, bool) {
+	if content.Text == nil {
+		return nil, false
+	}
+	if content.Encoding != nil && strings.EqualFold(*content.Encoding, "base64") {
+		decoded, err := base64.StdEncoding.DecodeString(*content.Text)
+		return decoded, err == nil
+	}
+	return []byte(*content.Text), true
+}
+
+// ImageHeader reads the format and size of an image from its header. PNG, GIF and JPEG are read by the standard
+// library, the others have simple enough headers to read here
+func ImageHeader(data []byte) (ImageInfo, bool) {
+	if config, format, err := image.DecodeConfig(bytes.NewReader(data)); err == nil {
+		return ImageInfo{Format: strings.ToUpper(format), Width: config.Width, Height: config.Height}, true
+	}
+	switch {
+	case len(data) >= 30 && string(data[0:4]) == "RIFF" && string(data[8:12]) == "WEBP":
+		switch string(data[12:16]) {
+		case "VP8 ":
+			return ImageInfo{"WebP", int(binary.LittleEndian.Uint16(data[26:]) & 0x3fff), int(binary.LittleEndian.Uint16(data[28:]) & 0x3fff)}, true
+		case "VP8L":
+			bits := binary.LittleEndian.Uint32(data[21:])
+			return ImageInfo{"WebP", int(bits&0x3fff) + 1, int(bits>>14&0x3fff) + 1}, true
+		case "VP8X":
+			width := int(data[24]) | int(data[25])<<8 | int(data[26])<<16
+			height := int(data[27]) | int(data[28])<<8 | int(data[29])<<16
+			return ImageInfo{"WebP", width + 1, height + 1}, true
+		}
+	case len(data) >= 12 && string(data[4:8]) == "ftyp" && (string(data[8:12]) == "avif" || string(data[8:12]) == "avis"):
+		// The size is in the image spatial extents property, which comes early in the meta box
+		if at := bytes.Index(data, []byte("ispe")); at >= 0 && len(data) >= at+16 {
+			return ImageInfo{"AVIF", int(binary.BigEndian.Uint32(data[at+8:])), int(binary.BigEndian.Uint32(data[at+12:]))}, true
+		}
+		return ImageInfo{Format: "AVIF"}, true
+	case len(data) >= 26 && string(data[0:2]) == "BM":
+		height := int(int32(binary.LittleEndian.Uint32(data[22:])))
+		return ImageInfo{"BMP", int(int32(binary.LittleEndian.Uint32(data[18:]))), max(height, -height)}, true
+	case len(data) >= 8 && bytes.Equal(data[0:4], []byte{0, 0, 1, 0}):
+		// Icons hold several images, this is the first, where a size of 0 means 256
+		width, height := int(data[6]), int(data[7])
+		return ImageInfo{"ICO", Tertiary(width == 0, 256, width), Tertiary(height == 0, 256, height)}, true
+	}
+	return ImageInfo{}, false
+}
+
+// IsBinaryImage is whether a mime type is an image which wouldn't be readable printed as text, unlike SVG
+func IsBinaryImage(mimeType string) bool {
+	lower := strings.ToLower(mimeType)
+	return strings.HasPrefix(lower, "image/") && !strings.Contains(lower, "svg")
+}
+
+// FormatImagePreview draws the image with half block characters, the top half coloured by one pixel and the bottom by
+// the one below it, which needs a terminal with 24-bit colour
+func FormatImagePreview(data []byte) (string, bool) {
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return "", false
+	}
+	bounds := img.Bounds()
+	if bounds.Dx() == 0 || bounds.Dy() == 0 {
+		return "", false
+	}
+	columns := min(bounds.Dx(), maxPreviewColumns)
+	if width := LayoutWidth(); width > 0 {
+		columns = min(columns, max(width-4, 8))
+	}
+	rows := max(1, bounds.Dy()*columns/bounds.Dx()/2)
+	if rows > maxPreviewRows {
+		columns = max(1, columns*maxPreviewRows/rows)
+		rows = maxPreviewRows
+	}
+
+	pixel := func(x int, y int) string {
+		r, g, b, _ := img.At(bounds.Min.X+x*bounds.Dx()/columns, bounds.Min.Y+y*bounds.Dy()/(rows*2)).RGBA()
+		return strconv.Itoa(int(r>>8)) + ";" + strconv.Itoa(int(g>>8)) + ";" + strconv.Itoa(int(b>>8))
+	}
+	lines := make([]string, rows)
+	for y := 0; y < rows; y++ {
+		var line strings.Builder
+		for x := 0; x < columns; x++ {
+			line.WriteString("\x1b[38;2;" + pixel(x, y*2) + "m\x1b[48;2;" + pixel(x, y*2+1) + "m▀")
+		}
+		lines[y] = line.String() + "\x1b[0m"
+	}
+	return strings.Join(lines, "\n"), true
+}
+
+// FormatImageContent summarises an image body in place of printing it, with a preview when --image-preview is given
+func FormatImageContent(content Content) (string, bool) {
+	if !IsBinaryImage(content.MimeType) {
+		return "", false
+	}
+	data, ok := ContentBytes(content)
+	if !ok || len(data) == 0 {
+		return "", false
+	}
+	info, ok := ImageHeader(data)
+	if !ok {
+		return color.HiBlackString("[binary image, " + FormatBytes(len(data)) + "]"), true
+	}
+	summary := color.HiBlackString("Image: ") + color.YellowString(info.Format)
+	if info.Width > 0 && info.Height > 0 {
+		summary += " " + color.YellowString(strconv.Itoa(info.Width)+"×"+strconv.Itoa(info.Height))
+	}
+	summary += color.HiBlackString(", " + FormatBytes(len(data)))
+	if CLI.ImagePreview != nil && *CLI.ImagePreview && !color.NoColor {
+		if preview, ok := FormatImagePreview(data); ok {
+			summary += "\n" + preview
+		}
+	}
+	return summary, true
+}
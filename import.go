@@ -0,0 +1,5 @@
+package main
+
+type ImportCmd struct {
+	Burp ImportBurpCmd `cmd:"" name:"burp" help:"Convert the XML written by Burp's Save items to a HAR file"`
+}
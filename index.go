@@ -0,0 +1,211 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"strings"
+)
+
+// indexVersion is bumped whenever IndexEntry changes so old sidecar files are rebuilt rather than misread
+const indexVersion = 6
+
+// IndexEntry is where an entry is in the HAR file along with the fields the filters and aggregates need, so queries
+// can be answered without decoding the entries which don't match
+type IndexEntry struct {
+	Offset          int64    `json:"offset"`
+	Length          int      `json:"length"`
+	PageRef         *string  `json:"pageref,omitempty"`
+	StartedDateTime string   `json:"startedDateTime"`
+	Time            float64  `json:"time"`
+	Method          string   `json:"method"`
+	Url             string   `json:"url"`
+	Status          int      `json:"status"`
+	RequestVersion  string   `json:"requestHttpVersion,omitempty"`
+	HttpVersion     string   `json:"httpVersion,omitempty"`
+	RedirectUrl     *string  `json:"redirectURL,omitempty"`
+	MimeType        string   `json:"mimeType"`
+	HeadersSize     int      `json:"headersSize"`
+	BodySize        int      `json:"bodySize"`
+	ContentSize     int      `json:"contentSize"`
+	TransferSize    *int     `json:"transferSize,omitempty"`
+	FromCache       *string  `json:"fromCache,omitempty"`
+	ServerTiming    []string `json:"serverTiming,omitempty"`
+	ServerIP        *string  `json:"serverIPAddress,omitempty"`
+	Connect         *float64 `json:"connect,omitempty"`
+	Ssl             *float64 `json:"ssl,omitempty"`
+}
+
+type HarIndex struct {
+	Version int          `json:"version"`
+	Size    int64        `json:"size"`
+	ModTime int64        `json:"modTime"`
+	Pages   *[]Page      `json:"pages,omitempty"`
+	Entries []IndexEntry `json:"entries"`
+}
+
+func IndexPath(file string) string {
+	return file + ".idx"
+}
+
+// IndexCanFilter is false when a filter needs fields which aren't kept in the index, such as headers or bodies
+func IndexCanFilter() bool {
+	return CLI.Grep == nil && CLI.LocationIncludes == nil && CLI.RequestHasBody == nil && CLI.ResponseHasBody == nil &&
+		CLI.TlsVersion == nil && CLI.WithChain == nil && CLI.GraphqlOperation == nil && CLI.SoapAction == nil && (CLI.Lenient == nil || !*CLI.Lenient)
+}
+
+// LoadIndex reads the sidecar index for file, building it (and trying to save it) if it is missing or the file has
+// changed since it was written
+func LoadIndex(file string) (HarIndex, error) {
+	info, err := os.Stat(file)
+	if err != nil {
+		return HarIndex{}, err
+	}
+
+	if content, err := os.ReadFile(IndexPath(file)); err == nil {
+		var index HarIndex
+		if json.Unmarshal(content, &index) == nil && index.Version == indexVersion &&
+			index.Size == info.Size() && index.ModTime == info.ModTime().UnixNano() {
+			slog.Info("Using the existing index", "path", IndexPath(file), "entries", len(index.Entries))
+			return index, nil
+		}
+		slog.Info("The index is out of date, rebuilding it", "path", IndexPath(file))
+	}
+
+	index, err := BuildIndex(file)
+	if err != nil {
+		return HarIndex{}, err
+	}
+	index.Size = info.Size()
+	index.ModTime = info.ModTime().UnixNano()
+
+	content, _ := json.Marshal(index)
+	if err := os.WriteFile(IndexPath(file), content, 0644); err != nil {
+		slog.Warn("Could not save the index, it will be rebuilt next time", "path", IndexPath(file), "error", err)
+	} else {
+		slog.Info("Saved the index", "path", IndexPath(file), "entries", len(index.Entries))
+	}
+	return index, nil
+}
+
+func BuildIndex(file string) (HarIndex, error) {
+	input, err := os.Open(file)
+	if err != nil {
+		return HarIndex{}, err
+	}
+	defer input.Close()
+
+	index := HarIndex{Version: indexVersion, Entries: make([]IndexEntry, 0)}
+	har, _, err := HarWalker{}.Walk(input, func(entry Entry, offset int64, length int) error {
+		indexed := IndexEntry{
+			Offset:          offset,
+			Length:          length,
+			PageRef:         entry.PageRef,
+			StartedDateTime: entry.StartedDateTime,
+			Time:            entry.TimeMs,
+			Method:          entry.Request.Method,
+			Url:             entry.Request.Url,
+			Status:          entry.Response.Status,
+			RequestVersion:  entry.Request.HttpVersion,
+			HttpVersion:     entry.Response.HttpVersion,
+			RedirectUrl:     entry.Response.RedirectUrl,
+			HeadersSize:     entry.Response.HeadersSize,
+			BodySize:        entry.Response.BodySize,
+			TransferSize:    entry.Response.TransferSize,
+			FromCache:       entry.FromCache,
+			ServerIP:        entry.ServerIP,
+			Connect:         entry.Timings.Connect,
+			Ssl:             entry.Timings.Ssl,
+		}
+		for _, header := range entry.Response.Headers {
+			if strings.EqualFold(header.Name, "server-timing") {
+				indexed.ServerTiming = append(indexed.ServerTiming, header.Value)
+			}
+		}
+		if entry.Response.Content != nil {
+			indexed.MimeType = entry.Response.Content.MimeType
+			indexed.ContentSize = entry.Response.Content.Size
+		}
+		index.Entries = append(index.Entries, indexed)
+		return nil
+	})
+	if err != nil {
+		return HarIndex{}, fmt.Errorf("failed to index %s, %s", file, err)
+	}
+	index.Pages = har.Log.Pages
+	return index, nil
+}
+
+// Skeleton fills in as much of an entry as the index knows, which is enough for the filters, summary and aggregates
+func (indexed IndexEntry) Skeleton() Entry {
+	headers := make([]Header, 0, len(indexed.ServerTiming))
+	for _, value := range indexed.ServerTiming {
+		headers = append(headers, Header{Name: "Server-Timing", Value: value})
+	}
+	return Entry{
+		PageRef:         indexed.PageRef,
+		StartedDateTime: indexed.StartedDateTime,
+		TimeMs:          indexed.Time,
+		Request: Request{
+			Method:      indexed.Method,
+			Url:         indexed.Url,
+			HttpVersion: indexed.RequestVersion,
+		},
+		Response: Response{
+			Status:       indexed.Status,
+			HttpVersion:  indexed.HttpVersion,
+			Headers:      headers,
+			RedirectUrl:  indexed.RedirectUrl,
+			HeadersSize:  indexed.HeadersSize,
+			BodySize:     indexed.BodySize,
+			TransferSize: indexed.TransferSize,
+			Content:      &Content{Size: indexed.ContentSize, MimeType: indexed.MimeType},
+		},
+		Timings:   EntryTimings{Connect: indexed.Connect, Ssl: indexed.Ssl},
+		FromCache: indexed.FromCache,
+		ServerIP:  indexed.ServerIP,
+	}
+}
+
+// SelectIndexed is SelectEntries for an index, returning the positions of the matching entries
+func SelectIndexed(index HarIndex) ([]IndexEntry, error) {
+	skeletons := make([]Entry, len(index.Entries))
+	for i, indexed := range index.Entries {
+		skeletons[i] = indexed.Skeleton()
+	}
+	CaptureStart = EarliestStart(skeletons)
+
+	pageId := ""
+	if CLI.Page != nil {
+		page, err := FindPage(Log{Pages: index.Pages}, *CLI.Page)
+		if err != nil {
+			return nil, err
+		}
+		pageId = page.Id
+	}
+
+	selected := make([]IndexEntry, 0)
+	for i, skeleton := range skeletons {
+		if CLI.Page != nil && (skeleton.PageRef == nil || *skeleton.PageRef != pageId) {
+			continue
+		}
+		if IsEntryValid(skeleton) {
+			selected = append(selected, index.Entries[i])
+		}
+	}
+	return selected, nil
+}
+
+// ReadIndexedEntry decodes a single entry straight from its position in the file
+func ReadIndexedEntry(file *os.File, indexed IndexEntry) (Entry, error) {
+	raw := make([]byte, indexed.Length)
+	if _, err := file.ReadAt(raw, indexed.Offset); err != nil {
+		return Entry{}, err
+	}
+	var entry Entry
+	if err := UnmarshalWithExtensions(raw, &entry); err != nil {
+		return Entry{}, fmt.Errorf("the index is out of date with %s, %s", file.Name(), err)
+	}
+	return entry, nil
+}
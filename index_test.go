@@ -0,0 +1,130 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// copyTestHar copies a fixture into a temporary directory, so the index can be written next to it
+func copyTestHar(t *testing.T, file string) string {
+	content, err := os.ReadFile(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), filepath.Base(file))
+	if err := os.WriteFile(path, content, 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// markIndex leaves a single entry in the saved index, so whether LoadIndex used it or rebuilt it shows in the count
+func markIndex(t *testing.T, file string) {
+	content, err := os.ReadFile(IndexPath(file))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var index HarIndex
+	if err := json.Unmarshal(content, &index); err != nil {
+		t.Fatal(err)
+	}
+	index.Entries = index.Entries[:1]
+	if content, err = json.Marshal(index); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(IndexPath(file), content, 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestLoadIndex(t *testing.T) {
+	file := copyTestHar(t, "testdata/chrome.har")
+	index, err := LoadIndex(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(index.Entries) != 3 {
+		t.Fatalf("indexed %d entries, expected 3", len(index.Entries))
+	}
+	if _, err := os.Stat(IndexPath(file)); err != nil {
+		t.Fatalf("the index wasn't saved, %s", err)
+	}
+
+	input, err := os.Open(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer input.Close()
+	har := readTestHar(t, "testdata/chrome.har")
+	for i, indexed := range index.Entries {
+		entry, err := ReadIndexedEntry(input, indexed)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if entry.Request.Url != har.Log.Entries[i].Request.Url || entry.StartedDateTime != indexed.StartedDateTime {
+			t.Errorf("entry %d at offset %d is %s, expected %s", i, indexed.Offset, entry.Request.Url, har.Log.Entries[i].Request.Url)
+		}
+	}
+
+	markIndex(t, file)
+	if index, err = LoadIndex(file); err != nil || len(index.Entries) != 1 {
+		t.Errorf("expected the saved index to be used, got %d entries, %v", len(index.Entries), err)
+	}
+}
+
+func TestLoadIndexRebuildsStaleIndex(t *testing.T) {
+	cases := map[string]func(t *testing.T, file string){
+		"modified": func(t *testing.T, file string) {
+			later := time.Now().Add(time.Hour)
+			if err := os.Chtimes(file, later, later); err != nil {
+				t.Fatal(err)
+			}
+		},
+		"resized": func(t *testing.T, file string) {
+			content, _ := os.ReadFile(file)
+			info, _ := os.Stat(file)
+			if err := os.WriteFile(file, append(content, '\n'), 0644); err != nil {
+				t.Fatal(err)
+			}
+			// Keep the modification time so only the size gives the change away
+			if err := os.Chtimes(file, info.ModTime(), info.ModTime()); err != nil {
+				t.Fatal(err)
+			}
+		},
+		"old version": func(t *testing.T, file string) {
+			content, _ := os.ReadFile(IndexPath(file))
+			var index HarIndex
+			if err := json.Unmarshal(content, &index); err != nil {
+				t.Fatal(err)
+			}
+			index.Version = indexVersion - 1
+			content, _ = json.Marshal(index)
+			if err := os.WriteFile(IndexPath(file), content, 0644); err != nil {
+				t.Fatal(err)
+			}
+		},
+		"corrupt": func(t *testing.T, file string) {
+			if err := os.WriteFile(IndexPath(file), []byte("{"), 0644); err != nil {
+				t.Fatal(err)
+			}
+		},
+	}
+	for name, change := range cases {
+		file := copyTestHar(t, "testdata/chrome.har")
+		if _, err := LoadIndex(file); err != nil {
+			t.Fatal(err)
+		}
+		markIndex(t, file)
+		change(t, file)
+		index, err := LoadIndex(file)
+		if err != nil {
+			t.Fatalf("%s: %s", name, err)
+		}
+		if len(index.Entries) != 3 {
+			t.Errorf("%s: expected the index to be rebuilt with 3 entries, got %d", name, len(index.Entries))
+		}
+	}
+}
@@ -0,0 +1,124 @@
+package main
+
+import (
+	"encoding/json"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type ExportInsomniaCmd struct {
+	Name *string `name:"name" help:"The name of the workspace, defaults to the name of the file"`
+	File string  `arg:"" help:"The HAR file to parse, or - to read it from stdin" type:"existingfile"`
+}
+
+// InsomniaResource is a workspace, folder or request in an Insomnia v4 export, which are all kept in one flat list
+// linked by their parent IDs
+type InsomniaResource struct {
+	Id             string             `json:"_id"`
+	Type           string             `json:"_type"`
+	ParentId       *string            `json:"parentId"`
+	Name           string             `json:"name"`
+	Description    string             `json:"description"`
+	Scope          string             `json:"scope,omitempty"`
+	Method         string             `json:"method,omitempty"`
+	Url            string             `json:"url,omitempty"`
+	Body           *InsomniaBody      `json:"body,omitempty"`
+	Headers        []Header           `json:"headers,omitempty"`
+	Authentication *InsomniaAuth      `json:"authentication,omitempty"`
+	Environment    *map[string]string `json:"environment,omitempty"`
+}
+
+type InsomniaBody struct {
+	MimeType string           `json:"mimeType"`
+	Text     string           `json:"text,omitempty"`
+	Params   []QueryParameter `json:"params,omitempty"`
+}
+
+type InsomniaAuth struct {
+	Type     string `json:"type"`
+	Token    string `json:"token,omitempty"`
+	Username string `json:"username,omitempty"`
+	Password string `json:"password,omitempty"`
+}
+
+type InsomniaExport struct {
+	Type      string             `json:"_type"`
+	Format    int                `json:"__export_format"`
+	Date      string             `json:"__export_date"`
+	Source    string             `json:"__export_source"`
+	Resources []InsomniaResource `json:"resources"`
+}
+
+func NewInsomniaRequest(id string, parentId string, entry Entry) InsomniaResource {
+	request := entry.Request
+	resource := InsomniaResource{
+		Id:       id,
+		Type:     "request",
+		ParentId: &parentId,
+		Name:     strings.ToUpper(request.Method) + " " + request.Url,
+		Method:   strings.ToUpper(request.Method),
+		Url:      request.Url,
+	}
+	if requestUrl, err := url.Parse(request.Url); err == nil {
+		resource.Name = strings.ToUpper(request.Method) + " " + MiddleEllipsis(Tertiary(requestUrl.Path == "", "/", requestUrl.Path), maxSequenceLabel)
+	}
+
+	auth := RequestAuth(request)
+	resource.Headers = ReplayHeaders(request, auth != nil)
+	if auth != nil {
+		resource.Authentication = &InsomniaAuth{Type: auth.Type, Token: auth.Token, Username: auth.Username, Password: auth.Password}
+	}
+	if request.PostData != nil && (request.PostData.Text != "" || len(request.PostData.Params) > 0) {
+		resource.Body = &InsomniaBody{MimeType: request.PostData.MimeType}
+		if fields, ok := FormFields(*request.PostData); ok {
+			resource.Body.Params = fields
+		} else {
+			resource.Body.Text = request.PostData.Text
+		}
+	}
+	return resource
+}
+
+// BuildInsomniaExport puts the requests in a folder for each host, in the order the hosts were first contacted
+func BuildInsomniaExport(name string, entries []Entry) InsomniaExport {
+	workspaceId := "wrk_harv"
+	resources := []InsomniaResource{{Id: workspaceId, Type: "workspace", Name: name, Scope: "collection"}}
+	folders := make(map[string]string)
+	for i, entry := range entries {
+		host := "[invalid url]"
+		if requestUrl, err := url.Parse(entry.Request.Url); err == nil {
+			host = strings.ToLower(requestUrl.Host)
+		}
+		folderId, ok := folders[host]
+		if !ok {
+			folderId = "fld_" + strconv.Itoa(len(folders)+1)
+			folders[host] = folderId
+			environment := map[string]string{}
+			resources = append(resources, InsomniaResource{Id: folderId, Type: "request_group", ParentId: &workspaceId, Name: host, Environment: &environment})
+		}
+		resources = append(resources, NewInsomniaRequest("req_"+strconv.Itoa(i+1), folderId, entry))
+	}
+	return InsomniaExport{Type: "export", Format: 4, Date: time.Now().UTC().Format(time.RFC3339), Source: "harv", Resources: resources}
+}
+
+func (cmd *ExportInsomniaCmd) Run() error {
+	har, err := ReadHarFile(cmd.File)
+	if err != nil {
+		return err
+	}
+	entries, err := SelectEntries(har.Log)
+	if err != nil {
+		return err
+	}
+	name := CollectionName(cmd.File)
+	if cmd.Name != nil {
+		name = *cmd.Name
+	}
+	content, err := json.MarshalIndent(BuildInsomniaExport(name, entries), "", "  ")
+	if err != nil {
+		return err
+	}
+	return WriteOutput(string(content))
+}
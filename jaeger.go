@@ -0,0 +1,175 @@
+package main
+
+import (
+	"encoding/json"
+	"sort"
+	"strconv"
+	"time"
+)
+
+type ExportJaegerCmd struct {
+	ServiceName string `name:"service-name" default:"browser" help:"The service name of the spans"`
+	File        string `arg:"" help:"The HAR file to parse, or - to read it from stdin" type:"existingfile"`
+}
+
+type JaegerTag struct {
+	Key   string `json:"key"`
+	Type  string `json:"type"`
+	Value any    `json:"value"`
+}
+
+type JaegerLog struct {
+	Timestamp int64       `json:"timestamp"`
+	Fields    []JaegerTag `json:"fields"`
+}
+
+type JaegerReference struct {
+	RefType string `json:"refType"`
+	TraceId string `json:"traceID"`
+	SpanId  string `json:"spanID"`
+}
+
+type JaegerSpan struct {
+	TraceId       string            `json:"traceID"`
+	SpanId        string            `json:"spanID"`
+	Flags         int               `json:"flags"`
+	OperationName string            `json:"operationName"`
+	References    []JaegerReference `json:"references"`
+	StartTime     int64             `json:"startTime"`
+	Duration      int64             `json:"duration"`
+	Tags          []JaegerTag       `json:"tags"`
+	Logs          []JaegerLog       `json:"logs"`
+	ProcessId     string            `json:"processID"`
+}
+
+type JaegerProcess struct {
+	ServiceName string      `json:"serviceName"`
+	Tags        []JaegerTag `json:"tags"`
+}
+
+type JaegerTrace struct {
+	TraceId   string                   `json:"traceID"`
+	Spans     []JaegerSpan             `json:"spans"`
+	Processes map[string]JaegerProcess `json:"processes"`
+}
+
+// jaegerTags converts OTLP attributes to the typed tags of Jaeger's JSON
+func jaegerTags(attributes []OtlpAttribute) []JaegerTag {
+	tags := make([]JaegerTag, 0, len(attributes))
+	for _, attribute := range attributes {
+		switch {
+		case attribute.Value.StringValue != nil:
+			tags = append(tags, JaegerTag{Key: attribute.Key, Type: "string", Value: *attribute.Value.StringValue})
+		case attribute.Value.IntValue != nil:
+			number, _ := strconv.ParseInt(*attribute.Value.IntValue, 10, 64)
+			tags = append(tags, JaegerTag{Key: attribute.Key, Type: "int64", Value: number})
+		case attribute.Value.DoubleValue != nil:
+			tags = append(tags, JaegerTag{Key: attribute.Key, Type: "float64", Value: *attribute.Value.DoubleValue})
+		}
+	}
+	return tags
+}
+
+func jaegerMicros(unixNano string) int64 {
+	nanos, _ := strconv.ParseInt(unixNano, 10, 64)
+	return nanos / 1000
+}
+
+// BuildJaegerTrace makes the document of a page the root span, with each request a child of the earlier request which
+// loaded its initiator. Requests whose initiator wasn't loaded in the page, or which have none, are children of the
+// document
+func BuildJaegerTrace(log Log, group []Entry, serviceName string) JaegerTrace {
+	sort.SliceStable(group, func(i, j int) bool {
+		left, _ := ParseStartedDateTime(group[i].StartedDateTime)
+		right, _ := ParseStartedDateTime(group[j].StartedDateTime)
+		return left.Before(right)
+	})
+	root := 0
+	for i, entry := range group {
+		if IsDocument(entry) {
+			root = i
+			break
+		}
+	}
+
+	reference := ""
+	if group[root].PageRef != nil {
+		reference = *group[root].PageRef
+	}
+	traceId := otlpId(16, reference, group[root].StartedDateTime, group[root].Request.Url)
+	spanIds := EntrySpanIds(traceId, group)
+	// Only requests which started earlier can be a parent, so a page which loads itself can't make a cycle
+	byUrl := map[string]string{group[root].Request.Url: spanIds[root]}
+
+	trace := JaegerTrace{TraceId: traceId, Spans: make([]JaegerSpan, 0, len(group)), Processes: map[string]JaegerProcess{"p1": {ServiceName: serviceName, Tags: []JaegerTag{}}}}
+	for i, entry := range group {
+		started, _ := ParseStartedDateTime(entry.StartedDateTime)
+		parentId := ""
+		if i != root {
+			parentId = spanIds[root]
+			if initiator, ok := byUrl[InitiatorUrl(entry)]; ok {
+				parentId = initiator
+			}
+			if _, ok := byUrl[entry.Request.Url]; !ok {
+				byUrl[entry.Request.Url] = spanIds[i]
+			}
+		}
+		span := EntrySpan(entry, started, traceId, spanIds[i], parentId)
+
+		tags := append([]JaegerTag{{Key: "span.kind", Type: "string", Value: "client"}}, jaegerTags(span.Attributes)...)
+		if span.Status.Code == otlpStatusError {
+			tags = append(tags, JaegerTag{Key: "error", Type: "bool", Value: true})
+			if span.Status.Message != "" {
+				tags = append(tags, JaegerTag{Key: "otel.status_description", Type: "string", Value: span.Status.Message})
+			}
+		}
+		if i == root && reference != "" {
+			if page, err := FindPage(log, reference); err == nil {
+				tags = append(tags, JaegerTag{Key: "harv.page.id", Type: "string", Value: page.Id}, JaegerTag{Key: "harv.page.title", Type: "string", Value: page.Title})
+			}
+		}
+		logs := make([]JaegerLog, len(span.Events))
+		for j, event := range span.Events {
+			fields := append([]JaegerTag{{Key: "event", Type: "string", Value: event.Name}}, jaegerTags(event.Attributes)...)
+			logs[j] = JaegerLog{Timestamp: jaegerMicros(event.TimeUnixNano), Fields: fields}
+		}
+		references := make([]JaegerReference, 0, 1)
+		if parentId != "" {
+			references = append(references, JaegerReference{RefType: "CHILD_OF", TraceId: traceId, SpanId: parentId})
+		}
+		trace.Spans = append(trace.Spans, JaegerSpan{
+			TraceId:       traceId,
+			SpanId:        spanIds[i],
+			Flags:         1,
+			OperationName: span.Name,
+			References:    references,
+			StartTime:     started.UnixMicro(),
+			Duration:      int64(millis(entry.TimeMs) / time.Microsecond),
+			Tags:          tags,
+			Logs:          logs,
+			ProcessId:     "p1",
+		})
+	}
+	return trace
+}
+
+func (cmd *ExportJaegerCmd) Run() error {
+	har, err := ReadHar(cmd.File, false)
+	if err != nil {
+		return err
+	}
+	entries, err := SelectEntries(har.Log)
+	if err != nil {
+		return err
+	}
+	traces := make([]JaegerTrace, 0)
+	for _, group := range PageGroups(entries) {
+		traces = append(traces, BuildJaegerTrace(har.Log, group, cmd.ServiceName))
+	}
+	// This is the shape of Jaeger's own API responses, which the UI can open from a file
+	content, err := json.MarshalIndent(map[string]any{"data": traces}, "", "  ")
+	if err != nil {
+		return err
+	}
+	return WriteOutput(string(content))
+}
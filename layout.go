@@ -0,0 +1,85 @@
+package main
+
+import (
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode/utf8"
+
+	"golang.org/x/term"
+)
+
+// defaultWidth is used when --truncate-url is given but the output isn't a terminal
+const defaultWidth = 80
+
+var terminalWidth int
+var terminalOnce sync.Once
+
+// TerminalWidth is the width of stdout if it is a terminal, falling back to $COLUMNS, or 0 if neither is known
+func TerminalWidth() int {
+	terminalOnce.Do(func() {
+		if width, _, err := term.GetSize(int(os.Stdout.Fd())); err == nil && width > 0 {
+			terminalWidth = width
+		} else if columns, err := strconv.Atoi(os.Getenv("COLUMNS")); err == nil && columns > 0 {
+			terminalWidth = columns
+		}
+	})
+	return terminalWidth
+}
+
+// LayoutWidth is how wide lines should be kept, or 0 if long lines shouldn't be shortened. This is on by default when
+// writing to a terminal, and when piping it can be turned on with --truncate-url or --width
+func LayoutWidth() int {
+	if CLI.FullUrl != nil && *CLI.FullUrl {
+		return 0
+	}
+	if CLI.Width != nil && *CLI.Width > 0 {
+		return *CLI.Width
+	}
+	if (CLI.Out == nil && term.IsTerminal(int(os.Stdout.Fd()))) || (CLI.TruncateUrl != nil && *CLI.TruncateUrl) {
+		return Tertiary(TerminalWidth() > 0, TerminalWidth(), defaultWidth)
+	}
+	return 0
+}
+
+// VisibleLength counts the characters in v which take up space, ignoring colour codes
+func VisibleLength(v string) int {
+	return utf8.RuneCountInString(escapeSequencePattern.ReplaceAllString(v, ""))
+}
+
+// MiddleEllipsis shortens v to width characters by replacing the middle with …, keeping the start and end which are
+// usually the most telling parts of a URL. Colour codes are all kept so the colours either side are unaffected
+func MiddleEllipsis(v string, width int) string {
+	length := VisibleLength(v)
+	if width <= 0 || length <= width {
+		return v
+	}
+	if width == 1 {
+		return "…"
+	}
+
+	head := (width - 1) / 2
+	tail := width - 1 - head
+	output := strings.Builder{}
+	visible := 0
+	last := 0
+	keep := func(text string) {
+		for _, r := range text {
+			if visible < head || visible >= length-tail {
+				output.WriteRune(r)
+			}
+			if visible == head {
+				output.WriteString("…")
+			}
+			visible++
+		}
+	}
+	for _, escape := range escapeSequencePattern.FindAllStringIndex(v, -1) {
+		keep(v[last:escape[0]])
+		output.WriteString(v[escape[0]:escape[1]])
+		last = escape[1]
+	}
+	keep(v[last:])
+	return output.String()
+}
@@ -0,0 +1,149 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/fatih/color"
+)
+
+type ParseProblem struct {
+	Entry int
+	Line  int
+	// Offset is where in the file the problem was, or -1 when it isn't about one place
+	Offset  int64
+	Skipped bool
+	Reason  string
+}
+
+type ParseReport struct {
+	Entries  int
+	Problems []ParseProblem
+}
+
+// LineCounter converts byte offsets into line numbers, it expects offsets to be requested in increasing order
+type LineCounter struct {
+	content []byte
+	offset  int64
+	line    int
+}
+
+func (counter *LineCounter) Line(offset int64) int {
+	if counter.line == 0 {
+		counter.line = 1
+	}
+	if offset > int64(len(counter.content)) {
+		offset = int64(len(counter.content))
+	}
+	if offset > counter.offset {
+		counter.line += bytes.Count(counter.content[counter.offset:offset], []byte{'\n'})
+		counter.offset = offset
+	}
+	return counter.line
+}
+
+func LineAndColumn(content []byte, offset int64) (int, int) {
+	if offset > int64(len(content)) {
+		offset = int64(len(content))
+	}
+	before := content[:offset]
+	line := bytes.Count(before, []byte{'\n'}) + 1
+	column := int(offset) - bytes.LastIndexByte(before, '\n')
+	return line, column
+}
+
+func DescribeJsonError(content []byte, err error) string {
+	var syntaxError *json.SyntaxError
+	var typeError *json.UnmarshalTypeError
+	if errors.As(err, &syntaxError) {
+		line, column := LineAndColumn(content, syntaxError.Offset)
+		return fmt.Sprintf("line %d column %d: %s", line, column, syntaxError.Error())
+	}
+	if errors.As(err, &typeError) {
+		line, column := LineAndColumn(content, typeError.Offset)
+		return fmt.Sprintf("line %d column %d: %s", line, column, DescribeTypeError(typeError))
+	}
+	if errors.Is(err, io.ErrUnexpectedEOF) {
+		return "the file ends part way through, it may have been truncated"
+	}
+	return err.Error()
+}
+
+func DescribeTypeError(err *json.UnmarshalTypeError) string {
+	field := err.Field
+	if field == "" {
+		field = "value"
+	}
+	return field + " should be " + err.Type.String() + " but was " + err.Value
+}
+
+// ReadHarLeniently decodes the file one entry at a time so a single malformed entry, or a file which was cut off
+// part way through being written, doesn't prevent everything else from being loaded
+func ReadHarLeniently(content []byte) (HarFile, ParseReport) {
+	var report ParseReport
+	entries := make([]Entry, 0)
+	lines := LineCounter{content: content}
+
+	walker := HarWalker{
+		Lenient: true,
+		Problem: func(problem ParseProblem) {
+			if problem.Offset >= 0 {
+				problem.Line = lines.Line(problem.Offset)
+			}
+			report.Problems = append(report.Problems, problem)
+		},
+		Describe: func(err error) string {
+			return DescribeJsonError(content, err)
+		},
+	}
+	har, total, _ := walker.Walk(bytes.NewReader(content), func(entry Entry, _ int64, _ int) error {
+		entries = append(entries, entry)
+		return nil
+	})
+	har.Log.Entries = entries
+	report.Entries = total
+	return har, report
+}
+
+func ExpectDelim(decoder *json.Decoder, delim json.Delim) bool {
+	token, err := decoder.Token()
+	return err == nil && token == delim
+}
+
+func FormatParseReport(report ParseReport) string {
+	skipped := make(map[int]bool)
+	partial := make(map[int]bool)
+	for _, problem := range report.Problems {
+		if problem.Entry < 0 {
+			continue
+		}
+		if problem.Skipped {
+			skipped[problem.Entry] = true
+		} else {
+			partial[problem.Entry] = true
+		}
+	}
+
+	output := color.YellowString("Lenient parsing loaded %d entries, skipped %d and partially loaded %d", report.Entries-len(skipped), len(skipped), len(partial))
+	for _, problem := range report.Problems {
+		location := "file"
+		if problem.Entry >= 0 {
+			location = "entry " + strconv.Itoa(problem.Entry)
+		}
+		if problem.Line > 0 {
+			location += " (line " + strconv.Itoa(problem.Line) + ")"
+		}
+		action := Tertiary(problem.Skipped, color.RedString("skipped"), color.YellowString("partial"))
+		if problem.Entry < 0 {
+			action = Tertiary(problem.Skipped, color.RedString("stopped"), color.YellowString("partial"))
+		}
+		output += "\n  " + action + " " + color.HiBlackString(location+": ") + strings.TrimSpace(problem.Reason)
+	}
+
+	return output
+}
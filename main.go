@@ -1,101 +1,122 @@
 package main
 
 import (
+	"bufio"
+	"bytes"
 	"encoding/json"
+	"fmt"
 	"github.com/TylerBrock/colorjson"
 	"github.com/alecthomas/kong"
 	"github.com/fatih/color"
 	"log/slog"
+	"math"
+	"net"
 	"net/url"
 	"os"
+	"regexp"
+	"runtime/pprof"
 	"strconv"
 	"strings"
+	"time"
 )
 
 type Creator struct {
-	Name    string  `json:"name"`
-	Version string  `json:"version"`
-	Comment *string `json:"comment"`
+	Name       string     `json:"name"`
+	Version    string     `json:"version"`
+	Comment    *string    `json:"comment,omitempty"`
+	Extensions Extensions `json:"-"`
 }
 
 type Browser struct {
-	Name    string  `json:"name"`
-	Version string  `json:"version"`
-	Comment *string `json:"comment"`
+	Name       string     `json:"name"`
+	Version    string     `json:"version"`
+	Comment    *string    `json:"comment,omitempty"`
+	Extensions Extensions `json:"-"`
 }
 
 type PageTiming struct {
-	ContentLoad *int    `json:"onContentLoad"`
-	Load        *int    `json:"onLoad"`
-	Comment     *string `json:"comment"`
+	ContentLoad *float64   `json:"onContentLoad,omitempty"`
+	Load        *float64   `json:"onLoad,omitempty"`
+	Comment     *string    `json:"comment,omitempty"`
+	Extensions  Extensions `json:"-"`
 }
 
 type Page struct {
-	StartedDateTime string       `json:"startedDateTime"`
-	Id              string       `json:"id"`
-	Title           string       `json:"title"`
-	PageTimings     []PageTiming `json:"pageTimings"`
-	Comment         *string      `json:"comment"`
+	StartedDateTime string     `json:"startedDateTime"`
+	Id              string     `json:"id"`
+	Title           string     `json:"title"`
+	PageTimings     PageTiming `json:"pageTimings"`
+	Comment         *string    `json:"comment,omitempty"`
+	Extensions      Extensions `json:"-"`
 }
 
 type Cookie struct {
-	Name     string  `json:"name"`
-	Value    string  `json:"value"`
-	Path     *string `json:"path"`
-	Domain   *string `json:"domain"`
-	Expires  *string `json:"expires"`
-	HttpOnly *bool   `json:"httpOnly"`
-	Secure   *bool   `json:"secure"`
-	Comment  *string `json:"comment"`
+	Name       string     `json:"name"`
+	Value      string     `json:"value"`
+	Path       *string    `json:"path,omitempty"`
+	Domain     *string    `json:"domain,omitempty"`
+	Expires    *string    `json:"expires,omitempty"`
+	HttpOnly   *bool      `json:"httpOnly,omitempty"`
+	Secure     *bool      `json:"secure,omitempty"`
+	Comment    *string    `json:"comment,omitempty"`
+	Extensions Extensions `json:"-"`
 }
 
 type Header struct {
-	Name    string  `json:"name"`
-	Value   string  `json:"value"`
-	Comment *string `json:"comment"`
+	Name       string     `json:"name"`
+	Value      string     `json:"value"`
+	Comment    *string    `json:"comment,omitempty"`
+	Extensions Extensions `json:"-"`
 }
 
 type QueryParameter struct {
-	Name    string  `json:"name"`
-	Value   string  `json:"value"`
-	Comment *string `json:"comment"`
+	Name       string     `json:"name"`
+	Value      string     `json:"value"`
+	Comment    *string    `json:"comment,omitempty"`
+	Extensions Extensions `json:"-"`
 }
 
 type PostParameters struct {
-	Name        string  `json:"name"`
-	Value       *string `json:"value"`
-	FileName    *string `json:"fileName"`
-	ContentType *string `json:"contentType"`
-	Comment     *string `json:"comment"`
+	Name        string     `json:"name"`
+	Value       *string    `json:"value,omitempty"`
+	FileName    *string    `json:"fileName,omitempty"`
+	ContentType *string    `json:"contentType,omitempty"`
+	Comment     *string    `json:"comment,omitempty"`
+	Extensions  Extensions `json:"-"`
 }
 
 type PostData struct {
-	MimeType string           `json:"mimeType"`
-	Params   []PostParameters `json:"params"`
-	Text     string           `json:"text"`
-	Comment  *string          `json:"comment"`
+	MimeType   string           `json:"mimeType"`
+	Params     []PostParameters `json:"params,omitempty"`
+	Text       string           `json:"text"`
+	Comment    *string          `json:"comment,omitempty"`
+	Extensions Extensions       `json:"-"`
 }
 
 type Content struct {
-	Size        int     `json:"size"`
-	Compression *int    `json:"compression"`
-	MimeType    string  `json:"mimeType"`
-	Text        *string `json:"text"`
-	Encoding    *string `json:"encoding"`
-	Comment     *string `json:"comment"`
+	Size        int        `json:"size"`
+	Compression *int       `json:"compression,omitempty"`
+	MimeType    string     `json:"mimeType"`
+	Text        *string    `json:"text,omitempty"`
+	Encoding    *string    `json:"encoding,omitempty"`
+	Comment     *string    `json:"comment,omitempty"`
+	Extensions  Extensions `json:"-"`
 }
 
 type Response struct {
-	Status      int      `json:"status"`
-	StatusText  string   `json:"statusText"`
-	HttpVersion string   `json:"httpVersion"`
-	Cookies     []Cookie `json:"cookies"`
-	Headers     []Header `json:"headers"`
-	Content     *Content `json:"content"`
-	RedirectUrl *string  `json:"redirectURL"`
-	HeadersSize int      `json:"headersSize"`
-	BodySize    int      `json:"bodySize"`
-	Comment     *string  `json:"comment"`
+	Status      int        `json:"status"`
+	StatusText  string     `json:"statusText"`
+	HttpVersion string     `json:"httpVersion"`
+	Cookies     []Cookie   `json:"cookies"`
+	Headers     []Header   `json:"headers"`
+	Content     *Content   `json:"content,omitempty"`
+	RedirectUrl *string    `json:"redirectURL,omitempty"`
+	HeadersSize int        `json:"headersSize"`
+	BodySize    int        `json:"bodySize"`
+	Comment     *string    `json:"comment,omitempty"`
+	Extensions  Extensions `json:"-"`
+
+	TransferSize *int `json:"_transferSize,omitempty"`
 }
 
 type Request struct {
@@ -105,61 +126,141 @@ type Request struct {
 	Cookies     []Cookie         `json:"cookies"`
 	Headers     []Header         `json:"headers"`
 	QueryString []QueryParameter `json:"queryString"`
-	PostData    *PostData        `json:"postData"`
-	HeaderSize  int              `json:"headerSize"`
+	PostData    *PostData        `json:"postData,omitempty"`
+	HeadersSize int              `json:"headersSize"`
 	BodySize    int              `json:"bodySize"`
-	Comment     *string          `json:"comment"`
+	Comment     *string          `json:"comment,omitempty"`
+	Extensions  Extensions       `json:"-"`
 }
 
 type CacheState struct {
-	Expires    *string `json:"expires"`
-	LastAccess string  `json:"lastAccess"`
-	ETag       string  `json:"eTag"`
-	HitCount   int     `json:"hitCount"`
-	Comment    *string `json:"comment"`
+	Expires    *string    `json:"expires,omitempty"`
+	LastAccess string     `json:"lastAccess"`
+	ETag       string     `json:"eTag"`
+	HitCount   int        `json:"hitCount"`
+	Comment    *string    `json:"comment,omitempty"`
+	Extensions Extensions `json:"-"`
 }
 
 type Cache struct {
-	BeforeRequest *CacheState `json:"beforeRequest"`
-	AfterRequest  *CacheState `json:"afterRequest"`
-	Comment       *string     `json:"comment"`
+	BeforeRequest *CacheState `json:"beforeRequest,omitempty"`
+	AfterRequest  *CacheState `json:"afterRequest,omitempty"`
+	Comment       *string     `json:"comment,omitempty"`
+	Extensions    Extensions  `json:"-"`
 }
 
 type EntryTimings struct {
-	Blocked *int    `json:"blocked"`
-	Dns     *int    `json:"dns"`
-	Connect *int    `json:"connect"`
-	Send    int     `json:"send"`
-	Wait    int     `json:"wait"`
-	Receive int     `json:"receive"`
-	Ssl     *int    `json:"ssl"`
-	Comment *string `json:"comment"`
+	Blocked *float64 `json:"blocked,omitempty"`
+	Dns     *float64 `json:"dns,omitempty"`
+	Connect *float64 `json:"connect,omitempty"`
+	Send    float64  `json:"send"`
+	Wait    float64  `json:"wait"`
+	Receive float64  `json:"receive"`
+	Ssl     *float64 `json:"ssl,omitempty"`
+	Comment *string  `json:"comment,omitempty"`
+
+	BlockedQueueing *float64   `json:"_blocked_queueing,omitempty"`
+	Extensions      Extensions `json:"-"`
+}
+
+type CallFrame struct {
+	FunctionName string     `json:"functionName"`
+	ScriptId     string     `json:"scriptId"`
+	Url          string     `json:"url"`
+	LineNumber   int        `json:"lineNumber"`
+	ColumnNumber int        `json:"columnNumber"`
+	Extensions   Extensions `json:"-"`
+}
+
+type StackTrace struct {
+	Description *string     `json:"description,omitempty"`
+	CallFrames  []CallFrame `json:"callFrames"`
+	Parent      *StackTrace `json:"parent,omitempty"`
+	Extensions  Extensions  `json:"-"`
+}
+
+type Initiator struct {
+	Type         string      `json:"type"`
+	Url          *string     `json:"url,omitempty"`
+	LineNumber   *int        `json:"lineNumber,omitempty"`
+	ColumnNumber *int        `json:"columnNumber,omitempty"`
+	Stack        *StackTrace `json:"stack,omitempty"`
+	Extensions   Extensions  `json:"-"`
+}
+
+type SignedCertificateTimestamp struct {
+	Status             string     `json:"status"`
+	Origin             string     `json:"origin"`
+	LogDescription     string     `json:"logDescription"`
+	LogId              string     `json:"logId"`
+	Timestamp          float64    `json:"timestamp"`
+	HashAlgorithm      string     `json:"hashAlgorithm"`
+	SignatureAlgorithm string     `json:"signatureAlgorithm"`
+	SignatureData      string     `json:"signatureData"`
+	Extensions         Extensions `json:"-"`
+}
+
+type SecurityDetails struct {
+	Protocol                          string                       `json:"protocol"`
+	KeyExchange                       string                       `json:"keyExchange"`
+	KeyExchangeGroup                  *string                      `json:"keyExchangeGroup,omitempty"`
+	Cipher                            string                       `json:"cipher"`
+	Mac                               *string                      `json:"mac,omitempty"`
+	CertificateId                     int                          `json:"certificateId"`
+	SubjectName                       string                       `json:"subjectName"`
+	SanList                           []string                     `json:"sanList"`
+	Issuer                            string                       `json:"issuer"`
+	ValidFrom                         float64                      `json:"validFrom"`
+	ValidTo                           float64                      `json:"validTo"`
+	SignedCertificateTimestampList    []SignedCertificateTimestamp `json:"signedCertificateTimestampList"`
+	CertificateTransparencyCompliance *string                      `json:"certificateTransparencyCompliance,omitempty"`
+	Extensions                        Extensions                   `json:"-"`
 }
 
 type Entry struct {
-	PageRef         *string      `json:"pageref"`
+	PageRef         *string      `json:"pageref,omitempty"`
 	StartedDateTime string       `json:"startedDateTime"`
-	TimeMs          int          `json:"time"`
+	TimeMs          float64      `json:"time"`
 	Request         Request      `json:"request"`
 	Response        Response     `json:"response"`
 	Cache           Cache        `json:"cache"`
 	Timings         EntryTimings `json:"timings"`
-	ServerIP        *string      `json:"serverIPAddress"`
-	Connection      *string      `json:"connection"`
-	Comment         *string      `json:"comment"`
+	ServerIP        *string      `json:"serverIPAddress,omitempty"`
+	Connection      *string      `json:"connection,omitempty"`
+	Comment         *string      `json:"comment,omitempty"`
+	Initiator       *Initiator   `json:"_initiator,omitempty"`
+	Priority        *string      `json:"_priority,omitempty"`
+	FromCache       *string      `json:"_fromCache,omitempty"`
+	ResourceType    *string      `json:"_resourceType,omitempty"`
+
+	SecurityDetails *SecurityDetails `json:"_securityDetails,omitempty"`
+	SecurityState   *string          `json:"_securityState,omitempty"`
+	Extensions      Extensions       `json:"-"`
 }
 
 type Log struct {
-	Version string   `json:"version"`
-	Creator Creator  `json:"creator"`
-	Browser *Browser `json:"browser"`
-	Pages   *[]Page  `json:"pages"`
-	Entries []Entry  `json:"entries"`
-	Comment *string  `json:"comment"`
+	Version    string     `json:"version"`
+	Creator    Creator    `json:"creator"`
+	Browser    *Browser   `json:"browser,omitempty"`
+	Pages      *[]Page    `json:"pages,omitempty"`
+	Entries    []Entry    `json:"entries"`
+	Comment    *string    `json:"comment,omitempty"`
+	Extensions Extensions `json:"-"`
+}
+
+// Unknown is used by the spec for sizes and timings which don't apply or couldn't be measured
+const Unknown = -1
+
+func OrUnknown(v *float64) float64 {
+	if v == nil {
+		return Unknown
+	}
+	return *v
 }
 
 type HarFile struct {
-	Log Log `json:"log"`
+	Log        Log        `json:"log"`
+	Extensions Extensions `json:"-"`
 }
 
 // Things to filter on
@@ -173,8 +274,8 @@ type HarFile struct {
 //   Include body
 //   Include timings
 
-// A       E F G   I J K L M N O   Q R S T     W X Y Z
-// a       e   g h   j k l   n o   q r   t     w x y z
+// A       E F G     J K L M N O     R S     V W X Y Z
+// a       e         j k l         q r         w x y z
 
 var CLI struct {
 	RequestDomain         *string   `short:"D" name:"request-domain" help:"Find results where the domain equals this value"`
@@ -188,18 +289,641 @@ var CLI struct {
 	ResponseInformational *bool     `short:"i" name:"response-informational" help:"Find requests where the response was successful"`
 	ResponseSuccessful    *bool     `short:"s" name:"response-success" help:"Find requests where the response was successful"`
 	ResponseFailed        *bool     `short:"f" name:"response-fail" help:"Find requests where the responses was unsuccessful"`
+	FromCache             *bool     `name:"from-cache" help:"Find requests which were served from the browser cache (Chrome _fromCache field)"`
+	NotFromCache          *bool     `name:"not-from-cache" help:"Find requests which were not served from the browser cache (Chrome _fromCache field)"`
+	HttpVersion           *[]string `name:"http-version" help:"Find requests which used one of these HTTP versions, eg h2,http/1.1, however the HAR spells them"`
+	RequestHttpVersion    *[]string `name:"request-http-version" help:"Find requests which were sent with one of these HTTP versions, or versions starting with them, eg h2 or http/1"`
+	ResponseHttpVersion   *[]string `name:"response-http-version" help:"Find requests whose response came back with one of these HTTP versions, or versions starting with them, eg h3 or http/1"`
+	TlsVersion            *[]string `name:"tls-version" help:"Find requests which negotiated one of these TLS versions, eg 1.2 or \"TLS 1.3\" (Chrome _securityDetails field)"`
+	NewConnections        *bool     `name:"new-connections-only" help:"Find requests which opened a new connection, having spent time connecting or on the TLS handshake"`
+	ReusedConnections     *bool     `name:"reused-connections-only" help:"Find requests which reused an open connection, leaving out those served from the browser cache"`
+	MinSslMs              *float64  `name:"min-ssl-ms" help:"Find requests whose TLS handshake took longer than this many milliseconds"`
+	StartOffset           *Offset   `name:"start-offset" help:"Find requests which started at least this long after the first request in the file, eg 30s"`
+	EndOffset             *Offset   `name:"end-offset" help:"Find requests which started at most this long after the first request in the file, eg 45s or 1m30s"`
+	Page                  *string   `name:"page" help:"Find requests which belong to the page with this ID, title or index in the list of pages. Part of a title is enough if only one page matches"`
+	HasPage               *bool     `name:"has-page" help:"Find requests which belong to a page (they have a pageref)"`
+	NoPage                *bool     `name:"no-page" help:"Find requests which don't belong to any page, such as background and service worker requests"`
+	Tag                   *[]string `name:"tag" help:"Find requests which were given any of these tags by harv tag"`
+	ServerIp              *net.IP   `name:"server-ip" help:"Find requests which were sent to the server with this IP address"`
+	ServerCidr            *Network  `name:"server-cidr" help:"Find requests which were sent to a server in this range of IP addresses, eg 10.0.0.0/8"`
+	RedirectsOnly         *bool     `name:"redirects-only" help:"Find redirects, which are 3xx responses with a redirectURL"`
+	RedirectTarget        *string   `name:"redirect-target-includes" help:"Find requests which redirected to a URL containing this value"`
+	GraphqlOperation      *[]string `name:"graphql-operation" help:"Find GraphQL requests which ran one of these operations, by name or as persisted:ID for persisted queries"`
+	SoapAction            *string   `name:"soap-action" help:"Find SOAP requests for this operation, or with this SOAPAction, as all requests to a service share its URL"`
+	WithChain             *bool     `name:"with-chain" help:"Also include the redirects which led to or followed from each matching request, and the request which initiated it"`
+	RedirectTo            *Pattern  `name:"redirect-to" help:"Find requests which redirected to a URL matching this regular expression"`
+	LocationIncludes      *string   `name:"location-includes" help:"Find requests where the Location response header contains this value"`
+	MinRatio              *float64  `name:"min-ratio" help:"Find responses whose decoded size is at least this many times their transferred size"`
+	MaxRatio              *float64  `name:"max-ratio" help:"Find responses whose decoded size is at most this many times their transferred size, eg 1.1 to find poorly compressed responses"`
+	Grep                  *string   `short:"g" name:"grep" help:"Find requests where this text appears anywhere in the URL, query, headers, cookies or bodies, and show where it matched"`
 	IncludeHeaders        *bool     `short:"H" name:"print-headers" help:"If specified, the request and response headers (excluding Cookie headers, use -C for that) will be included in the output"`
 	IncludeCookies        *bool     `short:"C" name:"print-cookies" help:"If specified, the request and response cookies will be included in the output"`
 	IncludeRequestBody    *bool     `short:"u" name:"print-request-body" help:"If specified, include the body of the request, including JSON highlighting"`
 	IncludeResponseBody   *bool     `short:"U" name:"print-response-body" help:"If specified, include the body of the response, including JSON highlighting"`
 	IncludeTimings        *bool     `short:"t" name:"print-timings" help:"If specified, include the request timings"`
-	File                  string    `arg:"" help:"The HAR file to parse" type:"existingfile"`
+	IncludeInitiator      *bool     `short:"I" name:"print-initiator" help:"If specified, include what triggered the request (Chrome _initiator field) including the call stack"`
+	IncludeTls            *bool     `short:"T" name:"print-tls" help:"If specified, include the TLS connection and certificate details (Chrome _securityDetails field)"`
+	IncludeQuery          *bool     `short:"Q" name:"print-query" help:"If specified, include the decoded query string parameters of the request"`
+	IncludeConnection     *bool     `short:"n" name:"print-connection" help:"If specified, include the server IP, connection ID, negotiated HTTP version and cache state"`
+	GeoIp                 *[]string `name:"geoip" help:"Show the country, city and ASN of each server IP using these MaxMind DB files, eg GeoLite2-City.mmdb,GeoLite2-ASN.mmdb"`
+	ImagePreview          *bool     `name:"image-preview" help:"Draw a small preview of image bodies with coloured half blocks under their format and size, needs a 24-bit colour terminal"`
+	ProtoDescriptors      *string   `name:"proto-descriptors" help:"A FileDescriptorSet written by protoc --descriptor_set_out, used to name the fields of gRPC-web messages in the bodies"`
+	Providers             *string   `name:"providers" help:"A CSV of cidr,provider lines used by --group-by provider before the built in CDN and cloud ranges"`
+	ResolveIps            *bool     `name:"resolve-ips" help:"Look up the reverse DNS name of each server IP and show it next to the address"`
+	ResolveTimeoutMs      int       `name:"resolve-timeout-ms" default:"2000" help:"How long to wait for each --resolve-ips lookup in milliseconds"`
+	PrintProtocol         *bool     `name:"print-protocol" help:"Show the negotiated HTTP and TLS version of each entry in place of the requested HTTP version, including in --oneline"`
+	RawNumbers            *bool     `name:"raw-numbers" help:"Print sizes in bytes and durations in milliseconds without rounding them or adding units"`
+	PrintTime             *string   `name:"print-time" enum:"relative,absolute,both" help:"Include when the request started, either relative to the first request in the file, as a local time, or both"`
+	Lenient               *bool     `name:"lenient" help:"Skip or partially load malformed entries instead of failing, and report what was dropped"`
+	Width                 *int      `name:"width" help:"Lay out the output for this many columns instead of the width of the terminal"`
+	TruncateUrl           *bool     `name:"truncate-url" help:"Shorten long URLs in the middle to fit the width, this is the default when writing to a terminal"`
+	FullUrl               *bool     `name:"full-url" help:"Never shorten URLs, even when writing to a terminal"`
+	Out                   *string   `short:"o" name:"out" help:"Write the output to this file instead of stdout, without colour unless --color=always is given"`
+	Append                *bool     `name:"append" help:"Add to the end of the --out file instead of replacing it"`
+	Color                 *string   `name:"color" enum:"auto,always,never" help:"Whether to colour the output, auto colours it when writing to a terminal"`
+	Verbose               int       `short:"v" type:"counter" help:"Print what harv is doing to stderr, -vv to include debug output"`
+	LogLevel              *string   `name:"log-level" enum:"debug,info,warn,error" help:"Only print diagnostics at or above this level to stderr, overrides -v"`
+	Profile               *string   `name:"profile" help:"Write a CPU profile to this path for use with go tool pprof"`
+	Timing                *bool     `name:"timing" help:"Print how long was spent parsing, filtering and formatting to stderr, formatting time is summed across the --jobs workers"`
+
+	View        ViewCmd        `cmd:"" default:"withargs" help:"Print the entries of the HAR file (default)"`
+	Audit       AuditCmd       `cmd:"" help:"Check the entries of the HAR file for common problems"`
+	Validate    ValidateCmd    `cmd:"" help:"Check the HAR file conforms to the HAR 1.2 specification"`
+	Scan        ScanCmd        `cmd:"" help:"Check the entries of the HAR file for sensitive data before sharing it"`
+	Anonymize   AnonymizeCmd   `cmd:"" help:"Write a copy of the HAR file with hostnames, IPs, emails, IDs and tokens replaced by consistent pseudonyms"`
+	Flow        FlowCmd        `cmd:"" help:"Reconstruct authentication flows from the entries of the HAR file"`
+	Trace       TraceCmd       `cmd:"" help:"Show every entry where a token, cookie or header value appears, in order"`
+	Export      ExportCmd      `cmd:"" help:"Convert the entries of the HAR file into the formats used by other tools"`
+	Import      ImportCmd      `cmd:"" help:"Convert captures made by other tools into a HAR file"`
+	Capture     CaptureCmd     `cmd:"" help:"Pull the messages recorded by a running OWASP ZAP instance into a HAR file"`
+	Diff        DiffCmd        `cmd:"" help:"Compare the endpoints called in two HAR files, or the calls to one --endpoint"`
+	Compare     CompareCmd     `cmd:"" help:"Compare the p50 and p95 durations and sizes of each endpoint in two HAR files"`
+	Report      ReportCmd      `cmd:"" help:"Write a static HTML page with a summary and a waterfall of the entries, small enough to attach to a ticket"`
+	Tags        TagCmd         `cmd:"" name:"tag" help:"Tag entries of the HAR file to find them again with --tag, the tags are saved next to the file"`
+	Annotate    AnnotateCmd    `cmd:"" help:"Write a comment into entries of the HAR file, keeping everything else in it"`
+	Sessions    SessionsCmd    `cmd:"" help:"Group the entries by the session cookie they carried, with the requests, endpoints and time range of each session"`
+	BodyDiff    BodyDiffCmd    `cmd:"" name:"body-diff" help:"Compare the bodies of two entries, structurally if they are JSON and line by line otherwise"`
+	InferSchema InferSchemaCmd `cmd:"" name:"infer-schema" help:"Infer a JSON Schema for each endpoint from the JSON bodies it returned"`
+	Replay      ReplayCmd      `cmd:"" help:"Send the entries again, optionally at a fixed rate from several workers, and report the latencies and errors"`
+}
+
+type ViewCmd struct {
+	OutputHar         *string `name:"output-har" help:"Instead of printing the matching entries, write them to this path as a new HAR file"`
+	GroupBy           *string `name:"group-by" enum:"page,domain,status,mime,method,endpoint,protocol,country,asn,provider,graphql-operation" help:"Print the entries under a heading for each page, or instead of listing them print the count, bytes and p50/p95 duration of each domain, status, mime, method, endpoint, GraphQL operation, hosting provider, or server country or ASN with --geoip, or how many requests to each origin used each HTTP version"`
+	NoSummary         *bool   `name:"no-summary" help:"Don't print the summary of the matching entries after the listing"`
+	Oneline           *bool   `name:"oneline" help:"Print exactly one line for each entry with when it started, the method, status, duration, size and URL"`
+	List              *string `name:"list" enum:"pages" help:"Instead of listing the entries, list the pages with their IDs, titles, requests and load timings, to find the one to give --page"`
+	SetCookieTimeline *bool   `name:"print-set-cookie-timeline" help:"Instead of listing the entries, list every Set-Cookie in order with its attributes, marking when cookies were overwritten, deleted or expired"`
+	Index             *bool   `name:"index" help:"Save an index of the file to file.har.idx on the first run and use it to answer later queries without parsing the whole file"`
+	Jobs              int     `name:"jobs" default:"0" help:"How many entries to format at once, 0 uses one worker per CPU"`
+	File              string  `arg:"" help:"The HAR file to parse, or - to read it from stdin" type:"existingfile"`
+}
+
+func (cmd *ViewCmd) EntryFormatter() func(entry Entry) string {
+	if cmd.Oneline != nil && *cmd.Oneline {
+		return FormatOneline
+	}
+	return FormatEntry
+}
+
+// Streamable is true when the entries can be printed as they are read, the page filter, grouping, listing the pages,
+// the cookie timeline, following chains and writing a new HAR all need the whole file first
+func (cmd *ViewCmd) Streamable() bool {
+	return cmd.OutputHar == nil && cmd.GroupBy == nil && cmd.List == nil && cmd.SetCookieTimeline == nil && CLI.Page == nil && CLI.WithChain == nil && (CLI.Lenient == nil || !*CLI.Lenient)
+}
+
+func (cmd *ViewCmd) Run() error {
+	output, err := OpenOutput()
+	if err != nil {
+		return err
+	}
+	defer output.Close()
+	out := bufio.NewWriter(output)
+	defer out.Flush()
+
+	if cmd.Index != nil && *cmd.Index && cmd.File != "-" && cmd.OutputHar == nil && IndexCanFilter() && (cmd.GroupBy == nil || *cmd.GroupBy != "graphql-operation") {
+		slog.Debug("Answering the query from the index", "file", cmd.File)
+		return cmd.RunIndexed(out)
+	}
+	if cmd.Streamable() {
+		slog.Debug("Streaming the entries", "file", cmd.File, "jobs", cmd.Jobs)
+		return cmd.Stream(out)
+	}
+	slog.Debug("Reading the whole file before printing", "file", cmd.File)
+
+	stopParse := Timer.Time("parse")
+	har, err := ReadHar(cmd.File, cmd.OutputHar != nil || cmd.GroupBy != nil && *cmd.GroupBy == "graphql-operation")
+	stopParse()
+	if err != nil {
+		return err
+	}
+
+	stopFilter := Timer.Time("filter")
+	validEntries, err := SelectEntries(har.Log)
+	stopFilter()
+	if err != nil {
+		return err
+	}
+	if cmd.OutputHar != nil {
+		har.Log.Entries = validEntries
+		return WriteHarFile(*cmd.OutputHar, har)
+	}
+	return cmd.Print(out, har.Log, validEntries, len(har.Log.Entries))
+}
+
+// RunIndexed answers the query from the sidecar index, only decoding the matching entries from the file and not even
+// those when they are being aggregated
+func (cmd *ViewCmd) RunIndexed(out *bufio.Writer) error {
+	stopParse := Timer.Time("parse")
+	index, err := LoadIndex(cmd.File)
+	stopParse()
+	if err != nil {
+		return err
+	}
+	stopFilter := Timer.Time("filter")
+	selected, err := SelectIndexed(index)
+	stopFilter()
+	if err != nil {
+		return err
+	}
+
+	entries := make([]Entry, 0, len(selected))
+	if cmd.List != nil || cmd.GroupBy != nil && *cmd.GroupBy != "page" {
+		for _, indexed := range selected {
+			entries = append(entries, indexed.Skeleton())
+		}
+	} else {
+		file, err := os.Open(cmd.File)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		defer Timer.Time("parse")()
+		for _, indexed := range selected {
+			entry, err := ReadIndexedEntry(file, indexed)
+			if err != nil {
+				return err
+			}
+			entries = append(entries, entry)
+		}
+	}
+	return cmd.Print(out, Log{Pages: index.Pages}, entries, len(index.Entries))
+}
+
+func (cmd *ViewCmd) Print(out *bufio.Writer, log Log, entries []Entry, total int) error {
+	if cmd.List != nil {
+		fmt.Fprintln(out, FormatPageList(log, entries))
+		return nil
+	} else if cmd.SetCookieTimeline != nil && *cmd.SetCookieTimeline {
+		stopFormat := Timer.Time("format")
+		output := FormatSetCookieTimeline(entries)
+		stopFormat()
+		fmt.Fprintln(out, output)
+		return nil
+	} else if cmd.GroupBy != nil && *cmd.GroupBy == "page" {
+		stopFormat := Timer.Time("format")
+		output := FormatEntriesByPage(log, entries, cmd.EntryFormatter())
+		stopFormat()
+		fmt.Fprintln(out, output)
+	} else if cmd.GroupBy != nil && *cmd.GroupBy == "protocol" {
+		stopFormat := Timer.Time("format")
+		output := FormatProtocolStats(entries)
+		stopFormat()
+		fmt.Fprintln(out, output)
+	} else if cmd.GroupBy != nil {
+		stopFormat := Timer.Time("format")
+		groups := AggregateEntries(entries, func(entry Entry) string {
+			return GroupKey(entry, *cmd.GroupBy)
+		})
+		output := FormatAggregates(groups, *cmd.GroupBy)
+		stopFormat()
+		fmt.Fprintln(out, output)
+	} else {
+		formatter := NewOrderedFormatter(cmd.Jobs, Timed("format", cmd.EntryFormatter()), func(output string) error {
+			_, err := fmt.Fprintln(out, output)
+			return err
+		})
+		for _, entry := range entries {
+			formatter.Add(entry)
+		}
+		if err := formatter.Close(); err != nil {
+			return err
+		}
+	}
+
+	if cmd.NoSummary == nil || !*cmd.NoSummary {
+		summary := Summary{Total: total}
+		for _, entry := range entries {
+			summary.Add(entry)
+		}
+		fmt.Fprintln(out, "\n"+FormatSummary(summary))
+	}
+	return nil
+}
+
+// Stream filters and prints each entry as soon as it has been decoded. When reading from stdin the output is flushed
+// after every entry so a HAR which is still being written can be followed
+func (cmd *ViewCmd) Stream(out *bufio.Writer) error {
+	input, err := OpenInput(cmd.File)
+	if err != nil {
+		return err
+	}
+	defer input.Close()
+
+	formatter := NewOrderedFormatter(cmd.Jobs, Timed("format", cmd.EntryFormatter()), func(output string) error {
+		fmt.Fprintln(out, output)
+		if cmd.File == "-" {
+			return out.Flush()
+		}
+		return nil
+	})
+
+	// Everything outside of the handler is time spent reading and decoding
+	var summary Summary
+	var handling time.Duration
+	started := time.Now()
+	_, total, err := HarWalker{}.Walk(input, func(entry Entry, _ int64, _ int) error {
+		handlerStarted := time.Now()
+		defer func() {
+			handling += time.Since(handlerStarted)
+		}()
+
+		// Entries are meant to be sorted so the first one is taken as the start of the capture. It is never changed
+		// afterwards as the workers may already be reading it
+		if started, ok := ParseStartedDateTime(entry.StartedDateTime); ok && CaptureStart.IsZero() {
+			CaptureStart = started
+		}
+		stopFilter := Timer.Time("filter")
+		valid := IsEntryValid(entry)
+		stopFilter()
+		if !valid {
+			return nil
+		}
+		summary.Add(entry)
+		formatter.Add(entry)
+		return nil
+	})
+	Timer.Add("parse", time.Since(started)-handling)
+	slog.Info("Finished reading", "file", cmd.File, "entries", total, "matched", summary.Matched)
+	if err := formatter.Close(); err != nil {
+		return err
+	}
+	if err != nil {
+		return fmt.Errorf("failed to parse %s, %s (use --lenient to load the valid entries anyway)", cmd.File, err)
+	}
+
+	if cmd.NoSummary == nil || !*cmd.NoSummary {
+		summary.Total = total
+		fmt.Fprintln(out, "\n"+FormatSummary(summary))
+	}
+	return nil
+}
+
+// TransferSize is the number of bytes received for the response, preferring Chrome's own measurement and falling back
+// to the decoded size when the body size wasn't recorded
+func TransferSize(entry Entry) int {
+	if entry.Response.TransferSize != nil && *entry.Response.TransferSize >= 0 {
+		return *entry.Response.TransferSize
+	}
+	if entry.Response.BodySize >= 0 {
+		return entry.Response.BodySize + max(entry.Response.HeadersSize, 0)
+	}
+	if entry.Response.Content != nil && entry.Response.Content.Size > 0 {
+		return entry.Response.Content.Size
+	}
+	return 0
+}
+
+// CompressionRatio compares the size of the response body on the wire with its decoded size
+func CompressionRatio(entry Entry) (int, int, float64, bool) {
+	if entry.Response.Content == nil || entry.Response.Content.Size <= 0 {
+		return 0, 0, 0, false
+	}
+
+	transferred := entry.Response.BodySize
+	if transferred < 0 && entry.Response.TransferSize != nil && entry.Response.HeadersSize >= 0 {
+		transferred = *entry.Response.TransferSize - entry.Response.HeadersSize
+	}
+	if transferred <= 0 {
+		return 0, 0, 0, false
+	}
+
+	decoded := entry.Response.Content.Size
+	return transferred, decoded, float64(decoded) / float64(transferred), true
+}
+
+// HasRequestBody uses bodySize where it is known, a size of -1 means unknown (which Chrome uses a lot) so fall back
+// to whatever made it into postData
+func HasRequestBody(request Request) bool {
+	if request.BodySize >= 0 {
+		return request.BodySize > 0
+	}
+	return request.PostData != nil && (request.PostData.Text != "" || len(request.PostData.Params) > 0)
+}
+
+// HasResponseBody uses bodySize where it is known and otherwise falls back to the content size and text
+func HasResponseBody(response Response) bool {
+	if response.BodySize >= 0 {
+		return response.BodySize > 0
+	}
+	if response.Content == nil {
+		return false
+	}
+	return response.Content.Size > 0 || (response.Content.Text != nil && *response.Content.Text != "")
+}
+
+func IsErrorResponse(response Response) bool {
+	return response.Status == 0 || response.Status >= 400
+}
+
+// Summary keeps running totals of the matching entries so the footer doesn't need them all held in memory
+type Summary struct {
+	Matched     int
+	Total       int
+	Transferred int
+	Failed      int
+	First       time.Time
+	Last        time.Time
+}
+
+func (summary *Summary) Add(entry Entry) {
+	summary.Matched++
+	summary.Transferred += TransferSize(entry)
+	if IsErrorResponse(entry.Response) {
+		summary.Failed++
+	}
+	if started, ok := ParseStartedDateTime(entry.StartedDateTime); ok {
+		finished := started.Add(time.Duration(entry.TimeMs * float64(time.Millisecond)))
+		if summary.First.IsZero() || started.Before(summary.First) {
+			summary.First = started
+		}
+		if finished.After(summary.Last) {
+			summary.Last = finished
+		}
+	}
+}
+
+func FormatSummary(summary Summary) string {
+	output := color.HiBlackString("Found ") + color.YellowString(strconv.Itoa(summary.Matched)) +
+		color.HiBlackString(" of ") + color.YellowString(strconv.Itoa(summary.Total)) + color.HiBlackString(" entries, ") +
+		color.YellowString(FormatBytes(summary.Transferred)) + color.HiBlackString(" transferred")
+	if !summary.First.IsZero() {
+		output += color.HiBlackString(" over ") + color.YellowString(FormatDuration(float64(summary.Last.Sub(summary.First))/float64(time.Millisecond)))
+	}
+	output += color.HiBlackString(", ") + Tertiary(summary.Failed > 0, color.RedString, color.YellowString)(strconv.Itoa(summary.Failed)) +
+		color.HiBlackString(Tertiary(summary.Failed == 1, " error", " errors"))
+	return output
+}
+
+func ReadHarFile(file string) (HarFile, error) {
+	return ReadHar(file, true)
+}
+
+// ReadHar loads the whole file, decoding it straight from the file one entry at a time rather than reading it into
+// memory first. Without keepBodies the body text of each entry is dropped as soon as it is decoded, so queries which
+// only look at URLs, statuses and sizes don't hold every body in memory at once
+func ReadHar(file string, keepBodies bool) (HarFile, error) {
+	// The filters which look at bodies need them whatever the command does with the entries afterwards
+	keepBodies = keepBodies || BodiesNeeded()
+
+	if CLI.Lenient != nil && *CLI.Lenient {
+		// The report gives line numbers, which needs the content to count them in
+		content, err := ReadInput(file)
+		if err != nil {
+			return HarFile{}, err
+		}
+		har, report := ReadHarLeniently(content)
+		if len(report.Problems) > 0 {
+			fmt.Fprintln(os.Stderr, FormatParseReport(report))
+		}
+		if !keepBodies {
+			for i := range har.Log.Entries {
+				StripBodies(&har.Log.Entries[i])
+			}
+		}
+		return har, nil
+	}
+
+	input, err := OpenInput(file)
+	if err != nil {
+		return HarFile{}, err
+	}
+	defer input.Close()
+
+	slog.Debug("Decoding the file", "file", file, "keepBodies", keepBodies)
+	entries := make([]Entry, 0)
+	har, _, err := HarWalker{}.Walk(input, func(entry Entry, _ int64, _ int) error {
+		if !keepBodies {
+			StripBodies(&entry)
+		}
+		entries = append(entries, entry)
+		return nil
+	})
+	if err != nil {
+		reason := err.Error()
+		// Reading the file again to find the line and column is only worth it once it has failed
+		if file != "-" {
+			if content, readErr := os.ReadFile(file); readErr == nil {
+				if fullErr := UnmarshalWithExtensions(content, &HarFile{}); fullErr != nil {
+					reason = DescribeJsonError(content, fullErr)
+				}
+			}
+		}
+		return HarFile{}, fmt.Errorf("failed to parse %s, %s (use --lenient to load the valid entries anyway)", file, reason)
+	}
+	har.Log.Entries = entries
+	return har, nil
+}
+
+// BodiesNeeded is whether any of the flags look at the request or response body text
+func BodiesNeeded() bool {
+	return (CLI.IncludeRequestBody != nil && *CLI.IncludeRequestBody) ||
+		(CLI.IncludeResponseBody != nil && *CLI.IncludeResponseBody) ||
+		CLI.RequestHasBody != nil || CLI.ResponseHasBody != nil || CLI.Grep != nil || CLI.GraphqlOperation != nil ||
+		CLI.SoapAction != nil
+}
+
+func StripBodies(entry *Entry) {
+	if entry.Request.PostData != nil {
+		entry.Request.PostData.Text = ""
+	}
+	if entry.Response.Content != nil {
+		entry.Response.Content.Text = nil
+	}
+}
+
+// MarshalHar indents a HAR the way browsers export them, without escaping the HTML characters json.Marshal would
+func MarshalHar(har HarFile) ([]byte, error) {
+	var buffer bytes.Buffer
+	encoder := json.NewEncoder(&buffer)
+	encoder.SetEscapeHTML(false)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(har); err != nil {
+		return nil, err
+	}
+	return bytes.TrimRight(buffer.Bytes(), "\n"), nil
+}
+
+func WriteHarFile(file string, har HarFile) error {
+	content, err := MarshalHar(har)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(file, append(content, '\n'), 0644)
+}
+
+// CaptureStart is when the earliest request in the file started, relative times are measured from here
+var CaptureStart time.Time
+
+// SelectEntries applies the filters which need to know about the log as a whole before the per entry filters
+func SelectEntries(log Log) ([]Entry, error) {
+	indexes, err := SelectEntryIndexes(log)
+	if err != nil {
+		return nil, err
+	}
+	entries := make([]Entry, len(indexes))
+	for i, index := range indexes {
+		entries[i] = log.Entries[index]
+	}
+	return entries, nil
+}
+
+// SelectEntryIndexes is SelectEntries for commands which report where in the file each entry was
+func SelectEntryIndexes(log Log) ([]int, error) {
+	CaptureStart = EarliestStart(log.Entries)
+
+	pageId := ""
+	if CLI.Page != nil {
+		page, err := FindPage(log, *CLI.Page)
+		if err != nil {
+			return nil, err
+		}
+		pageId = page.Id
+	}
+
+	indexes := make([]int, 0)
+	for i, entry := range log.Entries {
+		if CLI.Page != nil && (entry.PageRef == nil || *entry.PageRef != pageId) {
+			continue
+		}
+		if IsEntryValid(entry) {
+			indexes = append(indexes, i)
+		}
+	}
+	if CLI.WithChain != nil {
+		indexes = ChainIndexes(log.Entries, indexes)
+	}
+	return indexes, nil
+}
+
+func ParseStartedDateTime(v string) (time.Time, bool) {
+	started, err := time.Parse(time.RFC3339Nano, v)
+	return started, err == nil
+}
+
+func EarliestStart(entries []Entry) time.Time {
+	var earliest time.Time
+	for _, entry := range entries {
+		started, ok := ParseStartedDateTime(entry.StartedDateTime)
+		if ok && (earliest.IsZero() || started.Before(earliest)) {
+			earliest = started
+		}
+	}
+	return earliest
+}
+
+func FindPage(log Log, reference string) (Page, error) {
+	if log.Pages == nil || len(*log.Pages) == 0 {
+		return Page{}, fmt.Errorf("--page was used but the HAR file doesn't contain any pages")
+	}
+	for _, page := range *log.Pages {
+		if page.Id == reference {
+			return page, nil
+		}
+	}
+	if index, err := strconv.Atoi(reference); err == nil && index >= 0 && index < len(*log.Pages) {
+		return (*log.Pages)[index], nil
+	}
+	// Titles are often the whole URL, so part of one is enough as long as it only matches one page
+	matches := make([]Page, 0)
+	for _, page := range *log.Pages {
+		if strings.EqualFold(page.Title, reference) {
+			return page, nil
+		}
+		if strings.Contains(strings.ToLower(page.Title), strings.ToLower(reference)) {
+			matches = append(matches, page)
+		}
+	}
+	if len(matches) == 1 {
+		return matches[0], nil
+	}
+	if len(matches) > 1 {
+		ids := make([]string, len(matches))
+		for i, page := range matches {
+			ids[i] = page.Id
+		}
+		return Page{}, fmt.Errorf("%q is in the titles of %d pages, use the ID of one of them: %s", reference, len(matches), strings.Join(ids, ", "))
+	}
+	return Page{}, fmt.Errorf("there is no page with the ID, index or title %q, use --list pages to see them", reference)
+}
+
+// Offset is how long after the first request in the file something happened, given like 30s or 1m30s
+type Offset = time.Duration
+
+// Pattern is a case-insensitive regular expression which is compiled while the flags are parsed
+type Pattern struct {
+	*regexp.Regexp
+}
+
+func (pattern *Pattern) UnmarshalText(text []byte) error {
+	compiled, err := regexp.Compile("(?i)" + string(text))
+	if err != nil {
+		return err
+	}
+	pattern.Regexp = compiled
+	return nil
+}
+
+// Network is a range of IP addresses which is parsed from CIDR notation while the flags are parsed
+type Network struct {
+	*net.IPNet
+}
+
+func (network *Network) UnmarshalText(text []byte) error {
+	_, parsed, err := net.ParseCIDR(string(text))
+	if err != nil {
+		return err
+	}
+	network.IPNet = parsed
+	return nil
+}
+
+// OpenedConnection is whether the entry spent time connecting, browsers write 0 or -1 when a connection was reused
+func OpenedConnection(entry Entry) bool {
+	return OrUnknown(entry.Timings.Connect) > 0 || OrUnknown(entry.Timings.Ssl) > 0
+}
+
+// EntryServerIp is the address the entry was sent to, which some browsers write with the brackets of an IPv6 host
+func EntryServerIp(entry Entry) net.IP {
+	if entry.ServerIP == nil {
+		return nil
+	}
+	return net.ParseIP(strings.Trim(*entry.ServerIP, "[]"))
+}
+
+func FindHeader(headers []Header, name string) *Header {
+	for i := range headers {
+		if strings.EqualFold(headers[i].Name, name) {
+			return &headers[i]
+		}
+	}
+	return nil
 }
 
 func IsEntryValid(entry Entry) bool {
 	requestUrl, err := url.Parse(entry.Request.Url)
 	if err != nil {
-		slog.Error("Failed to process url, ignoring request", "url", entry.Request.Url, "error", "err")
+		slog.Warn("Failed to process url, ignoring request", "url", entry.Request.Url, "error", err)
 		return false
 	}
 
@@ -224,25 +948,13 @@ func IsEntryValid(entry Entry) bool {
 		}
 	}
 	if CLI.RequestHasBody != nil {
-		if *CLI.RequestHasBody {
-			if entry.Request.BodySize == 0 {
-				return false
-			}
-		} else {
-			if entry.Request.BodySize > 0 {
-				return false
-			}
+		if *CLI.RequestHasBody != HasRequestBody(entry.Request) {
+			return false
 		}
 	}
 	if CLI.ResponseHasBody != nil {
-		if *CLI.ResponseHasBody {
-			if entry.Response.BodySize == 0 {
-				return false
-			}
-		} else {
-			if entry.Response.BodySize > 0 {
-				return false
-			}
+		if *CLI.ResponseHasBody != HasResponseBody(entry.Response) {
+			return false
 		}
 	}
 	if CLI.MethodIn != nil {
@@ -258,6 +970,49 @@ func IsEntryValid(entry Entry) bool {
 			return false
 		}
 	}
+	if CLI.RequestHttpVersion != nil {
+		anyMatch := false
+		for _, wanted := range *CLI.RequestHttpVersion {
+			if MatchesHttpVersion(entry.Request.HttpVersion, wanted) {
+				anyMatch = true
+				break
+			}
+		}
+
+		if !anyMatch {
+			return false
+		}
+	}
+	if CLI.ResponseHttpVersion != nil {
+		anyMatch := false
+		for _, wanted := range *CLI.ResponseHttpVersion {
+			if MatchesHttpVersion(entry.Response.HttpVersion, wanted) {
+				anyMatch = true
+				break
+			}
+		}
+
+		if !anyMatch {
+			return false
+		}
+	}
+	if CLI.TlsVersion != nil {
+		if entry.SecurityDetails == nil {
+			return false
+		}
+		version := NormalizeTlsVersion(entry.SecurityDetails.Protocol)
+		anyMatch := false
+		for _, wanted := range *CLI.TlsVersion {
+			if NormalizeTlsVersion(wanted) == version {
+				anyMatch = true
+				break
+			}
+		}
+
+		if !anyMatch {
+			return false
+		}
+	}
 	if CLI.ResponseCode != nil {
 		if entry.Response.Status != *CLI.ResponseCode {
 			return false
@@ -278,6 +1033,125 @@ func IsEntryValid(entry Entry) bool {
 			return false
 		}
 	}
+	if CLI.Grep != nil && GrepPattern() != nil {
+		if len(GrepEntry(entry, GrepPattern())) == 0 {
+			return false
+		}
+	}
+	if CLI.ServerIp != nil || CLI.ServerCidr != nil {
+		ip := EntryServerIp(entry)
+		if ip == nil {
+			return false
+		}
+		if CLI.ServerIp != nil && !ip.Equal(*CLI.ServerIp) {
+			return false
+		}
+		if CLI.ServerCidr != nil && !CLI.ServerCidr.Contains(ip) {
+			return false
+		}
+	}
+	if CLI.GraphqlOperation != nil && !MatchesGraphqlOperation(entry, *CLI.GraphqlOperation) {
+		return false
+	}
+	if CLI.SoapAction != nil && !MatchesSoapAction(entry, *CLI.SoapAction) {
+		return false
+	}
+	if CLI.RedirectsOnly != nil {
+		if entry.Response.Status < 300 || entry.Response.Status > 399 || entry.Response.RedirectUrl == nil || *entry.Response.RedirectUrl == "" {
+			return false
+		}
+	}
+	if CLI.RedirectTarget != nil {
+		if entry.Response.RedirectUrl == nil || !strings.Contains(strings.ToLower(*entry.Response.RedirectUrl), strings.ToLower(*CLI.RedirectTarget)) {
+			return false
+		}
+	}
+	if CLI.RedirectTo != nil {
+		if entry.Response.RedirectUrl == nil || !CLI.RedirectTo.MatchString(*entry.Response.RedirectUrl) {
+			return false
+		}
+	}
+	if CLI.LocationIncludes != nil {
+		location := FindHeader(entry.Response.Headers, "location")
+		if location == nil || !strings.Contains(strings.ToLower(location.Value), strings.ToLower(*CLI.LocationIncludes)) {
+			return false
+		}
+	}
+	if CLI.MinRatio != nil || CLI.MaxRatio != nil {
+		_, _, ratio, ok := CompressionRatio(entry)
+		if !ok {
+			return false
+		}
+		if CLI.MinRatio != nil && ratio < *CLI.MinRatio {
+			return false
+		}
+		if CLI.MaxRatio != nil && ratio > *CLI.MaxRatio {
+			return false
+		}
+	}
+	if CLI.FromCache != nil {
+		if entry.FromCache == nil || *entry.FromCache == "" {
+			return false
+		}
+	}
+	if CLI.NotFromCache != nil {
+		if entry.FromCache != nil && *entry.FromCache != "" {
+			return false
+		}
+	}
+	if CLI.NewConnections != nil && !OpenedConnection(entry) {
+		return false
+	}
+	if CLI.ReusedConnections != nil {
+		if OpenedConnection(entry) || entry.FromCache != nil && *entry.FromCache != "" {
+			return false
+		}
+	}
+	if CLI.MinSslMs != nil && OrUnknown(entry.Timings.Ssl) <= *CLI.MinSslMs {
+		return false
+	}
+	if CLI.StartOffset != nil || CLI.EndOffset != nil {
+		started, ok := ParseStartedDateTime(entry.StartedDateTime)
+		if !ok {
+			return false
+		}
+		offset := started.Sub(CaptureStart)
+		if CLI.StartOffset != nil && offset < *CLI.StartOffset {
+			return false
+		}
+		if CLI.EndOffset != nil && offset > *CLI.EndOffset {
+			return false
+		}
+	}
+	if CLI.Tag != nil {
+		if !taggedEntries[EntryTagKey(entry)] {
+			return false
+		}
+	}
+	if CLI.HasPage != nil {
+		if entry.PageRef == nil || *entry.PageRef == "" {
+			return false
+		}
+	}
+	if CLI.NoPage != nil {
+		if entry.PageRef != nil && *entry.PageRef != "" {
+			return false
+		}
+	}
+	if CLI.HttpVersion != nil {
+		version := EntryHttpVersion(entry)
+		anyMatch := false
+		for _, wanted := range *CLI.HttpVersion {
+			if NormalizeHttpVersion(wanted) == version {
+				anyMatch = true
+				break
+			}
+		}
+
+		if !anyMatch {
+			return false
+		}
+	}
 
 	return true
 }
@@ -301,9 +1175,21 @@ func Tertiary[T interface{}](c bool, ok T, not T) T {
 	}
 }
 
+// Pointer is for filling in the optional fields of a HAR from values which aren't variables
+func Pointer[T interface{}](v T) *T {
+	return &v
+}
+
 func FormatPostBody(post PostData) string {
 	output := color.HiBlackString("Mime Type: ") + TypeColor(post.MimeType)
-	if strings.Contains(post.MimeType, "application/json") || IsValidJson(post.Text) {
+	if formatted, ok := FormatSoapBody(post.MimeType, post.Text); ok {
+		output += "\n" + formatted
+	} else if IsNdjson(post.MimeType, post.Text) {
+		if !IsNdjsonMimeType(post.MimeType) {
+			output += color.HiBlackString(" (inferred application/x-ndjson)")
+		}
+		output += "\n" + FormatNdjson(post.Text)
+	} else if strings.Contains(post.MimeType, "application/json") || IsValidJson(post.Text) {
 		var i interface{}
 		err := json.Unmarshal([]byte(post.Text), &i)
 		if err == nil {
@@ -319,10 +1205,10 @@ func FormatPostBody(post PostData) string {
 				output += string(processed)
 				return output
 			} else {
-				slog.Error("Failed to color the json", "error", err)
+				slog.Debug("Failed to color the json", "error", err)
 			}
 		} else {
-			slog.Error("Failed to unmarshall the json into this type", "error", err)
+			slog.Debug("Failed to unmarshall the json into this type", "error", err)
 		}
 	} else {
 		output += "\n" + post.Text
@@ -352,15 +1238,30 @@ func FormatPostBody(post PostData) string {
 	return output
 }
 func FormatContent(post Content) string {
-	headers := color.HiBlackString("Size: ") + TypeColor(strconv.Itoa(post.Size)) + "\n"
+	headers := color.HiBlackString("Size: ") + color.YellowString(FormatBytes(post.Size)) + "\n"
 
 	if post.Encoding != nil {
 		headers += color.HiBlackString("Encoding: ") + TypeColor(*post.Encoding) + "\n"
 	}
 	if post.Compression != nil {
-		headers += color.HiBlackString("Compression: ") + TypeColor(strconv.Itoa(*post.Compression)) + "\n"
+		headers += color.HiBlackString("Compression: ") + color.YellowString(FormatBytes(*post.Compression)) + "\n"
 	}
 
+	if summary, ok := FormatImageContent(post); ok {
+		return color.HiBlackString("Mime Type: ") + TypeColor(post.MimeType) + "\n" + headers + summary
+	}
+	if post.Text != nil {
+		if formatted, ok := FormatSoapBody(post.MimeType, *post.Text); ok {
+			return color.HiBlackString("Mime Type: ") + TypeColor(post.MimeType) + "\n" + headers + formatted
+		}
+	}
+	if post.Text != nil && IsNdjson(post.MimeType, *post.Text) {
+		output := color.HiBlackString("Mime Type: ") + TypeColor(post.MimeType)
+		if !IsNdjsonMimeType(post.MimeType) {
+			output += color.HiBlackString(" (inferred application/x-ndjson)")
+		}
+		return output + "\n" + headers + FormatNdjson(*post.Text)
+	}
 	if post.Text != nil && (strings.Contains(post.MimeType, "application/json") || IsValidJson(*post.Text)) {
 		var i interface{}
 		err := json.Unmarshal([]byte(*post.Text), &i)
@@ -379,10 +1280,10 @@ func FormatContent(post Content) string {
 				output += string(processed)
 				return output
 			} else {
-				slog.Error("Failed to color the json", "error", err)
+				slog.Debug("Failed to color the json", "error", err)
 			}
 		} else {
-			slog.Error("Failed to unmarshall the json into this type", "error", err)
+			slog.Debug("Failed to unmarshall the json into this type", "error", err)
 		}
 	}
 
@@ -393,6 +1294,46 @@ func FormatContent(post Content) string {
 	}
 }
 
+// FormatMs prints a millisecond value with at most microsecond precision
+func FormatMs(ms float64) string {
+	return strconv.FormatFloat(math.Round(ms*1000)/1000, 'f', -1, 64)
+}
+
+func FormatDuration(ms float64) string {
+	if CLI.RawNumbers != nil && *CLI.RawNumbers {
+		return FormatMs(ms)
+	}
+
+	switch {
+	case ms < 10:
+		return strconv.FormatFloat(ms, 'f', 1, 64) + " ms"
+	case ms < 1000:
+		return strconv.FormatFloat(ms, 'f', 0, 64) + " ms"
+	case ms < 60_000:
+		return strconv.FormatFloat(ms/1000, 'f', 1, 64) + " s"
+	default:
+		return strconv.FormatFloat(ms/60_000, 'f', 1, 64) + " min"
+	}
+}
+
+func FormatBytes(size int) string {
+	if CLI.RawNumbers != nil && *CLI.RawNumbers {
+		return strconv.Itoa(size)
+	}
+	if size < 1024 && size > -1024 {
+		return strconv.Itoa(size) + " B"
+	}
+
+	value := float64(size)
+	for _, unit := range []string{"KiB", "MiB", "GiB"} {
+		value /= 1024
+		if math.Abs(value) < 1024 || unit == "GiB" {
+			return strconv.FormatFloat(value, 'f', 1, 64) + " " + unit
+		}
+	}
+	return strconv.Itoa(size)
+}
+
 func IsValidJson(v string) bool {
 	var i interface{}
 	err := json.Unmarshal([]byte(v[:]), &i)
@@ -422,15 +1363,516 @@ func Indent(v string, spaces int) string {
 	return strings.Join(indented, "\n")
 }
 
+// Chrome records script positions zero-based, these are shifted to match what devtools displays
+func FormatScriptLocation(url string, line int, column int) string {
+	return url + color.HiBlackString(":"+strconv.Itoa(line+1)+":"+strconv.Itoa(column+1))
+}
+
+func FormatInitiator(initiator Initiator) string {
+	output := color.HiBlackString("Type: ") + TypeColor(initiator.Type)
+	if initiator.Url != nil {
+		output += color.HiBlackString("\nURL: ")
+		if initiator.LineNumber != nil {
+			column := 0
+			if initiator.ColumnNumber != nil {
+				column = *initiator.ColumnNumber
+			}
+			output += FormatScriptLocation(*initiator.Url, *initiator.LineNumber, column)
+		} else {
+			output += *initiator.Url
+		}
+	}
+
+	if initiator.Stack != nil {
+		output += color.HiBlackString("\nStack:")
+		for stack := initiator.Stack; stack != nil; stack = stack.Parent {
+			if stack.Description != nil {
+				output += color.HiBlackString("\n  (" + *stack.Description + ")")
+			}
+			for _, frame := range stack.CallFrames {
+				name := Tertiary(frame.FunctionName == "", "(anonymous)", frame.FunctionName)
+				output += "\n    " + color.CyanString(name) + color.HiBlackString(" @ ") + FormatScriptLocation(frame.Url, frame.LineNumber, frame.ColumnNumber)
+			}
+		}
+	}
+
+	return output
+}
+
+func FormatOptional(v *string) string {
+	if v == nil || *v == "" {
+		return "[unknown]"
+	}
+	return TypeColor(*v)
+}
+
+func FormatCacheState(state *CacheState) string {
+	if state == nil {
+		return "[not cached]"
+	}
+
+	output := color.HiBlackString("ETag: ") + TypeColor(state.ETag) +
+		color.HiBlackString(", Hits: ") + TypeColor(strconv.Itoa(state.HitCount)) +
+		color.HiBlackString(", Last Access: ") + TypeColor(state.LastAccess)
+	if state.Expires != nil {
+		output += color.HiBlackString(", Expires: ") + TypeColor(*state.Expires)
+	}
+	if state.Comment != nil {
+		output += " (" + *state.Comment + ")"
+	}
+	return output
+}
+
+func FormatConnection(entry Entry) string {
+	output := color.HiBlackString("Server IP: ") + FormatOptional(entry.ServerIP)
+	if name := EntryPtrName(entry); name != "" {
+		output += " " + color.CyanString(name)
+	}
+	if location := EntryGeoLocation(entry); location != nil {
+		output += color.HiBlackString(" (" + location.String() + ")")
+	}
+	if provider := EntryProvider(entry); provider != "" {
+		output += color.HiBlackString("\nProvider: ") + provider
+	}
+	output += color.HiBlackString("\nConnection: ") + FormatOptional(entry.Connection)
+	output += color.HiBlackString("\nHTTP Version: ") + TypeColor(entry.Response.HttpVersion)
+	if !strings.EqualFold(entry.Request.HttpVersion, entry.Response.HttpVersion) {
+		output += color.HiBlackString(" (requested " + entry.Request.HttpVersion + ")")
+	}
+	output += color.HiBlackString("\nCache Before Request: ") + FormatCacheState(entry.Cache.BeforeRequest)
+	output += color.HiBlackString("\nCache After Request: ") + FormatCacheState(entry.Cache.AfterRequest)
+	if entry.Cache.Comment != nil {
+		output += color.HiBlackString("\nCache Comment: ") + *entry.Cache.Comment
+	}
+	return output
+}
+
+func FormatSecurityDetails(details SecurityDetails, state *string) string {
+	output := ""
+	if state != nil {
+		output += color.HiBlackString("State: ") + TypeColor(*state) + "\n"
+	}
+	output += color.HiBlackString("Protocol: ") + TypeColor(details.Protocol)
+	keyExchange := details.KeyExchange
+	if details.KeyExchangeGroup != nil {
+		keyExchange = strings.TrimSpace(keyExchange + " " + *details.KeyExchangeGroup)
+	}
+	if keyExchange != "" {
+		output += color.HiBlackString("\nKey Exchange: ") + TypeColor(keyExchange)
+	}
+	if details.Cipher != "" {
+		output += color.HiBlackString("\nCipher: ") + TypeColor(details.Cipher)
+		if details.Mac != nil && *details.Mac != "" {
+			output += color.HiBlackString(" with ") + TypeColor(*details.Mac)
+		}
+	}
+	if details.SubjectName != "" {
+		output += color.HiBlackString("\nSubject: ") + TypeColor(details.SubjectName)
+	}
+	if details.Issuer != "" {
+		output += color.HiBlackString("\nIssuer: ") + TypeColor(details.Issuer)
+	}
+	// A reused connection has no certificate, so there are no dates rather than ones at the epoch
+	if details.ValidFrom != 0 || details.ValidTo != 0 {
+		output += color.HiBlackString("\nValid: ") + TypeColor(FormatUnixSeconds(details.ValidFrom, time.RFC3339)) +
+			color.HiBlackString(" to ") + TypeColor(FormatUnixSeconds(details.ValidTo, time.RFC3339))
+	}
+	if len(details.SanList) > 0 {
+		output += color.HiBlackString("\nSAN: ")
+		for _, san := range details.SanList {
+			output += "\n  " + TypeColor(san)
+		}
+	}
+	if details.CertificateTransparencyCompliance != nil {
+		output += color.HiBlackString("\nCertificate Transparency: ") + TypeColor(*details.CertificateTransparencyCompliance)
+	}
+
+	return output
+}
+
+func UnixSeconds(seconds float64) time.Time {
+	return time.Unix(int64(seconds), 0)
+}
+
+// FormatUnixSeconds formats a certificate date, which is 0 when the HAR doesn't have it
+func FormatUnixSeconds(seconds float64, layout string) string {
+	if seconds == 0 {
+		return "unknown"
+	}
+	return UnixSeconds(seconds).Format(layout)
+}
+
+// QueryParameters returns the recorded query string, or parses it from the URL if the HAR didn't include it
+func QueryParameters(request Request) []QueryParameter {
+	if len(request.QueryString) > 0 {
+		return request.QueryString
+	}
+
+	parameters := make([]QueryParameter, 0)
+	requestUrl, err := url.Parse(request.Url)
+	if err != nil || requestUrl.RawQuery == "" {
+		return parameters
+	}
+	for _, pair := range strings.Split(requestUrl.RawQuery, "&") {
+		if pair == "" {
+			continue
+		}
+		name, value, _ := strings.Cut(pair, "=")
+		parameters = append(parameters, QueryParameter{Name: name, Value: value})
+	}
+	return parameters
+}
+
+func DecodeQueryComponent(v string) string {
+	decoded, err := url.QueryUnescape(v)
+	if err != nil {
+		return v
+	}
+	return decoded
+}
+
+func FormatPage(page Page, entries []Entry) string {
+	output := color.New(color.FgYellow, color.Bold).Sprint("Page: "+page.Title) + color.HiBlackString(" ("+page.Id+")")
+	for _, entry := range entries {
+		if entry.PageRef != nil && *entry.PageRef == page.Id {
+			output += color.HiBlackString("\n  URL: ") + entry.Request.Url
+			break
+		}
+	}
+	output += color.HiBlackString("\n  Started: ") + TypeColor(page.StartedDateTime)
+	if page.PageTimings.ContentLoad != nil && *page.PageTimings.ContentLoad >= 0 {
+		output += color.HiBlackString("\n  DOMContentLoaded: ") + color.YellowString(FormatDuration(*page.PageTimings.ContentLoad))
+	}
+	if page.PageTimings.Load != nil && *page.PageTimings.Load >= 0 {
+		output += color.HiBlackString("\n  Load: ") + color.YellowString(FormatDuration(*page.PageTimings.Load))
+	}
+	if page.Comment != nil {
+		output += color.HiBlackString("\n  Comment: ") + *page.Comment
+	}
+	return output
+}
+
+// FormatPageList is a row for each page with how many of the entries belong to it, entries which are in no page are
+// counted in a last row
+func FormatPageList(log Log, entries []Entry) string {
+	if log.Pages == nil || len(*log.Pages) == 0 {
+		return "The HAR file doesn't contain any pages"
+	}
+	counts := make(map[string]int)
+	bytes := make(map[string]int)
+	for _, entry := range entries {
+		reference := ""
+		if entry.PageRef != nil {
+			reference = *entry.PageRef
+		}
+		counts[reference]++
+		bytes[reference] += TransferSize(entry)
+	}
+	timing := func(value *float64) string {
+		if value == nil || *value < 0 {
+			return "-"
+		}
+		return FormatDuration(*value)
+	}
+
+	rows := make([][]string, 0, len(*log.Pages)+1)
+	for i, page := range *log.Pages {
+		rows = append(rows, []string{
+			strconv.Itoa(i),
+			page.Id,
+			page.Title,
+			page.StartedDateTime,
+			strconv.Itoa(counts[page.Id]),
+			FormatBytes(bytes[page.Id]),
+			timing(page.PageTimings.ContentLoad),
+			timing(page.PageTimings.Load),
+		})
+		delete(counts, page.Id)
+		delete(bytes, page.Id)
+	}
+	orphans, size := 0, 0
+	for reference, count := range counts {
+		orphans += count
+		size += bytes[reference]
+	}
+	if orphans > 0 {
+		rows = append(rows, []string{"", "[none]", "", "", strconv.Itoa(orphans), FormatBytes(size), "", ""})
+	}
+	columns := []Column{
+		{Name: "#", Right: true},
+		{Name: "ID"},
+		{Name: "Title"},
+		{Name: "Started"},
+		{Name: "Entries", Right: true},
+		{Name: "Bytes", Right: true},
+		{Name: "DOMContentLoaded", Right: true},
+		{Name: "Load", Right: true},
+	}
+	return FormatTable(columns, rows)
+}
+
+func FormatEntriesByPage(log Log, entries []Entry, format func(entry Entry) string) string {
+	pages := make([]Page, 0)
+	if log.Pages != nil {
+		pages = *log.Pages
+	}
+
+	sections := make([]string, 0)
+	grouped := make(map[string]bool)
+	for _, page := range pages {
+		pageEntries := Filter(entries, func(entry Entry) bool {
+			return entry.PageRef != nil && *entry.PageRef == page.Id
+		})
+		if len(pageEntries) == 0 {
+			continue
+		}
+
+		section := FormatPage(page, pageEntries)
+		for _, entry := range pageEntries {
+			section += "\n" + Indent(format(entry), 2)
+		}
+		sections = append(sections, section)
+		grouped[page.Id] = true
+	}
+
+	remaining := Filter(entries, func(entry Entry) bool {
+		return entry.PageRef == nil || !grouped[*entry.PageRef]
+	})
+	if len(remaining) > 0 {
+		section := color.New(color.FgYellow, color.Bold).Sprint("No Page")
+		for _, entry := range remaining {
+			section += "\n" + Indent(format(entry), 2)
+		}
+		sections = append(sections, section)
+	}
+
+	return strings.Join(sections, "\n\n")
+}
+
+func FormatRelativeTime(started time.Time) string {
+	return "+" + strconv.FormatFloat(started.Sub(CaptureStart).Seconds(), 'f', 3, 64) + "s"
+}
+
+func FormatStartTime(entry Entry, mode string) string {
+	started, ok := ParseStartedDateTime(entry.StartedDateTime)
+	if !ok {
+		return color.HiBlackString("[" + entry.StartedDateTime + "]")
+	}
+
+	switch mode {
+	case "relative":
+		return color.HiBlackString(FormatRelativeTime(started))
+	case "absolute":
+		return color.HiBlackString(started.Local().Format("2006-01-02 15:04:05.000"))
+	default:
+		return color.HiBlackString(started.Local().Format("2006-01-02 15:04:05.000") + " (" + FormatRelativeTime(started) + ")")
+	}
+}
+
+type TimingPhase struct {
+	Name  string
+	Value float64
+	Color color.Attribute
+}
+
+func TimingPhases(timings EntryTimings) []TimingPhase {
+	connect := OrUnknown(timings.Connect)
+	ssl := OrUnknown(timings.Ssl)
+	// The ssl phase is included in connect so it is split back out to avoid counting it twice
+	if ssl > 0 && connect >= ssl {
+		connect -= ssl
+	}
+
+	return []TimingPhase{
+		{Name: "blocked", Value: OrUnknown(timings.Blocked), Color: color.BgWhite},
+		{Name: "dns", Value: OrUnknown(timings.Dns), Color: color.BgCyan},
+		{Name: "connect", Value: connect, Color: color.BgYellow},
+		{Name: "ssl", Value: ssl, Color: color.BgMagenta},
+		{Name: "send", Value: timings.Send, Color: color.BgGreen},
+		{Name: "wait", Value: timings.Wait, Color: color.BgBlue},
+		{Name: "receive", Value: timings.Receive, Color: color.BgRed},
+	}
+}
+
+func CenterText(v string, width int, fill string) string {
+	if len(v) > width {
+		v = v[:width]
+	}
+	left := (width - len(v)) / 2
+	return strings.Repeat(fill, left) + v + strings.Repeat(fill, width-len(v)-left)
+}
+
+// FormatTimingBar draws the phases of the request in proportion to how long they took, every phase which took any
+// time gets at least one cell so that it is still visible
+func FormatTimingBar(timings EntryTimings, width int) string {
+	phases := Filter(TimingPhases(timings), func(phase TimingPhase) bool {
+		return phase.Value > 0
+	})
+	total := 0.0
+	for _, phase := range phases {
+		total += phase.Value
+	}
+	if total <= 0 || len(phases) > width {
+		return ""
+	}
+
+	widths := make([]int, len(phases))
+	used := 0
+	largest := 0
+	for i, phase := range phases {
+		widths[i] = max(1, int(math.Round(phase.Value/total*float64(width))))
+		used += widths[i]
+		if widths[i] > widths[largest] {
+			largest = i
+		}
+	}
+	widths[largest] = max(1, widths[largest]+width-used)
+
+	output := ""
+	for i, phase := range phases {
+		if color.NoColor {
+			output += "|" + CenterText(phase.Name, widths[i], "=")
+		} else {
+			output += color.New(phase.Color, color.FgBlack).Sprint(CenterText(phase.Name, widths[i], " "))
+		}
+	}
+	return output + Tertiary(color.NoColor, "|", "")
+}
+
+// FormatOneline is the header of FormatEntry reduced to fixed width columns, so the output lines up for scanning and
+// can be passed to grep, sort or awk
+func FormatOneline(entry Entry) string {
+	started := FormatStartTime(entry, "relative")
+	if CLI.PrintTime != nil {
+		started = FormatStartTime(entry, *CLI.PrintTime)
+	}
+
+	prefix := strings.Repeat(" ", max(9-VisibleLength(started), 0)) + started + " " +
+		MethodColor(entry.Request.Method)(fmt.Sprintf("%-7s", entry.Request.Method)) + " " +
+		StatusColor(entry.Response.Status)(fmt.Sprintf("%3d", entry.Response.Status)) + " " +
+		fmt.Sprintf("%9s %10s", FormatDuration(entry.TimeMs), FormatBytes(TransferSize(entry)))
+	if CLI.PrintProtocol != nil && *CLI.PrintProtocol {
+		prefix += " " + color.YellowString(fmt.Sprintf("%-*s", onelineProtocolWidth, OnelineProtocol(entry)))
+	}
+	suffix := ""
+	if entry.Comment != nil && *entry.Comment != "" {
+		suffix = " " + color.New(color.FgMagenta, color.Bold).Sprint("// "+strings.Join(strings.Fields(*entry.Comment), " "))
+	}
+	requestUrl := HighlightUrl(entry.Request.Url)
+	if width := LayoutWidth(); width > 0 {
+		requestUrl = MiddleEllipsis(requestUrl, max(width-VisibleLength(prefix)-VisibleLength(suffix)-1, minUrlWidth))
+	}
+	return prefix + " " + requestUrl + suffix
+}
+
+// FormatComment highlights a comment written into the HAR, usually a note left by whoever captured or investigated it
+func FormatComment(comment string) string {
+	lines := strings.Split(strings.TrimRight(comment, "\n"), "\n")
+	for i, line := range lines {
+		lines[i] = color.New(color.FgMagenta, color.Bold).Sprint("// " + line)
+	}
+	return strings.Join(lines, "\n")
+}
+
+// minUrlWidth stops URLs being shortened to nothing when the rest of the header line already fills the terminal
+const minUrlWidth = 24
+
+// StatusColor colours statuses by class so failures stand out when scanning the list
+func StatusColor(status int) func(format string, a ...interface{}) string {
+	switch {
+	case status >= 200 && status < 300:
+		return color.GreenString
+	case status >= 300 && status < 400:
+		return color.YellowString
+	case status >= 400 || status == 0:
+		return color.RedString
+	}
+	return color.HiBlackString
+}
+
+func MethodColor(method string) func(format string, a ...interface{}) string {
+	switch strings.ToUpper(method) {
+	case "GET":
+		return color.CyanString
+	case "POST":
+		return color.BlueString
+	case "PUT", "PATCH":
+		return color.MagentaString
+	case "DELETE":
+		return color.RedString
+	}
+	return color.WhiteString
+}
+
 func FormatEntry(entry Entry) string {
-	result := color.YellowString(strings.ToLower(entry.Request.HttpVersion)+" "+entry.Request.Method) + " " + entry.Request.Url
+	version := strings.ToLower(entry.Request.HttpVersion)
+	if CLI.PrintProtocol != nil && *CLI.PrintProtocol {
+		version = NegotiatedProtocol(entry)
+	}
+	prefix := color.YellowString(version) + " " + MethodColor(entry.Request.Method)(entry.Request.Method) +
+		" " + StatusColor(entry.Response.Status)(strconv.Itoa(entry.Response.Status))
+	if CLI.PrintTime != nil {
+		prefix = FormatStartTime(entry, *CLI.PrintTime) + " " + prefix
+	}
+	suffix := ""
+	if entry.Priority != nil {
+		suffix += color.HiBlackString(" (" + *entry.Priority + ")")
+	}
+	if entry.FromCache != nil && *entry.FromCache != "" {
+		suffix += color.GreenString(" [from " + *entry.FromCache + " cache]")
+	}
+	if name := SoapName(entry); name != "" {
+		suffix += color.MagentaString(" [SOAP " + name + "]")
+	}
+	if status, message, ok := GrpcStatus(entry); ok {
+		suffix += " [" + FormatGrpcStatus(status, message) + "]"
+	}
+	if transferred, decoded, ratio, ok := CompressionRatio(entry); ok && transferred != decoded {
+		suffix += color.HiBlackString(" " + FormatBytes(transferred) + " → " + FormatBytes(decoded) + " (" + strconv.FormatFloat(ratio, 'f', 1, 64) + "x)")
+	}
+	requestUrl := HighlightUrl(entry.Request.Url)
+	if width := LayoutWidth(); width > 0 {
+		requestUrl = MiddleEllipsis(requestUrl, max(width-VisibleLength(prefix)-VisibleLength(suffix)-1, minUrlWidth))
+	}
+	result := prefix + " " + requestUrl + suffix
+	if CLI.RedirectTo != nil && entry.Response.RedirectUrl != nil && *entry.Response.RedirectUrl != "" {
+		// Shown so it's clear which part of the redirect matched
+		result += color.HiBlackString("\n  Redirects to: ") + HighlightRedirect(*entry.Response.RedirectUrl)
+	}
+	if entry.Comment != nil && *entry.Comment != "" {
+		result += "\n" + Indent(FormatComment(*entry.Comment), 2)
+	}
+	if CLI.Grep != nil && GrepPattern() != nil {
+		result += color.YellowString("\n  Matches:\n") + Indent(FormatGrepMatches(GrepEntry(entry, GrepPattern())), 4)
+	}
+	if CLI.IncludeInitiator != nil && *CLI.IncludeInitiator && entry.Initiator != nil {
+		result += color.YellowString("\n  Initiator:\n") + Indent(FormatInitiator(*entry.Initiator), 4)
+	}
+	if CLI.IncludeConnection != nil && *CLI.IncludeConnection {
+		result += color.YellowString("\n  Connection:\n") + Indent(FormatConnection(entry), 4)
+	}
+	if CLI.IncludeTls != nil && *CLI.IncludeTls && entry.SecurityDetails != nil {
+		result += color.YellowString("\n  TLS:\n") + Indent(FormatSecurityDetails(*entry.SecurityDetails, entry.SecurityState), 4)
+	}
+	if CLI.IncludeQuery != nil && *CLI.IncludeQuery {
+		parameters := QueryParameters(entry.Request)
+		if len(parameters) > 0 {
+			result += color.YellowString("\n  Query Parameters:")
+			for _, parameter := range parameters {
+				result += GrepHighlight("\n    " + color.HiBlackString(DecodeQueryComponent(parameter.Name)) + " = " + TypeColor(DecodeQueryComponent(parameter.Value)))
+				if parameter.Comment != nil {
+					result += " (" + *parameter.Comment + ")"
+				}
+			}
+		}
+	}
 	if CLI.IncludeHeaders != nil && *CLI.IncludeHeaders {
 		result += color.YellowString("\n  Request Headers:")
 		for _, header := range entry.Request.Headers {
 			if strings.ToLower(header.Name) == "cookie" {
 				continue
 			}
-			result += "\n    " + color.HiBlackString(header.Name) + " = " + TypeColor(header.Value)
+			result += GrepHighlight("\n    " + color.HiBlackString(header.Name) + " = " + TypeColor(header.Value))
 			if header.Comment != nil {
 				result += " (" + *header.Comment + ")"
 			}
@@ -439,17 +1881,19 @@ func FormatEntry(entry Entry) string {
 	if CLI.IncludeCookies != nil && *CLI.IncludeCookies && len(entry.Request.Cookies) > 0 {
 		result += color.YellowString("\n  Request Cookies:")
 		for _, header := range entry.Request.Cookies {
-			result += "\n    " + color.HiBlackString(header.Name) + " = " + TypeColor(header.Value)
+			result += GrepHighlight("\n    " + color.HiBlackString(header.Name) + " = " + TypeColor(header.Value))
 			if header.Comment != nil {
 				result += " (" + *header.Comment + ")"
 			}
 		}
 	}
 	if CLI.IncludeRequestBody != nil && *CLI.IncludeRequestBody && entry.Request.PostData != nil {
-		if entry.Request.BodySize == 0 {
+		if !HasRequestBody(entry.Request) {
 			result += color.YellowString("\n  Request Body:\n    ") + "[no content]"
+		} else if IsGrpcWeb(entry.Request.PostData.MimeType) {
+			result += color.YellowString("\n  Request Body:\n") + Indent(GrepHighlight(FormatGrpcWebBody(entry, false)), 4)
 		} else {
-			result += color.YellowString("\n  Request Body:\n") + Indent(FormatPostBody(*entry.Request.PostData), 4)
+			result += color.YellowString("\n  Request Body:\n") + Indent(GrepHighlight(FormatPostBody(*entry.Request.PostData)), 4)
 		}
 	}
 
@@ -459,7 +1903,7 @@ func FormatEntry(entry Entry) string {
 			if strings.ToLower(header.Name) == "cookie" {
 				continue
 			}
-			result += "\n    " + color.HiBlackString(header.Name) + " = " + TypeColor(header.Value)
+			result += GrepHighlight("\n    " + color.HiBlackString(header.Name) + " = " + TypeColor(header.Value))
 			if header.Comment != nil {
 				result += " (" + *header.Comment + ")"
 			}
@@ -468,7 +1912,7 @@ func FormatEntry(entry Entry) string {
 	if CLI.IncludeCookies != nil && *CLI.IncludeCookies && len(entry.Response.Cookies) > 0 {
 		result += color.YellowString("\n  Response Cookies:")
 		for _, header := range entry.Response.Cookies {
-			result += "\n    " + color.HiBlackString(header.Name) + " = " + TypeColor(header.Value)
+			result += GrepHighlight("\n    " + color.HiBlackString(header.Name) + " = " + TypeColor(header.Value))
 			if header.Comment != nil {
 				result += " (" + *header.Comment + ")"
 			}
@@ -477,34 +1921,73 @@ func FormatEntry(entry Entry) string {
 	if CLI.IncludeResponseBody != nil && *CLI.IncludeResponseBody && entry.Response.Content != nil {
 		if (*entry.Response.Content).Size == 0 {
 			result += color.YellowString("\n  Response Body:\n    ") + "[no content]"
+		} else if IsGrpcWeb(entry.Response.Content.MimeType) {
+			result += color.YellowString("\n  Response Body:\n") + Indent(GrepHighlight(FormatGrpcWebBody(entry, true)), 4)
 		} else {
-			result += color.YellowString("\n  Response Body:\n") + Indent(FormatContent(*entry.Response.Content), 4)
+			result += color.YellowString("\n  Response Body:\n") + Indent(GrepHighlight(FormatContent(*entry.Response.Content)), 4)
 		}
 	}
 	if CLI.IncludeTimings != nil && *CLI.IncludeTimings {
-		result += color.YellowString("\n  Timings:    ")
-		if entry.Timings.Dns != nil && *entry.Timings.Dns >= 0 {
-			result += color.HiBlackString("\n        DNS: ") + TypeColor(strconv.Itoa(*entry.Timings.Dns))
+		result += color.YellowString("\n  Timings:    ") + FormatTimingBar(entry.Timings, 48)
+		if blocked := OrUnknown(entry.Timings.Blocked); blocked >= 0 {
+			result += color.HiBlackString("\n    Blocked: ") + color.YellowString(FormatDuration(blocked))
+		}
+		if queueing := OrUnknown(entry.Timings.BlockedQueueing); queueing >= 0 {
+			result += color.HiBlackString("\n   Queueing: ") + color.YellowString(FormatDuration(queueing))
+		}
+		if dns := OrUnknown(entry.Timings.Dns); dns >= 0 {
+			result += color.HiBlackString("\n        DNS: ") + color.YellowString(FormatDuration(dns))
 		}
-		if entry.Timings.Connect != nil && *entry.Timings.Connect >= 0 {
-			result += color.HiBlackString("\n    Connect: ") + TypeColor(strconv.Itoa(*entry.Timings.Connect))
+		if connect := OrUnknown(entry.Timings.Connect); connect >= 0 {
+			result += color.HiBlackString("\n    Connect: ") + color.YellowString(FormatDuration(connect))
 		}
-		result += color.HiBlackString("\n       Send: ") + TypeColor(strconv.Itoa(entry.Timings.Send))
-		result += color.HiBlackString("\n       Wait: ") + TypeColor(strconv.Itoa(entry.Timings.Wait))
-		result += color.HiBlackString("\n    Receive: ") + TypeColor(strconv.Itoa(entry.Timings.Receive))
-		if entry.Timings.Ssl != nil && *entry.Timings.Ssl >= 0 {
-			result += color.HiBlackString("\n        SSL: ") + TypeColor(strconv.Itoa(*entry.Timings.Ssl))
+		result += color.HiBlackString("\n       Send: ") + color.YellowString(FormatDuration(entry.Timings.Send))
+		result += color.HiBlackString("\n       Wait: ") + color.YellowString(FormatDuration(entry.Timings.Wait))
+		result += color.HiBlackString("\n    Receive: ") + color.YellowString(FormatDuration(entry.Timings.Receive))
+		if ssl := OrUnknown(entry.Timings.Ssl); ssl >= 0 {
+			result += color.HiBlackString("\n        SSL: ") + color.YellowString(FormatDuration(ssl))
 		}
 		if entry.Timings.Comment != nil {
-			result += color.HiBlackString("\n    Comment: ") + *entry.Comment
+			result += color.HiBlackString("\n    Comment: ") + *entry.Timings.Comment
+		}
+		if metrics := ServerTimings(entry); len(metrics) > 0 {
+			result += FormatServerTimings(metrics, entry.Timings.Wait)
 		}
 	}
 
 	return result
 }
 
+// ConfigureColor turns colour off when writing to a file, as the escape codes are rarely wanted there
+func ConfigureColor() {
+	mode := "auto"
+	if CLI.Color != nil {
+		mode = *CLI.Color
+	}
+	switch {
+	case mode == "always":
+		color.NoColor = false
+	case mode == "never" || CLI.Out != nil:
+		color.NoColor = true
+	}
+}
+
+// ConfigureLogging sends all diagnostics to stderr so stdout only ever contains the requested output
+func ConfigureLogging() {
+	level := slog.LevelWarn
+	switch {
+	case CLI.LogLevel != nil:
+		_ = level.UnmarshalText([]byte(*CLI.LogLevel))
+	case CLI.Verbose == 1:
+		level = slog.LevelInfo
+	case CLI.Verbose > 1:
+		level = slog.LevelDebug
+	}
+	slog.SetDefault(slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: level})))
+}
+
 func main() {
-	kong.Parse(&CLI,
+	ctx := kong.Parse(&CLI,
 		kong.Name("harv"),
 		kong.Description("A simple command line HAR file viewer"),
 		kong.UsageOnError(),
@@ -513,20 +1996,28 @@ func main() {
 			Summary: true,
 		}))
 
-	//spew.Dump(CLI)
-	content, err := os.ReadFile(CLI.File)
-	if err != nil {
-		panic(err)
-		return
-	}
+	ConfigureLogging()
+	ConfigureColor()
+	ctx.FatalIfErrorf(ConfigureGeoIp())
+	ctx.FatalIfErrorf(ConfigureProviders())
+	ctx.FatalIfErrorf(ConfigureProtoDescriptors())
+	ctx.FatalIfErrorf(ConfigureTags(ctx))
 
-	var har HarFile
-	json.Unmarshal(content, &har)
+	if CLI.Profile != nil {
+		profile, err := os.Create(*CLI.Profile)
+		ctx.FatalIfErrorf(err)
+		ctx.FatalIfErrorf(pprof.StartCPUProfile(profile))
+		defer profile.Close()
+		defer pprof.StopCPUProfile()
+	}
 
-	validEntries := Filter(har.Log.Entries, IsEntryValid)
-	//spew.Dump(validEntries)
-	//fmt.Printf("Found %+v valid entries\n", len(validEntries))
-	for _, entry := range validEntries {
-		println(FormatEntry(entry))
+	//spew.Dump(CLI)
+	err := ctx.Run()
+	if CLI.Timing != nil && *CLI.Timing {
+		fmt.Fprintln(os.Stderr, FormatPhaseTimings(&Timer))
+	}
+	if err != nil {
+		pprof.StopCPUProfile()
 	}
+	ctx.FatalIfErrorf(err)
 }
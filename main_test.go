@@ -0,0 +1,83 @@
+package main
+
+import (
+	"os"
+	"testing"
+)
+
+func readTestHar(t *testing.T, file string) HarFile {
+	t.Helper()
+	content, err := os.ReadFile(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var har HarFile
+	if err := UnmarshalWithExtensions(content, &har); err != nil {
+		t.Fatal(err)
+	}
+	return har
+}
+
+func TestHasBodyBrowserHars(t *testing.T) {
+	cases := map[string][][2]bool{
+		// bodySize is -1 for the POST and both bodies with content, and 0 for a response from the memory cache
+		"testdata/chrome.har": {{false, true}, {true, true}, {false, false}},
+		// bodySize is 25 for the form, 0 for the empty bodies and -1 for a response with text
+		"testdata/firefox.har": {{true, false}, {false, false}, {false, true}},
+	}
+	for file, expected := range cases {
+		har := readTestHar(t, file)
+		if len(har.Log.Entries) != len(expected) {
+			t.Fatalf("%s has %d entries, expected %d", file, len(har.Log.Entries), len(expected))
+		}
+		for i, entry := range har.Log.Entries {
+			if has := HasRequestBody(entry.Request); has != expected[i][0] {
+				t.Errorf("%s entry %d: HasRequestBody = %t, expected %t", file, i, has, expected[i][0])
+			}
+			if has := HasResponseBody(entry.Response); has != expected[i][1] {
+				t.Errorf("%s entry %d: HasResponseBody = %t, expected %t", file, i, has, expected[i][1])
+			}
+		}
+	}
+}
+
+func TestHasRequestBody(t *testing.T) {
+	cases := []struct {
+		name     string
+		request  Request
+		expected bool
+	}{
+		{"known size", Request{BodySize: 12}, true},
+		{"known empty", Request{BodySize: 0, PostData: &PostData{Text: "ignored"}}, false},
+		{"unknown size with text", Request{BodySize: -1, PostData: &PostData{Text: "a=1"}}, true},
+		{"unknown size with params and no text", Request{BodySize: -1, PostData: &PostData{MimeType: "multipart/form-data", Params: []PostParameters{{Name: "file"}}}}, true},
+		{"unknown size with empty post data", Request{BodySize: -1, PostData: &PostData{MimeType: "application/json"}}, false},
+		{"unknown size without post data", Request{BodySize: -1}, false},
+	}
+	for _, c := range cases {
+		if has := HasRequestBody(c.request); has != c.expected {
+			t.Errorf("%s: HasRequestBody = %t, expected %t", c.name, has, c.expected)
+		}
+	}
+}
+
+func TestHasResponseBody(t *testing.T) {
+	object, empty := "{}", ""
+	cases := []struct {
+		name     string
+		response Response
+		expected bool
+	}{
+		{"known size", Response{BodySize: 512}, true},
+		{"known empty", Response{BodySize: 0, Content: &Content{Size: 100}}, false},
+		{"unknown size with content size and no text", Response{BodySize: -1, Content: &Content{Size: 1200}}, true},
+		{"unknown size with text and no content size", Response{BodySize: -1, Content: &Content{Text: &object}}, true},
+		{"unknown size with empty content", Response{BodySize: -1, Content: &Content{Text: &empty}}, false},
+		{"unknown size without content", Response{BodySize: -1}, false},
+	}
+	for _, c := range cases {
+		if has := HasResponseBody(c.response); has != c.expected {
+			t.Errorf("%s: HasResponseBody = %t, expected %t", c.name, has, c.expected)
+		}
+	}
+}
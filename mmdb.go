@@ -0,0 +1,253 @@
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"net"
+	"os"
+)
+
+// MaxMindDb reads the MaxMind DB format used by the GeoLite2 and GeoIP2 databases, which is a binary search tree over
+// the bits of the address whose leaves point into a data section of maps, strings and numbers
+type MaxMindDb struct {
+	buffer     []byte
+	data       []byte
+	nodeCount  uint
+	recordSize uint
+	ipv4Start  uint
+	Type       string
+}
+
+var mmdbMetadataMarker = []byte("\xAB\xCD\xEFMaxMind.com")
+
+func OpenMaxMindDb(path string) (*MaxMindDb, error) {
+	buffer, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	start := bytes.LastIndex(buffer, mmdbMetadataMarker)
+	if start == -1 {
+		return nil, fmt.Errorf("%s is not a MaxMind DB file", path)
+	}
+	metadata := buffer[start+len(mmdbMetadataMarker):]
+	value, _, err := decodeMmdb(metadata, 0)
+	if err != nil {
+		return nil, fmt.Errorf("reading the metadata of %s: %w", path, err)
+	}
+	fields, ok := value.(map[string]any)
+	if !ok {
+		return nil, fmt.Errorf("reading the metadata of %s: not a map", path)
+	}
+
+	db := &MaxMindDb{buffer: buffer}
+	nodeCount, _ := fields["node_count"].(uint64)
+	recordSize, _ := fields["record_size"].(uint64)
+	ipVersion, _ := fields["ip_version"].(uint64)
+	db.Type, _ = fields["database_type"].(string)
+	db.nodeCount = uint(nodeCount)
+	db.recordSize = uint(recordSize)
+	if db.recordSize != 24 && db.recordSize != 28 && db.recordSize != 32 {
+		return nil, fmt.Errorf("%s has an unsupported record size of %d", path, db.recordSize)
+	}
+
+	treeSize := db.nodeCount * db.recordSize / 4
+	// The data section starts after the tree and 16 bytes of zeros
+	if treeSize+16 > uint(start) {
+		return nil, fmt.Errorf("%s is truncated", path)
+	}
+	db.data = buffer[treeSize+16 : start]
+
+	// IPv4 addresses are stored under ::/96 in IPv6 databases, so skip the 96 zero bits once
+	if ipVersion == 6 {
+		for i := 0; i < 96 && db.ipv4Start < db.nodeCount; i++ {
+			db.ipv4Start, err = db.record(db.ipv4Start, 0)
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+	return db, nil
+}
+
+func (db *MaxMindDb) record(node uint, bit uint) (uint, error) {
+	offset := node * db.recordSize / 4
+	if offset+db.recordSize/4 > uint(len(db.buffer)) {
+		return 0, errors.New("search tree node out of range")
+	}
+	b := db.buffer[offset:]
+	switch db.recordSize {
+	case 24:
+		if bit == 0 {
+			return uint(b[0])<<16 | uint(b[1])<<8 | uint(b[2]), nil
+		}
+		return uint(b[3])<<16 | uint(b[4])<<8 | uint(b[5]), nil
+	case 28:
+		if bit == 0 {
+			return uint(b[3]&0xF0)<<20 | uint(b[0])<<16 | uint(b[1])<<8 | uint(b[2]), nil
+		}
+		return uint(b[3]&0x0F)<<24 | uint(b[4])<<16 | uint(b[5])<<8 | uint(b[6]), nil
+	}
+	if bit == 0 {
+		return uint(binary.BigEndian.Uint32(b)), nil
+	}
+	return uint(binary.BigEndian.Uint32(b[4:])), nil
+}
+
+// Lookup returns the record for ip, with found false if the database has nothing for it
+func (db *MaxMindDb) Lookup(ip net.IP) (value any, found bool, err error) {
+	address := ip.To4()
+	node := db.ipv4Start
+	if address == nil {
+		address = ip.To16()
+		node = 0
+	}
+	if address == nil {
+		return nil, false, fmt.Errorf("invalid IP address %s", ip)
+	}
+
+	for i := 0; i < len(address)*8 && node < db.nodeCount; i++ {
+		bit := uint(address[i/8]>>(7-uint(i%8))) & 1
+		node, err = db.record(node, bit)
+		if err != nil {
+			return nil, false, err
+		}
+	}
+	if node <= db.nodeCount {
+		return nil, false, nil
+	}
+	offset := node - db.nodeCount - 16
+	if offset >= uint(len(db.data)) {
+		return nil, false, errors.New("data pointer out of range")
+	}
+	value, _, err = decodeMmdb(db.data, offset)
+	return value, err == nil, err
+}
+
+// decodeMmdb decodes the value at offset in section, returning it with the offset of the value after it. Maps are
+// decoded to map[string]any, arrays to []any, unsigned integers to uint64 and floats to float64
+func decodeMmdb(section []byte, offset uint) (any, uint, error) {
+	read := func(n uint) ([]byte, error) {
+		if offset+n > uint(len(section)) {
+			return nil, errors.New("unexpected end of data")
+		}
+		b := section[offset : offset+n]
+		offset += n
+		return b, nil
+	}
+	unsigned := func(b []byte) uint64 {
+		var value uint64
+		for _, c := range b {
+			value = value<<8 | uint64(c)
+		}
+		return value
+	}
+
+	control, err := read(1)
+	if err != nil {
+		return nil, 0, err
+	}
+	kind := uint(control[0] >> 5)
+
+	if kind == 1 {
+		// Pointers are relative to the start of the data section and the value after one is the byte after it
+		size := uint(control[0]>>3) & 3
+		b, err := read(size + 1)
+		if err != nil {
+			return nil, 0, err
+		}
+		var pointer uint
+		switch size {
+		case 0:
+			pointer = uint(control[0]&7)<<8 | uint(b[0])
+		case 1:
+			pointer = (uint(control[0]&7)<<16 | uint(unsigned(b))) + 2048
+		case 2:
+			pointer = (uint(control[0]&7)<<24 | uint(unsigned(b))) + 526336
+		default:
+			pointer = uint(unsigned(b))
+		}
+		value, _, err := decodeMmdb(section, pointer)
+		return value, offset, err
+	}
+
+	if kind == 0 {
+		extended, err := read(1)
+		if err != nil {
+			return nil, 0, err
+		}
+		kind = 7 + uint(extended[0])
+	}
+	size := uint(control[0] & 0x1F)
+	switch size {
+	case 29, 30, 31:
+		b, err := read(size - 28)
+		if err != nil {
+			return nil, 0, err
+		}
+		size = map[uint]uint{29: 29, 30: 285, 31: 65821}[size] + uint(unsigned(b))
+	}
+
+	switch kind {
+	case 2:
+		b, err := read(size)
+		return string(b), offset, err
+	case 3:
+		b, err := read(8)
+		if err != nil {
+			return nil, 0, err
+		}
+		return math.Float64frombits(binary.BigEndian.Uint64(b)), offset, nil
+	case 4:
+		b, err := read(size)
+		return b, offset, err
+	case 5, 6, 9:
+		b, err := read(size)
+		return unsigned(b), offset, err
+	case 8:
+		b, err := read(size)
+		return int64(int32(unsigned(b))), offset, err
+	case 10:
+		// 128 bit integers don't fit, only the low 64 bits are kept
+		b, err := read(size)
+		return unsigned(b[max(0, len(b)-8):]), offset, err
+	case 7:
+		value := make(map[string]any, size)
+		for i := uint(0); i < size; i++ {
+			key, next, err := decodeMmdb(section, offset)
+			if err != nil {
+				return nil, 0, err
+			}
+			item, after, err := decodeMmdb(section, next)
+			if err != nil {
+				return nil, 0, err
+			}
+			name, _ := key.(string)
+			value[name] = item
+			offset = after
+		}
+		return value, offset, nil
+	case 11:
+		value := make([]any, 0, size)
+		for i := uint(0); i < size; i++ {
+			item, next, err := decodeMmdb(section, offset)
+			if err != nil {
+				return nil, 0, err
+			}
+			value = append(value, item)
+			offset = next
+		}
+		return value, offset, nil
+	case 14:
+		return size != 0, offset, nil
+	case 15:
+		b, err := read(4)
+		if err != nil {
+			return nil, 0, err
+		}
+		return float64(math.Float32frombits(binary.BigEndian.Uint32(b))), offset, nil
+	}
+	return nil, 0, fmt.Errorf("unsupported data type %d", kind)
+}
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+type ExportMswCmd struct {
+	File string `arg:"" help:"The HAR file to parse, or - to read it from stdin" type:"existingfile"`
+}
+
+// mswMethods are the methods MSW has a request handler for
+var mswMethods = []string{"get", "post", "put", "patch", "delete", "head", "options"}
+
+const mswTemplate = `// Generated by harv from FILE
+import { http, HttpResponse } from "msw";
+
+// Serves the responses in the order they were recorded, repeating the last one
+function sequence(responses: Array<() => Response>) {
+  let call = 0;
+  return () => responses[Math.min(call++, responses.length - 1)]();
+}
+
+export const handlers = [
+HANDLERS
+];
+`
+
+// MswResponse writes a recorded response as an expression creating it with MSW, with JSON bodies written as objects so
+// they are easy to edit
+func MswResponse(response MockResponse, indent string) string {
+	init := "{ status: " + strconv.Itoa(response.Status) + ", headers: " + jsLiteral(HeaderMap(response.Headers)) + " }"
+	switch {
+	case response.Body == "":
+		return "new HttpResponse(null, " + init + ")"
+	case response.Base64:
+		return "new HttpResponse(Uint8Array.from(atob(" + jsLiteral(response.Body) + "), (c) => c.charCodeAt(0)), " + init + ")"
+	}
+	if _, ok := ParseJsonBody(response.Body); ok {
+		var indented bytes.Buffer
+		if json.Indent(&indented, []byte(strings.TrimSpace(response.Body)), indent, "  ") == nil {
+			return "HttpResponse.json(" + indented.String() + ", " + init + ")"
+		}
+	}
+	return "new HttpResponse(" + jsLiteral(response.Body) + ", " + init + ")"
+}
+
+// FormatMswHandlers writes a handler for each method and URL, which MSW matches without the query string
+func FormatMswHandlers(file string, entries []Entry) string {
+	keys := make([]string, 0)
+	responses := make(map[string][]MockResponse)
+	for _, entry := range entries {
+		method := strings.ToLower(entry.Request.Method)
+		requestUrl, err := url.Parse(entry.Request.Url)
+		if err != nil || entry.Response.Status == 0 || !slices.Contains(mswMethods, method) {
+			continue
+		}
+		requestUrl.RawQuery = ""
+		requestUrl.Fragment = ""
+		key := method + " " + requestUrl.String()
+		if _, ok := responses[key]; !ok {
+			keys = append(keys, key)
+		}
+		responses[key] = append(responses[key], NewMockResponse(entry))
+	}
+
+	handlers := make([]string, 0, len(keys))
+	for _, key := range keys {
+		method, address, _ := strings.Cut(key, " ")
+		recorded := responses[key]
+		if len(recorded) == 1 {
+			handlers = append(handlers, "  http."+method+"("+jsLiteral(address)+", () => "+MswResponse(recorded[0], "  ")+"),")
+			continue
+		}
+		handler := []string{"  http." + method + "(" + jsLiteral(address) + ", sequence(["}
+		for _, response := range recorded {
+			handler = append(handler, "    () => "+MswResponse(response, "    ")+",")
+		}
+		handlers = append(handlers, strings.Join(append(handler, "  ])),"), "\n"))
+	}
+	return strings.NewReplacer("FILE", Tertiary(file == "-", "stdin", file), "HANDLERS", strings.Join(handlers, "\n")).Replace(mswTemplate)
+}
+
+func (cmd *ExportMswCmd) Run() error {
+	har, err := ReadHarFile(cmd.File)
+	if err != nil {
+		return err
+	}
+	entries, err := SelectEntries(har.Log)
+	if err != nil {
+		return err
+	}
+	return WriteOutput(FormatMswHandlers(cmd.File, entries))
+}
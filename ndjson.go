@@ -0,0 +1,74 @@
+package main
+
+import (
+	"encoding/json"
+	"strconv"
+	"strings"
+
+	"github.com/TylerBrock/colorjson"
+	"github.com/fatih/color"
+)
+
+// ndjsonMimeTypes are the names used for newline delimited JSON, which streaming and bulk APIs return
+var ndjsonMimeTypes = []string{"ndjson", "jsonl", "json-seq"}
+
+// NdjsonLines are the non-empty lines of text, with the record separators of application/json-seq removed
+func NdjsonLines(text string) []string {
+	lines := make([]string, 0)
+	for _, line := range strings.Split(text, "\n") {
+		if line = strings.TrimSpace(strings.TrimPrefix(line, "\x1e")); line != "" {
+			lines = append(lines, line)
+		}
+	}
+	return lines
+}
+
+func IsNdjsonMimeType(mimeType string) bool {
+	lower := strings.ToLower(mimeType)
+	for _, name := range ndjsonMimeTypes {
+		if strings.Contains(lower, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// IsNdjson is whether a body is newline delimited JSON, either by its mime type or because it has several lines which
+// are each a JSON object or array and isn't JSON as a whole
+func IsNdjson(mimeType string, text string) bool {
+	if IsNdjsonMimeType(mimeType) {
+		return true
+	}
+	lines := NdjsonLines(text)
+	if len(lines) < 2 || IsValidJson(text) {
+		return false
+	}
+	for _, line := range lines {
+		if !strings.HasPrefix(line, "{") && !strings.HasPrefix(line, "[") || !IsValidJson(line) {
+			return false
+		}
+	}
+	return true
+}
+
+// FormatNdjson highlights each line as its own JSON value under its index, leaving lines which aren't JSON as they are
+func FormatNdjson(text string) string {
+	formatter := colorjson.NewFormatter()
+	formatter.Indent = 2
+	output := make([]string, 0)
+	for i, line := range NdjsonLines(text) {
+		output = append(output, color.HiBlackString("["+strconv.Itoa(i)+"]"))
+		var value any
+		if err := json.Unmarshal([]byte(line), &value); err != nil {
+			output = append(output, line)
+			continue
+		}
+		processed, err := formatter.Marshal(value)
+		if err != nil {
+			output = append(output, line)
+			continue
+		}
+		output = append(output, string(processed))
+	}
+	return strings.Join(output, "\n")
+}
@@ -0,0 +1,249 @@
+package main
+
+import (
+	"encoding/json"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+type FlowOauthCmd struct {
+	File string `arg:"" help:"The HAR file to parse, or - to read it from stdin" type:"existingfile"`
+}
+
+var oauthTokenParameters = []string{"access_token", "id_token", "refresh_token"}
+
+// OauthFlow tracks what was sent in the authorize request so the later steps can be checked against it
+type OauthFlow struct {
+	*Flow
+	ClientId      string
+	State         string
+	CodeChallenge string
+	// Sites are where the tokens are expected to go, the authorization server and the client itself
+	Sites  map[string]bool
+	Tokens map[string]string
+}
+
+func IsOauthAuthorize(entry Entry) bool {
+	query := UrlQuery(entry.Request.Url)
+	return query.Get("response_type") != "" && query.Get("client_id") != ""
+}
+
+func IsOauthToken(entry Entry) bool {
+	return strings.EqualFold(entry.Request.Method, "POST") && FormValues(entry.Request).Get("grant_type") != ""
+}
+
+func OauthFlowType(responseType string, pkce bool) string {
+	types := strings.Fields(responseType)
+	hasCode := false
+	hasToken := false
+	for _, t := range types {
+		hasCode = hasCode || t == "code"
+		hasToken = hasToken || t == "token" || t == "id_token"
+	}
+	switch {
+	case hasCode && hasToken:
+		return "hybrid"
+	case hasCode && pkce:
+		return "authorization code with PKCE"
+	case hasCode:
+		return "authorization code"
+	case hasToken:
+		return "implicit"
+	}
+	return responseType
+}
+
+// TokensInResponse pulls the tokens out of a token endpoint's JSON response
+func TokensInResponse(entry Entry) map[string]string {
+	tokens := make(map[string]string)
+	if entry.Response.Content == nil {
+		return tokens
+	}
+	text, ok := DecodedBody(*entry.Response.Content)
+	if !ok {
+		return tokens
+	}
+	var body map[string]interface{}
+	if json.Unmarshal([]byte(text), &body) != nil {
+		return tokens
+	}
+	for _, name := range oauthTokenParameters {
+		if value, ok := body[name].(string); ok && value != "" {
+			tokens[value] = name
+		}
+	}
+	return tokens
+}
+
+func BearerToken(request Request) string {
+	authorization := FindHeader(request.Headers, "authorization")
+	if authorization == nil {
+		return ""
+	}
+	scheme, token, found := strings.Cut(authorization.Value, " ")
+	if !found || !strings.EqualFold(scheme, "bearer") {
+		return ""
+	}
+	return strings.TrimSpace(token)
+}
+
+func AnalyzeOauth(entries []Entry, indexes []int) []*Flow {
+	flows := make([]*OauthFlow, 0)
+	var current *OauthFlow
+	findFlow := func(clientId string) *OauthFlow {
+		for i := len(flows) - 1; i >= 0; i-- {
+			if clientId == "" || flows[i].ClientId == clientId {
+				return flows[i]
+			}
+		}
+		return nil
+	}
+
+	for i, entry := range entries {
+		step := FlowStep{Index: indexes[i], Entry: entry}
+		query := UrlQuery(entry.Request.Url)
+		requestUrl, _ := url.Parse(entry.Request.Url)
+		insecure := requestUrl != nil && requestUrl.Scheme == "http" && requestUrl.Hostname() != "localhost"
+
+		for _, name := range oauthTokenParameters {
+			if query.Get(name) != "" && current != nil {
+				current.Add(SeverityError, name+" sent in the URL of "+WithoutQuery(entry.Request.Url)+", it will be kept in logs and history")
+			}
+		}
+
+		switch {
+		case IsOauthAuthorize(entry):
+			flow := &OauthFlow{
+				Flow:          &Flow{},
+				ClientId:      query.Get("client_id"),
+				State:         query.Get("state"),
+				CodeChallenge: query.Get("code_challenge"),
+				Sites:         map[string]bool{SiteOf(entry.Request.Url): true},
+				Tokens:        make(map[string]string),
+			}
+			flow.Name = OauthFlowType(query.Get("response_type"), flow.CodeChallenge != "")
+			flow.Summary = "client " + flow.ClientId
+			if redirect := query.Get("redirect_uri"); redirect != "" {
+				flow.Sites[SiteOf(redirect)] = true
+			}
+			flows = append(flows, flow)
+			current = flow
+
+			step.Kind = "authorize"
+			step.Param("response_type", query.Get("response_type"))
+			step.Param("redirect_uri", query.Get("redirect_uri"))
+			step.Param("scope", query.Get("scope"))
+			step.Param("state", Redact(flow.State))
+			step.Param("code_challenge_method", query.Get("code_challenge_method"))
+
+			if flow.State == "" {
+				flow.Add(SeverityWarning, "authorize request has no state parameter to protect against CSRF")
+			}
+			if flow.Name == "implicit" || flow.Name == "hybrid" {
+				flow.Add(SeverityWarning, "the "+flow.Name+" flow returns tokens in the URL, use the authorization code flow with PKCE instead")
+			}
+			if flow.CodeChallenge != "" && query.Get("code_challenge_method") != "S256" {
+				flow.Add(SeverityWarning, "PKCE code challenge uses the plain method rather than S256")
+			}
+			if flow.CodeChallenge == "" && strings.Contains(flow.Name, "authorization code") {
+				flow.Add(SeverityWarning, "authorization code flow without PKCE")
+			}
+			if strings.Contains(query.Get("scope"), "openid") && query.Get("nonce") == "" && (flow.Name == "implicit" || flow.Name == "hybrid") {
+				flow.Add(SeverityWarning, "OpenID Connect request without a nonce")
+			}
+
+		case query.Get("code") != "" && query.Get("state") != "" || query.Get("error") != "" && query.Get("state") != "":
+			if current == nil {
+				continue
+			}
+			step.Kind = "callback"
+			step.Param("code", Redact(query.Get("code")))
+			step.Param("state", Redact(query.Get("state")))
+			step.Param("error", query.Get("error"))
+			current.Sites[SiteOf(entry.Request.Url)] = true
+			if current.State != "" && query.Get("state") != current.State {
+				current.Add(SeverityError, "callback state does not match the state sent to authorize")
+			}
+
+		case IsOauthToken(entry):
+			form := FormValues(entry.Request)
+			flow := findFlow(form.Get("client_id"))
+			if flow == nil {
+				flow = &OauthFlow{Flow: &Flow{}, ClientId: form.Get("client_id"), Sites: map[string]bool{}, Tokens: make(map[string]string)}
+				flow.Name = form.Get("grant_type") + " grant"
+				flow.Summary = "client " + Tertiary(flow.ClientId != "", flow.ClientId, "unknown")
+				flows = append(flows, flow)
+			}
+			current = flow
+			current.Sites[SiteOf(entry.Request.Url)] = true
+
+			step.Kind = "token"
+			step.Param("grant_type", form.Get("grant_type"))
+			step.Param("code_verifier", Tertiary(form.Get("code_verifier") != "", "present", ""))
+			if form.Get("grant_type") == "authorization_code" && current.CodeChallenge != "" && form.Get("code_verifier") == "" {
+				current.Add(SeverityError, "token request has no code_verifier for the PKCE code challenge")
+			}
+			if query.Get("client_secret") != "" {
+				current.Add(SeverityError, "client_secret sent in the URL of the token request")
+			}
+			for token, name := range TokensInResponse(entry) {
+				current.Tokens[token] = name
+			}
+
+		case strings.Contains(strings.ToLower(entry.Request.Url), "/userinfo"):
+			if current == nil {
+				continue
+			}
+			step.Kind = "userinfo"
+
+		case strings.Contains(entry.Request.Url, "/.well-known/openid-configuration") || strings.Contains(entry.Request.Url, "/.well-known/jwks"):
+			if current == nil {
+				continue
+			}
+			step.Kind = "discovery"
+
+		default:
+			// Requests outside of the flow only matter if they carry one of its tokens somewhere unexpected
+			token := BearerToken(entry.Request)
+			for _, flow := range flows {
+				if name, ok := flow.Tokens[token]; ok && token != "" && !flow.Sites[SiteOf(entry.Request.Url)] {
+					flow.Add(SeverityError, name+" sent to third party "+Origin(entry.Request.Url)+" (entry "+strconv.Itoa(indexes[i])+")")
+				}
+			}
+			if location := FindHeader(entry.Response.Headers, "location"); location != nil && current != nil {
+				if fragment, _ := url.Parse(location.Value); fragment != nil && strings.Contains(fragment.Fragment, "access_token=") {
+					current.Add(SeverityWarning, "access token returned in the fragment of a redirect to "+WithoutQuery(location.Value))
+				}
+			}
+			continue
+		}
+
+		if insecure && current != nil {
+			current.Add(SeverityError, step.Kind+" request made over plain HTTP")
+		}
+		current.Steps = append(current.Steps, step)
+	}
+
+	result := make([]*Flow, len(flows))
+	for i, flow := range flows {
+		result[i] = flow.Flow
+	}
+	return result
+}
+
+func (cmd *FlowOauthCmd) Run() error {
+	har, err := ReadHarFile(cmd.File)
+	if err != nil {
+		return err
+	}
+	indexes, err := SelectEntryIndexes(har.Log)
+	if err != nil {
+		return err
+	}
+	entries := make([]Entry, len(indexes))
+	for i, index := range indexes {
+		entries[i] = har.Log.Entries[index]
+	}
+	return WriteOutput(FormatFlows(AnalyzeOauth(entries, indexes), "OAuth"))
+}
@@ -0,0 +1,399 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type ExportOtlpCmd struct {
+	Endpoint    *string `name:"endpoint" help:"Send the spans to this OTLP/HTTP collector, eg localhost:4318, instead of writing them as OTLP JSON"`
+	ServiceName string  `name:"service-name" default:"browser" help:"The service.name of the spans"`
+	File        string  `arg:"" help:"The HAR file to parse, or - to read it from stdin" type:"existingfile"`
+}
+
+// The span kinds and status codes as OTLP numbers them
+const (
+	otlpKindInternal = 1
+	otlpKindClient   = 3
+	otlpStatusError  = 2
+)
+
+type OtlpValue struct {
+	StringValue *string  `json:"stringValue,omitempty"`
+	IntValue    *string  `json:"intValue,omitempty"`
+	DoubleValue *float64 `json:"doubleValue,omitempty"`
+}
+
+type OtlpAttribute struct {
+	Key   string    `json:"key"`
+	Value OtlpValue `json:"value"`
+}
+
+type OtlpEvent struct {
+	TimeUnixNano string          `json:"timeUnixNano"`
+	Name         string          `json:"name"`
+	Attributes   []OtlpAttribute `json:"attributes,omitempty"`
+}
+
+type OtlpStatus struct {
+	Code    int    `json:"code,omitempty"`
+	Message string `json:"message,omitempty"`
+}
+
+type OtlpSpan struct {
+	TraceId           string          `json:"traceId"`
+	SpanId            string          `json:"spanId"`
+	ParentSpanId      string          `json:"parentSpanId,omitempty"`
+	Name              string          `json:"name"`
+	Kind              int             `json:"kind"`
+	StartTimeUnixNano string          `json:"startTimeUnixNano"`
+	EndTimeUnixNano   string          `json:"endTimeUnixNano"`
+	Attributes        []OtlpAttribute `json:"attributes,omitempty"`
+	Events            []OtlpEvent     `json:"events,omitempty"`
+	Status            OtlpStatus      `json:"status"`
+}
+
+type OtlpScope struct {
+	Name string `json:"name"`
+}
+
+type OtlpScopeSpans struct {
+	Scope OtlpScope  `json:"scope"`
+	Spans []OtlpSpan `json:"spans"`
+}
+
+type OtlpResource struct {
+	Attributes []OtlpAttribute `json:"attributes"`
+}
+
+type OtlpResourceSpans struct {
+	Resource   OtlpResource     `json:"resource"`
+	ScopeSpans []OtlpScopeSpans `json:"scopeSpans"`
+}
+
+// OtlpTraces is the body of an OTLP/HTTP export request, which is also what OTLP JSON files contain
+type OtlpTraces struct {
+	ResourceSpans []OtlpResourceSpans `json:"resourceSpans"`
+}
+
+func stringAttribute(key string, value string) OtlpAttribute {
+	return OtlpAttribute{Key: key, Value: OtlpValue{StringValue: &value}}
+}
+
+func intAttribute(key string, value int64) OtlpAttribute {
+	return OtlpAttribute{Key: key, Value: OtlpValue{IntValue: Pointer(strconv.FormatInt(value, 10))}}
+}
+
+func doubleAttribute(key string, value float64) OtlpAttribute {
+	return OtlpAttribute{Key: key, Value: OtlpValue{DoubleValue: &value}}
+}
+
+func unixNano(t time.Time) string {
+	return strconv.FormatInt(t.UnixNano(), 10)
+}
+
+// otlpId hashes the parts into an ID of size bytes. The IDs are only as stable as the parts they are made from
+func otlpId(size int, parts ...string) string {
+	sum := sha256.Sum256([]byte(strings.Join(parts, "\x00")))
+	return hex.EncodeToString(sum[:size])
+}
+
+// EntrySpanIds hashes the start, method and URL of each entry into its span ID, so exporting the same file twice, or
+// with other entries filtered out, gives the same spans rather than duplicates. Entries which are the same in all three
+// are told apart by how many came before them
+func EntrySpanIds(traceId string, entries []Entry) []string {
+	ids := make([]string, len(entries))
+	seen := make(map[string]int)
+	for i, entry := range entries {
+		key := strings.Join([]string{entry.StartedDateTime, strings.ToUpper(entry.Request.Method), entry.Request.Url}, "\x00")
+		ids[i] = otlpId(8, traceId, key, strconv.Itoa(seen[key]))
+		seen[key]++
+	}
+	return ids
+}
+
+// RedactUrl replaces the credentials and the values of sensitive query parameters in a URL with REDACTED, as the
+// OpenTelemetry conventions ask of url.full
+func RedactUrl(raw string) string {
+	parsed, err := url.Parse(raw)
+	if err != nil {
+		return raw
+	}
+	if parsed.User != nil {
+		parsed.User = url.UserPassword("REDACTED", "REDACTED")
+	}
+	if parsed.RawQuery != "" {
+		pairs := strings.Split(parsed.RawQuery, "&")
+		for i, pair := range pairs {
+			if name, _, ok := strings.Cut(pair, "="); ok && IsSensitiveParameter(DecodeQueryComponent(name)) {
+				pairs[i] = name + "=REDACTED"
+			}
+		}
+		parsed.RawQuery = strings.Join(pairs, "&")
+	}
+	return parsed.String()
+}
+
+func millis(value float64) time.Duration {
+	return time.Duration(value * float64(time.Millisecond))
+}
+
+// EntrySpan converts an entry to a client span using the OpenTelemetry HTTP conventions, with an event at the start of
+// each timing phase and one when the first byte of the response arrived
+func EntrySpan(entry Entry, started time.Time, traceId string, spanId string, parentId string) OtlpSpan {
+	attributes := []OtlpAttribute{stringAttribute("http.request.method", strings.ToUpper(entry.Request.Method)), stringAttribute("url.full", RedactUrl(entry.Request.Url))}
+	name := strings.ToUpper(entry.Request.Method)
+	if requestUrl, err := url.Parse(entry.Request.Url); err == nil {
+		name += " " + TemplatePath(requestUrl.Path)
+		attributes = append(attributes, stringAttribute("url.scheme", requestUrl.Scheme), stringAttribute("server.address", requestUrl.Hostname()))
+		port := requestUrl.Port()
+		if port == "" {
+			port = Tertiary(requestUrl.Scheme == "https", "443", "80")
+		}
+		if number, err := strconv.ParseInt(port, 10, 64); err == nil {
+			attributes = append(attributes, intAttribute("server.port", number))
+		}
+	}
+	if entry.Response.Status > 0 {
+		attributes = append(attributes, intAttribute("http.response.status_code", int64(entry.Response.Status)))
+	}
+	if version, ok := ecsHttpVersions[EntryHttpVersion(entry)]; ok {
+		attributes = append(attributes, stringAttribute("network.protocol.name", "http"), stringAttribute("network.protocol.version", version))
+	}
+	if entry.ServerIP != nil && *entry.ServerIP != "" {
+		attributes = append(attributes, stringAttribute("network.peer.address", strings.Trim(*entry.ServerIP, "[]")))
+	}
+	if size := TransferSize(entry); size >= 0 {
+		attributes = append(attributes, intAttribute("http.response.size", int64(size)))
+	}
+	if agent := HeaderValue(entry.Request.Headers, "user-agent"); agent != "" {
+		attributes = append(attributes, stringAttribute("user_agent.original", agent))
+	}
+	if entry.SecurityDetails != nil && entry.SecurityDetails.Protocol != "" {
+		attributes = append(attributes, stringAttribute("tls.protocol.version", NormalizeTlsVersion(entry.SecurityDetails.Protocol)))
+	}
+	if entry.ResourceType != nil {
+		attributes = append(attributes, stringAttribute("harv.resource_type", *entry.ResourceType))
+	}
+
+	events := make([]OtlpEvent, 0)
+	offset := 0.0
+	for _, phase := range TimingPhases(entry.Timings) {
+		if phase.Value <= 0 {
+			continue
+		}
+		events = append(events, OtlpEvent{
+			TimeUnixNano: unixNano(started.Add(millis(offset))),
+			Name:         Tertiary(phase.Name == "ssl", "tls", phase.Name),
+			Attributes:   []OtlpAttribute{doubleAttribute("duration_ms", phase.Value)},
+		})
+		offset += phase.Value
+		if phase.Name == "wait" {
+			events = append(events, OtlpEvent{TimeUnixNano: unixNano(started.Add(millis(offset))), Name: "first byte", Attributes: []OtlpAttribute{doubleAttribute("ttfb_ms", offset)}})
+		}
+	}
+
+	span := OtlpSpan{
+		TraceId:           traceId,
+		SpanId:            spanId,
+		ParentSpanId:      parentId,
+		Name:              name,
+		Kind:              otlpKindClient,
+		StartTimeUnixNano: unixNano(started),
+		EndTimeUnixNano:   unixNano(started.Add(millis(entry.TimeMs))),
+		Attributes:        attributes,
+		Events:            events,
+	}
+	if IsErrorResponse(entry.Response) {
+		span.Status.Code = otlpStatusError
+		if entry.Response.Status == 0 {
+			span.Attributes = append(span.Attributes, stringAttribute("error.type", "connection"))
+			if entry.Comment != nil {
+				span.Status.Message = *entry.Comment
+			}
+		} else {
+			span.Attributes = append(span.Attributes, stringAttribute("error.type", strconv.Itoa(entry.Response.Status)))
+		}
+	}
+	return span
+}
+
+// PageGroups splits the entries by the page they belong to, in the order the pages were first seen, with the entries
+// which aren't in a page as one more group. Entries without a valid start time are left out
+func PageGroups(entries []Entry) [][]Entry {
+	order := make([]string, 0)
+	grouped := make(map[string][]Entry)
+	for _, entry := range entries {
+		if _, ok := ParseStartedDateTime(entry.StartedDateTime); !ok {
+			continue
+		}
+		reference := ""
+		if entry.PageRef != nil {
+			reference = *entry.PageRef
+		}
+		if _, ok := grouped[reference]; !ok {
+			order = append(order, reference)
+		}
+		grouped[reference] = append(grouped[reference], entry)
+	}
+	groups := make([][]Entry, len(order))
+	for i, reference := range order {
+		groups[i] = grouped[reference]
+	}
+	return groups
+}
+
+// BuildOtlpTraces puts the entries of each page in a trace under a span for the page load, and the entries which
+// aren't in a page in one more trace. The spans of each trace are returned together
+func BuildOtlpTraces(log Log, entries []Entry) [][]OtlpSpan {
+	groups := PageGroups(entries)
+	traces := make([][]OtlpSpan, 0, len(groups))
+	for _, group := range groups {
+		reference := ""
+		if group[0].PageRef != nil {
+			reference = *group[0].PageRef
+		}
+		started := EarliestStart(group)
+		name := "capture"
+		attributes := make([]OtlpAttribute, 0)
+		// A page keeps its trace whichever of its entries are exported
+		identity := started.Format(time.RFC3339Nano)
+		if page, err := FindPage(log, reference); reference != "" && err == nil {
+			name = "page load " + Tertiary(page.Title != "", page.Title, page.Id)
+			attributes = append(attributes, stringAttribute("harv.page.id", page.Id))
+			identity = page.StartedDateTime
+			if pageStarted, ok := ParseStartedDateTime(page.StartedDateTime); ok && pageStarted.Before(started) {
+				started = pageStarted
+			}
+		}
+		finished := started
+		for _, entry := range group {
+			entryStarted, _ := ParseStartedDateTime(entry.StartedDateTime)
+			if entryFinished := entryStarted.Add(millis(entry.TimeMs)); entryFinished.After(finished) {
+				finished = entryFinished
+			}
+		}
+
+		traceId := otlpId(16, reference, identity)
+		rootId := otlpId(8, traceId, "root")
+		spans := []OtlpSpan{{
+			TraceId:           traceId,
+			SpanId:            rootId,
+			Name:              name,
+			Kind:              otlpKindInternal,
+			StartTimeUnixNano: unixNano(started),
+			EndTimeUnixNano:   unixNano(finished),
+			Attributes:        attributes,
+		}}
+		spanIds := EntrySpanIds(traceId, group)
+		for i, entry := range group {
+			entryStarted, _ := ParseStartedDateTime(entry.StartedDateTime)
+			spans = append(spans, EntrySpan(entry, entryStarted, traceId, spanIds[i], rootId))
+		}
+		traces = append(traces, spans)
+	}
+	return traces
+}
+
+func NewOtlpTraces(serviceName string, spans []OtlpSpan) OtlpTraces {
+	return OtlpTraces{ResourceSpans: []OtlpResourceSpans{{
+		Resource:   OtlpResource{Attributes: []OtlpAttribute{stringAttribute("service.name", serviceName)}},
+		ScopeSpans: []OtlpScopeSpans{{Scope: OtlpScope{Name: "harv"}, Spans: spans}},
+	}}}
+}
+
+// OtlpTracesUrl completes a collector address to the URL traces are posted to, which is /v1/traces on port 4318 by
+// default. Port 4317 is refused, collectors take OTLP/gRPC there and harv only sends OTLP/HTTP
+func OtlpTracesUrl(endpoint string) (*url.URL, error) {
+	if !strings.Contains(endpoint, "://") {
+		endpoint = "http://" + endpoint
+	}
+	target, err := url.Parse(endpoint)
+	if err != nil {
+		return nil, err
+	}
+	if target.Host == "" {
+		return nil, fmt.Errorf("%q isn't a collector address, expected eg localhost:4318", endpoint)
+	}
+	if target.Port() == "" {
+		target.Host += ":4318"
+	}
+	if target.Port() == "4317" {
+		return nil, fmt.Errorf("%s is the OTLP/gRPC port, harv sends OTLP/HTTP which collectors accept on 4318, eg %s", target.Host, net.JoinHostPort(target.Hostname(), "4318"))
+	}
+	if target.Path == "" || target.Path == "/" {
+		target.Path = "/v1/traces"
+	}
+	return target, nil
+}
+
+// SendOtlpTraces posts the traces to an OTLP/HTTP collector as JSON
+func SendOtlpTraces(target *url.URL, traces OtlpTraces) error {
+	content, err := json.Marshal(traces)
+	if err != nil {
+		return err
+	}
+	client := &http.Client{Timeout: 30 * time.Second}
+	response, err := client.Post(target.String(), "application/json", bytes.NewReader(content))
+	if err != nil {
+		return fmt.Errorf("failed to send the spans to %s, %w", target, err)
+	}
+	defer response.Body.Close()
+	if response.StatusCode < 200 || response.StatusCode >= 300 {
+		body, _ := io.ReadAll(io.LimitReader(response.Body, 1024))
+		message := strings.TrimSpace(string(body))
+		return errors.New("the collector responded with " + strconv.Itoa(response.StatusCode) + Tertiary(message != "", ", "+message, ""))
+	}
+	return nil
+}
+
+func (cmd *ExportOtlpCmd) Run() error {
+	har, err := ReadHar(cmd.File, false)
+	if err != nil {
+		return err
+	}
+	entries, err := SelectEntries(har.Log)
+	if err != nil {
+		return err
+	}
+	traces := BuildOtlpTraces(har.Log, entries)
+
+	if cmd.Endpoint == nil {
+		spans := make([]OtlpSpan, 0)
+		for _, trace := range traces {
+			spans = append(spans, trace...)
+		}
+		content, err := json.MarshalIndent(NewOtlpTraces(cmd.ServiceName, spans), "", "  ")
+		if err != nil {
+			return err
+		}
+		return WriteOutput(string(content))
+	}
+
+	target, err := OtlpTracesUrl(*cmd.Endpoint)
+	if err != nil {
+		return err
+	}
+	// Each trace is sent on its own so a large capture doesn't go over the collector's request size limit
+	spans := 0
+	for _, trace := range traces {
+		if err := SendOtlpTraces(target, NewOtlpTraces(cmd.ServiceName, trace)); err != nil {
+			return err
+		}
+		spans += len(trace)
+	}
+	fmt.Fprintln(os.Stderr, "Sent "+strconv.Itoa(spans)+" spans in "+strconv.Itoa(len(traces))+Tertiary(len(traces) == 1, " trace", " traces")+" to "+target.String())
+	return nil
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"runtime"
+)
+
+type formatTask struct {
+	entry  Entry
+	result chan string
+}
+
+// OrderedFormatter formats entries on a pool of workers and writes the results in the same order the entries were
+// given to it, however long each one takes to format
+type OrderedFormatter struct {
+	format  func(entry Entry) string
+	write   func(output string) error
+	tasks   chan formatTask
+	pending chan chan string
+	done    chan error
+	err     error
+}
+
+// NewOrderedFormatter starts jobs workers, with 0 meaning one per CPU. With a single job entries are formatted and
+// written inline as they are added
+func NewOrderedFormatter(jobs int, format func(entry Entry) string, write func(output string) error) *OrderedFormatter {
+	if jobs <= 0 {
+		jobs = runtime.NumCPU()
+	}
+	formatter := &OrderedFormatter{format: format, write: write}
+	if jobs == 1 {
+		return formatter
+	}
+
+	formatter.tasks = make(chan formatTask)
+	formatter.pending = make(chan chan string, jobs*4)
+	formatter.done = make(chan error, 1)
+	for i := 0; i < jobs; i++ {
+		go func() {
+			for task := range formatter.tasks {
+				task.result <- format(task.entry)
+			}
+		}()
+	}
+	go func() {
+		var err error
+		for result := range formatter.pending {
+			output := <-result
+			if err == nil {
+				err = write(output)
+			}
+		}
+		formatter.done <- err
+	}()
+	return formatter
+}
+
+func (formatter *OrderedFormatter) Add(entry Entry) {
+	if formatter.tasks == nil {
+		if formatter.err == nil {
+			formatter.err = formatter.write(formatter.format(entry))
+		}
+		return
+	}
+
+	result := make(chan string, 1)
+	formatter.pending <- result
+	formatter.tasks <- formatTask{entry: entry, result: result}
+}
+
+// Close waits for everything added to be written and returns the first error from writing
+func (formatter *OrderedFormatter) Close() error {
+	if formatter.tasks == nil {
+		return formatter.err
+	}
+	close(formatter.tasks)
+	close(formatter.pending)
+	return <-formatter.done
+}
@@ -0,0 +1,201 @@
+package main
+
+import (
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/fatih/color"
+)
+
+type ScanPiiCmd struct {
+	File string `arg:"" help:"The HAR file to scan, or - to read it from stdin" type:"existingfile"`
+}
+
+type PiiPattern struct {
+	Kind    string
+	Pattern *regexp.Regexp
+	// Valid rules out matches which are the right shape but can't be real, such as card numbers failing the Luhn check
+	Valid func(match string) bool
+}
+
+var piiPatterns = []PiiPattern{
+	{Kind: "email", Pattern: emailPattern},
+	// North American numbers are recognised however they're written, other numbers only with their +country code
+	// as without one they can't be told apart from other runs of digits. E.164 numbers have 8 to 15 digits
+	{Kind: "phone number", Pattern: regexp.MustCompile(`(?:\+\d{1,3}[\s.-]?)?\(?\b\d{3}\)?[\s.-]\d{3}[\s.-]\d{4}\b|\+[1-9]\d{0,3}(?:[\s.-]?\(?\d{1,5}\)?){2,6}\b`), Valid: IsPhoneLength},
+	{Kind: "card number", Pattern: regexp.MustCompile(`\b\d(?:[ -]?\d){12,18}\b`), Valid: IsLuhnValid},
+	{Kind: "us ssn", Pattern: regexp.MustCompile(`\b\d{3}-\d{2}-\d{4}\b`), Valid: IsPlausibleSsn},
+	{Kind: "uk ni number", Pattern: regexp.MustCompile(`\b[A-CEGHJ-PR-TW-Z][A-CEGHJ-NPR-TW-Z] ?\d{2} ?\d{2} ?\d{2} ?[A-D]\b`)},
+}
+
+// IsLuhnValid checks the digits of a card number add up, which most long runs of digits like IDs and timestamps won't
+func IsLuhnValid(v string) bool {
+	digits := strings.Map(func(r rune) rune {
+		if r >= '0' && r <= '9' {
+			return r
+		}
+		return -1
+	}, v)
+	if len(digits) < 13 || len(digits) > 19 {
+		return false
+	}
+
+	sum := 0
+	for i := 0; i < len(digits); i++ {
+		digit := int(digits[len(digits)-1-i] - '0')
+		if i%2 == 1 {
+			digit *= 2
+			if digit > 9 {
+				digit -= 9
+			}
+		}
+		sum += digit
+	}
+	return sum%10 == 0
+}
+
+func IsPhoneLength(v string) bool {
+	digits := strings.Count(strings.Map(func(r rune) rune {
+		if r >= '0' && r <= '9' {
+			return 'd'
+		}
+		return -1
+	}, v), "d")
+	return digits >= 8 && digits <= 15
+}
+
+// IsPlausibleSsn rules out the area, group and serial numbers which are never issued
+func IsPlausibleSsn(v string) bool {
+	parts := strings.Split(v, "-")
+	return parts[0] != "000" && parts[0] != "666" && parts[0][0] != '9' && parts[1] != "00" && parts[2] != "0000"
+}
+
+type PiiFinding struct {
+	Kind     string
+	Location string
+	Value    string
+}
+
+func ScanPii(location string, text string) []PiiFinding {
+	findings := make([]PiiFinding, 0)
+	for _, pattern := range piiPatterns {
+		for _, match := range pattern.Pattern.FindAllString(text, -1) {
+			if pattern.Valid == nil || pattern.Valid(match) {
+				findings = append(findings, PiiFinding{Kind: pattern.Kind, Location: location, Value: match})
+			}
+		}
+	}
+	return findings
+}
+
+func ScanEntryPii(entry Entry) []PiiFinding {
+	findings := make([]PiiFinding, 0)
+	// Query strings and form bodies are percent encoded, so bob%40example.com is only an email once decoded
+	for _, parameter := range QueryParameters(entry.Request) {
+		findings = append(findings, ScanPii("query "+DecodeQueryComponent(parameter.Name), DecodeQueryComponent(parameter.Value))...)
+	}
+	if postData := entry.Request.PostData; postData != nil {
+		if fields, ok := FormFields(*postData); ok {
+			for _, field := range fields {
+				// Chrome records the parameters still encoded where Firefox decodes them
+				value := Tertiary(strings.Contains(field.Value, "%"), DecodeQueryComponent(field.Value), field.Value)
+				findings = append(findings, ScanPii("request parameter "+field.Name, value)...)
+			}
+		} else {
+			for _, param := range postData.Params {
+				if param.Value != nil {
+					findings = append(findings, ScanPii("request parameter "+param.Name, *param.Value)...)
+				}
+			}
+			findings = append(findings, ScanPii("request body", postData.Text)...)
+		}
+	}
+	if entry.Response.Content != nil {
+		if text, ok := DecodedBody(*entry.Response.Content); ok {
+			findings = append(findings, ScanPii("response body", text)...)
+		}
+	}
+	return findings
+}
+
+// PiiGroup counts the distinct values of each kind found in each place for one endpoint
+type PiiGroup struct {
+	Endpoint string
+	Entries  int
+	values   map[[2]string]map[string]bool
+	examples map[[2]string]string
+	order    [][2]string
+}
+
+func (group *PiiGroup) Add(finding PiiFinding) {
+	key := [2]string{finding.Kind, finding.Location}
+	if group.values[key] == nil {
+		group.values[key] = make(map[string]bool)
+		group.examples[key] = finding.Value
+		group.order = append(group.order, key)
+	}
+	group.values[key][finding.Value] = true
+}
+
+func GroupPiiByEndpoint(entries []Entry) []*PiiGroup {
+	groups := make([]*PiiGroup, 0)
+	byEndpoint := make(map[string]*PiiGroup)
+	for _, entry := range entries {
+		findings := ScanEntryPii(entry)
+		if len(findings) == 0 {
+			continue
+		}
+		endpoint := Endpoint(entry)
+		group, ok := byEndpoint[endpoint]
+		if !ok {
+			group = &PiiGroup{Endpoint: endpoint, values: make(map[[2]string]map[string]bool), examples: make(map[[2]string]string)}
+			byEndpoint[endpoint] = group
+			groups = append(groups, group)
+		}
+		group.Entries++
+		for _, finding := range findings {
+			group.Add(finding)
+		}
+	}
+
+	sort.SliceStable(groups, func(i, j int) bool {
+		return groups[i].Entries > groups[j].Entries
+	})
+	return groups
+}
+
+func FormatPiiGroups(groups []*PiiGroup, scanned int) string {
+	if len(groups) == 0 {
+		return color.GreenString("No personal data found in %d %s", scanned, Tertiary(scanned == 1, "entry", "entries"))
+	}
+
+	output := make([]string, 0)
+	for _, group := range groups {
+		output = append(output, color.YellowString(group.Endpoint)+color.HiBlackString(" ("+strconv.Itoa(group.Entries)+Tertiary(group.Entries == 1, " entry)", " entries)")))
+		rows := make([][]string, 0, len(group.order))
+		for _, key := range group.order {
+			rows = append(rows, []string{key[0], key[1], strconv.Itoa(len(group.values[key])), Redact(group.examples[key])})
+		}
+		output = append(output, Indent(FormatTable([]Column{
+			{Name: "Type"},
+			{Name: "Location"},
+			{Name: "Values", Right: true},
+			{Name: "Example"},
+		}, rows), 2), "")
+	}
+	return strings.TrimRight(strings.Join(output, "\n"), "\n")
+}
+
+func (cmd *ScanPiiCmd) Run() error {
+	har, err := ReadHarFile(cmd.File)
+	if err != nil {
+		return err
+	}
+	entries, err := SelectEntries(har.Log)
+	if err != nil {
+		return err
+	}
+	return WriteOutput(FormatPiiGroups(GroupPiiByEndpoint(entries), len(entries)))
+}
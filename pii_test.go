@@ -0,0 +1,62 @@
+package main
+
+import "testing"
+
+func piiKinds(findings []PiiFinding) map[string]string {
+	kinds := make(map[string]string)
+	for _, finding := range findings {
+		kinds[finding.Kind+" in "+finding.Location] = finding.Value
+	}
+	return kinds
+}
+
+func TestScanEntryPiiEncoded(t *testing.T) {
+	encodedEmail := "bob%40example.com"
+	cases := []struct {
+		name     string
+		request  Request
+		expected map[string]string
+	}{
+		{
+			"encoded query",
+			Request{Url: "https://example.com/signup?email=bob%40example.com&phone=%2B44%207700%20900123"},
+			map[string]string{"email in query email": "bob@example.com", "phone number in query phone": "+44 7700 900123"},
+		},
+		{
+			"form body without params",
+			Request{Url: "https://example.com/signup", PostData: &PostData{MimeType: "application/x-www-form-urlencoded", Text: "email=bob%40example.com&phone=%2B33+6+12+34+56+78"}},
+			map[string]string{"email in request parameter email": "bob@example.com", "phone number in request parameter phone": "+33 6 12 34 56 78"},
+		},
+		{
+			"form body with encoded params",
+			Request{Url: "https://example.com/signup", PostData: &PostData{MimeType: "application/x-www-form-urlencoded", Params: []PostParameters{{Name: "email", Value: &encodedEmail}}}},
+			map[string]string{"email in request parameter email": "bob@example.com"},
+		},
+	}
+	for _, c := range cases {
+		kinds := piiKinds(ScanEntryPii(Entry{Request: c.request}))
+		for kind, value := range c.expected {
+			if kinds[kind] != value {
+				t.Errorf("%s: %s was %q, expected %q (found %v)", c.name, kind, kinds[kind], value, kinds)
+			}
+		}
+		if len(kinds) != len(c.expected) {
+			t.Errorf("%s: found %v, expected %v", c.name, kinds, c.expected)
+		}
+	}
+}
+
+func TestScanPiiPhoneNumbers(t *testing.T) {
+	for _, text := range []string{"(555) 123-4567", "555.123.4567", "+1 555 123 4567", "+44 7700 900123", "+447700900123", "+49 30 1234567", "+33 6 12 34 56 78"} {
+		if findings := ScanPii("body", text); len(findings) != 1 || findings[0].Kind != "phone number" || findings[0].Value != text {
+			t.Errorf("%q found %+v, expected one phone number", text, findings)
+		}
+	}
+	for _, text := range []string{"2024-03-01T11:00:00.000+01:00", "version 1.2.3", "+1 5", "id 1234567890123456789012"} {
+		for _, finding := range ScanPii("body", text) {
+			if finding.Kind == "phone number" {
+				t.Errorf("%q was reported as the phone number %q", text, finding.Value)
+			}
+		}
+	}
+}
@@ -0,0 +1,113 @@
+package main
+
+import (
+	"encoding/json"
+	"strconv"
+	"strings"
+)
+
+type ExportPlaywrightCmd struct {
+	Format string `name:"format" default:"routes" enum:"routes,har" help:"Write TypeScript page.route() handlers, or a HAR of only the matching entries for page.routeFromHAR()"`
+	File   string `arg:"" help:"The HAR file to parse, or - to read it from stdin" type:"existingfile"`
+}
+
+// playwrightRoutesTemplate serves the recorded responses, which are substituted for RESPONSES, from one route so the
+// order responses to the same request are served in can be tracked
+const playwrightRoutesTemplate = `// Generated by harv from FILE. Call mockRoutes(page) before navigating to serve the recorded responses
+import type { Page } from "@playwright/test";
+
+type MockResponse = { status: number; headers: Record<string, string>; body: string; base64?: boolean };
+
+// Responses to the same method and URL are served in the order they were recorded, repeating the last one
+const responses: Record<string, MockResponse[]> = {
+RESPONSES
+};
+
+const urls = new Set(Object.keys(responses).map((key) => key.slice(key.indexOf(" ") + 1)));
+
+export async function mockRoutes(page: Page): Promise<void> {
+  const served: Record<string, number> = {};
+  await page.route(
+    (url) => urls.has(url.href),
+    async (route) => {
+      const key = route.request().method() + " " + route.request().url();
+      const recorded = responses[key];
+      if (!recorded) {
+        return route.fallback();
+      }
+      const count = served[key] ?? 0;
+      served[key] = count + 1;
+      const response = recorded[Math.min(count, recorded.length - 1)];
+      await route.fulfill({
+        status: response.status,
+        headers: response.headers,
+        body: response.base64 ? Buffer.from(response.body, "base64") : response.body,
+      });
+    },
+  );
+}
+`
+
+// jsLiteral writes a value as JavaScript, which JSON is a subset of since Go escapes U+2028 and U+2029
+func jsLiteral(value any) string {
+	content, err := json.Marshal(value)
+	if err != nil {
+		return "null"
+	}
+	return string(content)
+}
+
+// GroupMockResponses collects the responses to each method and URL in the order they were first requested, leaving out
+// requests which failed as there is nothing to serve for them
+func GroupMockResponses(entries []Entry) ([]string, map[string][]MockResponse) {
+	keys := make([]string, 0)
+	responses := make(map[string][]MockResponse)
+	for _, entry := range entries {
+		if entry.Response.Status == 0 {
+			continue
+		}
+		key := strings.ToUpper(entry.Request.Method) + " " + entry.Request.Url
+		if _, ok := responses[key]; !ok {
+			keys = append(keys, key)
+		}
+		responses[key] = append(responses[key], NewMockResponse(entry))
+	}
+	return keys, responses
+}
+
+func FormatPlaywrightRoutes(file string, entries []Entry) string {
+	keys, responses := GroupMockResponses(entries)
+	lines := make([]string, 0)
+	for _, key := range keys {
+		lines = append(lines, "  "+jsLiteral(key)+": [")
+		for _, response := range responses[key] {
+			fields := "status: " + strconv.Itoa(response.Status) + ", headers: " + jsLiteral(HeaderMap(response.Headers)) + ", body: " + jsLiteral(response.Body)
+			if response.Base64 {
+				fields += ", base64: true"
+			}
+			lines = append(lines, "    { "+fields+" },")
+		}
+		lines = append(lines, "  ],")
+	}
+	return strings.NewReplacer("FILE", Tertiary(file == "-", "stdin", file), "RESPONSES", strings.Join(lines, "\n")).Replace(playwrightRoutesTemplate)
+}
+
+func (cmd *ExportPlaywrightCmd) Run() error {
+	har, err := ReadHarFile(cmd.File)
+	if err != nil {
+		return err
+	}
+	entries, err := SelectEntries(har.Log)
+	if err != nil {
+		return err
+	}
+	if cmd.Format == "har" {
+		har.Log.Entries = entries
+		content, err := MarshalHar(har)
+		if err != nil {
+			return err
+		}
+		return WriteOutput(string(content))
+	}
+	return WriteOutput(FormatPlaywrightRoutes(cmd.File, entries))
+}
@@ -0,0 +1,149 @@
+package main
+
+import (
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+type ExportPromCmd struct {
+	Prefix  string    `name:"prefix" default:"harv" help:"The prefix of the metric names"`
+	Buckets []float64 `name:"buckets" default:"0.005,0.01,0.025,0.05,0.1,0.25,0.5,1,2.5,5,10" help:"The upper bounds in seconds of the duration histogram buckets"`
+	File    string    `arg:"" help:"The HAR file to parse, or - to read it from stdin" type:"existingfile"`
+}
+
+// PromSeries is the totals of one endpoint and status. Failed requests have the status 0
+type PromSeries struct {
+	Method  string
+	Host    string
+	Path    string
+	Status  int
+	Count   int
+	Errors  int
+	Bytes   int
+	Seconds float64
+	// Buckets are how many of the requests took at most each of the bucket bounds
+	Buckets []int
+}
+
+func (series *PromSeries) Labels(status bool) string {
+	labels := []string{
+		"method=" + promLabelValue(series.Method),
+		"host=" + promLabelValue(series.Host),
+		"path=" + promLabelValue(series.Path),
+	}
+	if status {
+		labels = append(labels, "status="+promLabelValue(strconv.Itoa(series.Status)))
+	}
+	return strings.Join(labels, ",")
+}
+
+func promLabelValue(value string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(value) + `"`
+}
+
+func promNumber(value float64) string {
+	return strconv.FormatFloat(value, 'g', -1, 64)
+}
+
+// CollectPromSeries totals the entries by endpoint and status, ordered by endpoint and then status so the output is
+// stable between runs
+func CollectPromSeries(entries []Entry, buckets []float64) []*PromSeries {
+	series := make([]*PromSeries, 0)
+	byKey := make(map[string]*PromSeries)
+	for _, entry := range entries {
+		method, host, path := strings.ToUpper(entry.Request.Method), "", entry.Request.Url
+		if requestUrl, err := url.Parse(entry.Request.Url); err == nil {
+			host, path = strings.ToLower(requestUrl.Host), TemplatePath(requestUrl.Path)
+		}
+		key := method + " " + host + path + " " + strconv.Itoa(entry.Response.Status)
+		current, ok := byKey[key]
+		if !ok {
+			current = &PromSeries{Method: method, Host: host, Path: path, Status: entry.Response.Status, Buckets: make([]int, len(buckets))}
+			byKey[key] = current
+			series = append(series, current)
+		}
+		current.Count++
+		if IsErrorResponse(entry.Response) {
+			current.Errors++
+		}
+		current.Bytes += max(0, TransferSize(entry))
+		seconds := max(0, entry.TimeMs) / 1000
+		current.Seconds += seconds
+		for i, bound := range buckets {
+			if seconds <= bound {
+				current.Buckets[i]++
+			}
+		}
+	}
+	sort.SliceStable(series, func(i, j int) bool {
+		if left, right := series[i].Labels(false), series[j].Labels(false); left != right {
+			return left < right
+		}
+		return series[i].Status < series[j].Status
+	})
+	return series
+}
+
+// FormatProm writes the series in the Prometheus text exposition format. There are no timestamps, as the Pushgateway
+// rejects them, so the metrics take the time they are pushed
+func FormatProm(series []*PromSeries, prefix string, buckets []float64) string {
+	var builder strings.Builder
+	header := func(name string, kind string, help string) string {
+		builder.WriteString("# HELP " + prefix + "_" + name + " " + help + "\n")
+		builder.WriteString("# TYPE " + prefix + "_" + name + " " + kind + "\n")
+		return prefix + "_" + name
+	}
+
+	name := header("requests_total", "counter", "Requests in the capture by endpoint and status.")
+	for _, current := range series {
+		builder.WriteString(name + "{" + current.Labels(true) + "} " + strconv.Itoa(current.Count) + "\n")
+	}
+
+	// The errors and bytes are totalled per endpoint, the series of an endpoint are next to each other
+	byEndpoint := make([]*PromSeries, 0)
+	for _, current := range series {
+		if last := len(byEndpoint) - 1; last >= 0 && byEndpoint[last].Labels(false) == current.Labels(false) {
+			byEndpoint[last].Errors += current.Errors
+			byEndpoint[last].Bytes += current.Bytes
+			continue
+		}
+		copied := *current
+		byEndpoint = append(byEndpoint, &copied)
+	}
+	name = header("request_errors_total", "counter", "Requests which failed or had a 4xx or 5xx status, by endpoint.")
+	for _, current := range byEndpoint {
+		builder.WriteString(name + "{" + current.Labels(false) + "} " + strconv.Itoa(current.Errors) + "\n")
+	}
+	name = header("response_bytes_total", "counter", "Bytes transferred in responses, by endpoint.")
+	for _, current := range byEndpoint {
+		builder.WriteString(name + "{" + current.Labels(false) + "} " + strconv.Itoa(current.Bytes) + "\n")
+	}
+
+	name = header("request_duration_seconds", "histogram", "How long requests took, by endpoint and status.")
+	for _, current := range series {
+		labels := current.Labels(true)
+		for i, bound := range buckets {
+			builder.WriteString(name + "_bucket{" + labels + ",le=" + promLabelValue(promNumber(bound)) + "} " + strconv.Itoa(current.Buckets[i]) + "\n")
+		}
+		builder.WriteString(name + "_bucket{" + labels + `,le="+Inf"} ` + strconv.Itoa(current.Count) + "\n")
+		builder.WriteString(name + "_sum{" + labels + "} " + promNumber(current.Seconds) + "\n")
+		builder.WriteString(name + "_count{" + labels + "} " + strconv.Itoa(current.Count) + "\n")
+	}
+	return strings.TrimSuffix(builder.String(), "\n")
+}
+
+func (cmd *ExportPromCmd) Run() error {
+	har, err := ReadHar(cmd.File, false)
+	if err != nil {
+		return err
+	}
+	entries, err := SelectEntries(har.Log)
+	if err != nil {
+		return err
+	}
+	buckets := append([]float64(nil), cmd.Buckets...)
+	sort.Float64s(buckets)
+	return WriteOutput(FormatProm(CollectPromSeries(entries, buckets), cmd.Prefix, buckets))
+}
@@ -0,0 +1,365 @@
+package main
+
+import (
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// ProtoField is one field of a protobuf message as it is on the wire, Value is set for varints and fixed width fields
+// and Data for length delimited ones
+type ProtoField struct {
+	Number   int
+	WireType int
+	Value    uint64
+	Data     []byte
+}
+
+func readProtoVarint(data []byte) (uint64, int, error) {
+	value, size := binary.Uvarint(data)
+	if size <= 0 {
+		return 0, 0, errors.New("truncated varint")
+	}
+	return value, size, nil
+}
+
+// ParseProtoFields splits a message into its fields without a schema, in the same way as protoc --decode_raw
+func ParseProtoFields(data []byte) ([]ProtoField, error) {
+	fields := make([]ProtoField, 0)
+	for len(data) > 0 {
+		key, size, err := readProtoVarint(data)
+		if err != nil {
+			return nil, err
+		}
+		data = data[size:]
+		field := ProtoField{Number: int(key >> 3), WireType: int(key & 7)}
+		if field.Number <= 0 {
+			return nil, errors.New("invalid field number " + strconv.Itoa(field.Number))
+		}
+		switch field.WireType {
+		case 0:
+			if field.Value, size, err = readProtoVarint(data); err != nil {
+				return nil, err
+			}
+		case 1:
+			if size = 8; len(data) < size {
+				return nil, errors.New("truncated fixed64")
+			}
+			field.Value = binary.LittleEndian.Uint64(data)
+		case 2:
+			length, read, err := readProtoVarint(data)
+			if err != nil {
+				return nil, err
+			}
+			if length > uint64(len(data)-read) {
+				return nil, errors.New("truncated field " + strconv.Itoa(field.Number))
+			}
+			field.Data = data[read : read+int(length)]
+			size = read + int(length)
+		case 5:
+			if size = 4; len(data) < size {
+				return nil, errors.New("truncated fixed32")
+			}
+			field.Value = uint64(binary.LittleEndian.Uint32(data))
+		default:
+			return nil, errors.New("unsupported wire type " + strconv.Itoa(field.WireType))
+		}
+		data = data[size:]
+		fields = append(fields, field)
+	}
+	return fields, nil
+}
+
+// ProtoFieldType is a field from a descriptor, with the type numbers of FieldDescriptorProto.Type
+type ProtoFieldType struct {
+	Name     string
+	Type     int
+	TypeName string
+}
+
+type ProtoMessageType struct {
+	Name   string
+	Fields map[int]ProtoFieldType
+}
+
+// ProtoDescriptors are the messages and the input and output of each method in a FileDescriptorSet
+type ProtoDescriptors struct {
+	Messages map[string]*ProtoMessageType
+	// Methods are keyed by their gRPC path, eg /package.Service/Method
+	Methods map[string][2]string
+}
+
+func protoString(fields []ProtoField, number int) string {
+	for _, field := range fields {
+		if field.Number == number && field.WireType == 2 {
+			return string(field.Data)
+		}
+	}
+	return ""
+}
+
+func protoInt(fields []ProtoField, number int) int {
+	for _, field := range fields {
+		if field.Number == number && field.WireType == 0 {
+			return int(field.Value)
+		}
+	}
+	return 0
+}
+
+func protoMessages(fields []ProtoField, number int) ([][]ProtoField, error) {
+	messages := make([][]ProtoField, 0)
+	for _, field := range fields {
+		if field.Number == number && field.WireType == 2 {
+			message, err := ParseProtoFields(field.Data)
+			if err != nil {
+				return nil, err
+			}
+			messages = append(messages, message)
+		}
+	}
+	return messages, nil
+}
+
+func (descriptors *ProtoDescriptors) addMessage(prefix string, message []ProtoField) error {
+	name := prefix + "." + protoString(message, 1)
+	messageType := &ProtoMessageType{Name: strings.TrimPrefix(name, "."), Fields: make(map[int]ProtoFieldType)}
+	fields, err := protoMessages(message, 2)
+	if err != nil {
+		return err
+	}
+	for _, field := range fields {
+		messageType.Fields[protoInt(field, 3)] = ProtoFieldType{Name: protoString(field, 1), Type: protoInt(field, 5), TypeName: protoString(field, 6)}
+	}
+	descriptors.Messages[name] = messageType
+
+	nested, err := protoMessages(message, 3)
+	if err != nil {
+		return err
+	}
+	for _, child := range nested {
+		if err := descriptors.addMessage(name, child); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ParseProtoDescriptors reads the FileDescriptorSet written by protoc --descriptor_set_out
+func ParseProtoDescriptors(data []byte) (*ProtoDescriptors, error) {
+	descriptors := &ProtoDescriptors{Messages: make(map[string]*ProtoMessageType), Methods: make(map[string][2]string)}
+	set, err := ParseProtoFields(data)
+	if err != nil {
+		return nil, err
+	}
+	files, err := protoMessages(set, 1)
+	if err != nil {
+		return nil, err
+	}
+	for _, file := range files {
+		prefix := ""
+		if pkg := protoString(file, 2); pkg != "" {
+			prefix = "." + pkg
+		}
+		messages, err := protoMessages(file, 4)
+		if err != nil {
+			return nil, err
+		}
+		for _, message := range messages {
+			if err := descriptors.addMessage(prefix, message); err != nil {
+				return nil, err
+			}
+		}
+		services, err := protoMessages(file, 6)
+		if err != nil {
+			return nil, err
+		}
+		for _, service := range services {
+			methods, err := protoMessages(service, 2)
+			if err != nil {
+				return nil, err
+			}
+			for _, method := range methods {
+				path := "/" + strings.TrimPrefix(prefix+"."+protoString(service, 1), ".") + "/" + protoString(method, 1)
+				descriptors.Methods[path] = [2]string{protoString(method, 2), protoString(method, 3)}
+			}
+		}
+	}
+	return descriptors, nil
+}
+
+// protoDescriptors are loaded from --proto-descriptors before the command runs
+var protoDescriptors *ProtoDescriptors
+
+func ConfigureProtoDescriptors() error {
+	if CLI.ProtoDescriptors == nil {
+		return nil
+	}
+	content, err := os.ReadFile(*CLI.ProtoDescriptors)
+	if err != nil {
+		return err
+	}
+	if protoDescriptors, err = ParseProtoDescriptors(content); err != nil {
+		return errors.New("failed to read " + *CLI.ProtoDescriptors + " as a FileDescriptorSet, " + err.Error())
+	}
+	slog.Info("Loaded protobuf descriptors", "path", *CLI.ProtoDescriptors, "messages", len(protoDescriptors.Messages), "methods", len(protoDescriptors.Methods))
+	return nil
+}
+
+// isPrintable is whether bytes are more likely text than a nested message or binary
+func isPrintable(data []byte) bool {
+	if !utf8.Valid(data) {
+		return false
+	}
+	for _, r := range string(data) {
+		if !unicode.IsPrint(r) && !unicode.IsSpace(r) {
+			return false
+		}
+	}
+	return true
+}
+
+func protoNumber(value any) any {
+	switch number := value.(type) {
+	case int64:
+		return json.Number(strconv.FormatInt(number, 10))
+	case uint64:
+		return json.Number(strconv.FormatUint(number, 10))
+	case float64:
+		if math.IsInf(number, 0) || math.IsNaN(number) {
+			return strconv.FormatFloat(number, 'g', -1, 64)
+		}
+		return number
+	}
+	return value
+}
+
+// protoScalar decodes a field with the type from its descriptor, which may be a packed list of them
+func protoScalar(field ProtoField, fieldType ProtoFieldType) []any {
+	if field.WireType == 2 && fieldType.Type != 9 && fieldType.Type != 11 && fieldType.Type != 12 {
+		values := make([]any, 0)
+		data := field.Data
+		for len(data) > 0 {
+			unpacked := ProtoField{Number: field.Number}
+			switch fieldType.Type {
+			case 1, 6, 16:
+				if len(data) < 8 {
+					return values
+				}
+				unpacked.WireType, unpacked.Value, data = 1, binary.LittleEndian.Uint64(data), data[8:]
+			case 2, 7, 15:
+				if len(data) < 4 {
+					return values
+				}
+				unpacked.WireType, unpacked.Value, data = 5, uint64(binary.LittleEndian.Uint32(data)), data[4:]
+			default:
+				value, size, err := readProtoVarint(data)
+				if err != nil {
+					return values
+				}
+				unpacked.Value, data = value, data[size:]
+			}
+			values = append(values, protoScalar(unpacked, fieldType)...)
+		}
+		return values
+	}
+
+	var value any
+	switch fieldType.Type {
+	case 1:
+		value = math.Float64frombits(field.Value)
+	case 2:
+		value = float64(math.Float32frombits(uint32(field.Value)))
+	case 3, 16:
+		value = int64(field.Value)
+	case 5, 14:
+		value = int64(int32(field.Value))
+	case 15:
+		value = int64(int32(uint32(field.Value)))
+	case 8:
+		value = field.Value != 0
+	case 9:
+		value = string(field.Data)
+	case 12:
+		value = base64.StdEncoding.EncodeToString(field.Data)
+	case 17, 18:
+		value = int64(field.Value>>1) ^ -int64(field.Value&1)
+	default:
+		value = field.Value
+	}
+	return []any{protoNumber(value)}
+}
+
+// DecodeProto turns a message into a map for printing as JSON. With its type the fields are named and typed as in the
+// descriptor, otherwise they are keyed by number and length delimited fields are guessed to be text, a nested message
+// or bytes
+func DecodeProto(data []byte, messageType *ProtoMessageType) (map[string]any, error) {
+	fields, err := ParseProtoFields(data)
+	if err != nil {
+		return nil, err
+	}
+	decoded := make(map[string]any)
+	repeated := make(map[string]bool)
+	for _, field := range fields {
+		key := strconv.Itoa(field.Number)
+		values := make([]any, 0, 1)
+		fieldType, known := ProtoFieldType{}, false
+		if messageType != nil {
+			fieldType, known = messageType.Fields[field.Number]
+		}
+
+		switch {
+		case known && fieldType.Type == 11:
+			key = fieldType.Name
+			var nestedType *ProtoMessageType
+			if protoDescriptors != nil {
+				nestedType = protoDescriptors.Messages[fieldType.TypeName]
+			}
+			nested, err := DecodeProto(field.Data, nestedType)
+			if err != nil {
+				values = append(values, base64.StdEncoding.EncodeToString(field.Data))
+			} else {
+				values = append(values, nested)
+			}
+		case known:
+			key = fieldType.Name
+			values = protoScalar(field, fieldType)
+		case field.WireType == 2 && isPrintable(field.Data):
+			values = append(values, string(field.Data))
+		case field.WireType == 2:
+			if nested, err := DecodeProto(field.Data, nil); err == nil && len(field.Data) > 0 {
+				values = append(values, nested)
+			} else {
+				values = append(values, base64.StdEncoding.EncodeToString(field.Data))
+			}
+		default:
+			values = append(values, protoNumber(field.Value))
+		}
+
+		// A field seen more than once is repeated, so it is collected into a list
+		for _, value := range values {
+			existing, ok := decoded[key]
+			switch {
+			case !ok && len(values) == 1:
+				decoded[key] = value
+			case !ok:
+				decoded[key] = []any{value}
+				repeated[key] = true
+			case repeated[key]:
+				decoded[key] = append(existing.([]any), value)
+			default:
+				decoded[key] = []any{existing, value}
+				repeated[key] = true
+			}
+		}
+	}
+	return decoded, nil
+}
@@ -0,0 +1,139 @@
+package main
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// httpVersionOrder is the order the versions are shown in, newest first
+var httpVersionOrder = []string{"h3", "h2", "http/1.1", "http/1.0", "unknown"}
+
+// NormalizeHttpVersion maps the different ways HARs write a version, such as HTTP/2.0 or h3-29, to its ALPN name
+func NormalizeHttpVersion(version string) string {
+	lower := strings.ToLower(strings.TrimSpace(version))
+	switch {
+	case lower == "":
+		return "unknown"
+	case lower == "h3" || strings.HasPrefix(lower, "h3-") || strings.HasPrefix(lower, "http/3") || lower == "quic":
+		return "h3"
+	case lower == "h2" || lower == "h2c" || strings.HasPrefix(lower, "http/2"):
+		return "h2"
+	case lower == "http/1.1":
+		return "http/1.1"
+	case lower == "http/1.0" || lower == "http/1":
+		return "http/1.0"
+	}
+	return lower
+}
+
+// EntryHttpVersion is the version the response was received over, which is the negotiated one in Chrome's HARs
+func EntryHttpVersion(entry Entry) string {
+	if entry.Response.HttpVersion != "" {
+		return NormalizeHttpVersion(entry.Response.HttpVersion)
+	}
+	return NormalizeHttpVersion(entry.Request.HttpVersion)
+}
+
+// MatchesHttpVersion is whether a version from the HAR is the wanted one however either is spelt, or starts with it so
+// that http/1 finds both http/1.0 and http/1.1
+func MatchesHttpVersion(version string, wanted string) bool {
+	if version == "" {
+		return false
+	}
+	normalized := NormalizeHttpVersion(version)
+	lower := strings.ToLower(strings.TrimSpace(wanted))
+	return normalized == NormalizeHttpVersion(wanted) || strings.HasPrefix(normalized, lower) || strings.HasPrefix(strings.ToLower(version), lower)
+}
+
+// FormatProtocolStats counts the requests to each origin by HTTP version, with the share which used h2 or h3
+func FormatProtocolStats(entries []Entry) string {
+	counts := make(map[string]map[string]int)
+	origins := make([]string, 0)
+	seen := make(map[string]bool)
+	for _, entry := range entries {
+		origin := Origin(entry.Request.Url)
+		if counts[origin] == nil {
+			counts[origin] = make(map[string]int)
+			origins = append(origins, origin)
+		}
+		version := EntryHttpVersion(entry)
+		counts[origin][version]++
+		seen[version] = true
+	}
+
+	versions := make([]string, 0)
+	for _, version := range httpVersionOrder {
+		if seen[version] {
+			versions = append(versions, version)
+			delete(seen, version)
+		}
+	}
+	others := make([]string, 0, len(seen))
+	for version := range seen {
+		others = append(others, version)
+	}
+	sort.Strings(others)
+	versions = append(versions, others...)
+
+	total := func(origin string) int {
+		sum := 0
+		for _, count := range counts[origin] {
+			sum += count
+		}
+		return sum
+	}
+	sort.SliceStable(origins, func(i, j int) bool {
+		return total(origins[i]) > total(origins[j])
+	})
+
+	columns := []Column{{Name: "Origin"}}
+	for _, version := range versions {
+		columns = append(columns, Column{Name: version, Right: true})
+	}
+	columns = append(columns, Column{Name: "h2+", Right: true})
+
+	rows := make([][]string, 0, len(origins))
+	for _, origin := range origins {
+		row := []string{origin}
+		for _, version := range versions {
+			row = append(row, Tertiary(counts[origin][version] > 0, strconv.Itoa(counts[origin][version]), "-"))
+		}
+		modern := counts[origin]["h2"] + counts[origin]["h3"]
+		row = append(row, strconv.Itoa(modern*100/total(origin))+"%")
+		rows = append(rows, row)
+	}
+	return FormatTable(columns, rows)
+}
+
+// NormalizeTlsVersion maps TLS 1.3, TLSv1.3 and tls1.3 to the same name so they can be compared
+func NormalizeTlsVersion(version string) string {
+	lower := strings.ToLower(strings.ReplaceAll(version, " ", ""))
+	lower = strings.TrimPrefix(strings.TrimPrefix(lower, "tls"), "v")
+	return lower
+}
+
+// NegotiatedProtocol is the ALPN protocol of the entry followed by its TLS version when the HAR has security details,
+// eg "h2 TLS 1.3"
+func NegotiatedProtocol(entry Entry) string {
+	version := EntryHttpVersion(entry)
+	if entry.SecurityDetails != nil && entry.SecurityDetails.Protocol != "" {
+		return version + " " + entry.SecurityDetails.Protocol
+	}
+	return version
+}
+
+// onelineProtocolWidth fits the longest usual protocol, "h1.1 TLS1.3", so --oneline columns stay lined up
+const onelineProtocolWidth = 11
+
+// OnelineProtocol is NegotiatedProtocol abbreviated to the fixed width --oneline column, eg "h1.1 TLS1.2"
+func OnelineProtocol(entry Entry) string {
+	protocol := strings.Replace(EntryHttpVersion(entry), "http/", "h", 1)
+	if protocol == "unknown" {
+		protocol = "-"
+	}
+	if entry.SecurityDetails != nil && entry.SecurityDetails.Protocol != "" {
+		protocol += " " + strings.Replace(entry.SecurityDetails.Protocol, " ", "", 1)
+	}
+	return MiddleEllipsis(protocol, onelineProtocolWidth)
+}
@@ -0,0 +1,36 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/fatih/color"
+)
+
+func TestOnelineProtocolWidth(t *testing.T) {
+	color.NoColor = true
+	printProtocol := true
+	CLI.PrintProtocol = &printProtocol
+	defer func() {
+		CLI.PrintProtocol = nil
+	}()
+
+	entries := []Entry{
+		{Request: Request{Method: "GET", Url: "https://a.example/"}, Response: Response{Status: 200, HttpVersion: "http/1.1"}, SecurityDetails: &SecurityDetails{Protocol: "TLS 1.0"}},
+		{Request: Request{Method: "GET", Url: "https://b.example/"}, Response: Response{Status: 200, HttpVersion: "h3"}, SecurityDetails: &SecurityDetails{Protocol: "QUIC"}},
+		{Request: Request{Method: "GET", Url: "https://c.example/"}, Response: Response{Status: 200, HttpVersion: "h2"}},
+		{Request: Request{Method: "GET", Url: "https://d.example/"}, Response: Response{Status: 200, HttpVersion: "SPDY/3.1-experimental"}, SecurityDetails: &SecurityDetails{Protocol: "TLS 1.3"}},
+	}
+	column := -1
+	for _, entry := range entries {
+		line := FormatOneline(entry)
+		if start := VisibleLength(line[:strings.Index(line, "https://")]); column == -1 {
+			column = start
+		} else if start != column {
+			t.Errorf("the URL starts at column %d, expected %d:\n%s", start, column, line)
+		}
+	}
+	if protocol := OnelineProtocol(entries[0]); protocol != "h1.1 TLS1.0" {
+		t.Errorf("protocol is %q, expected h1.1 TLS1.0", protocol)
+	}
+}
@@ -0,0 +1,205 @@
+package main
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// knownProviderRanges are the published ranges of the largest CDNs and clouds, which cover most third party requests.
+// They are not complete, --providers adds more and --geoip falls back to the ASN of the address
+var knownProviderRanges = []struct {
+	Cidr     string
+	Provider string
+}{
+	{"173.245.48.0/20", "Cloudflare"},
+	{"103.21.244.0/22", "Cloudflare"},
+	{"103.22.200.0/22", "Cloudflare"},
+	{"103.31.4.0/22", "Cloudflare"},
+	{"141.101.64.0/18", "Cloudflare"},
+	{"108.162.192.0/18", "Cloudflare"},
+	{"190.93.240.0/20", "Cloudflare"},
+	{"188.114.96.0/20", "Cloudflare"},
+	{"197.234.240.0/22", "Cloudflare"},
+	{"198.41.128.0/17", "Cloudflare"},
+	{"162.158.0.0/15", "Cloudflare"},
+	{"104.16.0.0/13", "Cloudflare"},
+	{"104.24.0.0/14", "Cloudflare"},
+	{"172.64.0.0/13", "Cloudflare"},
+	{"131.0.72.0/22", "Cloudflare"},
+	{"2400:cb00::/32", "Cloudflare"},
+	{"2606:4700::/32", "Cloudflare"},
+	{"2803:f800::/32", "Cloudflare"},
+	{"2405:b500::/32", "Cloudflare"},
+	{"2405:8100::/32", "Cloudflare"},
+	{"2a06:98c0::/29", "Cloudflare"},
+	{"2c0f:f248::/32", "Cloudflare"},
+	{"151.101.0.0/16", "Fastly"},
+	{"199.232.0.0/16", "Fastly"},
+	{"146.75.0.0/17", "Fastly"},
+	{"23.235.32.0/20", "Fastly"},
+	{"2a04:4e40::/32", "Fastly"},
+	{"2a04:4e42::/32", "Fastly"},
+	{"13.32.0.0/15", "Amazon CloudFront"},
+	{"13.224.0.0/14", "Amazon CloudFront"},
+	{"18.64.0.0/14", "Amazon CloudFront"},
+	{"52.84.0.0/15", "Amazon CloudFront"},
+	{"54.230.0.0/16", "Amazon CloudFront"},
+	{"54.239.128.0/18", "Amazon CloudFront"},
+	{"99.84.0.0/16", "Amazon CloudFront"},
+	{"143.204.0.0/16", "Amazon CloudFront"},
+	{"205.251.192.0/19", "Amazon CloudFront"},
+	{"2600:9000::/28", "Amazon CloudFront"},
+	{"3.0.0.0/9", "Amazon Web Services"},
+	{"18.128.0.0/9", "Amazon Web Services"},
+	{"52.0.0.0/10", "Amazon Web Services"},
+	{"54.64.0.0/11", "Amazon Web Services"},
+	{"142.250.0.0/15", "Google"},
+	{"172.217.0.0/16", "Google"},
+	{"216.58.192.0/19", "Google"},
+	{"172.253.0.0/16", "Google"},
+	{"74.125.0.0/16", "Google"},
+	{"2607:f8b0::/32", "Google"},
+	{"2a00:1450::/32", "Google"},
+	{"34.64.0.0/10", "Google Cloud"},
+	{"35.184.0.0/13", "Google Cloud"},
+	{"23.0.0.0/12", "Akamai"},
+	{"23.192.0.0/11", "Akamai"},
+	{"2.16.0.0/13", "Akamai"},
+	{"104.64.0.0/10", "Akamai"},
+	{"184.24.0.0/13", "Akamai"},
+	{"2600:1400::/24", "Akamai"},
+	{"13.64.0.0/11", "Microsoft Azure"},
+	{"20.33.0.0/16", "Microsoft Azure"},
+	{"40.64.0.0/10", "Microsoft Azure"},
+	{"13.107.0.0/16", "Microsoft"},
+	{"157.240.0.0/16", "Meta"},
+	{"31.13.24.0/21", "Meta"},
+	{"2a03:2880::/32", "Meta"},
+	{"192.229.128.0/17", "Edgio"},
+	{"93.184.216.0/24", "Edgio"},
+}
+
+type ProviderRange struct {
+	Network  *net.IPNet
+	Provider string
+}
+
+// providerRanges are the ranges from --providers, which are checked before builtInProviderRanges
+var providerRanges []ProviderRange
+var builtInProviderRanges []ProviderRange
+
+// providerCache is shared by the --jobs workers like the GeoIP cache
+var providerCache = struct {
+	sync.Mutex
+	providers map[string]string
+}{providers: make(map[string]string)}
+
+// ReadProviderRanges reads a CSV of cidr,provider lines, skipping a header line and # comments
+func ReadProviderRanges(reader io.Reader) ([]ProviderRange, error) {
+	records := csv.NewReader(reader)
+	records.Comment = '#'
+	records.FieldsPerRecord = -1
+	ranges := make([]ProviderRange, 0)
+	for line := 1; ; line++ {
+		record, err := records.Read()
+		if err == io.EOF {
+			return ranges, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		if len(record) < 2 {
+			return nil, fmt.Errorf("line %d: expected cidr,provider", line)
+		}
+		_, network, err := net.ParseCIDR(strings.TrimSpace(record[0]))
+		if err != nil {
+			if line == 1 {
+				continue
+			}
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		ranges = append(ranges, ProviderRange{Network: network, Provider: strings.TrimSpace(record[1])})
+	}
+}
+
+func ConfigureProviders() error {
+	if CLI.Providers != nil {
+		file, err := os.Open(*CLI.Providers)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		ranges, err := ReadProviderRanges(file)
+		if err != nil {
+			return fmt.Errorf("reading %s: %w", *CLI.Providers, err)
+		}
+		slog.Info("Loaded provider ranges", "path", *CLI.Providers, "ranges", len(ranges))
+		providerRanges = ranges
+	}
+	for _, known := range knownProviderRanges {
+		_, network, err := net.ParseCIDR(known.Cidr)
+		if err != nil {
+			return err
+		}
+		builtInProviderRanges = append(builtInProviderRanges, ProviderRange{Network: network, Provider: known.Provider})
+	}
+	return nil
+}
+
+// MostSpecificProvider returns the provider of the longest prefix in ranges which contains ip
+func MostSpecificProvider(ranges []ProviderRange, ip net.IP) string {
+	provider := ""
+	bestPrefix := -1
+	for _, known := range ranges {
+		if prefix, _ := known.Network.Mask.Size(); known.Network.Contains(ip) && prefix > bestPrefix {
+			provider = known.Provider
+			bestPrefix = prefix
+		}
+	}
+	return provider
+}
+
+// LookupProvider checks the --providers ranges, then the built in ones, then the ASN organisation from --geoip, and
+// otherwise returns an empty string
+func LookupProvider(address string) string {
+	address = strings.Trim(address, "[]")
+	ip := net.ParseIP(address)
+	if ip == nil {
+		return ""
+	}
+	providerCache.Lock()
+	if provider, ok := providerCache.providers[address]; ok {
+		providerCache.Unlock()
+		return provider
+	}
+	providerCache.Unlock()
+
+	provider := MostSpecificProvider(providerRanges, ip)
+	if provider == "" {
+		provider = MostSpecificProvider(builtInProviderRanges, ip)
+	}
+	if provider == "" {
+		if location := LookupGeoIp(address); location != nil && location.Asn != 0 {
+			provider = strings.TrimSpace(location.Organization + " (AS" + strconv.FormatUint(location.Asn, 10) + ")")
+		}
+	}
+
+	providerCache.Lock()
+	providerCache.providers[address] = provider
+	providerCache.Unlock()
+	return provider
+}
+
+func EntryProvider(entry Entry) string {
+	if entry.ServerIP == nil {
+		return ""
+	}
+	return LookupProvider(*entry.ServerIP)
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"context"
+	"log/slog"
+	"net"
+	"strings"
+	"sync"
+	"time"
+)
+
+type ptrLookup struct {
+	once sync.Once
+	name string
+}
+
+// ptrCache makes sure each address is only looked up once, even when several --jobs workers want it at the same time
+var ptrCache = struct {
+	sync.Mutex
+	lookups map[string]*ptrLookup
+}{lookups: make(map[string]*ptrLookup)}
+
+// ReverseDns returns the PTR name of address, or an empty string if it has none or the lookup timed out
+func ReverseDns(address string, timeout time.Duration) string {
+	address = strings.Trim(address, "[]")
+	if net.ParseIP(address) == nil {
+		return ""
+	}
+	ptrCache.Lock()
+	lookup, ok := ptrCache.lookups[address]
+	if !ok {
+		lookup = &ptrLookup{}
+		ptrCache.lookups[address] = lookup
+	}
+	ptrCache.Unlock()
+
+	lookup.once.Do(func() {
+		ctx, cancel := context.WithTimeout(context.Background(), timeout)
+		defer cancel()
+		names, err := net.DefaultResolver.LookupAddr(ctx, address)
+		if err != nil || len(names) == 0 {
+			slog.Debug("No PTR record", "address", address, "error", err)
+			return
+		}
+		lookup.name = strings.TrimSuffix(names[0], ".")
+	})
+	return lookup.name
+}
+
+// EntryPtrName is the reverse DNS name of the server address when --resolve-ips was given
+func EntryPtrName(entry Entry) string {
+	if CLI.ResolveIps == nil || !*CLI.ResolveIps || entry.ServerIP == nil {
+		return ""
+	}
+	return ReverseDns(*entry.ServerIP, time.Duration(CLI.ResolveTimeoutMs)*time.Millisecond)
+}
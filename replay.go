@@ -0,0 +1,622 @@
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/cookiejar"
+	"net/http/httptrace"
+	"net/url"
+	"os"
+	"os/signal"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/fatih/color"
+)
+
+type ReplayCmd struct {
+	Rps       float64       `name:"rps" help:"Send at most this many requests a second across all workers, by default as fast as the workers can"`
+	Duration  time.Duration `name:"duration" help:"Stop after this long, eg 30s or 5m. With --loop the entries are sent until then, otherwise the replay stops early if it hasn't finished"`
+	Workers   int           `name:"workers" default:"1" help:"How many requests to have in flight at once"`
+	Loop      bool          `name:"loop" help:"Start again from the first entry after the last, until --duration has passed or the replay is interrupted"`
+	TimeoutMs int           `name:"timeout-ms" default:"30000" help:"How long to wait for each response in milliseconds"`
+
+	SetHeader   []string `name:"set-header" help:"Send a header in place of the recorded one, eg 'Authorization: Bearer $TOKEN'. Environment variables in the value are substituted"`
+	StripHeader []string `name:"strip-header" help:"Don't send the recorded header with this name"`
+	CookieJar   *string  `name:"cookie-jar" help:"Send the cookies in this Netscape cookie file, as written by curl -c or export cookies, in place of the recorded ones"`
+
+	BaseUrl *string `name:"base-url" help:"Send the requests to this server instead, eg https://staging.example.com, keeping their paths and queries"`
+	Record  *string `name:"record" help:"Write the requests which were sent and their responses, with fresh timings, to this HAR file"`
+
+	File string `arg:"" help:"The HAR file to parse, or - to read it from stdin" type:"existingfile"`
+}
+
+// ExpandEnvironment substitutes $NAME and ${NAME} in a value, failing on variables which aren't set rather than
+// sending an empty credential
+func ExpandEnvironment(value string) (string, error) {
+	missing := make([]string, 0)
+	expanded := os.Expand(value, func(name string) string {
+		if name == "$" {
+			return "$"
+		}
+		variable, ok := os.LookupEnv(name)
+		if !ok {
+			missing = append(missing, name)
+		}
+		return variable
+	})
+	if len(missing) > 0 {
+		return "", errors.New("the environment variable " + strings.Join(missing, ", ") + " used in '" + value + "' is not set")
+	}
+	return expanded, nil
+}
+
+// ParseHeaderOverride reads a header given as 'Name: value' on the command line
+func ParseHeaderOverride(value string) (Header, error) {
+	name, headerValue, ok := strings.Cut(value, ":")
+	if !ok || strings.TrimSpace(name) == "" {
+		return Header{}, errors.New("headers should be given as 'Name: value', not '" + value + "'")
+	}
+	expanded, err := ExpandEnvironment(strings.TrimSpace(headerValue))
+	if err != nil {
+		return Header{}, err
+	}
+	return Header{Name: strings.TrimSpace(name), Value: expanded}, nil
+}
+
+// NewReplayCookieJar loads cookies read from a cookie file into a jar, which also keeps the cookies the responses set
+// during the replay
+func NewReplayCookieJar(cookies []JarCookie) (http.CookieJar, error) {
+	jar, err := cookiejar.New(nil)
+	if err != nil {
+		return nil, err
+	}
+	for _, cookie := range cookies {
+		if !cookie.Expires.IsZero() && cookie.Expires.Before(time.Now()) {
+			continue
+		}
+		cookieUrl := &url.URL{Scheme: Tertiary(cookie.Secure, "https", "http"), Host: cookie.Domain, Path: cookie.Path}
+		jar.SetCookies(cookieUrl, []*http.Cookie{{
+			Name:  cookie.Name,
+			Value: cookie.Value,
+			Path:  cookie.Path,
+			// Cookies without a domain are only sent to the host which set them
+			Domain:   Tertiary(cookie.HostOnly, "", cookie.Domain),
+			Expires:  cookie.Expires,
+			Secure:   cookie.Secure,
+			HttpOnly: cookie.HttpOnly,
+		}})
+	}
+	return jar, nil
+}
+
+// RebaseUrl points a recorded URL at another server, keeping its path and query under the path of the base URL
+func RebaseUrl(recorded string, base *url.URL) (string, error) {
+	requestUrl, err := url.Parse(recorded)
+	if err != nil {
+		return "", err
+	}
+	requestUrl.Scheme = base.Scheme
+	requestUrl.Host = base.Host
+	requestUrl.User = base.User
+	if prefix := strings.TrimSuffix(base.Path, "/"); prefix != "" {
+		if requestUrl.RawPath != "" {
+			requestUrl.RawPath = strings.TrimSuffix(base.EscapedPath(), "/") + requestUrl.RawPath
+		}
+		requestUrl.Path = prefix + requestUrl.Path
+	}
+	return requestUrl.String(), nil
+}
+
+// Replayer sends entries again, with the changes to their headers and server asked for on the command line
+type Replayer struct {
+	Client       *http.Client
+	SetHeaders   []Header
+	StripHeaders []string
+	BaseUrl      *url.URL
+	// Record keeps the request and response of each result as an entry
+	Record bool
+}
+
+func NewReplayer(timeout time.Duration) *Replayer {
+	return &Replayer{Client: &http.Client{
+		Timeout: timeout,
+		// Redirects are replayed as they were recorded, the entry they led to is in the capture too
+		CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse },
+	}}
+}
+
+// NewRequest rebuilds the request of an entry, with the headers a client sets itself left for Go to set
+func (replayer *Replayer) NewRequest(ctx context.Context, entry Entry) (*http.Request, error) {
+	var body io.Reader
+	if entry.Request.PostData != nil {
+		text := entry.Request.PostData.Text
+		if fields, ok := FormFields(*entry.Request.PostData); ok && text == "" {
+			pairs := make([]string, len(fields))
+			for i, field := range fields {
+				pairs[i] = url.QueryEscape(field.Name) + "=" + url.QueryEscape(field.Value)
+			}
+			text = strings.Join(pairs, "&")
+		}
+		body = strings.NewReader(text)
+	}
+	requestUrl := entry.Request.Url
+	if replayer.BaseUrl != nil {
+		var err error
+		if requestUrl, err = RebaseUrl(requestUrl, replayer.BaseUrl); err != nil {
+			return nil, err
+		}
+	}
+	request, err := http.NewRequestWithContext(ctx, strings.ToUpper(entry.Request.Method), requestUrl, body)
+	if err != nil {
+		return nil, err
+	}
+	for _, header := range ReplayHeaders(entry.Request, false) {
+		// Go only decompresses the body itself if it chose the encodings
+		if strings.EqualFold(header.Name, "accept-encoding") || slices.ContainsFunc(replayer.StripHeaders, func(name string) bool {
+			return strings.EqualFold(name, header.Name)
+		}) {
+			continue
+		}
+		// The jar's cookies are added by the client, and would be sent alongside the recorded ones
+		if replayer.Client.Jar != nil && strings.EqualFold(header.Name, "cookie") {
+			continue
+		}
+		request.Header.Add(header.Name, header.Value)
+	}
+	for _, header := range replayer.SetHeaders {
+		request.Header.Del(header.Name)
+	}
+	for _, header := range replayer.SetHeaders {
+		request.Header.Add(header.Name, header.Value)
+	}
+	return request, nil
+}
+
+// ReplayResult is the outcome of sending one entry again
+type ReplayResult struct {
+	Endpoint  string
+	Status    int
+	LatencyMs float64
+	Err       error
+	// Entry is the request as it was sent and the response to it, when the replay is recorded
+	Entry *Entry
+}
+
+// replayTrace collects when each phase of a request happened, to record its timings as a browser would
+type replayTrace struct {
+	lock                                                                            sync.Mutex
+	dnsStart, dnsDone, connectStart, connectDone, tlsStart, tlsDone, gotConn, wrote time.Time
+	firstByte                                                                       time.Time
+	serverIp, connection                                                            string
+}
+
+func (trace *replayTrace) mark(at *time.Time, first bool) {
+	trace.lock.Lock()
+	defer trace.lock.Unlock()
+	// Dialing may try several addresses, so a phase runs from when it first started until it last finished
+	if !first || at.IsZero() {
+		*at = time.Now()
+	}
+}
+
+func (trace *replayTrace) ClientTrace() *httptrace.ClientTrace {
+	return &httptrace.ClientTrace{
+		DNSStart:          func(httptrace.DNSStartInfo) { trace.mark(&trace.dnsStart, true) },
+		DNSDone:           func(httptrace.DNSDoneInfo) { trace.mark(&trace.dnsDone, false) },
+		ConnectStart:      func(string, string) { trace.mark(&trace.connectStart, true) },
+		ConnectDone:       func(string, string, error) { trace.mark(&trace.connectDone, false) },
+		TLSHandshakeStart: func() { trace.mark(&trace.tlsStart, true) },
+		TLSHandshakeDone:  func(tls.ConnectionState, error) { trace.mark(&trace.tlsDone, false) },
+		GotConn: func(info httptrace.GotConnInfo) {
+			trace.mark(&trace.gotConn, false)
+			trace.lock.Lock()
+			defer trace.lock.Unlock()
+			trace.serverIp, _, _ = net.SplitHostPort(info.Conn.RemoteAddr().String())
+			// The local address is unique to each connection, so requests which reused one share its ID
+			_, trace.connection, _ = net.SplitHostPort(info.Conn.LocalAddr().String())
+		},
+		WroteRequest:         func(httptrace.WroteRequestInfo) { trace.mark(&trace.wrote, false) },
+		GotFirstResponseByte: func() { trace.mark(&trace.firstByte, false) },
+	}
+}
+
+// Timings splits the time from started to finished into the phases of a HAR entry, with -1 for the phases which
+// didn't happen, like DNS and connecting on a reused connection
+func (trace *replayTrace) Timings(started time.Time, finished time.Time) EntryTimings {
+	trace.lock.Lock()
+	defer trace.lock.Unlock()
+	between := func(from time.Time, to time.Time) float64 {
+		if from.IsZero() || to.IsZero() || to.Before(from) {
+			return 0
+		}
+		return float64(to.Sub(from)) / float64(time.Millisecond)
+	}
+	phase := func(from time.Time, to time.Time) *float64 {
+		if from.IsZero() || to.IsZero() {
+			return Pointer(float64(Unknown))
+		}
+		return Pointer(between(from, to))
+	}
+	// Connecting includes the TLS handshake in a HAR
+	connected := Tertiary(trace.tlsDone.IsZero(), trace.connectDone, trace.tlsDone)
+	waited := trace.gotConn
+	for _, at := range []time.Time{trace.dnsStart, trace.connectStart} {
+		if !at.IsZero() && (waited.IsZero() || at.Before(waited)) {
+			waited = at
+		}
+	}
+	return EntryTimings{
+		Blocked: Pointer(between(started, waited)),
+		Dns:     phase(trace.dnsStart, trace.dnsDone),
+		Connect: phase(trace.connectStart, connected),
+		Ssl:     phase(trace.tlsStart, trace.tlsDone),
+		Send:    between(trace.gotConn, trace.wrote),
+		Wait:    between(trace.wrote, trace.firstByte),
+		Receive: between(trace.firstByte, finished),
+	}
+}
+
+// NewRecordedEntry is an entry for a request which was replayed, keeping the page and initiator of the entry it was
+// sent from. Requests which failed are recorded with a status of 0 and the error as the comment, as browsers do
+func NewRecordedEntry(recorded Entry, request *http.Request, response *http.Response, body []byte, trace *replayTrace, started time.Time, finished time.Time, err error) Entry {
+	headers := func(values http.Header, host string) []Header {
+		list := make([]Header, 0, len(values)+1)
+		if host != "" {
+			list = append(list, Header{Name: "Host", Value: host})
+		}
+		names := make([]string, 0, len(values))
+		for name := range values {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			for _, value := range values[name] {
+				list = append(list, Header{Name: name, Value: value})
+			}
+		}
+		return list
+	}
+	entry := Entry{
+		PageRef:         recorded.PageRef,
+		StartedDateTime: started.UTC().Format(time.RFC3339Nano),
+		Request: Request{
+			Method:      request.Method,
+			Url:         request.URL.String(),
+			HttpVersion: "HTTP/1.1",
+			Cookies:     HarCookies(request.Cookies()),
+			Headers:     headers(request.Header, request.Host),
+			QueryString: make([]QueryParameter, 0),
+			PostData:    recorded.Request.PostData,
+			HeadersSize: Unknown,
+			BodySize:    recorded.Request.BodySize,
+		},
+		Response: Response{
+			Cookies:     make([]Cookie, 0),
+			Headers:     make([]Header, 0),
+			Content:     &Content{MimeType: "x-unknown"},
+			HeadersSize: Unknown,
+			BodySize:    Unknown,
+		},
+		Timings:      trace.Timings(started, finished),
+		Initiator:    recorded.Initiator,
+		ResourceType: recorded.ResourceType,
+	}
+	for name, values := range request.URL.Query() {
+		for _, value := range values {
+			entry.Request.QueryString = append(entry.Request.QueryString, QueryParameter{Name: name, Value: value})
+		}
+	}
+	sort.SliceStable(entry.Request.QueryString, func(i, j int) bool {
+		return entry.Request.QueryString[i].Name < entry.Request.QueryString[j].Name
+	})
+	if trace.serverIp != "" {
+		entry.ServerIP = Pointer(trace.serverIp)
+		entry.Connection = Pointer(trace.connection)
+	}
+
+	if err != nil {
+		entry.Comment = Pointer(err.Error())
+	} else {
+		entry.Request.HttpVersion = response.Proto
+		entry.Response.Status = response.StatusCode
+		entry.Response.StatusText = strings.TrimSpace(strings.TrimPrefix(response.Status, strconv.Itoa(response.StatusCode)))
+		entry.Response.HttpVersion = response.Proto
+		entry.Response.Cookies = HarCookies(response.Cookies())
+		entry.Response.Headers = headers(response.Header, "")
+		if location := response.Header.Get("Location"); location != "" {
+			entry.Response.RedirectUrl = Pointer(location)
+		}
+		entry.Response.Content = &Content{Size: len(body), MimeType: Tertiary(response.Header.Get("Content-Type") == "", "x-unknown", response.Header.Get("Content-Type"))}
+		if !response.Uncompressed {
+			// Go decompresses the body itself when it asked for it compressed, so its size on the wire isn't known
+			entry.Response.BodySize = len(body)
+		}
+		if len(body) > 0 {
+			if utf8.Valid(body) {
+				entry.Response.Content.Text = Pointer(string(body))
+			} else {
+				entry.Response.Content.Text = Pointer(base64.StdEncoding.EncodeToString(body))
+				entry.Response.Content.Encoding = Pointer("base64")
+			}
+		}
+	}
+	timings := entry.Timings
+	entry.TimeMs = timings.Send + timings.Wait + timings.Receive
+	for _, phase := range []*float64{timings.Blocked, timings.Dns, timings.Connect} {
+		entry.TimeMs += max(*phase, 0)
+	}
+	return entry
+}
+
+// Replay sends the entries with workers goroutines, paced to rps when it is above zero, until they have all been sent or
+// ctx is done. With loop they are sent over and over until ctx is done
+func (replayer *Replayer) Replay(ctx context.Context, entries []Entry, workers int, rps float64, loop bool) []ReplayResult {
+	jobs := make(chan Entry)
+	go func() {
+		defer close(jobs)
+		var tick <-chan time.Time
+		if rps > 0 {
+			// Above a billion a second the interval rounds down to nothing, which the ticker won't take
+			ticker := time.NewTicker(max(time.Duration(float64(time.Second)/rps), time.Nanosecond))
+			defer ticker.Stop()
+			tick = ticker.C
+		}
+		for {
+			for _, entry := range entries {
+				if tick != nil {
+					select {
+					case <-tick:
+					case <-ctx.Done():
+						return
+					}
+				}
+				select {
+				case jobs <- entry:
+				case <-ctx.Done():
+					return
+				}
+			}
+			if !loop || len(entries) == 0 {
+				return
+			}
+		}
+	}()
+
+	var lock sync.Mutex
+	results := make([]ReplayResult, 0, len(entries))
+	var wait sync.WaitGroup
+	for i := 0; i < max(workers, 1); i++ {
+		wait.Add(1)
+		go func() {
+			defer wait.Done()
+			for entry := range jobs {
+				result := ReplayResult{Endpoint: Endpoint(entry)}
+				trace := &replayTrace{}
+				started := time.Now()
+				request, err := replayer.NewRequest(httptrace.WithClientTrace(ctx, trace.ClientTrace()), entry)
+				if err != nil {
+					result.Err = err
+				} else {
+					// With --base-url the results are for the server the request was sent to
+					sent := entry
+					sent.Request.Url = request.URL.String()
+					result.Endpoint = Endpoint(sent)
+					var response *http.Response
+					var body []byte
+					if response, err = replayer.Client.Do(request); err == nil {
+						// The latency includes reading the body, as the time of an entry does
+						if replayer.Record {
+							body, err = io.ReadAll(response.Body)
+						} else {
+							_, err = io.Copy(io.Discard, response.Body)
+						}
+						response.Body.Close()
+						result.Status = response.StatusCode
+					}
+					if err != nil && ctx.Err() != nil {
+						// Requests cut off by the end of the run say nothing about the server
+						continue
+					}
+					result.Err = err
+					if replayer.Record {
+						recorded := NewRecordedEntry(entry, request, response, body, trace, started, time.Now(), err)
+						result.Entry = &recorded
+					}
+				}
+				result.LatencyMs = float64(time.Since(started)) / float64(time.Millisecond)
+				lock.Lock()
+				results = append(results, result)
+				lock.Unlock()
+			}
+		}()
+	}
+	wait.Wait()
+	return results
+}
+
+// FormatReplayResults summarises the latency percentiles, error rate and status codes overall and for each endpoint
+func FormatReplayResults(results []ReplayResult, elapsed time.Duration) string {
+	if len(results) == 0 {
+		return color.YellowString("No requests were sent")
+	}
+	type summary struct {
+		name      string
+		latencies []float64
+		errors    int
+		statuses  map[int]int
+	}
+	total := &summary{name: "All", statuses: make(map[int]int)}
+	byEndpoint := make(map[string]*summary)
+	order := make([]*summary, 0)
+	errorsSeen := make(map[string]int)
+	for _, result := range results {
+		endpoint, ok := byEndpoint[result.Endpoint]
+		if !ok {
+			endpoint = &summary{name: result.Endpoint, statuses: make(map[int]int)}
+			byEndpoint[result.Endpoint] = endpoint
+			order = append(order, endpoint)
+		}
+		for _, group := range []*summary{total, endpoint} {
+			group.latencies = append(group.latencies, result.LatencyMs)
+			if result.Err != nil || result.Status >= 500 {
+				group.errors++
+			}
+			if result.Err == nil {
+				group.statuses[result.Status]++
+			}
+		}
+		if result.Err != nil {
+			errorsSeen[result.Err.Error()]++
+		}
+	}
+	sort.SliceStable(order, func(i, j int) bool {
+		return len(order[i].latencies) > len(order[j].latencies)
+	})
+
+	row := func(group *summary) []string {
+		sort.Float64s(group.latencies)
+		statuses := make([]int, 0, len(group.statuses))
+		for status := range group.statuses {
+			statuses = append(statuses, status)
+		}
+		sort.Ints(statuses)
+		counts := make([]string, len(statuses))
+		for i, status := range statuses {
+			counts[i] = StatusColor(status)(strconv.Itoa(status)) + " (" + strconv.Itoa(group.statuses[status]) + ")"
+		}
+		rate := float64(group.errors) / float64(len(group.latencies)) * 100
+		return []string{
+			group.name,
+			strconv.Itoa(len(group.latencies)),
+			Tertiary(group.errors > 0, color.RedString(strconv.FormatFloat(rate, 'f', 1, 64)+"%"), "0%"),
+			FormatDuration(Percentile(group.latencies, 50)),
+			FormatDuration(Percentile(group.latencies, 90)),
+			FormatDuration(Percentile(group.latencies, 95)),
+			FormatDuration(Percentile(group.latencies, 99)),
+			FormatDuration(group.latencies[len(group.latencies)-1]),
+			strings.Join(counts, ", "),
+		}
+	}
+	rows := [][]string{row(total)}
+	for _, endpoint := range order {
+		rows = append(rows, row(endpoint))
+	}
+
+	output := []string{
+		color.HiBlackString("Sent ") + strconv.Itoa(len(results)) + color.HiBlackString(" requests in ") + FormatDuration(float64(elapsed)/float64(time.Millisecond)) +
+			color.HiBlackString(", ") + strconv.FormatFloat(float64(len(results))/elapsed.Seconds(), 'f', 1, 64) + color.HiBlackString(" a second"),
+		"",
+		FormatTable([]Column{
+			{Name: "Endpoint"},
+			{Name: "Requests", Right: true},
+			{Name: "Errors", Right: true},
+			{Name: "p50", Right: true},
+			{Name: "p90", Right: true},
+			{Name: "p95", Right: true},
+			{Name: "p99", Right: true},
+			{Name: "Max", Right: true},
+			{Name: "Statuses"},
+		}, rows),
+	}
+	if len(errorsSeen) > 0 {
+		messages := make([]string, 0, len(errorsSeen))
+		for message := range errorsSeen {
+			messages = append(messages, message)
+		}
+		sort.Slice(messages, func(i, j int) bool { return errorsSeen[messages[i]] > errorsSeen[messages[j]] })
+		output = append(output, "", color.YellowString("Errors:"))
+		for _, message := range messages {
+			output = append(output, "  "+color.RedString(strconv.Itoa(errorsSeen[message]))+" "+message)
+		}
+	}
+	return strings.Join(output, "\n")
+}
+
+func (cmd *ReplayCmd) Run() error {
+	har, err := ReadHarFile(cmd.File)
+	if err != nil {
+		return err
+	}
+	entries, err := SelectEntries(har.Log)
+	if err != nil {
+		return err
+	}
+	if cmd.Rps < 0 {
+		return errors.New("the rate should be a number of requests a second above 0, not " + strconv.FormatFloat(cmd.Rps, 'g', -1, 64))
+	}
+	replayer := NewReplayer(time.Duration(cmd.TimeoutMs) * time.Millisecond)
+	replayer.StripHeaders = cmd.StripHeader
+	for _, value := range cmd.SetHeader {
+		header, err := ParseHeaderOverride(value)
+		if err != nil {
+			return err
+		}
+		replayer.SetHeaders = append(replayer.SetHeaders, header)
+	}
+	if cmd.BaseUrl != nil {
+		base, err := url.Parse(*cmd.BaseUrl)
+		if err != nil || base.Scheme == "" || base.Host == "" {
+			return errors.New("the base URL should be an absolute URL like https://staging.example.com, not '" + *cmd.BaseUrl + "'")
+		}
+		replayer.BaseUrl = base
+	}
+	replayer.Record = cmd.Record != nil
+	if cmd.CookieJar != nil {
+		content, err := os.ReadFile(*cmd.CookieJar)
+		if err != nil {
+			return err
+		}
+		cookies, err := ParseNetscapeCookies(string(content))
+		if err != nil {
+			return err
+		}
+		if replayer.Client.Jar, err = NewReplayCookieJar(cookies); err != nil {
+			return err
+		}
+	}
+	if cmd.Loop && cmd.Duration <= 0 {
+		fmt.Fprintln(os.Stderr, color.HiBlackString("Looping until interrupted, press Ctrl+C to stop and see the results"))
+	}
+
+	// Interrupting the replay stops it early but still reports what was sent
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	if cmd.Duration > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, cmd.Duration)
+		defer cancel()
+	}
+	started := time.Now()
+	results := replayer.Replay(ctx, entries, cmd.Workers, cmd.Rps, cmd.Loop)
+	if cmd.Record != nil {
+		har.Log.Entries = make([]Entry, 0, len(results))
+		for _, result := range results {
+			if result.Entry != nil {
+				har.Log.Entries = append(har.Log.Entries, *result.Entry)
+			}
+		}
+		// The workers finish requests out of order, so sort them back into the order they were sent
+		sort.SliceStable(har.Log.Entries, func(i, j int) bool {
+			left, _ := ParseStartedDateTime(har.Log.Entries[i].StartedDateTime)
+			right, _ := ParseStartedDateTime(har.Log.Entries[j].StartedDateTime)
+			return left.Before(right)
+		})
+		if err := WriteHarFile(*cmd.Record, har); err != nil {
+			return err
+		}
+		fmt.Fprintln(os.Stderr, "Recorded "+strconv.Itoa(len(har.Log.Entries))+" entries to "+*cmd.Record)
+	}
+	return WriteOutput(FormatReplayResults(results, time.Since(started)))
+}
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/fatih/color"
+)
+
+func replayEntries(server *httptest.Server, paths ...string) []Entry {
+	entries := make([]Entry, len(paths))
+	for i, path := range paths {
+		entries[i] = Entry{Request: Request{Method: "GET", Url: server.URL + path}}
+	}
+	return entries
+}
+
+func TestReplayPacesToRps(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+
+	started := time.Now()
+	results := NewReplayer(time.Second).Replay(context.Background(), replayEntries(server, "/a", "/b", "/c", "/d", "/e"), 4, 50, false)
+	if len(results) != 5 {
+		t.Fatalf("sent %d requests, expected 5", len(results))
+	}
+	// Each request waits for a tick, so five at 50 a second take at least 100ms however many workers there are
+	if elapsed := time.Since(started); elapsed < 90*time.Millisecond {
+		t.Errorf("sent 5 requests in %s, faster than 50 a second", elapsed)
+	}
+}
+
+func TestReplayRpsAboveTickerResolution(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+
+	if results := NewReplayer(time.Second).Replay(context.Background(), replayEntries(server, "/a"), 1, 1e12, false); len(results) != 1 {
+		t.Errorf("sent %d requests, expected 1", len(results))
+	}
+}
+
+func TestReplayLoopsUntilDuration(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+	started := time.Now()
+	results := NewReplayer(time.Second).Replay(ctx, replayEntries(server, "/a", "/b"), 2, 100, true)
+	if elapsed := time.Since(started); elapsed > time.Second {
+		t.Errorf("the loop ran for %s after its duration", elapsed)
+	}
+	if len(results) <= 2 {
+		t.Errorf("sent %d requests, expected the entries to be sent more than once", len(results))
+	}
+	for _, result := range results {
+		// Requests cut off when the duration passed aren't counted as errors
+		if result.Err != nil || result.Status != http.StatusOK {
+			t.Errorf("%s failed with %d %v", result.Endpoint, result.Status, result.Err)
+		}
+	}
+}
+
+func TestReplayErrorRate(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/fail" {
+			w.WriteHeader(http.StatusInternalServerError)
+		} else if r.URL.Path == "/missing" {
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	results := NewReplayer(time.Second).Replay(context.Background(), replayEntries(server, "/ok", "/fail", "/missing", "/ok"), 1, 0, false)
+	results = append(results, ReplayResult{Endpoint: "GET unreachable.invalid/", Err: errors.New("no such host")})
+
+	color.NoColor = true
+	output := FormatReplayResults(results, time.Second)
+	lines := strings.Split(output, "\n")
+	total := ""
+	for _, line := range lines {
+		if strings.HasPrefix(strings.TrimSpace(line), "All ") {
+			total = line
+		}
+	}
+	// A 500 and a failed request are errors out of five, a 404 is an answer from the server
+	if !strings.Contains(total, "40.0%") {
+		t.Errorf("expected an error rate of 40%% overall, got %q", total)
+	}
+	for _, expected := range []string{"200 (2)", "404 (1)", "500 (1)", "1 no such host"} {
+		if !strings.Contains(output, expected) {
+			t.Errorf("expected %q in\n%s", expected, output)
+		}
+	}
+}
@@ -0,0 +1,206 @@
+package main
+
+import (
+	"encoding/json"
+	"html"
+	"math"
+	"net/url"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type ReportCmd struct {
+	WaterfallOnly bool   `name:"waterfall-only" help:"Leave out the summary and the table of domains, writing only the waterfall"`
+	File          string `arg:"" help:"The HAR file to parse, or - to read it from stdin" type:"existingfile"`
+}
+
+// WaterfallData is everything the page needs to draw the waterfall. Rows are arrays rather than objects and the URLs
+// have no query, which keeps the page small for large captures and leaves tokens in query strings out of it
+type WaterfallData struct {
+	// Entries are [start offset ms, method, url, status, mime, bytes, [blocked, dns, connect, ssl, send, wait, receive]]
+	// followed by the entry's comment when it has one
+	Entries [][]any `json:"entries"`
+	// Marks are [label, offset ms] for when each page's DOMContentLoaded and load events fired
+	Marks [][]any `json:"marks"`
+}
+
+func roundTenth(ms float64) float64 {
+	return math.Round(max(0, ms)*10) / 10
+}
+
+func BuildWaterfallData(log Log, entries []Entry) WaterfallData {
+	data := WaterfallData{Entries: make([][]any, 0, len(entries)), Marks: make([][]any, 0)}
+	first := EarliestStart(entries)
+	for _, entry := range entries {
+		started, ok := ParseStartedDateTime(entry.StartedDateTime)
+		if !ok {
+			continue
+		}
+		phases := make([]float64, 0, 7)
+		for _, phase := range TimingPhases(entry.Timings) {
+			phases = append(phases, roundTenth(phase.Value))
+		}
+		address := entry.Request.Url
+		if requestUrl, err := url.Parse(entry.Request.Url); err == nil {
+			requestUrl.RawQuery, requestUrl.Fragment = "", ""
+			address = requestUrl.String()
+		}
+		row := []any{
+			roundTenth(float64(started.Sub(first)) / float64(time.Millisecond)),
+			strings.ToUpper(entry.Request.Method),
+			address,
+			entry.Response.Status,
+			MimeType(entry),
+			TransferSize(entry),
+			phases,
+		}
+		if entry.Comment != nil && *entry.Comment != "" {
+			row = append(row, *entry.Comment)
+		}
+		data.Entries = append(data.Entries, row)
+	}
+	if log.Pages != nil {
+		for _, page := range *log.Pages {
+			started, ok := ParseStartedDateTime(page.StartedDateTime)
+			if !ok || first.IsZero() {
+				continue
+			}
+			offset := float64(started.Sub(first)) / float64(time.Millisecond)
+			name := Tertiary(len(*log.Pages) > 1, page.Id+" ", "")
+			if page.PageTimings.ContentLoad != nil && *page.PageTimings.ContentLoad >= 0 {
+				data.Marks = append(data.Marks, []any{name + "DOMContentLoaded", roundTenth(offset + *page.PageTimings.ContentLoad)})
+			}
+			if page.PageTimings.Load != nil && *page.PageTimings.Load >= 0 {
+				data.Marks = append(data.Marks, []any{name + "load", roundTenth(offset + *page.PageTimings.Load)})
+			}
+		}
+	}
+	return data
+}
+
+// FormatReportSummary is the totals of the report and a table of the domains, the same as --group-by domain
+func FormatReportSummary(total int, entries []Entry) string {
+	summary := Summary{Total: total}
+	for _, entry := range entries {
+		summary.Add(entry)
+	}
+	text := "Found " + strconv.Itoa(summary.Matched) + " of " + strconv.Itoa(summary.Total) + " entries, " + FormatBytes(summary.Transferred) + " transferred"
+	if !summary.First.IsZero() {
+		text += " over " + FormatDuration(float64(summary.Last.Sub(summary.First))/float64(time.Millisecond))
+	}
+	text += ", " + strconv.Itoa(summary.Failed) + Tertiary(summary.Failed == 1, " error", " errors")
+
+	lines := []string{
+		"<p>" + html.EscapeString(text) + "</p>",
+		"<table>",
+		"<tr><th>Domain</th><th>Count</th><th>Bytes</th><th>p50</th><th>p95</th></tr>",
+	}
+	for _, group := range AggregateEntries(entries, func(entry Entry) string { return GroupKey(entry, "domain") }) {
+		lines = append(lines, "<tr><td>"+html.EscapeString(group.Key)+"</td><td>"+strconv.Itoa(group.Count)+"</td><td>"+
+			FormatBytes(group.Bytes)+"</td><td>"+FormatDuration(Percentile(group.Durations, 50))+"</td><td>"+
+			FormatDuration(Percentile(group.Durations, 95))+"</td></tr>")
+	}
+	return strings.Join(append(lines, "</table>"), "\n")
+}
+
+const reportTemplate = `<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>TITLE</title>
+<style>
+body { font: 13px -apple-system, "Segoe UI", Helvetica, sans-serif; margin: 16px; color: #222; }
+table { border-collapse: collapse; margin-bottom: 16px; }
+th, td { padding: 2px 10px; text-align: right; border-bottom: 1px solid #eee; }
+th:first-child, td:first-child { text-align: left; }
+#legend span { display: inline-block; margin-right: 12px; }
+#legend i { display: inline-block; width: 10px; height: 10px; margin-right: 4px; vertical-align: middle; }
+#chart { position: relative; margin-top: 8px; }
+.row { display: flex; height: 16px; line-height: 16px; }
+.row:nth-child(even) { background: #f6f6f6; }
+.label { width: 40%; flex: none; overflow: hidden; white-space: nowrap; text-overflow: ellipsis; padding-right: 8px; box-sizing: border-box; }
+.error { color: #c00; }
+.comment { color: #a0a; font-weight: bold; }
+.bar { position: relative; flex: 1; }
+.bar i { position: absolute; top: 3px; height: 10px; min-width: 1px; }
+.axis { color: #888; height: 18px; }
+.axis span { position: absolute; transform: translateX(-50%); }
+.mark { position: absolute; top: 0; bottom: 0; width: 0; border-left: 1px dashed; pointer-events: none; }
+</style>
+</head>
+<body>
+<h1>TITLE</h1>
+SUMMARY
+<div id="legend"></div>
+<div id="chart"></div>
+<script>
+const data = DATA;
+const phases = [["blocked", "#b0b0b0"], ["dns", "#1fb8c0"], ["connect", "#f0a030"], ["ssl", "#c040c0"], ["send", "#40b040"], ["wait", "#3c7ce0"], ["receive", "#e04848"]];
+const markColors = {DOMContentLoaded: "#3c7ce0", load: "#e04848"};
+const escape = text => String(text).replace(/[&<>"]/g, c => ({"&": "&amp;", "<": "&lt;", ">": "&gt;", '"': "&quot;"})[c]);
+const duration = ms => ms === 0 ? "0" : ms < 1000 ? ms.toFixed(ms < 10 ? 1 : 0) + " ms" : (ms / 1000).toFixed(1) + " s";
+
+let total = 1;
+for (const entry of data.entries) total = Math.max(total, entry[0] + entry[6].reduce((a, b) => a + b, 0));
+for (const mark of data.marks) total = Math.max(total, mark[1]);
+const percent = ms => (ms / total * 100).toFixed(3) + "%";
+const across = ms => "calc(40% + 60% * " + (ms / total).toFixed(5) + ")";
+
+document.getElementById("legend").innerHTML = phases.map(([name, color]) => '<span><i style="background:' + color + '"></i>' + name + "</span>").join("") +
+  data.marks.map(mark => '<span><i style="background:' + (markColors[mark[0].split(" ").pop()] || "#888") + '"></i>' + escape(mark[0]) + " " + duration(mark[1]) + "</span>").join("");
+
+let step = Math.pow(10, Math.floor(Math.log10(total / 8)));
+step *= total / step > 40 ? 5 : total / step > 16 ? 2 : 1;
+let axis = '<div class="row axis"><div class="label"></div><div class="bar">';
+for (let tick = 0; tick <= total; tick += step) axis += '<span style="left:' + percent(tick) + '">' + duration(tick) + "</span>";
+const rows = [axis + "</div></div>"];
+
+for (const [start, method, url, status, mime, bytes, timings, comment] of data.entries) {
+  let offset = start, segments = "", detail = [];
+  timings.forEach((value, i) => {
+    if (value <= 0) return;
+    segments += '<i style="left:' + percent(offset) + ";width:" + percent(value) + ";background:" + phases[i][1] + '"></i>';
+    detail.push(phases[i][0] + " " + duration(value));
+    offset += value;
+  });
+  const title = escape(method + " " + url + "\n" + status + " " + mime + ", " + bytes + " bytes, started at " + duration(start) + "\n" + detail.join(", ") + (comment ? "\n" + comment : ""));
+  const label = '<span class="' + (status === 0 || status >= 400 ? "error" : "") + '">' + (status || "failed") + "</span> " + escape(method + " " + url) +
+    (comment ? ' <span class="comment">// ' + escape(comment) + "</span>" : "");
+  rows.push('<div class="row" title="' + title + '"><div class="label">' + label + '</div><div class="bar">' + segments + "</div></div>");
+}
+for (const [name, at] of data.marks) rows.push('<div class="mark" title="' + escape(name) + '" style="left:' + across(at) + ";color:" + (markColors[name.split(" ").pop()] || "#888") + '"></div>');
+document.getElementById("chart").innerHTML = rows.join("");
+</script>
+</body>
+</html>`
+
+func FormatReport(file string, data WaterfallData, summary string) (string, error) {
+	content, err := json.Marshal(data)
+	if err != nil {
+		return "", err
+	}
+	title := Tertiary(file == "-", "stdin", filepath.Base(file))
+	return strings.NewReplacer("TITLE", html.EscapeString(title), "SUMMARY", summary, "DATA", string(content)).Replace(reportTemplate), nil
+}
+
+func (cmd *ReportCmd) Run() error {
+	har, err := ReadHar(cmd.File, false)
+	if err != nil {
+		return err
+	}
+	entries, err := SelectEntries(har.Log)
+	if err != nil {
+		return err
+	}
+	summary := ""
+	if !cmd.WaterfallOnly {
+		summary = FormatReportSummary(len(har.Log.Entries), entries)
+	}
+	output, err := FormatReport(cmd.File, BuildWaterfallData(har.Log, entries), summary)
+	if err != nil {
+		return err
+	}
+	return WriteOutput(output)
+}
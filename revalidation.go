@@ -0,0 +1,168 @@
+package main
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/fatih/color"
+)
+
+type AuditRevalidationCmd struct {
+	File string `arg:"" help:"The HAR file to parse, or - to read it from stdin" type:"existingfile"`
+}
+
+// Revalidation counts how an asset was fetched, Redownloaded is the full responses which could have been a 304
+type Revalidation struct {
+	Url          string
+	Requests     int
+	Conditional  int
+	NotModified  int
+	Redownloaded int
+	Wasted       int
+	AuditGroup
+	etags map[string]bool
+}
+
+func (revalidation *Revalidation) Efficiency() float64 {
+	if revalidation.Conditional == 0 {
+		return 0
+	}
+	return float64(revalidation.NotModified) / float64(revalidation.Conditional)
+}
+
+// NormalizeEtag drops the weak prefix, as If-None-Match uses the weak comparison
+func NormalizeEtag(etag string) string {
+	return strings.TrimPrefix(strings.TrimSpace(etag), "W/")
+}
+
+func EtagMatches(ifNoneMatch string, etag string) bool {
+	for _, candidate := range strings.Split(ifNoneMatch, ",") {
+		if strings.TrimSpace(candidate) == "*" || NormalizeEtag(candidate) == NormalizeEtag(etag) {
+			return true
+		}
+	}
+	return false
+}
+
+func HeaderValue(headers []Header, name string) string {
+	if header := FindHeader(headers, name); header != nil {
+		return header.Value
+	}
+	return ""
+}
+
+// AnalyzeRevalidation pairs the validators sent with each request to the response, counting 304s against full
+// responses and flagging full responses whose ETag shows the client already had the same content
+func AnalyzeRevalidation(entries []Entry) []*Revalidation {
+	assets := make([]*Revalidation, 0)
+	byUrl := make(map[string]*Revalidation)
+	for _, entry := range entries {
+		if !strings.EqualFold(entry.Request.Method, "GET") || entry.FromCache != nil {
+			continue
+		}
+		asset, ok := byUrl[entry.Request.Url]
+		if !ok {
+			asset = &Revalidation{Url: entry.Request.Url, etags: make(map[string]bool)}
+			byUrl[entry.Request.Url] = asset
+			assets = append(assets, asset)
+		}
+		asset.Requests++
+
+		ifNoneMatch := HeaderValue(entry.Request.Headers, "if-none-match")
+		ifModifiedSince := HeaderValue(entry.Request.Headers, "if-modified-since")
+		etag := HeaderValue(entry.Response.Headers, "etag")
+		conditional := ifNoneMatch != "" || ifModifiedSince != ""
+		if conditional {
+			asset.Conditional++
+		}
+
+		switch {
+		case entry.Response.Status == 304:
+			asset.NotModified++
+		case entry.Response.Status == 200 && conditional && ifNoneMatch != "" && etag != "" && EtagMatches(ifNoneMatch, etag):
+			asset.Redownloaded++
+			asset.Wasted += TransferSize(entry)
+			asset.Add(SeverityError, "re-downloaded with an unchanged ETag "+etag+" despite If-None-Match, the server ignores validators")
+		case entry.Response.Status == 200 && !conditional && etag != "" && asset.etags[NormalizeEtag(etag)]:
+			asset.Redownloaded++
+			asset.Wasted += TransferSize(entry)
+			asset.Add(SeverityWarning, "re-downloaded with an unchanged ETag "+etag+" without sending If-None-Match, check its Cache-Control")
+		}
+		if etag != "" {
+			asset.etags[NormalizeEtag(etag)] = true
+		}
+	}
+
+	assets = Filter(assets, func(asset *Revalidation) bool {
+		return asset.Conditional > 0 || asset.Redownloaded > 0
+	})
+	sort.SliceStable(assets, func(i, j int) bool {
+		return assets[i].Wasted > assets[j].Wasted
+	})
+	return assets
+}
+
+func FormatRevalidation(assets []*Revalidation) string {
+	if len(assets) == 0 {
+		return color.HiBlackString("No conditional or repeated requests found")
+	}
+
+	rows := make([][]string, 0, len(assets))
+	conditional := 0
+	notModified := 0
+	wasted := 0
+	for _, asset := range assets {
+		conditional += asset.Conditional
+		notModified += asset.NotModified
+		wasted += asset.Wasted
+		rows = append(rows, []string{
+			asset.Url,
+			strconv.Itoa(asset.Requests),
+			strconv.Itoa(asset.Conditional),
+			strconv.Itoa(asset.NotModified),
+			strconv.Itoa(asset.Redownloaded),
+			FormatBytes(asset.Wasted),
+			Tertiary(asset.Conditional > 0, strconv.Itoa(int(asset.Efficiency()*100))+"%", "-"),
+		})
+	}
+	output := []string{FormatTable([]Column{
+		{Name: "Asset"},
+		{Name: "Requests", Right: true},
+		{Name: "Conditional", Right: true},
+		{Name: "304", Right: true},
+		{Name: "Re-downloaded", Right: true},
+		{Name: "Wasted", Right: true},
+		{Name: "Efficiency", Right: true},
+	}, rows)}
+
+	for _, asset := range assets {
+		if len(asset.Findings) == 0 {
+			continue
+		}
+		output = append(output, "", color.YellowString(asset.Url))
+		for _, finding := range asset.Findings {
+			output = append(output, "  "+FormatFinding(finding))
+		}
+	}
+
+	efficiency := "-"
+	if conditional > 0 {
+		efficiency = strconv.Itoa(notModified*100/conditional) + "%"
+	}
+	output = append(output, "", color.HiBlackString("%d of %d conditional requests were answered with 304 (%s), %s re-downloaded unnecessarily",
+		notModified, conditional, efficiency, FormatBytes(wasted)))
+	return strings.Join(output, "\n")
+}
+
+func (cmd *AuditRevalidationCmd) Run() error {
+	har, err := ReadHar(cmd.File, false)
+	if err != nil {
+		return err
+	}
+	validEntries, err := SelectEntries(har.Log)
+	if err != nil {
+		return err
+	}
+	return WriteOutput(FormatRevalidation(AnalyzeRevalidation(validEntries)))
+}
@@ -0,0 +1,300 @@
+package main
+
+import (
+	"bytes"
+	"compress/flate"
+	"encoding/base64"
+	"encoding/xml"
+	"errors"
+	"io"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/fatih/color"
+)
+
+type FlowSamlCmd struct {
+	NoXml bool   `name:"no-xml" help:"Only show the sequence of messages, not the decoded XML"`
+	File  string `arg:"" help:"The HAR file to parse, or - to read it from stdin" type:"existingfile"`
+}
+
+// SamlMessage is a SAMLRequest or SAMLResponse parameter found in an entry
+type SamlMessage struct {
+	Parameter  string
+	Binding    string
+	RelayState string
+	Xml        []byte
+	Err        error
+}
+
+// SamlInfo holds the parts of a message needed to follow the round trip between the providers
+type SamlInfo struct {
+	Type         string
+	Id           string
+	InResponseTo string
+	Destination  string
+	Issuer       string
+	Status       string
+	NameId       string
+	Signed       bool
+	Assertion    bool
+	Encrypted    bool
+}
+
+// DecodeSaml undoes the encoding of a SAML parameter, the redirect binding deflates the message before base64 encoding
+// it while the POST binding only base64 encodes it
+func DecodeSaml(value string) ([]byte, error) {
+	value = strings.Join(strings.Fields(value), "")
+	decoded, err := base64.StdEncoding.DecodeString(value)
+	if err != nil {
+		decoded, err = base64.RawStdEncoding.DecodeString(strings.TrimRight(value, "="))
+		if err != nil {
+			return nil, errors.New("invalid base64: " + err.Error())
+		}
+	}
+	if bytes.HasPrefix(bytes.TrimSpace(decoded), []byte("<")) {
+		return decoded, nil
+	}
+	inflated, err := io.ReadAll(flate.NewReader(bytes.NewReader(decoded)))
+	if err != nil {
+		return nil, errors.New("could not inflate: " + err.Error())
+	}
+	return inflated, nil
+}
+
+func FindSamlMessages(entry Entry) []SamlMessage {
+	messages := make([]SamlMessage, 0)
+	add := func(values url.Values, binding string) {
+		for _, parameter := range []string{"SAMLRequest", "SAMLResponse"} {
+			if value := values.Get(parameter); value != "" {
+				decoded, err := DecodeSaml(value)
+				messages = append(messages, SamlMessage{Parameter: parameter, Binding: binding, RelayState: values.Get("RelayState"), Xml: decoded, Err: err})
+			}
+		}
+	}
+	add(UrlQuery(entry.Request.Url), "redirect")
+	if strings.EqualFold(entry.Request.Method, "POST") {
+		add(FormValues(entry.Request), "post")
+	}
+	return messages
+}
+
+func ParseSamlInfo(data []byte) (SamlInfo, error) {
+	var info SamlInfo
+	decoder := xml.NewDecoder(bytes.NewReader(data))
+	depth := 0
+	element := ""
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return info, err
+		}
+		switch token := token.(type) {
+		case xml.StartElement:
+			depth++
+			element = token.Name.Local
+			attr := func(name string) string {
+				for _, a := range token.Attr {
+					if a.Name.Local == name {
+						return a.Value
+					}
+				}
+				return ""
+			}
+			switch {
+			case depth == 1:
+				info.Type = token.Name.Local
+				info.Id = attr("ID")
+				info.InResponseTo = attr("InResponseTo")
+				info.Destination = attr("Destination")
+			case element == "StatusCode" && info.Status == "":
+				info.Status = attr("Value")
+			case element == "Signature":
+				info.Signed = true
+			case element == "Assertion":
+				info.Assertion = true
+			case element == "EncryptedAssertion":
+				info.Encrypted = true
+			}
+		case xml.EndElement:
+			depth--
+			element = ""
+		case xml.CharData:
+			text := strings.TrimSpace(string(token))
+			if element == "Issuer" && info.Issuer == "" {
+				info.Issuer = text
+			} else if element == "NameID" && info.NameId == "" {
+				info.NameId = text
+			}
+		}
+	}
+	return info, nil
+}
+
+type xmlNode struct {
+	Name     string
+	Attrs    []xml.Attr
+	Text     string
+	Children []*xmlNode
+}
+
+func rawName(name xml.Name) string {
+	if name.Space == "" {
+		return name.Local
+	}
+	return name.Space + ":" + name.Local
+}
+
+// FormatXml indents an XML document, reading it without resolving namespaces so the prefixes are printed as they were
+// in the original document
+func FormatXml(data []byte) (string, error) {
+	decoder := xml.NewDecoder(bytes.NewReader(data))
+	root := &xmlNode{}
+	stack := []*xmlNode{root}
+	for {
+		token, err := decoder.RawToken()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return "", err
+		}
+		parent := stack[len(stack)-1]
+		switch token := token.(type) {
+		case xml.StartElement:
+			node := &xmlNode{Name: rawName(token.Name), Attrs: token.Copy().Attr}
+			parent.Children = append(parent.Children, node)
+			stack = append(stack, node)
+		case xml.EndElement:
+			if len(stack) > 1 {
+				stack = stack[:len(stack)-1]
+			}
+		case xml.CharData:
+			parent.Text += strings.TrimSpace(string(token))
+		}
+	}
+
+	lines := make([]string, 0)
+	var write func(node *xmlNode, depth int)
+	write = func(node *xmlNode, depth int) {
+		var buffer bytes.Buffer
+		buffer.WriteString(strings.Repeat("  ", depth) + color.CyanString("<"+node.Name))
+		for _, attr := range node.Attrs {
+			buffer.WriteString(" " + color.HiBlackString(rawName(attr.Name)+"=") + "\"")
+			_ = xml.EscapeText(&buffer, []byte(attr.Value))
+			buffer.WriteString("\"")
+		}
+		if len(node.Children) == 0 && node.Text == "" {
+			lines = append(lines, buffer.String()+color.CyanString("/>"))
+			return
+		}
+		buffer.WriteString(color.CyanString(">"))
+		if len(node.Children) == 0 {
+			_ = xml.EscapeText(&buffer, []byte(node.Text))
+			lines = append(lines, buffer.String()+color.CyanString("</"+node.Name+">"))
+			return
+		}
+		lines = append(lines, buffer.String())
+		for _, child := range node.Children {
+			write(child, depth+1)
+		}
+		lines = append(lines, strings.Repeat("  ", depth)+color.CyanString("</"+node.Name+">"))
+	}
+	for _, child := range root.Children {
+		write(child, 0)
+	}
+	return strings.Join(lines, "\n"), nil
+}
+
+func AnalyzeSaml(entries []Entry, indexes []int, showXml bool) []*Flow {
+	flows := make([]*Flow, 0)
+	byRequestId := make(map[string]*Flow)
+	seen := make(map[string]bool)
+
+	for i, entry := range entries {
+		for _, message := range FindSamlMessages(entry) {
+			// The same message is often seen twice, in the redirect and in the request it leads to
+			if seen[string(message.Xml)] && message.Err == nil {
+				continue
+			}
+			seen[string(message.Xml)] = true
+
+			step := FlowStep{Index: indexes[i], Entry: entry, Kind: Tertiary(message.Parameter == "SAMLRequest", "SP → IdP", "IdP → SP")}
+			step.Param("binding", message.Binding)
+			if message.Err != nil {
+				step.Param("error", message.Err.Error())
+				flows = append(flows, &Flow{Steps: []FlowStep{step}, AuditGroup: AuditGroup{Name: message.Parameter}})
+				flows[len(flows)-1].Add(SeverityError, message.Parameter+" in entry "+strconv.Itoa(indexes[i])+" could not be decoded")
+				continue
+			}
+
+			info, err := ParseSamlInfo(message.Xml)
+			if err != nil {
+				step.Param("error", "invalid XML: "+err.Error())
+			}
+			step.Param("type", info.Type)
+			step.Param("id", info.Id)
+			step.Param("in response to", info.InResponseTo)
+			step.Param("issuer", info.Issuer)
+			step.Param("destination", info.Destination)
+			step.Param("status", strings.TrimPrefix(info.Status, "urn:oasis:names:tc:SAML:2.0:status:"))
+			step.Param("name id", info.NameId)
+			step.Param("relay state", message.RelayState)
+			if showXml {
+				if formatted, err := FormatXml(message.Xml); err == nil {
+					step.Detail = formatted
+				}
+			}
+
+			flow := byRequestId[info.InResponseTo]
+			if flow == nil || info.InResponseTo == "" {
+				flow = &Flow{AuditGroup: AuditGroup{Name: Tertiary(message.Parameter == "SAMLRequest", "SP initiated", "IdP initiated")}}
+				flows = append(flows, flow)
+			}
+			if message.Parameter == "SAMLRequest" && info.Id != "" {
+				byRequestId[info.Id] = flow
+				flow.Summary = Tertiary(info.Issuer != "", "service provider "+info.Issuer, "")
+			}
+			flow.Steps = append(flow.Steps, step)
+
+			if strings.HasPrefix(entry.Request.Url, "http://") {
+				flow.Add(SeverityError, info.Type+" sent over plain HTTP")
+			}
+			if info.Destination != "" && WithoutQuery(info.Destination) != WithoutQuery(entry.Request.Url) {
+				flow.Add(SeverityWarning, info.Type+" destination "+info.Destination+" is not where it was sent, "+WithoutQuery(entry.Request.Url))
+			}
+			if message.Parameter == "SAMLResponse" {
+				if info.Status != "" && !strings.HasSuffix(info.Status, ":Success") {
+					flow.Add(SeverityError, "identity provider returned "+strings.TrimPrefix(info.Status, "urn:oasis:names:tc:SAML:2.0:status:"))
+				}
+				if info.Assertion && !info.Signed {
+					flow.Add(SeverityError, "response and assertion are not signed")
+				}
+				if info.InResponseTo != "" && byRequestId[info.InResponseTo] == nil {
+					flow.Add(SeverityWarning, "response is to request "+info.InResponseTo+" which is not in the capture")
+				}
+			}
+		}
+	}
+	return flows
+}
+
+func (cmd *FlowSamlCmd) Run() error {
+	har, err := ReadHarFile(cmd.File)
+	if err != nil {
+		return err
+	}
+	indexes, err := SelectEntryIndexes(har.Log)
+	if err != nil {
+		return err
+	}
+	entries := make([]Entry, len(indexes))
+	for i, index := range indexes {
+		entries[i] = har.Log.Entries[index]
+	}
+	return WriteOutput(FormatFlows(AnalyzeSaml(entries, indexes, !cmd.NoXml), "SAML"))
+}
@@ -0,0 +1,270 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/fatih/color"
+)
+
+type ScanCmd struct {
+	Secrets ScanSecretsCmd `cmd:"" name:"secrets" help:"Find API keys, credentials, tokens and private keys in the URLs, headers, cookies and bodies"`
+	Pii     ScanPiiCmd     `cmd:"" name:"pii" help:"Find email addresses, phone numbers, card numbers and national IDs in the query strings and bodies, grouped by endpoint"`
+}
+
+type ScanSecretsCmd struct {
+	File string `arg:"" help:"The HAR file to scan, or - to read it from stdin" type:"existingfile"`
+}
+
+type SecretPattern struct {
+	Kind    string
+	Pattern *regexp.Regexp
+}
+
+// secretPatterns report their first capture group as the secret if they have one, otherwise the whole match
+var secretPatterns = []SecretPattern{
+	{"aws access key", regexp.MustCompile(`\b(?:AKIA|ASIA)[0-9A-Z]{16}\b`)},
+	{"aws secret key", regexp.MustCompile(`(?i)aws.{0,20}secret.{0,20}?["'\s:=]+([A-Za-z0-9/+=]{40})\b`)},
+	{"github token", regexp.MustCompile(`\bgh[pousr]_[A-Za-z0-9]{36,}\b`)},
+	{"slack token", regexp.MustCompile(`\bxox[abposr]-[A-Za-z0-9-]{10,}\b`)},
+	{"google api key", regexp.MustCompile(`\bAIza[0-9A-Za-z_-]{35}\b`)},
+	{"stripe key", regexp.MustCompile(`\b[sr]k_(?:live|test)_[0-9a-zA-Z]{16,}\b`)},
+	{"private key", regexp.MustCompile(`-----BEGIN (?:[A-Z]+ )*PRIVATE KEY-----`)},
+	{"jwt", jwtPattern},
+	{"bearer token", regexp.MustCompile(`(?i)\bbearer\s+([A-Za-z0-9._~+/-]{8,}=*)`)},
+	{"basic credentials", regexp.MustCompile(`(?i)\bbasic\s+([A-Za-z0-9+/]{8,}=*)`)},
+	{"password", regexp.MustCompile(`(?i)"[a-z0-9_-]*pass(?:word|wd)?"\s*:\s*"([^"]{4,})"`)},
+	{"secret field", regexp.MustCompile(`(?i)"[a-z0-9_-]*(?:secret|token|api_?key|access_?key)[a-z0-9_-]*"\s*:\s*"([^"]{8,})"`)},
+}
+
+// Values of sensitive headers, parameters and cookies which aren't matched by a pattern are still reported if they
+// look random enough to be a credential
+const (
+	minSecretEntropy = 3.5
+	minSecretLength  = 16
+)
+
+type SecretFinding struct {
+	Kind     string
+	Location string
+	Value    string
+}
+
+// ShannonEntropy is the average number of bits of information in each character of v
+func ShannonEntropy(v string) float64 {
+	counts := make(map[rune]int)
+	length := 0
+	for _, r := range v {
+		counts[r]++
+		length++
+	}
+	entropy := 0.0
+	for _, count := range counts {
+		p := float64(count) / float64(length)
+		entropy -= p * math.Log2(p)
+	}
+	return entropy
+}
+
+func LooksRandom(v string) bool {
+	return len(v) >= minSecretLength && ShannonEntropy(v) >= minSecretEntropy
+}
+
+// Redact keeps only the ends of a secret so the report doesn't leak it again
+func Redact(v string) string {
+	runes := []rune(v)
+	if len(runes) <= 12 {
+		return strings.Repeat("*", len(runes))
+	}
+	return string(runes[:4]) + "…" + string(runes[len(runes)-4:])
+}
+
+// SecretScanner collects the findings for one entry, ignoring the same secret seen again in a less specific place
+type SecretScanner struct {
+	Findings []SecretFinding
+	seen     map[string]bool
+}
+
+func (scanner *SecretScanner) Add(kind string, location string, value string) {
+	if scanner.seen == nil {
+		scanner.seen = make(map[string]bool)
+	}
+	if scanner.seen[value] {
+		return
+	}
+	scanner.seen[value] = true
+	scanner.Findings = append(scanner.Findings, SecretFinding{Kind: kind, Location: location, Value: value})
+}
+
+func (scanner *SecretScanner) Text(location string, text string) {
+	for _, pattern := range secretPatterns {
+		for _, match := range pattern.Pattern.FindAllStringSubmatch(text, -1) {
+			scanner.Add(pattern.Kind, location, match[len(match)-1])
+		}
+	}
+}
+
+// Body scans line by line so the report can say where in a large body the secret is
+func (scanner *SecretScanner) Body(location string, text string) {
+	for i, line := range strings.Split(text, "\n") {
+		scanner.Text(location+" line "+strconv.Itoa(i+1), line)
+	}
+}
+
+// Named scans a value with a name, such as a header, falling back to checking the randomness of the value when the
+// name suggests it holds a credential
+func (scanner *SecretScanner) Named(location string, value string, sensitive bool, kind string) {
+	scanner.Text(location, value)
+	if sensitive && LooksRandom(value) {
+		scanner.Add(kind, location, value)
+	}
+}
+
+// passwordParameter matches the names of form fields and parameters holding a password, like the password pattern does
+// for JSON bodies
+var passwordParameter = regexp.MustCompile(`(?i)^[a-z0-9_\[\].-]*pass(?:word|wd)?$`)
+
+// Parameter scans a query or form parameter. Passwords are reported whatever they look like, as they are often short
+// and not random enough for the other checks
+func (scanner *SecretScanner) Parameter(location string, name string, value string, kind string) {
+	if passwordParameter.MatchString(name) && len(value) >= 4 {
+		scanner.Add("password", location, value)
+	}
+	scanner.Named(location, value, IsSensitiveParameter(name), kind)
+}
+
+func IsSensitiveParameter(name string) bool {
+	lower := strings.ToLower(name)
+	for _, sensitive := range sensitiveParameters {
+		if strings.Contains(lower, sensitive) {
+			return true
+		}
+	}
+	return false
+}
+
+func IsSensitiveHeader(name string) bool {
+	for _, sensitive := range sensitiveHeaders {
+		if strings.EqualFold(name, sensitive) {
+			return true
+		}
+	}
+	return false
+}
+
+func (scanner *SecretScanner) Headers(location string, headers []Header) {
+	for _, header := range headers {
+		if strings.EqualFold(header.Name, "cookie") || strings.EqualFold(header.Name, "set-cookie") {
+			continue
+		}
+		location := location + " header " + header.Name
+		scanner.Text(location, header.Value)
+		if !IsSensitiveHeader(header.Name) {
+			continue
+		}
+		value := header.Value
+		if _, credentials, found := strings.Cut(value, " "); found {
+			// Only the credentials after the scheme, eg Bearer, are secret
+			value = credentials
+		}
+		if LooksRandom(value) {
+			scanner.Add("credential header", location, value)
+		}
+	}
+}
+
+func (scanner *SecretScanner) Cookies(location string, cookies []Cookie) {
+	for _, cookie := range cookies {
+		scanner.Named(location+" cookie "+cookie.Name, cookie.Value, true, "session cookie")
+	}
+}
+
+func ScanEntrySecrets(entry Entry) []SecretFinding {
+	var scanner SecretScanner
+
+	for _, parameter := range QueryParameters(entry.Request) {
+		scanner.Parameter("query parameter "+parameter.Name, parameter.Name, parameter.Value, "token in query parameter")
+	}
+	scanner.Text("url", entry.Request.Url)
+	scanner.Headers("request", entry.Request.Headers)
+	scanner.Cookies("request", entry.Request.Cookies)
+	if entry.Request.PostData != nil {
+		// Form bodies are split into their fields even when the HAR has no params for them
+		if fields, ok := FormFields(*entry.Request.PostData); ok {
+			for _, field := range fields {
+				scanner.Parameter("request parameter "+field.Name, field.Name, field.Value, "credential parameter")
+			}
+		} else {
+			for _, param := range entry.Request.PostData.Params {
+				if param.Value != nil {
+					scanner.Parameter("request parameter "+param.Name, param.Name, *param.Value, "credential parameter")
+				}
+			}
+		}
+		scanner.Body("request body", entry.Request.PostData.Text)
+	}
+
+	scanner.Headers("response", entry.Response.Headers)
+	scanner.Cookies("response", entry.Response.Cookies)
+	if entry.Response.Content != nil {
+		if text, ok := DecodedBody(*entry.Response.Content); ok {
+			scanner.Body("response body", text)
+		}
+	}
+	return scanner.Findings
+}
+
+func FormatSecretFindings(entries []Entry, indexes []int, findings [][]SecretFinding) string {
+	output := make([]string, 0)
+	total := 0
+	affected := 0
+	for i, entryFindings := range findings {
+		if len(entryFindings) == 0 {
+			continue
+		}
+		affected++
+		total += len(entryFindings)
+		entry := entries[i]
+		output = append(output, color.YellowString("Entry "+strconv.Itoa(indexes[i]))+" "+MethodColor(entry.Request.Method)(entry.Request.Method)+" "+entry.Request.Url)
+		for _, finding := range entryFindings {
+			output = append(output, "  "+color.RedString("["+finding.Kind+"] ")+color.HiBlackString(finding.Location+": ")+Redact(finding.Value))
+		}
+	}
+
+	if total == 0 {
+		return color.GreenString("No secrets found in %d %s", len(entries), Tertiary(len(entries) == 1, "entry", "entries"))
+	}
+	output = append(output, "", color.RedString("%d possible %s in %d %s", total, Tertiary(total == 1, "secret", "secrets"), affected, Tertiary(affected == 1, "entry", "entries")))
+	return strings.Join(output, "\n")
+}
+
+func (cmd *ScanSecretsCmd) Run() error {
+	har, err := ReadHarFile(cmd.File)
+	if err != nil {
+		return err
+	}
+	indexes, err := SelectEntryIndexes(har.Log)
+	if err != nil {
+		return err
+	}
+
+	entries := make([]Entry, len(indexes))
+	findings := make([][]SecretFinding, len(indexes))
+	found := false
+	for i, index := range indexes {
+		entries[i] = har.Log.Entries[index]
+		findings[i] = ScanEntrySecrets(entries[i])
+		found = found || len(findings[i]) > 0
+	}
+
+	if err := WriteOutput(FormatSecretFindings(entries, indexes, findings)); err != nil {
+		return err
+	}
+	if found {
+		return fmt.Errorf("%s contains possible secrets", cmd.File)
+	}
+	return nil
+}
@@ -0,0 +1,29 @@
+package main
+
+import "testing"
+
+func TestScanEntrySecretsFormBody(t *testing.T) {
+	password := "hunter22"
+	cases := map[string]PostData{
+		"text only": {MimeType: "application/x-www-form-urlencoded", Text: "username=a&password=hunter22"},
+		"params":    {MimeType: "application/x-www-form-urlencoded", Params: []PostParameters{{Name: "username", Value: new(string)}, {Name: "password", Value: &password}}},
+		"json":      {MimeType: "application/json", Text: `{"username": "a", "password": "hunter22"}`},
+	}
+	for name, postData := range cases {
+		entry := Entry{Request: Request{Method: "POST", Url: "https://example.com/login", PostData: &postData}}
+		findings := ScanEntrySecrets(entry)
+		if len(findings) != 1 || findings[0].Kind != "password" || findings[0].Value != "hunter22" {
+			t.Errorf("%s: found %+v, expected the password", name, findings)
+		}
+	}
+}
+
+func TestScanEntrySecretsIgnoresShortValues(t *testing.T) {
+	entry := Entry{Request: Request{Method: "POST", Url: "https://example.com/login", PostData: &PostData{
+		MimeType: "application/x-www-form-urlencoded",
+		Text:     "username=a&passport=x1&token=abc",
+	}}}
+	if findings := ScanEntrySecrets(entry); len(findings) != 0 {
+		t.Errorf("found %+v in a body without credentials", findings)
+	}
+}
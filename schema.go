@@ -0,0 +1,264 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/fatih/color"
+)
+
+type InferSchemaCmd struct {
+	Requests bool   `name:"requests" help:"Infer the schemas of the JSON request bodies instead of the responses"`
+	MaxEnum  int    `name:"max-enum" default:"5" help:"Strings with at most this many distinct values, each seen more than once, are listed as an enum"`
+	File     string `arg:"" help:"The HAR file to parse, or - to read it from stdin" type:"existingfile"`
+}
+
+// schemaTypeOrder is the order types are listed in when a value has been seen with more than one
+var schemaTypeOrder = []string{"object", "array", "string", "integer", "number", "boolean", "null"}
+
+// maxTrackedValues stops a string field such as an ID from remembering every value when it can't be an enum
+const maxTrackedValues = 64
+
+// SchemaNode accumulates every value seen at one position in a set of JSON documents
+type SchemaNode struct {
+	Samples    int
+	Types      map[string]int
+	Properties map[string]*SchemaNode
+	// Order is the order properties were first seen in
+	Order   []string
+	Objects int
+	Items   *SchemaNode
+	// Values counts each distinct string, and is nil once there were too many to be an enum
+	Values  map[string]int
+	Strings int
+}
+
+func NewSchemaNode() *SchemaNode {
+	return &SchemaNode{Types: make(map[string]int), Values: make(map[string]int)}
+}
+
+// ParseJsonBody decodes numbers as json.Number so integers and floats can be told apart
+func ParseJsonBody(text string) (any, bool) {
+	decoder := json.NewDecoder(strings.NewReader(text))
+	decoder.UseNumber()
+	var value any
+	if err := decoder.Decode(&value); err != nil || decoder.More() {
+		return nil, false
+	}
+	return value, true
+}
+
+func JsonType(value any) string {
+	switch typed := value.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case json.Number:
+		if _, err := typed.Int64(); err == nil {
+			return "integer"
+		}
+		return "number"
+	case string:
+		return "string"
+	case []any:
+		return "array"
+	case map[string]any:
+		return "object"
+	}
+	return "unknown"
+}
+
+func (node *SchemaNode) Add(value any) {
+	node.Samples++
+	node.Types[JsonType(value)]++
+	switch typed := value.(type) {
+	case string:
+		node.Strings++
+		if node.Values != nil {
+			node.Values[typed]++
+			if len(node.Values) > maxTrackedValues {
+				node.Values = nil
+			}
+		}
+	case []any:
+		if node.Items == nil {
+			node.Items = NewSchemaNode()
+		}
+		for _, item := range typed {
+			node.Items.Add(item)
+		}
+	case map[string]any:
+		node.Objects++
+		if node.Properties == nil {
+			node.Properties = make(map[string]*SchemaNode)
+		}
+		keys := make([]string, 0, len(typed))
+		for key := range typed {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			property, ok := node.Properties[key]
+			if !ok {
+				property = NewSchemaNode()
+				node.Properties[key] = property
+				node.Order = append(node.Order, key)
+			}
+			property.Add(typed[key])
+		}
+	}
+}
+
+// TypeNames lists the types seen, where integer is dropped if number was also seen since every integer is a number
+func (node *SchemaNode) TypeNames() []string {
+	names := make([]string, 0, len(node.Types))
+	for _, name := range schemaTypeOrder {
+		if node.Types[name] > 0 && !(name == "integer" && node.Types["number"] > 0) {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// Schema converts the node to JSON Schema, where a property is required if it was in every object seen
+func (node *SchemaNode) Schema(maxEnum int) map[string]any {
+	schema := make(map[string]any)
+	types := node.TypeNames()
+	if len(types) == 1 {
+		schema["type"] = types[0]
+	} else if len(types) > 1 {
+		schema["type"] = types
+	}
+
+	if node.Properties != nil {
+		properties := make(map[string]any, len(node.Properties))
+		required := make([]string, 0)
+		for _, key := range node.Order {
+			property := node.Properties[key]
+			properties[key] = property.Schema(maxEnum)
+			if property.Samples == node.Objects {
+				required = append(required, key)
+			}
+		}
+		schema["properties"] = properties
+		if len(required) > 0 {
+			schema["required"] = required
+		}
+	}
+	if node.Items != nil {
+		if node.Items.Samples > 0 {
+			schema["items"] = node.Items.Schema(maxEnum)
+		} else {
+			schema["maxItems"] = 0
+		}
+	}
+	// Only strings which repeat are likely to be an enum rather than free text that happened to be seen a few times
+	if node.Values != nil && len(node.Values) > 0 && len(node.Values) <= maxEnum && node.Strings > len(node.Values) {
+		values := make([]string, 0, len(node.Values))
+		for value := range node.Values {
+			values = append(values, value)
+		}
+		sort.Strings(values)
+		schema["enum"] = values
+	}
+	return schema
+}
+
+// EndpointSchema is the schema inferred from the bodies of one endpoint
+type EndpointSchema struct {
+	Endpoint string
+	Bodies   int
+	Root     *SchemaNode
+}
+
+// EntryJsonBody returns the parsed request or response body when it is JSON
+func EntryJsonBody(entry Entry, request bool) (any, bool) {
+	if request {
+		if entry.Request.PostData == nil {
+			return nil, false
+		}
+		return ParseJsonBody(entry.Request.PostData.Text)
+	}
+	if entry.Response.Content == nil {
+		return nil, false
+	}
+	text, ok := DecodedBody(*entry.Response.Content)
+	if !ok {
+		return nil, false
+	}
+	return ParseJsonBody(text)
+}
+
+// InferSchemas merges the JSON bodies of each endpoint, only using successful responses as error bodies usually have
+// a different shape
+func InferSchemas(entries []Entry, request bool) []*EndpointSchema {
+	schemas := make([]*EndpointSchema, 0)
+	byEndpoint := make(map[string]*EndpointSchema)
+	for _, entry := range entries {
+		if !request && (entry.Response.Status < 200 || entry.Response.Status >= 300) {
+			continue
+		}
+		body, ok := EntryJsonBody(entry, request)
+		if !ok {
+			continue
+		}
+		endpoint := Endpoint(entry)
+		schema, ok := byEndpoint[endpoint]
+		if !ok {
+			schema = &EndpointSchema{Endpoint: endpoint, Root: NewSchemaNode()}
+			byEndpoint[endpoint] = schema
+			schemas = append(schemas, schema)
+		}
+		schema.Bodies++
+		schema.Root.Add(body)
+	}
+	return schemas
+}
+
+// FormatSchemas writes a JSON object with a schema document for each endpoint
+func FormatSchemas(schemas []*EndpointSchema, maxEnum int, request bool) (string, error) {
+	documents := make(map[string]any, len(schemas))
+	for _, schema := range schemas {
+		document := schema.Root.Schema(maxEnum)
+		document["$schema"] = "https://json-schema.org/draft/2020-12/schema"
+		document["description"] = "Inferred from " + strconv.Itoa(schema.Bodies) + Tertiary(request, " request", " response") +
+			Tertiary(schema.Bodies == 1, " body", " bodies")
+		documents[schema.Endpoint] = document
+	}
+	var buffer bytes.Buffer
+	encoder := json.NewEncoder(&buffer)
+	encoder.SetEscapeHTML(false)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(documents); err != nil {
+		return "", err
+	}
+	return strings.TrimSuffix(buffer.String(), "\n"), nil
+}
+
+func (cmd *InferSchemaCmd) Run() error {
+	har, err := ReadHarFile(cmd.File)
+	if err != nil {
+		return err
+	}
+	validEntries, err := SelectEntries(har.Log)
+	if err != nil {
+		return err
+	}
+	schemas := InferSchemas(validEntries, cmd.Requests)
+	if len(schemas) == 0 {
+		// An empty object would look like a schema, so say why there isn't one instead
+		fmt.Fprintln(os.Stderr, color.YellowString("No JSON %s bodies found to infer a schema from", Tertiary(cmd.Requests, "request", "2xx response")))
+		return nil
+	}
+	output, err := FormatSchemas(schemas, cmd.MaxEnum, cmd.Requests)
+	if err != nil {
+		return err
+	}
+	return WriteOutput(output)
+}
@@ -0,0 +1,244 @@
+package main
+
+import (
+	"net/url"
+	"path"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+type ExportMermaidCmd struct {
+	Query bool   `name:"query" help:"Include the query string in the request labels"`
+	File  string `arg:"" help:"The HAR file to parse, or - to read it from stdin" type:"existingfile"`
+}
+
+type ExportPlantumlCmd struct {
+	Query          bool   `name:"query" help:"Include the query string in the request labels"`
+	ByPage         bool   `name:"by-page" help:"Put the calls made by each page in a group titled with the page"`
+	CollapseStatic bool   `name:"collapse-static" help:"Collapse runs of images, scripts, stylesheets, fonts and media from the same host into one arrow"`
+	File           string `arg:"" help:"The HAR file to parse, or - to read it from stdin" type:"existingfile"`
+}
+
+// maxSequenceLabel keeps long paths from stretching diagrams too wide to read
+const maxSequenceLabel = 60
+
+// SequenceCall is one request and its response in a sequence diagram
+type SequenceCall struct {
+	Host     string
+	Request  string
+	Response string
+}
+
+func NewSequenceCall(entry Entry, query bool) SequenceCall {
+	call := SequenceCall{Host: "[invalid url]", Request: entry.Request.Method + " " + entry.Request.Url}
+	if requestUrl, err := url.Parse(entry.Request.Url); err == nil {
+		call.Host = strings.ToLower(requestUrl.Host)
+		path := requestUrl.EscapedPath()
+		if path == "" {
+			path = "/"
+		}
+		if query && requestUrl.RawQuery != "" {
+			path += "?" + requestUrl.RawQuery
+		}
+		call.Request = strings.ToUpper(entry.Request.Method) + " " + MiddleEllipsis(path, maxSequenceLabel)
+	}
+
+	call.Response = strconv.Itoa(entry.Response.Status)
+	if entry.Response.StatusText != "" {
+		call.Response += " " + entry.Response.StatusText
+	}
+	if entry.Response.Status == 0 {
+		call.Response = "failed"
+	}
+	call.Response += " (" + FormatBytes(TransferSize(entry)) + ", " + FormatDuration(entry.TimeMs) + ")"
+	return call
+}
+
+// IsStaticAsset is true for the subresources which usually only add noise to a diagram of what an application did
+func IsStaticAsset(entry Entry) bool {
+	switch CspResourceKind(entry) {
+	case "img", "font", "style", "script", "media":
+		return true
+	}
+	return false
+}
+
+// SequenceSection is the calls made by one page, or every call when the diagram isn't split by page
+type SequenceSection struct {
+	Title string
+	Calls []SequenceCall
+}
+
+// collapseStaticCalls turns a run of static assets from one host into a single call summarising them
+func collapseStaticCalls(entries []Entry, query bool) []SequenceCall {
+	calls := make([]SequenceCall, 0, len(entries))
+	for i := 0; i < len(entries); {
+		call := NewSequenceCall(entries[i], query)
+		end := i + 1
+		if IsStaticAsset(entries[i]) {
+			for end < len(entries) && IsStaticAsset(entries[end]) && NewSequenceCall(entries[end], query).Host == call.Host {
+				end++
+			}
+		}
+		if end-i == 1 {
+			calls = append(calls, call)
+			i = end
+			continue
+		}
+
+		extensions := make([]string, 0)
+		bytes := 0
+		longest := 0.0
+		failed := 0
+		for _, entry := range entries[i:end] {
+			extension := strings.TrimPrefix(path.Ext(strings.SplitN(entry.Request.Url, "?", 2)[0]), ".")
+			if extension != "" && !slices.Contains(extensions, strings.ToLower(extension)) {
+				extensions = append(extensions, strings.ToLower(extension))
+			}
+			bytes += TransferSize(entry)
+			longest = max(longest, entry.TimeMs)
+			if entry.Response.Status == 0 || entry.Response.Status >= 400 {
+				failed++
+			}
+		}
+		call.Request = strconv.Itoa(end-i) + " static assets"
+		if len(extensions) > 0 {
+			call.Request += " (" + strings.Join(extensions, ", ") + ")"
+		}
+		call.Response = Tertiary(failed > 0, strconv.Itoa(failed)+" failed", "all succeeded") + " (" + FormatBytes(bytes) +
+			", longest " + FormatDuration(longest) + ")"
+		calls = append(calls, call)
+		i = end
+	}
+	return calls
+}
+
+// BuildSequence groups the entries into sections by page when byPage is set, keeping entries without a page in an
+// untitled section
+func BuildSequence(log Log, entries []Entry, byPage bool, collapseStatic bool, query bool) []SequenceSection {
+	toCalls := func(entries []Entry) []SequenceCall {
+		if collapseStatic {
+			return collapseStaticCalls(entries, query)
+		}
+		calls := make([]SequenceCall, len(entries))
+		for i, entry := range entries {
+			calls[i] = NewSequenceCall(entry, query)
+		}
+		return calls
+	}
+	if !byPage || log.Pages == nil {
+		return []SequenceSection{{Calls: toCalls(entries)}}
+	}
+
+	sections := make([]SequenceSection, 0)
+	// Consecutive entries of the same page are one section, a page which is returned to later starts a new one
+	for i := 0; i < len(entries); {
+		pageRef := ""
+		if entries[i].PageRef != nil {
+			pageRef = *entries[i].PageRef
+		}
+		end := i + 1
+		for end < len(entries) && (entries[end].PageRef == nil && pageRef == "" || entries[end].PageRef != nil && *entries[end].PageRef == pageRef) {
+			end++
+		}
+		title := ""
+		for _, page := range *log.Pages {
+			if page.Id == pageRef && pageRef != "" {
+				title = Tertiary(page.Title != "", page.Title, page.Id)
+			}
+		}
+		sections = append(sections, SequenceSection{Title: title, Calls: toCalls(entries[i:end])})
+		i = end
+	}
+	return sections
+}
+
+// SequenceParticipants lists the hosts in the order they were first contacted, so the diagram reads left to right
+func SequenceParticipants(sections []SequenceSection) []string {
+	hosts := make([]string, 0)
+	for _, section := range sections {
+		for _, call := range section.Calls {
+			if !slices.Contains(hosts, call.Host) {
+				hosts = append(hosts, call.Host)
+			}
+		}
+	}
+	return hosts
+}
+
+// mermaidText escapes the characters which end a message or start a comment in Mermaid
+func mermaidText(text string) string {
+	return strings.NewReplacer("#", "#35;", ";", "#59;", "\n", " ").Replace(text)
+}
+
+func FormatMermaid(sections []SequenceSection) string {
+	lines := []string{"sequenceDiagram", "    participant Client"}
+	aliases := make(map[string]string)
+	for i, host := range SequenceParticipants(sections) {
+		aliases[host] = "H" + strconv.Itoa(i+1)
+		lines = append(lines, "    participant "+aliases[host]+" as "+mermaidText(host))
+	}
+	for _, section := range sections {
+		for _, call := range section.Calls {
+			lines = append(lines,
+				"    Client->>"+aliases[call.Host]+": "+mermaidText(call.Request),
+				"    "+aliases[call.Host]+"-->>Client: "+mermaidText(call.Response))
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// plantumlText stops labels being read as PlantUML markup
+func plantumlText(text string) string {
+	return strings.NewReplacer("\n", " ", "\"", "'").Replace(text)
+}
+
+func FormatPlantuml(sections []SequenceSection) string {
+	lines := []string{"@startuml", "participant Client"}
+	aliases := make(map[string]string)
+	for i, host := range SequenceParticipants(sections) {
+		aliases[host] = "H" + strconv.Itoa(i+1)
+		lines = append(lines, "participant \""+plantumlText(host)+"\" as "+aliases[host])
+	}
+	for _, section := range sections {
+		indent := ""
+		if section.Title != "" {
+			lines = append(lines, "group "+plantumlText(section.Title))
+			indent = "  "
+		}
+		for _, call := range section.Calls {
+			lines = append(lines,
+				indent+"Client -> "+aliases[call.Host]+" : "+plantumlText(call.Request),
+				indent+aliases[call.Host]+" --> Client : "+plantumlText(call.Response))
+		}
+		if section.Title != "" {
+			lines = append(lines, "end")
+		}
+	}
+	return strings.Join(append(lines, "@enduml"), "\n")
+}
+
+func (cmd *ExportMermaidCmd) Run() error {
+	har, err := ReadHar(cmd.File, false)
+	if err != nil {
+		return err
+	}
+	entries, err := SelectEntries(har.Log)
+	if err != nil {
+		return err
+	}
+	return WriteOutput(FormatMermaid(BuildSequence(har.Log, entries, false, false, cmd.Query)))
+}
+
+func (cmd *ExportPlantumlCmd) Run() error {
+	har, err := ReadHar(cmd.File, false)
+	if err != nil {
+		return err
+	}
+	entries, err := SelectEntries(har.Log)
+	if err != nil {
+		return err
+	}
+	return WriteOutput(FormatPlantuml(BuildSequence(har.Log, entries, cmd.ByPage, cmd.CollapseStatic, cmd.Query)))
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/fatih/color"
+)
+
+type ServerTimingMetric struct {
+	Name        string
+	Duration    *float64
+	Description string
+}
+
+// ParseServerTiming reads a Server-Timing header value such as `db;dur=53.2, cache;desc="Cache Read";dur=2`, skipping
+// parameters it doesn't know and metrics without a name
+func ParseServerTiming(value string) []ServerTimingMetric {
+	metrics := make([]ServerTimingMetric, 0)
+	for _, part := range strings.Split(value, ",") {
+		fields := strings.Split(part, ";")
+		metric := ServerTimingMetric{Name: strings.TrimSpace(fields[0])}
+		if metric.Name == "" {
+			continue
+		}
+		for _, field := range fields[1:] {
+			name, value, _ := strings.Cut(field, "=")
+			value = strings.Trim(strings.TrimSpace(value), `"`)
+			switch strings.ToLower(strings.TrimSpace(name)) {
+			case "dur":
+				if duration, err := strconv.ParseFloat(value, 64); err == nil {
+					metric.Duration = &duration
+				}
+			case "desc":
+				metric.Description = value
+			}
+		}
+		metrics = append(metrics, metric)
+	}
+	return metrics
+}
+
+func ServerTimings(entry Entry) []ServerTimingMetric {
+	metrics := make([]ServerTimingMetric, 0)
+	for _, header := range entry.Response.Headers {
+		if strings.EqualFold(header.Name, "server-timing") {
+			metrics = append(metrics, ParseServerTiming(header.Value)...)
+		}
+	}
+	return metrics
+}
+
+// ServerTime is how long the server says it spent on the request. Metrics often overlap, so this is the metric called
+// total if there is one and otherwise the longest metric rather than their sum
+func ServerTime(metrics []ServerTimingMetric) (float64, bool) {
+	longest := 0.0
+	found := false
+	for _, metric := range metrics {
+		if metric.Duration == nil {
+			continue
+		}
+		if strings.EqualFold(metric.Name, "total") {
+			return *metric.Duration, true
+		}
+		longest = max(longest, *metric.Duration)
+		found = true
+	}
+	return longest, found
+}
+
+// FormatServerTimings lists the metrics below the network timings, comparing the server's time to the time spent
+// waiting for the response so the part spent on the wire stands out
+func FormatServerTimings(metrics []ServerTimingMetric, wait float64) string {
+	width := 0
+	for _, metric := range metrics {
+		width = max(width, len(metric.Name))
+	}
+
+	output := color.YellowString("\n  Server-Timing:")
+	for _, metric := range metrics {
+		output += "\n    " + color.HiBlackString(strings.Repeat(" ", width-len(metric.Name))+metric.Name+": ")
+		if metric.Duration != nil {
+			output += color.YellowString(FormatDuration(*metric.Duration))
+		} else {
+			output += color.HiBlackString("-")
+		}
+		if metric.Description != "" {
+			output += color.HiBlackString(" (" + metric.Description + ")")
+		}
+	}
+	if server, ok := ServerTime(metrics); ok && wait > 0 {
+		output += color.HiBlackString("\n    Wait minus server: ") + color.YellowString(FormatDuration(max(0, wait-server)))
+	}
+	return output
+}
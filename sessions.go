@@ -0,0 +1,149 @@
+package main
+
+import (
+	"errors"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/fatih/color"
+)
+
+type SessionsCmd struct {
+	Cookie *[]string `name:"cookie" help:"The names of the session cookies, by default any cookie named like session, sid or token"`
+	File   string    `arg:"" help:"The HAR file to parse, or - to read it from stdin" type:"existingfile"`
+}
+
+var sessionCookiePattern = regexp.MustCompile(`(?i)sess|^sid$|[_.-]sid$|token|^auth`)
+
+// Session is the requests which carried one value of a session cookie
+type Session struct {
+	Cookie    string
+	Value     string
+	Requests  int
+	Errors    int
+	First     time.Time
+	Last      time.Time
+	Endpoints []*Aggregate
+	entries   []Entry
+}
+
+func IsSessionCookie(name string, names *[]string) bool {
+	if names == nil {
+		return sessionCookiePattern.MatchString(name)
+	}
+	for _, wanted := range *names {
+		if strings.EqualFold(name, wanted) {
+			return true
+		}
+	}
+	return false
+}
+
+// EntrySession is the session cookie an entry was sent with. A request without one which was given one, such as a
+// login, is counted in the session it started
+func EntrySession(entry Entry, names *[]string) (string, string) {
+	for _, cookie := range RequestCookies(entry.Request) {
+		if cookie.Value != "" && IsSessionCookie(cookie.Name, names) {
+			return cookie.Name, cookie.Value
+		}
+	}
+	for _, cookie := range ResponseCookies(entry.Response) {
+		if cookie.Value != "" && IsSessionCookie(cookie.Name, names) {
+			return cookie.Name, cookie.Value
+		}
+	}
+	return "", ""
+}
+
+// GroupSessions splits the entries by session in the order the sessions were first seen, with the entries which had
+// no session cookie last under an empty value
+func GroupSessions(entries []Entry, names *[]string) []*Session {
+	sessions := make([]*Session, 0)
+	byValue := make(map[string]*Session)
+	var none *Session
+	for _, entry := range entries {
+		name, value := EntrySession(entry, names)
+		session := byValue[name+"="+value]
+		if session == nil {
+			session = &Session{Cookie: name, Value: value}
+			byValue[name+"="+value] = session
+			if value == "" {
+				none = session
+			} else {
+				sessions = append(sessions, session)
+			}
+		}
+		session.Requests++
+		if IsErrorResponse(entry.Response) {
+			session.Errors++
+		}
+		if started, ok := ParseStartedDateTime(entry.StartedDateTime); ok {
+			if session.First.IsZero() || started.Before(session.First) {
+				session.First = started
+			}
+			if started.After(session.Last) {
+				session.Last = started
+			}
+		}
+		session.entries = append(session.entries, entry)
+	}
+	if none != nil {
+		sessions = append(sessions, none)
+	}
+	for _, session := range sessions {
+		session.Endpoints = AggregateEntries(session.entries, Endpoint)
+	}
+	return sessions
+}
+
+func FormatSessions(sessions []*Session) string {
+	named := 0
+	for _, session := range sessions {
+		if session.Value != "" {
+			named++
+		}
+	}
+	if named == 0 {
+		return color.HiBlackString("No requests carried a session cookie, use --cookie to give its name")
+	}
+
+	rows := make([][]string, len(sessions))
+	for i, session := range sessions {
+		name := Tertiary(session.Value == "", "[none]", session.Cookie+"="+Redact(session.Value))
+		span := []string{"-", "-", "-"}
+		if !session.First.IsZero() {
+			span = []string{FormatRelativeTime(session.First), FormatRelativeTime(session.Last), FormatDuration(float64(session.Last.Sub(session.First)) / float64(time.Millisecond))}
+		}
+		rows[i] = append([]string{name, strconv.Itoa(session.Requests), strconv.Itoa(session.Errors), strconv.Itoa(len(session.Endpoints))}, span...)
+	}
+	output := []string{
+		FormatTable([]Column{{Name: "Session"}, {Name: "Requests", Right: true}, {Name: "Errors", Right: true}, {Name: "Endpoints", Right: true},
+			{Name: "First", Right: true}, {Name: "Last", Right: true}, {Name: "Span", Right: true}}, rows),
+	}
+
+	for i, session := range sessions {
+		endpoints := make([][]string, len(session.Endpoints))
+		for j, group := range session.Endpoints {
+			endpoints[j] = []string{strconv.Itoa(group.Count), group.Key}
+		}
+		output = append(output, "", color.CyanString(rows[i][0]), Indent(FormatTable([]Column{{Name: "Count", Right: true}, {Name: "Endpoint"}}, endpoints), 2))
+	}
+	return strings.Join(output, "\n")
+}
+
+func (cmd *SessionsCmd) Run() error {
+	if cmd.Cookie != nil && len(*cmd.Cookie) == 0 {
+		return errors.New("--cookie needs the name of at least one cookie")
+	}
+	har, err := ReadHar(cmd.File, false)
+	if err != nil {
+		return err
+	}
+	entries, err := SelectEntries(har.Log)
+	if err != nil {
+		return err
+	}
+	return WriteOutput(FormatSessions(GroupSessions(entries, cmd.Cookie)))
+}
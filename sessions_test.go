@@ -0,0 +1,13 @@
+package main
+
+import "testing"
+
+func TestGroupSessionsCookieHeaders(t *testing.T) {
+	sessions := GroupSessions(headerOnlyCookieEntries(), nil)
+	if len(sessions) != 1 {
+		t.Fatalf("found %d sessions, expected 1: %+v", len(sessions), sessions)
+	}
+	if sessions[0].Cookie != "sid" || sessions[0].Value != "abc123" || sessions[0].Requests != 2 {
+		t.Errorf("session is %s=%s with %d requests, expected sid=abc123 with 2", sessions[0].Cookie, sessions[0].Value, sessions[0].Requests)
+	}
+}
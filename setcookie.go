@@ -0,0 +1,92 @@
+package main
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/fatih/color"
+)
+
+var setCookieChangeColors = map[string]func(format string, a ...interface{}) string{
+	"set":         color.GreenString,
+	"overwritten": color.YellowString,
+	"refreshed":   color.CyanString,
+	"deleted":     color.RedString,
+	"expired":     color.RedString,
+}
+
+// FormatCookieAttributes lists the attributes as they were given in the Set-Cookie header
+func FormatCookieAttributes(cookie http.Cookie) string {
+	attributes := make([]string, 0)
+	if cookie.Domain != "" {
+		attributes = append(attributes, "Domain="+cookie.Domain)
+	}
+	if cookie.Path != "" {
+		attributes = append(attributes, "Path="+cookie.Path)
+	}
+	if !cookie.Expires.IsZero() {
+		attributes = append(attributes, "Expires="+cookie.Expires.UTC().Format(http.TimeFormat))
+	}
+	if cookie.MaxAge > 0 {
+		attributes = append(attributes, "Max-Age="+strconv.Itoa(cookie.MaxAge))
+	} else if cookie.MaxAge < 0 {
+		attributes = append(attributes, "Max-Age=0")
+	}
+	if cookie.Secure {
+		attributes = append(attributes, "Secure")
+	}
+	if cookie.HttpOnly {
+		attributes = append(attributes, "HttpOnly")
+	}
+	switch cookie.SameSite {
+	case http.SameSiteLaxMode:
+		attributes = append(attributes, "SameSite=Lax")
+	case http.SameSiteStrictMode:
+		attributes = append(attributes, "SameSite=Strict")
+	case http.SameSiteNoneMode:
+		attributes = append(attributes, "SameSite=None")
+	}
+	return strings.Join(attributes, "; ")
+}
+
+// FormatSetCookieTimeline lists every cookie set by the entries in order, replaying them into a jar to tell whether
+// each one created, replaced or removed a cookie
+func FormatSetCookieTimeline(entries []Entry) string {
+	mode := "relative"
+	if CLI.PrintTime != nil {
+		mode = *CLI.PrintTime
+	}
+	jar := NewCookieJar()
+	output := make([]string, 0)
+	total := 0
+	for _, entry := range entries {
+		cookies := SetCookies(entry)
+		if len(cookies) == 0 {
+			continue
+		}
+		output = append(output, FormatStartTime(entry, mode)+" "+
+			MethodColor(entry.Request.Method)(entry.Request.Method)+" "+StatusColor(entry.Response.Status)(strconv.Itoa(entry.Response.Status))+" "+entry.Request.Url)
+		for _, cookie := range cookies {
+			total++
+			stored, change := jar.Apply(entry, cookie)
+			line := "  " + setCookieChangeColors[change]("%-11s", change) + " " + cookie.Name + "=" + cookie.Value
+			if attributes := FormatCookieAttributes(cookie); attributes != "" {
+				line += color.HiBlackString("; " + attributes)
+			}
+			if stored.HostOnly {
+				line += color.HiBlackString(" (" + stored.Domain + stored.Path + ")")
+			} else {
+				line += color.HiBlackString(" (." + stored.Domain + stored.Path + ")")
+			}
+			output = append(output, line)
+		}
+	}
+
+	if total == 0 {
+		return color.HiBlackString("No cookies were set by %d %s", len(entries), Tertiary(len(entries) == 1, "entry", "entries"))
+	}
+	output = append(output, "", color.HiBlackString("%d Set-Cookie in %d %s, %d %s left at the end", total, len(entries), Tertiary(len(entries) == 1, "entry", "entries"),
+		len(jar.Cookies()), Tertiary(len(jar.Cookies()) == 1, "cookie", "cookies")))
+	return strings.Join(output, "\n")
+}
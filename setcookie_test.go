@@ -0,0 +1,29 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/fatih/color"
+)
+
+func setCookieEntry(started string, setCookie string) Entry {
+	return Entry{
+		StartedDateTime: started,
+		Request:         Request{Method: "GET", Url: "https://example.com/"},
+		Response:        Response{Status: 200, Headers: []Header{{Name: "Set-Cookie", Value: setCookie}}},
+	}
+}
+
+func TestSetCookieTimelineCountsCookiesSetAgain(t *testing.T) {
+	color.NoColor = true
+	entries := []Entry{
+		setCookieEntry("2024-01-01T00:00:00Z", "sid=one; Path=/"),
+		setCookieEntry("2024-01-01T00:00:01Z", "sid=; Path=/; Max-Age=0"),
+		setCookieEntry("2024-01-01T00:00:02Z", "sid=two; Path=/"),
+	}
+	output := FormatSetCookieTimeline(entries)
+	if !strings.HasSuffix(output, "3 Set-Cookie in 3 entries, 1 cookie left at the end") {
+		t.Errorf("unexpected footer in:\n%s", output)
+	}
+}
@@ -0,0 +1,155 @@
+package main
+
+import (
+	"strconv"
+	"strings"
+)
+
+type ExportSmoketestCmd struct {
+	Format  string `name:"format" default:"go" enum:"go,sh" help:"Write a Go test file or a shell script using curl"`
+	Package string `name:"package" default:"smoke" help:"The package of the Go test file"`
+	File    string `arg:"" help:"The HAR file to parse, or - to read it from stdin" type:"existingfile"`
+}
+
+// SmokeCheck is a URL to request and the status it is expected to respond with
+type SmokeCheck struct {
+	Name   string
+	Url    string
+	Status int
+}
+
+// CollectSmokeChecks picks the first recorded call to each GET endpoint, so endpoints with an ID in the path are only
+// checked once. Requests which failed are left out as there is no status to expect
+func CollectSmokeChecks(entries []Entry) []SmokeCheck {
+	checks := make([]SmokeCheck, 0)
+	seen := make(map[string]bool)
+	for _, entry := range entries {
+		if !strings.EqualFold(entry.Request.Method, "GET") || entry.Response.Status == 0 {
+			continue
+		}
+		endpoint := Endpoint(entry)
+		if seen[endpoint] {
+			continue
+		}
+		seen[endpoint] = true
+		checks = append(checks, SmokeCheck{Name: endpoint, Url: entry.Request.Url, Status: entry.Response.Status})
+	}
+	return checks
+}
+
+const smoketestGoTemplate = `// Generated by harv from FILE. Set SMOKE_BASE_URL, eg https://staging.example.com, to check another server
+package PACKAGE
+
+import (
+	"net/http"
+	"net/url"
+	"os"
+	"testing"
+	"time"
+)
+
+var smokeClient = &http.Client{
+	Timeout: 30 * time.Second,
+	// The recorded status of a redirect is the redirect itself
+	CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse },
+}
+
+func smokeUrl(t *testing.T, recorded string) string {
+	base := os.Getenv("SMOKE_BASE_URL")
+	if base == "" {
+		return recorded
+	}
+	target, err := url.Parse(recorded)
+	if err != nil {
+		t.Fatal(err)
+	}
+	server, err := url.Parse(base)
+	if err != nil {
+		t.Fatal(err)
+	}
+	target.Scheme, target.Host = server.Scheme, server.Host
+	return target.String()
+}
+
+func TestSmoke(t *testing.T) {
+	checks := []struct {
+		name   string
+		url    string
+		status int
+	}{
+CHECKS
+	}
+	for _, check := range checks {
+		check := check
+		t.Run(check.name, func(t *testing.T) {
+			t.Parallel()
+			response, err := smokeClient.Get(smokeUrl(t, check.url))
+			if err != nil {
+				t.Fatal(err)
+			}
+			response.Body.Close()
+			if response.StatusCode != check.status {
+				t.Errorf("GET %s responded with %d, expected %d", check.url, response.StatusCode, check.status)
+			}
+		})
+	}
+}`
+
+const smoketestShTemplate = `#!/bin/sh
+# Generated by harv from FILE. Set SMOKE_BASE_URL, eg https://staging.example.com, to check another server
+failed=0
+
+check() {
+  url="$2"
+  if [ -n "$SMOKE_BASE_URL" ]; then
+    rest="${url#*://}"
+    case "$rest" in
+      */*) url="${SMOKE_BASE_URL%/}/${rest#*/}" ;;
+      *) url="${SMOKE_BASE_URL%/}/" ;;
+    esac
+  fi
+  status=$(curl --silent --output /dev/null --max-time 30 --write-out '%{http_code}' "$url")
+  if [ "$status" = "$1" ]; then
+    echo "ok    $status $url"
+  else
+    echo "FAIL  $status $url (expected $1)"
+    failed=$((failed + 1))
+  fi
+}
+
+CHECKS
+
+if [ "$failed" -gt 0 ]; then
+  echo "$failed checks failed"
+  exit 1
+fi`
+
+// shellQuote quotes a value for a POSIX shell, where nothing inside single quotes is special
+func shellQuote(value string) string {
+	return "'" + strings.ReplaceAll(value, "'", `'\''`) + "'"
+}
+
+func FormatSmoketest(file string, format string, pkg string, checks []SmokeCheck) string {
+	lines := make([]string, len(checks))
+	for i, check := range checks {
+		if format == "sh" {
+			lines[i] = "check " + strconv.Itoa(check.Status) + " " + shellQuote(check.Url)
+		} else {
+			lines[i] = "\t\t{" + strconv.Quote(check.Name) + ", " + strconv.Quote(check.Url) + ", " + strconv.Itoa(check.Status) + "},"
+		}
+	}
+	template := Tertiary(format == "sh", smoketestShTemplate, smoketestGoTemplate)
+	return strings.NewReplacer("FILE", Tertiary(file == "-", "stdin", file), "PACKAGE", pkg, "CHECKS", strings.Join(lines, "\n")).Replace(template)
+}
+
+func (cmd *ExportSmoketestCmd) Run() error {
+	har, err := ReadHar(cmd.File, false)
+	if err != nil {
+		return err
+	}
+	entries, err := SelectEntries(har.Log)
+	if err != nil {
+		return err
+	}
+	return WriteOutput(FormatSmoketest(cmd.File, cmd.Format, cmd.Package, CollectSmokeChecks(entries)))
+}
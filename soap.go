@@ -0,0 +1,90 @@
+package main
+
+import (
+	"encoding/xml"
+	"mime"
+	"strings"
+)
+
+var soapNamespaces = []string{"http://schemas.xmlsoap.org/soap/envelope/", "http://www.w3.org/2003/05/soap-envelope"}
+
+// SoapOperation is the name of the first element in the Body of a SOAP envelope, which is the operation for a request
+// and usually the operation followed by Response for a response. It is false when the text isn't a SOAP envelope
+func SoapOperation(text string) (string, bool) {
+	if !strings.Contains(text, "Envelope") {
+		return "", false
+	}
+	decoder := xml.NewDecoder(strings.NewReader(text))
+	depth := 0
+	inBody := false
+	for {
+		token, err := decoder.Token()
+		if err != nil {
+			return "", depth > 0
+		}
+		switch token := token.(type) {
+		case xml.StartElement:
+			depth++
+			switch {
+			case depth == 1:
+				isSoap := false
+				for _, namespace := range soapNamespaces {
+					isSoap = isSoap || token.Name.Space == namespace
+				}
+				if token.Name.Local != "Envelope" || !isSoap {
+					return "", false
+				}
+			case depth == 2:
+				inBody = token.Name.Local == "Body"
+			case depth == 3 && inBody:
+				return token.Name.Local, true
+			}
+		case xml.EndElement:
+			depth--
+		}
+	}
+}
+
+// SoapAction is the action a request declared, in the SOAPAction header for SOAP 1.1 or the action parameter of the
+// content type for SOAP 1.2
+func SoapAction(entry Entry) string {
+	if action := strings.Trim(HeaderValue(entry.Request.Headers, "soapaction"), "\" "); action != "" {
+		return action
+	}
+	if mediaType, params, err := mime.ParseMediaType(HeaderValue(entry.Request.Headers, "content-type")); err == nil && mediaType == "application/soap+xml" {
+		return params["action"]
+	}
+	return ""
+}
+
+// SoapName is how a SOAP request is labelled, the operation in its envelope or otherwise the end of its action URI,
+// as everything sent to the service shares one URL
+func SoapName(entry Entry) string {
+	if entry.Request.PostData != nil {
+		if operation, ok := SoapOperation(entry.Request.PostData.Text); ok && operation != "" {
+			return operation
+		}
+	}
+	action := SoapAction(entry)
+	if cut := strings.LastIndexAny(action, "/#:"); cut >= 0 && cut < len(action)-1 {
+		return action[cut+1:]
+	}
+	return action
+}
+
+func MatchesSoapAction(entry Entry, wanted string) bool {
+	name := SoapName(entry)
+	return name != "" && strings.EqualFold(name, wanted) || strings.EqualFold(SoapAction(entry), wanted)
+}
+
+// FormatSoapBody indents a body which is a SOAP envelope, which is only looked for in XML content
+func FormatSoapBody(mimeType string, text string) (string, bool) {
+	if !strings.Contains(strings.ToLower(mimeType), "xml") {
+		return "", false
+	}
+	if _, ok := SoapOperation(text); !ok {
+		return "", false
+	}
+	formatted, err := FormatXml([]byte(text))
+	return formatted, err == nil
+}
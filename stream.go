@@ -0,0 +1,247 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strconv"
+	"time"
+)
+
+// OpenInput opens the HAR file to read, treating - as stdin
+func OpenInput(file string) (io.ReadCloser, error) {
+	if file == "-" {
+		return io.NopCloser(os.Stdin), nil
+	}
+	return os.Open(file)
+}
+
+func ReadInput(file string) ([]byte, error) {
+	input, err := OpenInput(file)
+	if err != nil {
+		return nil, err
+	}
+	defer input.Close()
+	return io.ReadAll(input)
+}
+
+type stdout struct {
+	io.Writer
+}
+
+func (stdout) Close() error {
+	return nil
+}
+
+// OpenOutput is where a command's output should be written, stdout unless -o was given
+func OpenOutput() (io.WriteCloser, error) {
+	if CLI.Out == nil {
+		return stdout{os.Stdout}, nil
+	}
+	flags := os.O_CREATE | os.O_WRONLY | Tertiary(CLI.Append != nil && *CLI.Append, os.O_APPEND, os.O_TRUNC)
+	return os.OpenFile(*CLI.Out, flags, 0644)
+}
+
+// WriteOutput is for commands which build all of their output before printing it
+func WriteOutput(output string) error {
+	out, err := OpenOutput()
+	if err != nil {
+		return err
+	}
+	if _, err := fmt.Fprintln(out, output); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
+
+// HarWalker reads a HAR one entry at a time, handing each to a callback as soon as it has been decoded, so output can
+// start before the whole file has been parsed and the entries don't all need to be held in memory
+type HarWalker struct {
+	// Lenient skips entries which can't be decoded and keeps what was read before the file stopped being valid JSON,
+	// passing each problem to Problem, where otherwise the first problem ends the walk with an error
+	Lenient bool
+	Problem func(problem ParseProblem)
+	// Describe explains a JSON error, by default giving where it was as a byte offset
+	Describe func(err error) string
+}
+
+// Walk calls handle with each entry and where in the file it was. Everything other than the entries is returned at
+// the end, along with how many entries the file had
+func (walker HarWalker) Walk(reader io.Reader, handle func(entry Entry, offset int64, length int) error) (HarFile, int, error) {
+	decoder := json.NewDecoder(bufio.NewReader(reader))
+	describe := walker.Describe
+	if describe == nil {
+		describe = DescribeStreamError
+	}
+	// The keys are kept in the order they were read so the file can be written back out the same way
+	fileKeys, fileFields := make([]string, 0), make(map[string]json.RawMessage)
+	logKeys, logFields := make([]string, 0), make(map[string]json.RawMessage)
+	entries := 0
+
+	finish := func() (HarFile, int, error) {
+		logFields["entries"] = json.RawMessage("[]")
+		fileFields["log"] = encodeObject(logKeys, logFields)
+		fileKeys = appendKey(fileKeys, "log")
+		var har HarFile
+		if err := UnmarshalWithExtensions(encodeObject(fileKeys, fileFields), &har); err != nil {
+			reason := err.Error()
+			var typeError *json.UnmarshalTypeError
+			if errors.As(err, &typeError) {
+				reason = DescribeTypeError(typeError)
+			}
+			if !walker.Lenient {
+				return HarFile{}, entries, errors.New("log: " + reason)
+			}
+			walker.Problem(ParseProblem{Entry: -1, Offset: -1, Reason: "log: " + reason})
+		}
+		return har, entries, nil
+	}
+	// stop ends the walk at a problem with the file as a whole, which keeps what was read so far when lenient
+	stop := func(reason string) (HarFile, int, error) {
+		if !walker.Lenient {
+			return HarFile{}, entries, errors.New(reason)
+		}
+		walker.Problem(ParseProblem{Entry: -1, Offset: decoder.InputOffset(), Skipped: true, Reason: reason})
+		return finish()
+	}
+	// skip reports a problem with one entry, which only ends the walk when it isn't lenient
+	skip := func(index int, offset int64, skipped bool, reason string) error {
+		if !walker.Lenient {
+			return errors.New("entry " + strconv.Itoa(index) + ": " + reason)
+		}
+		walker.Problem(ParseProblem{Entry: index, Offset: offset, Skipped: skipped, Reason: reason})
+		return nil
+	}
+
+	if !ExpectDelim(decoder, '{') {
+		return stop("the file is not a JSON object")
+	}
+	for decoder.More() {
+		token, err := decoder.Token()
+		if err != nil {
+			return stop(describe(err))
+		}
+		key, _ := token.(string)
+		if key != "log" {
+			var value json.RawMessage
+			if err := decoder.Decode(&value); err != nil {
+				return stop(describe(err))
+			}
+			fileKeys = appendKey(fileKeys, key)
+			fileFields[key] = value
+			continue
+		}
+		fileKeys = appendKey(fileKeys, key)
+
+		if !ExpectDelim(decoder, '{') {
+			return stop("log is not a JSON object")
+		}
+		for decoder.More() {
+			token, err := decoder.Token()
+			if err != nil {
+				return stop(describe(err))
+			}
+			name, _ := token.(string)
+			if name != "entries" {
+				var value json.RawMessage
+				if err := decoder.Decode(&value); err != nil {
+					return stop(describe(err))
+				}
+				logKeys = appendKey(logKeys, name)
+				logFields[name] = value
+				continue
+			}
+			logKeys = appendKey(logKeys, name)
+
+			if !ExpectDelim(decoder, '[') {
+				return stop("entries is not a JSON array")
+			}
+			for decoder.More() {
+				var raw json.RawMessage
+				if err := decoder.Decode(&raw); err != nil {
+					return stop("stopped reading after " + strconv.Itoa(entries) + " entries, " + describe(err))
+				}
+				index, offset := entries, decoder.InputOffset()-int64(len(raw))
+				entries++
+				if !bytes.HasPrefix(bytes.TrimSpace(raw), []byte{'{'}) {
+					if err := skip(index, offset, true, "entry is not a JSON object"); err != nil {
+						return HarFile{}, entries, err
+					}
+					continue
+				}
+
+				var entry Entry
+				if err := UnmarshalWithExtensions(raw, &entry); err != nil {
+					// A value of the wrong type only loses that field, anything else loses the entry
+					var typeError *json.UnmarshalTypeError
+					if !errors.As(err, &typeError) {
+						if err := skip(index, offset, true, err.Error()); err != nil {
+							return HarFile{}, entries, err
+						}
+						continue
+					}
+					if err := skip(index, offset, false, DescribeTypeError(typeError)); err != nil {
+						return HarFile{}, entries, err
+					}
+				}
+				if _, err := time.Parse(time.RFC3339, entry.StartedDateTime); err != nil && walker.Lenient {
+					walker.Problem(ParseProblem{Entry: index, Offset: offset, Reason: "startedDateTime " + strconv.Quote(entry.StartedDateTime) + " is not an ISO 8601 date"})
+				}
+				if err := handle(entry, offset, len(raw)); err != nil {
+					return HarFile{}, entries, err
+				}
+			}
+			if !ExpectDelim(decoder, ']') {
+				return stop("stopped reading after " + strconv.Itoa(entries) + " entries, the entries array is not closed")
+			}
+		}
+		if !ExpectDelim(decoder, '}') {
+			return stop("the log object is not closed")
+		}
+	}
+	return finish()
+}
+
+// appendKey adds a key the first time it is seen, a repeated key replaces the value but keeps its place
+func appendKey(keys []string, key string) []string {
+	if slices.Contains(keys, key) {
+		return keys
+	}
+	return append(keys, key)
+}
+
+// encodeObject puts the fields of an object back together in the order they were read
+func encodeObject(keys []string, fields map[string]json.RawMessage) json.RawMessage {
+	var buffer bytes.Buffer
+	buffer.WriteByte('{')
+	for i, key := range keys {
+		if i > 0 {
+			buffer.WriteByte(',')
+		}
+		name, _ := json.Marshal(key)
+		buffer.Write(name)
+		buffer.WriteByte(':')
+		buffer.Write(fields[key])
+	}
+	buffer.WriteByte('}')
+	return buffer.Bytes()
+}
+
+// DescribeStreamError is DescribeJsonError for when the content isn't held in memory, so positions can only be given
+// as a byte offset
+func DescribeStreamError(err error) string {
+	if errors.Is(err, io.ErrUnexpectedEOF) || errors.Is(err, io.EOF) {
+		return "the file ends part way through, it may have been truncated"
+	}
+	var syntaxError *json.SyntaxError
+	if errors.As(err, &syntaxError) {
+		return "byte " + strconv.FormatInt(syntaxError.Offset, 10) + ": " + err.Error()
+	}
+	return err.Error()
+}
@@ -0,0 +1,41 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestHarWalkerStrictAndLenient(t *testing.T) {
+	content, err := os.ReadFile("testdata/firefox.har")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Cut off part way through the last entry, and make the first one an array
+	broken := bytes.Replace(content[:bytes.LastIndex(content, []byte(`"pageref"`))], []byte(`"entries": [`), []byte(`"entries": [[1, 2],`), 1)
+
+	count := 0
+	_, _, err = HarWalker{}.Walk(bytes.NewReader(broken), func(Entry, int64, int) error {
+		count++
+		return nil
+	})
+	if err == nil || err.Error() != "entry 0: entry is not a JSON object" {
+		t.Errorf("strict walk returned %v, expected the first entry to fail", err)
+	}
+	if count != 0 {
+		t.Errorf("strict walk handled %d entries after the failure", count)
+	}
+
+	har, report := ReadHarLeniently(broken)
+	if len(har.Log.Entries) != 2 || report.Entries != 3 {
+		t.Errorf("lenient walk loaded %d of %d entries, expected 2 of 3", len(har.Log.Entries), report.Entries)
+	}
+	if har.Log.Creator.Name != "Firefox" {
+		t.Errorf("lenient walk lost the log fields, creator is %q", har.Log.Creator.Name)
+	}
+	if len(report.Problems) != 2 || !report.Problems[0].Skipped || report.Problems[0].Entry != 0 ||
+		!strings.Contains(report.Problems[1].Reason, "stopped reading after 3 entries") {
+		t.Errorf("unexpected problems %+v", report.Problems)
+	}
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"strings"
+	"unicode/utf8"
+
+	"github.com/fatih/color"
+)
+
+type Column struct {
+	Name  string
+	Right bool
+}
+
+// FormatTable pads every cell to the widest value in its column, cells must not contain colour codes as they would be
+// counted towards the width
+func FormatTable(columns []Column, rows [][]string) string {
+	widths := make([]int, len(columns))
+	for i, column := range columns {
+		widths[i] = utf8.RuneCountInString(column.Name)
+	}
+	for _, row := range rows {
+		for i, cell := range row {
+			widths[i] = max(widths[i], utf8.RuneCountInString(cell))
+		}
+	}
+
+	// Shorten the first column, usually the URL or endpoint, rather than let the rows wrap
+	shorten := func(v string, i int) string { return v }
+	if width := LayoutWidth(); width > 0 {
+		total := 2 * (len(widths) - 1)
+		for _, w := range widths {
+			total += w
+		}
+		if total > width {
+			widths[0] = max(widths[0]-(total-width), utf8.RuneCountInString(columns[0].Name), minUrlWidth)
+			shorten = func(v string, i int) string {
+				return Tertiary(i == 0, MiddleEllipsis(v, widths[0]), v)
+			}
+		}
+	}
+
+	pad := func(v string, i int) string {
+		padding := strings.Repeat(" ", widths[i]-utf8.RuneCountInString(v))
+		if columns[i].Right {
+			return padding + v
+		}
+		return v + padding
+	}
+
+	header := make([]string, len(columns))
+	for i, column := range columns {
+		header[i] = color.YellowString(pad(column.Name, i))
+	}
+	lines := []string{strings.TrimRight(strings.Join(header, "  "), " ")}
+	for _, row := range rows {
+		cells := make([]string, len(row))
+		for i, cell := range row {
+			cells[i] = pad(shorten(cell, i), i)
+			if i == 0 {
+				cells[i] = color.CyanString(cells[i])
+			}
+		}
+		lines = append(lines, strings.TrimRight(strings.Join(cells, "  "), " "))
+	}
+
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,215 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/alecthomas/kong"
+)
+
+type TagCmd struct {
+	Remove  bool    `name:"remove" help:"Take the tag off the entries instead of adding it"`
+	File    string  `arg:"" help:"The HAR file the entries are in, the tags are kept next to it in file.har.tags" type:"existingfile"`
+	Entries *string `arg:"" optional:"" help:"The indexes of the entries as shown by #N in other commands, eg 12,13 or 20-25. Without them the tags are listed"`
+	Tag     *string `arg:"" optional:"" help:"The tag to add to the entries"`
+}
+
+// TaggedEntry identifies an entry by when it started and what it requested rather than only its position, so the tags
+// can be matched while streaming or from the index, where positions aren't known
+type TaggedEntry struct {
+	Index           int    `json:"index"`
+	StartedDateTime string `json:"startedDateTime"`
+	Method          string `json:"method"`
+	Url             string `json:"url"`
+}
+
+func (tagged TaggedEntry) Key() string {
+	return tagged.StartedDateTime + " " + strings.ToUpper(tagged.Method) + " " + tagged.Url
+}
+
+// HarTags are the entries with each tag, as saved in the sidecar file
+type HarTags map[string][]TaggedEntry
+
+func TagsPath(file string) string {
+	return file + ".tags"
+}
+
+func EntryTagKey(entry Entry) string {
+	return TaggedEntry{StartedDateTime: entry.StartedDateTime, Method: entry.Request.Method, Url: entry.Request.Url}.Key()
+}
+
+// LoadTags reads the tags saved for file, there are none if the sidecar doesn't exist yet
+func LoadTags(file string) (HarTags, error) {
+	content, err := os.ReadFile(TagsPath(file))
+	if errors.Is(err, os.ErrNotExist) {
+		return make(HarTags), nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	tags := make(HarTags)
+	if err := json.Unmarshal(content, &tags); err != nil {
+		return nil, fmt.Errorf("failed to read %s, %s", TagsPath(file), DescribeJsonError(content, err))
+	}
+	return tags, nil
+}
+
+func SaveTags(file string, tags HarTags) error {
+	if len(tags) == 0 {
+		err := os.Remove(TagsPath(file))
+		if errors.Is(err, os.ErrNotExist) {
+			return nil
+		}
+		return err
+	}
+	// The file is meant to be read and shared, so the & in URLs isn't escaped
+	var content bytes.Buffer
+	encoder := json.NewEncoder(&content)
+	encoder.SetEscapeHTML(false)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(tags); err != nil {
+		return err
+	}
+	return os.WriteFile(TagsPath(file), content.Bytes(), 0644)
+}
+
+// ParseEntryIndexes reads a list of indexes and ranges such as 3,12-15
+func ParseEntryIndexes(text string) ([]int, error) {
+	indexes := make([]int, 0)
+	for _, part := range strings.Split(text, ",") {
+		part = strings.TrimPrefix(strings.TrimSpace(part), "#")
+		first, last, isRange := strings.Cut(part, "-")
+		start, err := strconv.Atoi(first)
+		if err != nil {
+			return nil, fmt.Errorf("%q isn't an entry index or range such as 12 or 20-25", part)
+		}
+		end := start
+		if isRange {
+			if end, err = strconv.Atoi(strings.TrimPrefix(last, "#")); err != nil || end < start {
+				return nil, fmt.Errorf("%q isn't an entry index or range such as 12 or 20-25", part)
+			}
+		}
+		for index := start; index <= end; index++ {
+			indexes = append(indexes, index)
+		}
+	}
+	return indexes, nil
+}
+
+// taggedEntries are the keys of the entries with any of the --tag tags, loaded before the command runs
+var taggedEntries map[string]bool
+
+// ConfigureTags loads the tags of the HAR file the command was given, which is found by the name of its argument
+func ConfigureTags(ctx *kong.Context) error {
+	if CLI.Tag == nil {
+		return nil
+	}
+	file := ""
+	if selected := ctx.Selected(); selected != nil {
+		for _, positional := range selected.Positional {
+			if positional.Name == "file" && positional.Target.Kind() == reflect.String {
+				file = positional.Target.String()
+			}
+		}
+	}
+	if file == "" || file == "-" {
+		return errors.New("--tag needs the HAR file to be given by its path, as the tags are saved next to it")
+	}
+	tags, err := LoadTags(file)
+	if err != nil {
+		return err
+	}
+	taggedEntries = make(map[string]bool)
+	for _, tag := range *CLI.Tag {
+		entries, ok := tags[tag]
+		if !ok {
+			return fmt.Errorf("no entries of %s are tagged %q, use harv tag %s to list the tags", file, tag, file)
+		}
+		for _, tagged := range entries {
+			taggedEntries[tagged.Key()] = true
+		}
+	}
+	slog.Info("Loaded tags", "path", TagsPath(file), "entries", len(taggedEntries))
+	return nil
+}
+
+func FormatTags(tags HarTags) string {
+	if len(tags) == 0 {
+		return "No entries have been tagged"
+	}
+	names := make([]string, 0, len(tags))
+	for name := range tags {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	rows := make([][]string, len(names))
+	for i, name := range names {
+		indexes := make([]string, len(tags[name]))
+		for j, tagged := range tags[name] {
+			indexes[j] = "#" + strconv.Itoa(tagged.Index)
+		}
+		rows[i] = []string{name, strconv.Itoa(len(tags[name])), strings.Join(indexes, ", ")}
+	}
+	return FormatTable([]Column{{Name: "Tag"}, {Name: "Count", Right: true}, {Name: "Entries"}}, rows)
+}
+
+func (cmd *TagCmd) Run() error {
+	if cmd.File == "-" {
+		return errors.New("the tags are saved next to the HAR file, so it must be given by its path")
+	}
+	tags, err := LoadTags(cmd.File)
+	if err != nil {
+		return err
+	}
+	if cmd.Entries == nil {
+		return WriteOutput(FormatTags(tags))
+	}
+	if cmd.Tag == nil || *cmd.Tag == "" {
+		return errors.New("give the tag to add after the entries, eg harv tag " + cmd.File + " " + *cmd.Entries + " bug-1234")
+	}
+	indexes, err := ParseEntryIndexes(*cmd.Entries)
+	if err != nil {
+		return err
+	}
+	har, err := ReadHar(cmd.File, false)
+	if err != nil {
+		return err
+	}
+
+	tagged := tags[*cmd.Tag]
+	for _, index := range indexes {
+		if index < 0 || index >= len(har.Log.Entries) {
+			return errors.New("there is no entry #" + strconv.Itoa(index) + ", the file has " + strconv.Itoa(len(har.Log.Entries)) + " entries")
+		}
+		entry := har.Log.Entries[index]
+		key := EntryTagKey(entry)
+		tagged = Filter(tagged, func(existing TaggedEntry) bool {
+			return existing.Key() != key
+		})
+		if !cmd.Remove {
+			tagged = append(tagged, TaggedEntry{Index: index, StartedDateTime: entry.StartedDateTime, Method: entry.Request.Method, Url: entry.Request.Url})
+		}
+	}
+	sort.SliceStable(tagged, func(i, j int) bool {
+		return tagged[i].Index < tagged[j].Index
+	})
+	if len(tagged) == 0 {
+		delete(tags, *cmd.Tag)
+	} else {
+		tags[*cmd.Tag] = tagged
+	}
+	if err := SaveTags(cmd.File, tags); err != nil {
+		return err
+	}
+	fmt.Fprintln(os.Stderr, Tertiary(cmd.Remove, "Untagged ", "Tagged ")+strconv.Itoa(len(indexes))+Tertiary(len(indexes) == 1, " entry", " entries")+
+		Tertiary(cmd.Remove, " from ", " as ")+*cmd.Tag+" in "+TagsPath(cmd.File))
+	return nil
+}
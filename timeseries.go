@@ -0,0 +1,115 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type ExportTimeseriesCmd struct {
+	Bucket time.Duration `name:"bucket" default:"5s" help:"The width of each bucket, eg 500ms, 5s or 1m"`
+	Format *string       `name:"format" enum:"csv,json" help:"Write CSV or a JSON array. Defaults to JSON when --out ends in .json"`
+	File   string        `arg:"" help:"The HAR file to parse, or - to read it from stdin" type:"existingfile"`
+}
+
+// TimeBucket is the requests which started in one bucket of the capture
+type TimeBucket struct {
+	Start             time.Time `json:"start"`
+	Requests          int       `json:"requests"`
+	RequestsPerSecond float64   `json:"requestsPerSecond"`
+	Errors            int       `json:"errors"`
+	ErrorRate         float64   `json:"errorRate"`
+	P95Ms             float64   `json:"p95Ms"`
+	Bytes             int       `json:"bytes"`
+	durations         []float64
+}
+
+// BucketEntries splits the capture into buckets of the same width from the first request, including the empty
+// buckets so the rows can be charted as they are
+func BucketEntries(entries []Entry, width time.Duration) []*TimeBucket {
+	first := EarliestStart(entries)
+	buckets := make([]*TimeBucket, 0)
+	for _, entry := range entries {
+		started, ok := ParseStartedDateTime(entry.StartedDateTime)
+		if !ok {
+			continue
+		}
+		index := int(started.Sub(first) / width)
+		for len(buckets) <= index {
+			buckets = append(buckets, &TimeBucket{Start: first.Add(time.Duration(len(buckets)) * width)})
+		}
+		bucket := buckets[index]
+		bucket.Requests++
+		if IsErrorResponse(entry.Response) {
+			bucket.Errors++
+		}
+		bucket.Bytes += max(0, TransferSize(entry))
+		bucket.durations = append(bucket.durations, entry.TimeMs)
+	}
+	for _, bucket := range buckets {
+		bucket.RequestsPerSecond = float64(bucket.Requests) / width.Seconds()
+		if bucket.Requests > 0 {
+			bucket.ErrorRate = float64(bucket.Errors) / float64(bucket.Requests)
+		}
+		sort.Float64s(bucket.durations)
+		bucket.P95Ms = Percentile(bucket.durations, 95)
+	}
+	return buckets
+}
+
+func FormatTimeseriesCsv(buckets []*TimeBucket) (string, error) {
+	var builder strings.Builder
+	writer := csv.NewWriter(&builder)
+	rows := [][]string{{"start", "requests", "requests_per_second", "errors", "error_rate", "p95_ms", "bytes"}}
+	for _, bucket := range buckets {
+		rows = append(rows, []string{
+			bucket.Start.UTC().Format(time.RFC3339Nano),
+			strconv.Itoa(bucket.Requests),
+			strconv.FormatFloat(bucket.RequestsPerSecond, 'f', -1, 64),
+			strconv.Itoa(bucket.Errors),
+			strconv.FormatFloat(bucket.ErrorRate, 'f', -1, 64),
+			strconv.FormatFloat(bucket.P95Ms, 'f', -1, 64),
+			strconv.Itoa(bucket.Bytes),
+		})
+	}
+	if err := writer.WriteAll(rows); err != nil {
+		return "", err
+	}
+	return strings.TrimSuffix(builder.String(), "\n"), nil
+}
+
+func (cmd *ExportTimeseriesCmd) Run() error {
+	if cmd.Bucket <= 0 {
+		return errors.New("--bucket must be longer than 0")
+	}
+	har, err := ReadHar(cmd.File, false)
+	if err != nil {
+		return err
+	}
+	entries, err := SelectEntries(har.Log)
+	if err != nil {
+		return err
+	}
+	buckets := BucketEntries(entries, cmd.Bucket)
+
+	asJson := CLI.Out != nil && strings.HasSuffix(strings.ToLower(*CLI.Out), ".json")
+	if cmd.Format != nil {
+		asJson = *cmd.Format == "json"
+	}
+	if asJson {
+		content, err := json.MarshalIndent(buckets, "", "  ")
+		if err != nil {
+			return err
+		}
+		return WriteOutput(string(content))
+	}
+	output, err := FormatTimeseriesCsv(buckets)
+	if err != nil {
+		return err
+	}
+	return WriteOutput(output)
+}
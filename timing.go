@@ -0,0 +1,66 @@
+package main
+
+import (
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/fatih/color"
+)
+
+// PhaseTimer adds up the time spent in each phase of a run for --timing. Phases are entered once per entry while
+// streaming and from several workers while formatting, so the durations are totals rather than wall clock spans
+type PhaseTimer struct {
+	mutex     sync.Mutex
+	started   time.Time
+	durations map[string]time.Duration
+}
+
+// phases are printed in the order they happen to an entry, rather than the order they were first timed in
+var phases = []string{"parse", "filter", "format"}
+
+var Timer = PhaseTimer{started: time.Now()}
+
+func (timer *PhaseTimer) Add(phase string, duration time.Duration) {
+	timer.mutex.Lock()
+	defer timer.mutex.Unlock()
+	if timer.durations == nil {
+		timer.durations = make(map[string]time.Duration)
+	}
+	timer.durations[phase] += duration
+}
+
+// Time starts timing a phase and returns the function which stops it
+func (timer *PhaseTimer) Time(phase string) func() {
+	started := time.Now()
+	return func() {
+		timer.Add(phase, time.Since(started))
+	}
+}
+
+// Timed wraps a formatter so the time spent in it is counted towards the format phase
+func Timed[T any](phase string, format func(v T) string) func(v T) string {
+	return func(v T) string {
+		defer Timer.Time(phase)()
+		return format(v)
+	}
+}
+
+func FormatPhaseTimings(timer *PhaseTimer) string {
+	timer.mutex.Lock()
+	defer timer.mutex.Unlock()
+
+	parts := make([]string, 0, len(phases)+1)
+	for _, phase := range phases {
+		if _, ok := timer.durations[phase]; !ok {
+			continue
+		}
+		parts = append(parts, color.HiBlackString(phase+" ")+color.YellowString(FormatDuration(Milliseconds(timer.durations[phase]))))
+	}
+	parts = append(parts, color.HiBlackString("total ")+color.YellowString(FormatDuration(Milliseconds(time.Since(timer.started)))))
+	return color.HiBlackString("Timing: ") + strings.Join(parts, color.HiBlackString(", "))
+}
+
+func Milliseconds(duration time.Duration) float64 {
+	return float64(duration) / float64(time.Millisecond)
+}
@@ -0,0 +1,228 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/fatih/color"
+)
+
+type TraceCmd struct {
+	Value string `name:"value" required:"" help:"The token or cookie value to follow, or the name of a cookie, header or parameter to follow whatever value it has"`
+	File  string `arg:"" help:"The HAR file to parse, or - to read it from stdin" type:"existingfile"`
+}
+
+// TraceHit is one place a traced value was seen in an entry
+type TraceHit struct {
+	Response bool
+	Location string
+	Value    string
+}
+
+// Tracer looks for the values being traced in names, values and bodies, keeping the distinct values seen so a rotated
+// session cookie shows up as a new value rather than the same one
+type Tracer struct {
+	// Name is the cookie, header or parameter name being traced, whose values are followed wherever they appear
+	Name   string
+	Terms  []string
+	Hits   []TraceHit
+	values map[string]int
+}
+
+// NewTracer traces search as a name if a cookie, header or parameter has that name, otherwise as a value
+func NewTracer(entries []Entry, search string) *Tracer {
+	names := &Tracer{Name: search}
+	terms := make([]string, 0)
+	seen := make(map[string]bool)
+	for _, entry := range entries {
+		for _, hit := range names.Entry(entry) {
+			if !seen[hit.Value] {
+				seen[hit.Value] = true
+				terms = append(terms, hit.Value)
+			}
+		}
+	}
+	if len(terms) == 0 {
+		return &Tracer{Terms: []string{search}}
+	}
+	return &Tracer{Name: search, Terms: terms}
+}
+
+func (tracer *Tracer) Named(response bool, location string, name string, value string) {
+	if tracer.Name != "" && strings.EqualFold(name, tracer.Name) && value != "" {
+		tracer.Hits = append(tracer.Hits, TraceHit{Response: response, Location: location, Value: value})
+		return
+	}
+	tracer.Text(response, location, value)
+}
+
+func (tracer *Tracer) Text(response bool, location string, text string) {
+	for _, term := range tracer.Terms {
+		if strings.Contains(text, term) {
+			tracer.Hits = append(tracer.Hits, TraceHit{Response: response, Location: location, Value: term})
+			return
+		}
+	}
+}
+
+// ValueNumber numbers the distinct values in the order they were first seen
+func (tracer *Tracer) ValueNumber(value string) int {
+	if tracer.values == nil {
+		tracer.values = make(map[string]int)
+	}
+	if _, ok := tracer.values[value]; !ok {
+		tracer.values[value] = len(tracer.values) + 1
+	}
+	return tracer.values[value]
+}
+
+func (tracer *Tracer) Entry(entry Entry) []TraceHit {
+	tracer.Hits = nil
+
+	for _, parameter := range QueryParameters(entry.Request) {
+		tracer.Named(false, "query "+parameter.Name, parameter.Name, parameter.Value)
+	}
+	for _, header := range entry.Request.Headers {
+		if !strings.EqualFold(header.Name, "cookie") {
+			tracer.Named(false, "header "+header.Name, header.Name, header.Value)
+		}
+	}
+	for _, cookie := range RequestCookies(entry.Request) {
+		tracer.Named(false, "cookie "+cookie.Name, cookie.Name, cookie.Value)
+	}
+	if entry.Request.PostData != nil {
+		for _, param := range entry.Request.PostData.Params {
+			if param.Value != nil {
+				tracer.Named(false, "parameter "+param.Name, param.Name, *param.Value)
+			}
+		}
+		if len(entry.Request.PostData.Params) == 0 {
+			tracer.Text(false, "body", entry.Request.PostData.Text)
+		}
+	}
+
+	for _, header := range entry.Response.Headers {
+		if !strings.EqualFold(header.Name, "set-cookie") {
+			tracer.Named(true, "header "+header.Name, header.Name, header.Value)
+		}
+	}
+	for _, cookie := range ResponseCookies(entry.Response) {
+		tracer.Named(true, "set-cookie "+cookie.Name, cookie.Name, cookie.Value)
+	}
+	if entry.Response.Content != nil {
+		if text, ok := DecodedBody(*entry.Response.Content); ok {
+			tracer.Text(true, "body", text)
+		}
+	}
+	return tracer.Hits
+}
+
+// RequestCookies are the cookies sent with a request, from the parsed cookies when the HAR has them and otherwise from
+// the Cookie headers, as some exporters leave the cookies empty
+func RequestCookies(request Request) []Cookie {
+	if len(request.Cookies) > 0 {
+		return request.Cookies
+	}
+	return headerCookies(request.Headers, "cookie")
+}
+
+// ResponseCookies are the cookies set by a response in the same way, falling back to the Set-Cookie headers
+func ResponseCookies(response Response) []Cookie {
+	if len(response.Cookies) > 0 {
+		return response.Cookies
+	}
+	return headerCookies(response.Headers, "set-cookie")
+}
+
+func headerCookies(headers []Header, name string) []Cookie {
+	values := make(http.Header)
+	for _, header := range headers {
+		values.Add(header.Name, header.Value)
+	}
+	if strings.EqualFold(name, "cookie") {
+		return HarCookies((&http.Request{Header: values}).Cookies())
+	}
+	return HarCookies((&http.Response{Header: values}).Cookies())
+}
+
+func FormatTrace(entries []Entry, indexes []int, search string) string {
+	tracer := NewTracer(entries, search)
+
+	output := make([]string, 0)
+	matched := 0
+	firstSent := -1
+	firstSet := -1
+	origins := make([]string, 0)
+	seenOrigins := make(map[string]bool)
+	for i, entry := range entries {
+		hits := tracer.Entry(entry)
+		if len(hits) == 0 {
+			continue
+		}
+		matched++
+
+		output = append(output, FormatStartTime(entry, "relative")+" "+color.HiBlackString("#"+strconv.Itoa(indexes[i]))+" "+
+			MethodColor(entry.Request.Method)(entry.Request.Method)+" "+StatusColor(entry.Response.Status)(strconv.Itoa(entry.Response.Status))+" "+entry.Request.Url)
+		for _, hit := range hits {
+			number := tracer.ValueNumber(hit.Value)
+			direction := color.CyanString("→ sent    ")
+			if hit.Response {
+				direction = color.MagentaString("← received")
+				if firstSet == -1 {
+					firstSet = indexes[i]
+					direction = color.GreenString("← set     ")
+				}
+			} else {
+				if firstSent == -1 {
+					firstSent = indexes[i]
+				}
+				if origin := Origin(entry.Request.Url); !seenOrigins[origin] {
+					seenOrigins[origin] = true
+					origins = append(origins, origin)
+				}
+			}
+			output = append(output, "  "+direction+" "+hit.Location+color.HiBlackString(" (value "+strconv.Itoa(number)+": ")+Redact(hit.Value)+color.HiBlackString(")"))
+		}
+	}
+
+	if matched == 0 {
+		return color.HiBlackString("%s was not found in %d %s", search, len(entries), Tertiary(len(entries) == 1, "entry", "entries"))
+	}
+
+	output = append(output, "")
+	output = append(output, color.HiBlackString("Entries:         ")+strconv.Itoa(matched)+" of "+strconv.Itoa(len(entries)))
+	output = append(output, color.HiBlackString("Distinct values: ")+strconv.Itoa(len(tracer.values)))
+	if firstSet != -1 {
+		output = append(output, color.HiBlackString("First set:       ")+"#"+strconv.Itoa(firstSet))
+	}
+	if firstSent != -1 {
+		output = append(output, color.HiBlackString("First sent:      ")+"#"+strconv.Itoa(firstSent))
+		if firstSet == -1 || firstSent < firstSet {
+			output = append(output, color.YellowString("Sent before it was set in the capture, it was issued before recording started"))
+		}
+		output = append(output, color.HiBlackString("Sent to:         ")+strings.Join(origins, ", "))
+	}
+	return strings.Join(output, "\n")
+}
+
+func (cmd *TraceCmd) Run() error {
+	if cmd.Value == "" {
+		return errors.New("--value can't be empty")
+	}
+	har, err := ReadHarFile(cmd.File)
+	if err != nil {
+		return err
+	}
+	indexes, err := SelectEntryIndexes(har.Log)
+	if err != nil {
+		return err
+	}
+	entries := make([]Entry, len(indexes))
+	for i, index := range indexes {
+		entries[i] = har.Log.Entries[index]
+	}
+	CaptureStart = EarliestStart(entries)
+	return WriteOutput(FormatTrace(entries, indexes, cmd.Value))
+}
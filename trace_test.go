@@ -0,0 +1,40 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/fatih/color"
+)
+
+// headerOnlyCookieEntries have their cookies only in the Cookie and Set-Cookie headers, as some exporters write them
+func headerOnlyCookieEntries() []Entry {
+	return []Entry{
+		{
+			StartedDateTime: "2024-01-01T00:00:00Z",
+			Request:         Request{Method: "POST", Url: "https://example.com/login"},
+			Response:        Response{Status: 302, Headers: []Header{{Name: "Set-Cookie", Value: "sid=abc123; Path=/; HttpOnly"}}},
+		},
+		{
+			StartedDateTime: "2024-01-01T00:00:01Z",
+			Request:         Request{Method: "GET", Url: "https://example.com/account", Headers: []Header{{Name: "Cookie", Value: "theme=dark; sid=abc123"}}},
+			Response:        Response{Status: 200},
+		},
+	}
+}
+
+func TestTraceCookieHeaders(t *testing.T) {
+	color.NoColor = true
+	for _, search := range []string{"sid", "abc123"} {
+		output := FormatTrace(headerOnlyCookieEntries(), []int{0, 1}, search)
+		if !strings.Contains(output, "First set:       #0") {
+			t.Errorf("tracing %s didn't find it set by #0:\n%s", search, output)
+		}
+		if !strings.Contains(output, "First sent:      #1") {
+			t.Errorf("tracing %s didn't find it sent by #1:\n%s", search, output)
+		}
+		if strings.Contains(output, "Sent before it was set") {
+			t.Errorf("tracing %s reported it sent before it was set:\n%s", search, output)
+		}
+	}
+}
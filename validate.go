@@ -0,0 +1,474 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/fatih/color"
+)
+
+type ValidateCmd struct {
+	File string `arg:"" help:"The HAR file to validate, or - to read it from stdin" type:"existingfile"`
+}
+
+// JsonNode is a decoded JSON value which remembers where in the file it came from, so that problems can be reported
+// against a line number
+type JsonNode struct {
+	Offset int64
+	Kind   string
+	Keys   []string
+	Fields map[string]*JsonNode
+	Items  []*JsonNode
+	Value  interface{}
+}
+
+const (
+	KindObject  = "object"
+	KindArray   = "array"
+	KindString  = "string"
+	KindNumber  = "number"
+	KindInteger = "integer"
+	KindBoolean = "boolean"
+	KindNull    = "null"
+)
+
+func ParseJsonNode(decoder *json.Decoder) (*JsonNode, error) {
+	token, err := decoder.Token()
+	if err != nil {
+		return nil, err
+	}
+
+	node := &JsonNode{Offset: decoder.InputOffset(), Value: token}
+	switch value := token.(type) {
+	case json.Delim:
+		if value == '{' {
+			node.Kind = KindObject
+			node.Fields = make(map[string]*JsonNode)
+			for decoder.More() {
+				key, err := decoder.Token()
+				if err != nil {
+					return nil, err
+				}
+				child, err := ParseJsonNode(decoder)
+				if err != nil {
+					return nil, err
+				}
+				name := key.(string)
+				node.Keys = append(node.Keys, name)
+				node.Fields[name] = child
+			}
+		} else {
+			node.Kind = KindArray
+			for decoder.More() {
+				child, err := ParseJsonNode(decoder)
+				if err != nil {
+					return nil, err
+				}
+				node.Items = append(node.Items, child)
+			}
+		}
+		// Consume the closing delimiter
+		if _, err := decoder.Token(); err != nil {
+			return nil, err
+		}
+	case string:
+		node.Kind = KindString
+	case json.Number:
+		node.Kind = KindNumber
+		if _, err := strconv.ParseInt(value.String(), 10, 64); err == nil {
+			node.Kind = KindInteger
+		}
+	case bool:
+		node.Kind = KindBoolean
+	case nil:
+		node.Kind = KindNull
+	}
+
+	return node, nil
+}
+
+func (node *JsonNode) Float() float64 {
+	if number, ok := node.Value.(json.Number); ok {
+		value, _ := number.Float64()
+		return value
+	}
+	return math.NaN()
+}
+
+func (node *JsonNode) Field(name string) *JsonNode {
+	if node == nil || node.Fields == nil {
+		return nil
+	}
+	field := node.Fields[name]
+	if field != nil && field.Kind == KindNull {
+		return nil
+	}
+	return field
+}
+
+type FieldRule struct {
+	Name     string
+	Kind     string
+	Required bool
+	Date     bool
+	Object   []FieldRule
+	Items    []FieldRule
+}
+
+var commentRule = FieldRule{Name: "comment", Kind: KindString}
+var nameValueRules = []FieldRule{{Name: "name", Kind: KindString, Required: true}, {Name: "value", Kind: KindString, Required: true}, commentRule}
+var creatorRules = []FieldRule{{Name: "name", Kind: KindString, Required: true}, {Name: "version", Kind: KindString, Required: true}, commentRule}
+var cookieRules = []FieldRule{
+	{Name: "name", Kind: KindString, Required: true},
+	{Name: "value", Kind: KindString, Required: true},
+	{Name: "path", Kind: KindString},
+	{Name: "domain", Kind: KindString},
+	{Name: "expires", Kind: KindString, Date: true},
+	{Name: "httpOnly", Kind: KindBoolean},
+	{Name: "secure", Kind: KindBoolean},
+	commentRule,
+}
+var cacheStateRules = []FieldRule{
+	{Name: "expires", Kind: KindString, Date: true},
+	{Name: "lastAccess", Kind: KindString, Required: true, Date: true},
+	{Name: "eTag", Kind: KindString, Required: true},
+	{Name: "hitCount", Kind: KindInteger, Required: true},
+	commentRule,
+}
+
+// HarSchema describes the log object of the HAR 1.2 specification
+var HarSchema = []FieldRule{
+	{Name: "version", Kind: KindString, Required: true},
+	{Name: "creator", Kind: KindObject, Required: true, Object: creatorRules},
+	{Name: "browser", Kind: KindObject, Object: creatorRules},
+	{Name: "pages", Kind: KindArray, Items: []FieldRule{
+		{Name: "startedDateTime", Kind: KindString, Required: true, Date: true},
+		{Name: "id", Kind: KindString, Required: true},
+		{Name: "title", Kind: KindString, Required: true},
+		{Name: "pageTimings", Kind: KindObject, Required: true, Object: []FieldRule{
+			{Name: "onContentLoad", Kind: KindNumber},
+			{Name: "onLoad", Kind: KindNumber},
+			commentRule,
+		}},
+		commentRule,
+	}},
+	{Name: "entries", Kind: KindArray, Required: true, Items: []FieldRule{
+		{Name: "pageref", Kind: KindString},
+		{Name: "startedDateTime", Kind: KindString, Required: true, Date: true},
+		{Name: "time", Kind: KindNumber, Required: true},
+		{Name: "request", Kind: KindObject, Required: true, Object: []FieldRule{
+			{Name: "method", Kind: KindString, Required: true},
+			{Name: "url", Kind: KindString, Required: true},
+			{Name: "httpVersion", Kind: KindString, Required: true},
+			{Name: "cookies", Kind: KindArray, Required: true, Items: cookieRules},
+			{Name: "headers", Kind: KindArray, Required: true, Items: nameValueRules},
+			{Name: "queryString", Kind: KindArray, Required: true, Items: nameValueRules},
+			{Name: "postData", Kind: KindObject, Object: []FieldRule{
+				{Name: "mimeType", Kind: KindString, Required: true},
+				{Name: "params", Kind: KindArray, Items: []FieldRule{
+					{Name: "name", Kind: KindString, Required: true},
+					{Name: "value", Kind: KindString},
+					{Name: "fileName", Kind: KindString},
+					{Name: "contentType", Kind: KindString},
+					commentRule,
+				}},
+				{Name: "text", Kind: KindString},
+				commentRule,
+			}},
+			{Name: "headersSize", Kind: KindInteger, Required: true},
+			{Name: "bodySize", Kind: KindInteger, Required: true},
+			commentRule,
+		}},
+		{Name: "response", Kind: KindObject, Required: true, Object: []FieldRule{
+			{Name: "status", Kind: KindInteger, Required: true},
+			{Name: "statusText", Kind: KindString, Required: true},
+			{Name: "httpVersion", Kind: KindString, Required: true},
+			{Name: "cookies", Kind: KindArray, Required: true, Items: cookieRules},
+			{Name: "headers", Kind: KindArray, Required: true, Items: nameValueRules},
+			{Name: "content", Kind: KindObject, Required: true, Object: []FieldRule{
+				{Name: "size", Kind: KindInteger, Required: true},
+				{Name: "compression", Kind: KindInteger},
+				{Name: "mimeType", Kind: KindString, Required: true},
+				{Name: "text", Kind: KindString},
+				{Name: "encoding", Kind: KindString},
+				commentRule,
+			}},
+			{Name: "redirectURL", Kind: KindString, Required: true},
+			{Name: "headersSize", Kind: KindInteger, Required: true},
+			{Name: "bodySize", Kind: KindInteger, Required: true},
+			commentRule,
+		}},
+		{Name: "cache", Kind: KindObject, Required: true, Object: []FieldRule{
+			{Name: "beforeRequest", Kind: KindObject, Object: cacheStateRules},
+			{Name: "afterRequest", Kind: KindObject, Object: cacheStateRules},
+			commentRule,
+		}},
+		{Name: "timings", Kind: KindObject, Required: true, Object: []FieldRule{
+			{Name: "blocked", Kind: KindNumber},
+			{Name: "dns", Kind: KindNumber},
+			{Name: "connect", Kind: KindNumber},
+			{Name: "send", Kind: KindNumber, Required: true},
+			{Name: "wait", Kind: KindNumber, Required: true},
+			{Name: "receive", Kind: KindNumber, Required: true},
+			{Name: "ssl", Kind: KindNumber},
+			commentRule,
+		}},
+		{Name: "serverIPAddress", Kind: KindString},
+		{Name: "connection", Kind: KindString},
+		commentRule,
+	}},
+	commentRule,
+}
+
+type Violation struct {
+	Offset   int64
+	Path     string
+	Severity string
+	Message  string
+}
+
+type VendorExtension struct {
+	Name        string
+	Occurrences int
+	Offset      int64
+}
+
+type Validator struct {
+	Violations []Violation
+	Extensions map[string]*VendorExtension
+}
+
+func (validator *Validator) Report(node *JsonNode, path string, severity string, message string) {
+	validator.Violations = append(validator.Violations, Violation{Offset: node.Offset, Path: path, Severity: severity, Message: message})
+}
+
+func KindMatches(expected string, actual string) bool {
+	return expected == actual || (expected == KindNumber && actual == KindInteger)
+}
+
+func (validator *Validator) CheckObject(node *JsonNode, path string, rules []FieldRule) {
+	known := make(map[string]bool)
+	for _, rule := range rules {
+		known[rule.Name] = true
+		field := node.Fields[rule.Name]
+		fieldPath := path + "." + rule.Name
+
+		if field == nil {
+			if rule.Required {
+				validator.Report(node, fieldPath, SeverityError, "required field is missing")
+			}
+			continue
+		}
+		if field.Kind == KindNull {
+			if rule.Required {
+				validator.Report(field, fieldPath, SeverityError, "required field is null")
+			}
+			continue
+		}
+		if !KindMatches(rule.Kind, field.Kind) {
+			validator.Report(field, fieldPath, SeverityError, "should be "+Tertiary(rule.Kind == KindInteger, "an ", "a ")+rule.Kind+" but was "+Tertiary(field.Kind == KindInteger || field.Kind == KindArray || field.Kind == KindObject, "an ", "a ")+field.Kind)
+			continue
+		}
+
+		if rule.Date {
+			if _, err := time.Parse(time.RFC3339Nano, field.Value.(string)); err != nil {
+				validator.Report(field, fieldPath, SeverityError, strconv.Quote(field.Value.(string))+" is not an ISO 8601 date")
+			}
+		}
+		if rule.Object != nil {
+			validator.CheckObject(field, fieldPath, rule.Object)
+		}
+		if rule.Items != nil {
+			for i, item := range field.Items {
+				itemPath := fieldPath + "[" + strconv.Itoa(i) + "]"
+				if item.Kind != KindObject {
+					validator.Report(item, itemPath, SeverityError, "should be an object but was "+Tertiary(item.Kind == KindInteger || item.Kind == KindArray, "an ", "a ")+item.Kind)
+					continue
+				}
+				validator.CheckObject(item, itemPath, rule.Items)
+			}
+		}
+	}
+
+	for _, key := range node.Keys {
+		if known[key] {
+			continue
+		}
+		if strings.HasPrefix(key, "_") {
+			extension, ok := validator.Extensions[key]
+			if !ok {
+				extension = &VendorExtension{Name: key, Offset: node.Fields[key].Offset}
+				validator.Extensions[key] = extension
+			}
+			extension.Occurrences++
+			continue
+		}
+		validator.Report(node.Fields[key], path+"."+key, SeverityWarning, "unknown field, custom fields should start with an underscore")
+	}
+}
+
+func (validator *Validator) CheckEntries(log *JsonNode) {
+	pages := make(map[string]bool)
+	if list := log.Field("pages"); list != nil {
+		for _, page := range list.Items {
+			if id := page.Field("id"); id != nil && id.Kind == KindString {
+				pages[id.Value.(string)] = true
+			}
+		}
+	}
+
+	entries := log.Field("entries")
+	if entries == nil || entries.Kind != KindArray {
+		return
+	}
+
+	var previousStart time.Time
+	for i, entry := range entries.Items {
+		path := "log.entries[" + strconv.Itoa(i) + "]"
+
+		if pageref := entry.Field("pageref"); pageref != nil && pageref.Kind == KindString && !pages[pageref.Value.(string)] {
+			validator.Report(pageref, path+".pageref", SeverityError, "refers to page "+strconv.Quote(pageref.Value.(string))+" which doesn't exist")
+		}
+
+		if started := entry.Field("startedDateTime"); started != nil && started.Kind == KindString {
+			if start, err := time.Parse(time.RFC3339Nano, started.Value.(string)); err == nil {
+				if start.Before(previousStart) {
+					validator.Report(started, path+".startedDateTime", SeverityWarning, "entries should be sorted by startedDateTime but this starts before the previous entry")
+				}
+				previousStart = start
+			}
+		}
+
+		for _, side := range []string{"request", "response"} {
+			for _, size := range []string{"headersSize", "bodySize"} {
+				if value := entry.Field(side).Field(size); value != nil && value.Float() < Unknown {
+					validator.Report(value, path+"."+side+"."+size, SeverityError, "should be -1 when unknown, not "+value.Value.(json.Number).String())
+				}
+			}
+		}
+
+		timings := entry.Field("timings")
+		if timings == nil {
+			continue
+		}
+		total := 0.0
+		for _, phase := range []string{"blocked", "dns", "connect", "send", "wait", "receive", "ssl"} {
+			value := timings.Field(phase)
+			if value == nil || math.IsNaN(value.Float()) {
+				continue
+			}
+			required := phase == "send" || phase == "wait" || phase == "receive"
+			if value.Float() < Unknown || (required && value.Float() < 0) {
+				validator.Report(value, path+".timings."+phase, SeverityError, Tertiary(required, "must not be negative", "should be -1 when not applicable")+", not "+value.Value.(json.Number).String())
+				continue
+			}
+			// The ssl phase is already included in the connect phase
+			if value.Float() > 0 && phase != "ssl" {
+				total += value.Float()
+			}
+		}
+
+		ssl := timings.Field("ssl")
+		connect := timings.Field("connect")
+		if ssl != nil && connect != nil && ssl.Float() > connect.Float() && connect.Float() >= 0 {
+			validator.Report(ssl, path+".timings.ssl", SeverityWarning, "is "+FormatMs(ssl.Float())+"ms which is longer than the "+FormatMs(connect.Float())+"ms connect time it should be included in")
+		}
+
+		if entryTime := entry.Field("time"); entryTime != nil && !math.IsNaN(entryTime.Float()) {
+			if math.Abs(entryTime.Float()-total) > math.Max(1, entryTime.Float()*0.01) {
+				validator.Report(entryTime, path+".time", SeverityWarning, "is "+FormatMs(entryTime.Float())+"ms but the timing phases add up to "+FormatMs(total)+"ms")
+			}
+		}
+	}
+}
+
+func ValidateHar(content []byte) (*Validator, error) {
+	decoder := json.NewDecoder(bytes.NewReader(content))
+	decoder.UseNumber()
+	root, err := ParseJsonNode(decoder)
+	if err != nil {
+		return nil, fmt.Errorf("the file is not valid JSON, %s", DescribeJsonError(content, err))
+	}
+
+	validator := &Validator{Extensions: make(map[string]*VendorExtension)}
+	if root.Kind != KindObject {
+		validator.Report(root, "", SeverityError, "the file should contain a JSON object")
+		return validator, nil
+	}
+
+	log := root.Field("log")
+	if log == nil || log.Kind != KindObject {
+		validator.Report(root, "log", SeverityError, "required object is missing")
+		return validator, nil
+	}
+	validator.CheckObject(log, "log", HarSchema)
+	validator.CheckEntries(log)
+
+	sort.SliceStable(validator.Violations, func(i, j int) bool {
+		return validator.Violations[i].Offset < validator.Violations[j].Offset
+	})
+	return validator, nil
+}
+
+func FormatValidation(content []byte, validator *Validator) string {
+	lines := LineCounter{content: content}
+	output := make([]string, 0)
+
+	errorCount := 0
+	for _, violation := range validator.Violations {
+		if violation.Severity == SeverityError {
+			errorCount++
+		}
+		location := color.HiBlackString("line " + strconv.Itoa(lines.Line(violation.Offset)) + ": ")
+		output = append(output, location+FormatFinding(Finding{Severity: violation.Severity, Message: color.CyanString(violation.Path) + " " + violation.Message}))
+	}
+
+	extensions := make([]*VendorExtension, 0, len(validator.Extensions))
+	for _, extension := range validator.Extensions {
+		extensions = append(extensions, extension)
+	}
+	sort.Slice(extensions, func(i, j int) bool {
+		return extensions[i].Offset < extensions[j].Offset
+	})
+	if len(extensions) > 0 {
+		output = append(output, color.YellowString("Vendor extensions:"))
+		for _, extension := range extensions {
+			line, _ := LineAndColumn(content, extension.Offset)
+			output = append(output, "  "+color.CyanString(extension.Name)+color.HiBlackString(" "+strconv.Itoa(extension.Occurrences)+Tertiary(extension.Occurrences == 1, " occurrence", " occurrences")+", first on line "+strconv.Itoa(line)))
+		}
+	}
+
+	summary := strconv.Itoa(errorCount) + Tertiary(errorCount == 1, " error, ", " errors, ") +
+		strconv.Itoa(len(validator.Violations)-errorCount) + Tertiary(len(validator.Violations)-errorCount == 1, " warning", " warnings")
+	output = append(output, Tertiary(errorCount > 0, color.RedString(summary), color.GreenString(summary)))
+
+	return strings.Join(output, "\n")
+}
+
+func (cmd *ValidateCmd) Run() error {
+	content, err := ReadInput(cmd.File)
+	if err != nil {
+		return err
+	}
+
+	validator, err := ValidateHar(content)
+	if err != nil {
+		return err
+	}
+	if err := WriteOutput(FormatValidation(content, validator)); err != nil {
+		return err
+	}
+
+	for _, violation := range validator.Violations {
+		if violation.Severity == SeverityError {
+			return fmt.Errorf("%s does not conform to HAR 1.2", cmd.File)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"os"
+	"strings"
+	"testing"
+)
+
+func validationMessages(t *testing.T, content string) []string {
+	validator, err := ValidateHar([]byte(content))
+	if err != nil {
+		t.Fatal(err)
+	}
+	messages := make([]string, len(validator.Violations))
+	for i, violation := range validator.Violations {
+		messages[i] = violation.Severity + " " + violation.Path + ": " + violation.Message
+	}
+	return messages
+}
+
+func TestCheckEntries(t *testing.T) {
+	messages := validationMessages(t, `{"log": {"version": "1.2", "creator": {"name": "test", "version": "1"},
+		"pages": [{"startedDateTime": "2024-01-01T00:00:00Z", "id": "page_1", "title": "", "pageTimings": {}}],
+		"entries": [
+			{"pageref": "page_1", "startedDateTime": "2024-01-01T00:00:01Z", "time": 30,
+				"request": {"method": "GET", "url": "https://example.com/", "httpVersion": "HTTP/1.1", "cookies": [], "headers": [], "queryString": [], "headersSize": -1, "bodySize": 0},
+				"response": {"status": 200, "statusText": "OK", "httpVersion": "HTTP/1.1", "cookies": [], "headers": [], "content": {"size": 0, "mimeType": "text/html"}, "redirectURL": "", "headersSize": -1, "bodySize": -2},
+				"cache": {}, "timings": {"connect": 10, "ssl": 20, "send": 0, "wait": 10, "receive": 10}},
+			{"pageref": "page_2", "startedDateTime": "2024-01-01T00:00:00Z", "time": 5,
+				"request": {"method": "GET", "url": "https://example.com/a", "httpVersion": "HTTP/1.1", "cookies": [], "headers": [], "queryString": [], "headersSize": -1, "bodySize": 0},
+				"response": {"status": 200, "statusText": "OK", "httpVersion": "HTTP/1.1", "cookies": [], "headers": [], "content": {"size": 0, "mimeType": "text/html"}, "redirectURL": "", "headersSize": -1, "bodySize": 0},
+				"cache": {}, "timings": {"dns": -3, "send": 0, "wait": -1, "receive": 5}}
+		]}}`)
+
+	expected := []string{
+		"error log.entries[0].response.bodySize: should be -1 when unknown, not -2",
+		"warning log.entries[0].timings.ssl: is 20ms which is longer than the 10ms connect time it should be included in",
+		"error log.entries[1].pageref: refers to page \"page_2\" which doesn't exist",
+		"warning log.entries[1].startedDateTime: entries should be sorted by startedDateTime but this starts before the previous entry",
+		"error log.entries[1].timings.dns: should be -1 when not applicable, not -3",
+		"error log.entries[1].timings.wait: must not be negative, not -1",
+	}
+	for _, message := range expected {
+		found := false
+		for _, reported := range messages {
+			found = found || reported == message
+		}
+		if !found {
+			t.Errorf("expected %q in\n%s", message, strings.Join(messages, "\n"))
+		}
+	}
+	if len(messages) != len(expected) {
+		t.Errorf("expected %d violations, got\n%s", len(expected), strings.Join(messages, "\n"))
+	}
+}
+
+func TestCheckEntriesTimeMismatch(t *testing.T) {
+	messages := validationMessages(t, `{"log": {"version": "1.2", "creator": {"name": "test", "version": "1"}, "entries": [
+		{"startedDateTime": "2024-01-01T00:00:00Z", "time": 100,
+			"request": {"method": "GET", "url": "https://example.com/", "httpVersion": "HTTP/1.1", "cookies": [], "headers": [], "queryString": [], "headersSize": -1, "bodySize": 0},
+			"response": {"status": 200, "statusText": "OK", "httpVersion": "HTTP/1.1", "cookies": [], "headers": [], "content": {"size": 0, "mimeType": "text/html"}, "redirectURL": "", "headersSize": -1, "bodySize": 0},
+			"cache": {}, "timings": {"blocked": -1, "dns": -1, "connect": -1, "send": 1, "wait": 20, "receive": 9}}
+	]}}`)
+	if len(messages) != 1 || messages[0] != "warning log.entries[0].time: is 100ms but the timing phases add up to 30ms" {
+		t.Errorf("expected the time to disagree with its phases, got\n%s", strings.Join(messages, "\n"))
+	}
+}
+
+func TestCheckEntriesBrowserHars(t *testing.T) {
+	for _, file := range []string{"testdata/chrome.har", "testdata/firefox.har"} {
+		content, err := os.ReadFile(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, message := range validationMessages(t, string(content)) {
+			if strings.HasPrefix(message, SeverityError) {
+				t.Errorf("%s: %s", file, message)
+			}
+		}
+	}
+}
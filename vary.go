@@ -0,0 +1,196 @@
+package main
+
+import (
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+type AuditVaryCmd struct {
+	File string `arg:"" help:"The HAR file to parse, or - to read it from stdin" type:"existingfile"`
+}
+
+// highCardinalityHeaders differ between almost every user, so varying on them leaves a shared cache with one copy per
+// user and few hits
+var highCardinalityHeaders = []string{"cookie", "user-agent", "authorization", "x-forwarded-for"}
+
+// cacheStatusHeaders are where CDNs report whether the response was a hit
+var cacheStatusHeaders = []string{"cf-cache-status", "x-cache", "x-cache-status", "x-vercel-cache", "cdn-cache", "x-proxy-cache"}
+
+// cacheBustingParameters are query parameters commonly given a new value on every request to skip caches
+var cacheBustingParameters = []string{"_", "cb", "cachebust", "cachebuster", "nocache", "t", "ts", "timestamp", "rnd", "rand", "random"}
+
+func VaryHeaders(response Response) []string {
+	names := make([]string, 0)
+	for _, header := range response.Headers {
+		if !strings.EqualFold(header.Name, "vary") {
+			continue
+		}
+		for _, name := range strings.Split(header.Value, ",") {
+			if name = strings.ToLower(strings.TrimSpace(name)); name != "" {
+				names = append(names, name)
+			}
+		}
+	}
+	return names
+}
+
+// CacheStatus is whether a CDN reported the response as a hit or miss, or empty if it didn't say
+func CacheStatus(response Response) string {
+	for _, name := range cacheStatusHeaders {
+		value := strings.ToUpper(HeaderValue(response.Headers, name))
+		switch {
+		case strings.Contains(value, "HIT"):
+			return "hit"
+		case strings.Contains(value, "MISS"), strings.Contains(value, "EXPIRED"), strings.Contains(value, "BYPASS"), strings.Contains(value, "DYNAMIC"):
+			return "miss"
+		}
+	}
+	return ""
+}
+
+type varyEndpoint struct {
+	*AuditGroup
+	vary      map[string]bool
+	varyOrder []string
+	// values are the distinct values of each varied request header
+	values   map[string]map[string]bool
+	variants map[string]bool
+	statuses map[string]map[string]int
+	busting  map[string]map[string]bool
+}
+
+// AuditVary groups the responses by endpoint and looks at what their caches are keyed on
+func AuditVary(entries []Entry) []*AuditGroup {
+	endpoints := make([]*varyEndpoint, 0)
+	byEndpoint := make(map[string]*varyEndpoint)
+	for _, entry := range entries {
+		name := Endpoint(entry)
+		endpoint, ok := byEndpoint[name]
+		if !ok {
+			endpoint = &varyEndpoint{
+				AuditGroup: &AuditGroup{Name: name},
+				vary:       make(map[string]bool),
+				values:     make(map[string]map[string]bool),
+				variants:   make(map[string]bool),
+				statuses:   make(map[string]map[string]int),
+				busting:    make(map[string]map[string]bool),
+			}
+			byEndpoint[name] = endpoint
+			endpoints = append(endpoints, endpoint)
+		}
+		endpoint.Entries++
+
+		vary := VaryHeaders(entry.Response)
+		endpoint.variants[strings.Join(vary, ", ")] = true
+		for _, header := range vary {
+			if !endpoint.vary[header] {
+				endpoint.vary[header] = true
+				endpoint.varyOrder = append(endpoint.varyOrder, header)
+				endpoint.values[header] = make(map[string]bool)
+			}
+			endpoint.values[header][HeaderValue(entry.Request.Headers, header)] = true
+		}
+
+		if status := CacheStatus(entry.Response); status != "" {
+			if endpoint.statuses[entry.Request.Url] == nil {
+				endpoint.statuses[entry.Request.Url] = make(map[string]int)
+			}
+			endpoint.statuses[entry.Request.Url][status]++
+		}
+
+		if requestUrl, err := url.Parse(entry.Request.Url); err == nil {
+			for name, values := range requestUrl.Query() {
+				for _, busting := range cacheBustingParameters {
+					if strings.EqualFold(name, busting) {
+						if endpoint.busting[name] == nil {
+							endpoint.busting[name] = make(map[string]bool)
+						}
+						endpoint.busting[name][strings.Join(values, ",")] = true
+					}
+				}
+			}
+		}
+	}
+
+	groups := make([]*AuditGroup, 0)
+	for _, endpoint := range endpoints {
+		if len(endpoint.varyOrder) > 0 {
+			endpoint.Summary = "Vary: " + strings.Join(endpoint.varyOrder, ", ")
+		}
+
+		for _, header := range endpoint.varyOrder {
+			distinct := len(endpoint.values[header])
+			switch {
+			case header == "*":
+				endpoint.Add(SeverityError, "Vary: * makes every response uncacheable by shared caches")
+			case ContainsFold(highCardinalityHeaders, header):
+				endpoint.Add(SeverityWarning, "varies on "+header+", which gives shared caches one copy per user ("+strconv.Itoa(distinct)+" distinct "+Tertiary(distinct == 1, "value", "values")+" seen)")
+			case distinct > 3:
+				endpoint.Add(SeverityWarning, "varies on "+header+", which had "+strconv.Itoa(distinct)+" distinct values in "+strconv.Itoa(endpoint.Entries)+" requests")
+			}
+		}
+		if len(endpoint.variants) > 1 {
+			variants := make([]string, 0, len(endpoint.variants))
+			for variant := range endpoint.variants {
+				variants = append(variants, Tertiary(variant != "", variant, "none"))
+			}
+			sort.Strings(variants)
+			endpoint.Add(SeverityWarning, "responses disagree on Vary: "+strings.Join(variants, " / "))
+		}
+
+		urls := make([]string, 0, len(endpoint.statuses))
+		for requestUrl := range endpoint.statuses {
+			urls = append(urls, requestUrl)
+		}
+		sort.Strings(urls)
+		for _, requestUrl := range urls {
+			statuses := endpoint.statuses[requestUrl]
+			if statuses["miss"] > 1 && statuses["hit"] == 0 {
+				endpoint.Add(SeverityWarning, "the CDN missed all "+strconv.Itoa(statuses["miss"])+" requests for "+requestUrl)
+			}
+		}
+
+		names := make([]string, 0, len(endpoint.busting))
+		for name := range endpoint.busting {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			if values := endpoint.busting[name]; len(values) > 1 && len(values) == endpoint.Entries {
+				endpoint.Add(SeverityWarning, "query parameter "+name+" has a new value on every request, which busts any cache")
+			}
+		}
+
+		if len(endpoint.varyOrder) > 0 || len(endpoint.Findings) > 0 {
+			groups = append(groups, endpoint.AuditGroup)
+		}
+	}
+
+	sort.SliceStable(groups, func(i, j int) bool {
+		return len(groups[i].Findings) > len(groups[j].Findings)
+	})
+	return groups
+}
+
+func ContainsFold(values []string, v string) bool {
+	for _, value := range values {
+		if strings.EqualFold(value, v) {
+			return true
+		}
+	}
+	return false
+}
+
+func (cmd *AuditVaryCmd) Run() error {
+	har, err := ReadHar(cmd.File, false)
+	if err != nil {
+		return err
+	}
+	validEntries, err := SelectEntries(har.Log)
+	if err != nil {
+		return err
+	}
+	return WriteOutput(FormatAuditGroups(AuditVary(validEntries)))
+}
@@ -0,0 +1,197 @@
+package main
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+type ExportWiremockCmd struct {
+	OutDir      string   `name:"out-dir" required:"" help:"The WireMock root directory to write mappings and __files to, or its mappings directory"`
+	MatchHeader []string `name:"match-header" default:"content-type" help:"Request headers the stubs require to have the recorded value"`
+	File        string   `arg:"" help:"The HAR file to parse, or - to read it from stdin" type:"existingfile"`
+}
+
+// WiremockMatcher is one of WireMock's value patterns, such as {"equalTo": "value"}
+type WiremockMatcher map[string]string
+
+type WiremockRequest struct {
+	Method          string                     `json:"method"`
+	UrlPath         string                     `json:"urlPath"`
+	QueryParameters map[string]WiremockMatcher `json:"queryParameters,omitempty"`
+	Headers         map[string]WiremockMatcher `json:"headers,omitempty"`
+}
+
+type WiremockResponse struct {
+	Status       int            `json:"status"`
+	Headers      map[string]any `json:"headers,omitempty"`
+	BodyFileName string         `json:"bodyFileName,omitempty"`
+}
+
+// WiremockMapping is a stub mapping. Requests which were made more than once are given a scenario whose state counts
+// the calls, so each recorded response is served in turn
+type WiremockMapping struct {
+	Name                  string           `json:"name"`
+	Request               WiremockRequest  `json:"request"`
+	Response              WiremockResponse `json:"response"`
+	ScenarioName          string           `json:"scenarioName,omitempty"`
+	RequiredScenarioState string           `json:"requiredScenarioState,omitempty"`
+	NewScenarioState      string           `json:"newScenarioState,omitempty"`
+}
+
+// WiremockHeaders writes repeated headers, like Set-Cookie, as a list of values which WireMock sends as separate headers
+func WiremockHeaders(headers []Header) map[string]any {
+	values := make(map[string][]string)
+	for _, header := range headers {
+		name := strings.ToLower(header.Name)
+		values[name] = append(values[name], header.Value)
+	}
+	result := make(map[string]any, len(values))
+	for name, list := range values {
+		if len(list) == 1 {
+			result[name] = list[0]
+		} else {
+			result[name] = list
+		}
+	}
+	return result
+}
+
+// WiremockRoot finds the directory holding mappings and __files from the directory the user gave
+func WiremockRoot(dir string) string {
+	if filepath.Base(filepath.Clean(dir)) == "mappings" {
+		return filepath.Dir(filepath.Clean(dir))
+	}
+	return dir
+}
+
+func NewWiremockRequest(entry Entry, matchHeaders []string) (WiremockRequest, bool) {
+	requestUrl, err := url.Parse(entry.Request.Url)
+	if err != nil {
+		return WiremockRequest{}, false
+	}
+	request := WiremockRequest{Method: strings.ToUpper(entry.Request.Method), UrlPath: Tertiary(requestUrl.Path == "", "/", requestUrl.Path)}
+	for name, values := range requestUrl.Query() {
+		if request.QueryParameters == nil {
+			request.QueryParameters = make(map[string]WiremockMatcher)
+		}
+		request.QueryParameters[name] = WiremockMatcher{"equalTo": values[0]}
+	}
+	for _, name := range matchHeaders {
+		header := FindHeader(entry.Request.Headers, name)
+		if header == nil {
+			continue
+		}
+		if request.Headers == nil {
+			request.Headers = make(map[string]WiremockMatcher)
+		}
+		if strings.EqualFold(name, "content-type") {
+			// Multipart boundaries change on every request, so only the type itself has to match
+			mime, _, _ := strings.Cut(header.Value, ";")
+			request.Headers[header.Name] = WiremockMatcher{"contains": strings.TrimSpace(mime)}
+		} else {
+			request.Headers[header.Name] = WiremockMatcher{"equalTo": header.Value}
+		}
+	}
+	return request, true
+}
+
+// WriteWiremockStubs writes a mapping for each entry and its body to __files, returning how many mappings were written
+func WriteWiremockStubs(dir string, entries []Entry, matchHeaders []string) (int, error) {
+	root := WiremockRoot(dir)
+	mappingsDir := filepath.Join(root, "mappings")
+	filesDir := filepath.Join(root, "__files")
+	for _, directory := range []string{mappingsDir, filesDir} {
+		if err := os.MkdirAll(directory, 0755); err != nil {
+			return 0, err
+		}
+	}
+
+	type stub struct {
+		key     string
+		mapping WiremockMapping
+	}
+	stubs := make([]stub, 0)
+	calls := make(map[string]int)
+	for _, entry := range entries {
+		if entry.Response.Status == 0 {
+			continue
+		}
+		request, ok := NewWiremockRequest(entry, matchHeaders)
+		if !ok {
+			continue
+		}
+		mock := NewMockResponse(entry)
+		mapping := WiremockMapping{
+			Name:     request.Method + " " + MiddleEllipsis(request.UrlPath, maxSequenceLabel),
+			Request:  request,
+			Response: WiremockResponse{Status: mock.Status, Headers: WiremockHeaders(mock.Headers)},
+		}
+		if mock.Body != "" {
+			body := []byte(mock.Body)
+			if mock.Base64 {
+				decoded, err := base64.StdEncoding.DecodeString(mock.Body)
+				if err != nil {
+					slog.Warn("Failed to decode the response body, skipping the stub", "url", entry.Request.Url, "error", err)
+					continue
+				}
+				body = decoded
+			}
+			name := strings.TrimSuffix(request.UrlPath, filepath.Ext(request.UrlPath))
+			mapping.Response.BodyFileName = ExportFileName(len(stubs)+1, request.Method, name, BodyExtension(MimeType(entry), mock.Base64))
+			if err := os.WriteFile(filepath.Join(filesDir, mapping.Response.BodyFileName), body, 0644); err != nil {
+				return 0, err
+			}
+		}
+		key := entry.Request.Method + " " + entry.Request.Url
+		calls[key]++
+		stubs = append(stubs, stub{key: key, mapping: mapping})
+	}
+
+	seen := make(map[string]int)
+	for i, stub := range stubs {
+		if total := calls[stub.key]; total > 1 {
+			seen[stub.key]++
+			call := seen[stub.key]
+			stub.mapping.ScenarioName = stub.key
+			stub.mapping.RequiredScenarioState = Tertiary(call == 1, "Started", "Call "+strconv.Itoa(call))
+			if call < total {
+				stub.mapping.NewScenarioState = "Call " + strconv.Itoa(call+1)
+			}
+		}
+		content, err := json.MarshalIndent(stub.mapping, "", "  ")
+		if err != nil {
+			return 0, err
+		}
+		// Mappings are named like their body files so the two are easy to pair up
+		request := stub.mapping.Request
+		name := ExportFileName(i+1, request.Method, strings.TrimSuffix(request.UrlPath, filepath.Ext(request.UrlPath)), ".json")
+		if err := os.WriteFile(filepath.Join(mappingsDir, name), append(content, '\n'), 0644); err != nil {
+			return 0, err
+		}
+	}
+	return len(stubs), nil
+}
+
+func (cmd *ExportWiremockCmd) Run() error {
+	har, err := ReadHarFile(cmd.File)
+	if err != nil {
+		return err
+	}
+	entries, err := SelectEntries(har.Log)
+	if err != nil {
+		return err
+	}
+	written, err := WriteWiremockStubs(cmd.OutDir, entries, cmd.MatchHeader)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintln(os.Stderr, "Wrote "+strconv.Itoa(written)+" stub mappings to "+filepath.Join(WiremockRoot(cmd.OutDir), "mappings"))
+	return nil
+}
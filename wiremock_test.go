@@ -0,0 +1,36 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestWiremockSkipsInvalidBase64(t *testing.T) {
+	png, broken, encoding := "iVBORw0KGgo=", "not base64!", "base64"
+	entries := []Entry{
+		{
+			Request:  Request{Method: "GET", Url: "https://example.com/broken.png"},
+			Response: Response{Status: 200, Content: &Content{MimeType: "image/png", Text: &broken, Encoding: &encoding}},
+		},
+		{
+			Request:  Request{Method: "GET", Url: "https://example.com/logo.png"},
+			Response: Response{Status: 200, Content: &Content{MimeType: "image/png", Text: &png, Encoding: &encoding}},
+		},
+	}
+	dir := t.TempDir()
+	written, err := WriteWiremockStubs(dir, entries, nil)
+	if err != nil {
+		t.Fatalf("the invalid body failed the export: %v", err)
+	}
+	if written != 1 {
+		t.Errorf("wrote %d stubs, expected only the valid one", written)
+	}
+	files, err := os.ReadDir(filepath.Join(dir, "__files"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 1 {
+		t.Errorf("wrote %d body files, expected 1", len(files))
+	}
+}
@@ -0,0 +1,191 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type CaptureCmd struct {
+	Zap     string  `name:"zap" required:"" help:"The address of a running OWASP ZAP instance to pull the recorded messages from, eg http://localhost:8080"`
+	ApiKey  *string `name:"api-key" env:"ZAP_API_KEY" help:"The ZAP API key, if the API requires one"`
+	BaseUrl *string `name:"base-url" help:"Only pull messages whose URL starts with this"`
+}
+
+type ExportZapCmd struct {
+	Zap    string  `name:"zap" required:"" help:"The address of a running OWASP ZAP instance to send the requests through, eg http://localhost:8080"`
+	ApiKey *string `name:"api-key" env:"ZAP_API_KEY" help:"The ZAP API key, if the API requires one"`
+	Scan   bool    `name:"scan" help:"Start an active scan of each origin once its requests are in ZAP"`
+	File   string  `arg:"" help:"The HAR file to parse, or - to read it from stdin" type:"existingfile"`
+}
+
+// zapPageSize is how many messages are pulled from ZAP at a time, so large sessions aren't built into one response
+const zapPageSize = 500
+
+// ZapClient calls the API of a running ZAP instance
+type ZapClient struct {
+	Address string
+	ApiKey  string
+	Client  *http.Client
+}
+
+func NewZapClient(address string, apiKey *string) *ZapClient {
+	client := &ZapClient{Address: strings.TrimSuffix(address, "/"), Client: &http.Client{Timeout: 5 * time.Minute}}
+	if apiKey != nil {
+		client.ApiKey = *apiKey
+	}
+	return client
+}
+
+// Call sends the parameters as a form to /format/component/kind/name/, eg /JSON/core/action/sendRequest/, and returns
+// the body of the response, or the message of ZAP's error
+func (zap *ZapClient) Call(format string, component string, kind string, name string, params url.Values) ([]byte, error) {
+	address := zap.Address + "/" + format + "/" + component + "/" + kind + "/" + name + "/"
+	request, err := http.NewRequest(http.MethodPost, address, strings.NewReader(params.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	if zap.ApiKey != "" {
+		request.Header.Set("X-ZAP-API-Key", zap.ApiKey)
+	}
+	response, err := zap.Client.Do(request)
+	if err != nil {
+		return nil, fmt.Errorf("failed to reach ZAP at %s, %w", zap.Address, err)
+	}
+	defer response.Body.Close()
+	body, err := io.ReadAll(response.Body)
+	if err != nil {
+		return nil, err
+	}
+	if response.StatusCode != http.StatusOK {
+		var zapError struct {
+			Code    string `json:"code"`
+			Message string `json:"message"`
+		}
+		if json.Unmarshal(body, &zapError) == nil && zapError.Message != "" {
+			return nil, errors.New("ZAP responded to " + name + " with " + strconv.Itoa(response.StatusCode) + ", " + zapError.Message)
+		}
+		return nil, errors.New("ZAP responded to " + name + " with " + strconv.Itoa(response.StatusCode))
+	}
+	return body, nil
+}
+
+// MessagesHar pulls the messages in ZAP's history as a HAR, a page at a time. The export moved to the exim add-on in
+// ZAP 2.13, so older versions are asked through the core API instead
+func (zap *ZapClient) MessagesHar(baseUrl *string) (HarFile, error) {
+	har := HarFile{Log: Log{Version: "1.2", Creator: Creator{Name: "harv", Version: "OWASP ZAP"}, Entries: make([]Entry, 0)}}
+	component, name := "exim", "exportHar"
+	for start := 0; ; start += zapPageSize {
+		params := url.Values{"start": {strconv.Itoa(start)}, "count": {strconv.Itoa(zapPageSize)}}
+		if baseUrl != nil {
+			params.Set("baseurl", *baseUrl)
+		}
+		content, err := zap.Call("OTHER", component, "other", name, params)
+		if err != nil && start == 0 && component == "exim" {
+			component, name = "core", "messagesHar"
+			content, err = zap.Call("OTHER", component, "other", name, params)
+		}
+		if err != nil {
+			return HarFile{}, err
+		}
+		var page HarFile
+		if err := UnmarshalWithExtensions(content, &page); err != nil {
+			return HarFile{}, fmt.Errorf("failed to parse the HAR from ZAP, %s", DescribeJsonError(content, err))
+		}
+		har.Log.Entries = append(har.Log.Entries, page.Log.Entries...)
+		if len(page.Log.Entries) < zapPageSize {
+			return har, nil
+		}
+	}
+}
+
+// SendRequest has ZAP send a request and add it to its history and sites tree, where it can be scanned
+func (zap *ZapClient) SendRequest(entry Entry) error {
+	requestUrl, err := url.Parse(entry.Request.Url)
+	if err != nil {
+		return err
+	}
+	// ZAP takes the scheme from an absolute URL on the request line, and only speaks HTTP/1.1 to the API
+	request := RawHttpRequest(entry, requestUrl, requestUrl.String(), "HTTP/1.1")
+	_, err = zap.Call("JSON", "core", "action", "sendRequest", url.Values{"request": {request}, "followRedirects": {"false"}})
+	return err
+}
+
+// Scan starts an active scan of everything ZAP has seen under the URL, returning the ID of the scan
+func (zap *ZapClient) Scan(target string) (string, error) {
+	content, err := zap.Call("JSON", "ascan", "action", "scan", url.Values{"url": {target}, "recurse": {"true"}})
+	if err != nil {
+		return "", err
+	}
+	var started struct {
+		Scan string `json:"scan"`
+	}
+	if err := json.Unmarshal(content, &started); err != nil {
+		return "", err
+	}
+	return started.Scan, nil
+}
+
+func (cmd *CaptureCmd) Run() error {
+	har, err := NewZapClient(cmd.Zap, cmd.ApiKey).MessagesHar(cmd.BaseUrl)
+	if err != nil {
+		return err
+	}
+	har.Log.Entries, err = SelectEntries(har.Log)
+	if err != nil {
+		return err
+	}
+	content, err := MarshalHar(har)
+	if err != nil {
+		return err
+	}
+	return WriteOutput(string(content))
+}
+
+func (cmd *ExportZapCmd) Run() error {
+	har, err := ReadHarFile(cmd.File)
+	if err != nil {
+		return err
+	}
+	entries, err := SelectEntries(har.Log)
+	if err != nil {
+		return err
+	}
+	zap := NewZapClient(cmd.Zap, cmd.ApiKey)
+	origins := make([]string, 0)
+	sent := 0
+	for _, entry := range entries {
+		requestUrl, err := url.Parse(entry.Request.Url)
+		if err != nil || requestUrl.Host == "" {
+			continue
+		}
+		if err := zap.SendRequest(entry); err != nil {
+			return err
+		}
+		sent++
+		origin := requestUrl.Scheme + "://" + requestUrl.Host + "/"
+		if !slices.Contains(origins, origin) {
+			origins = append(origins, origin)
+		}
+	}
+	fmt.Fprintln(os.Stderr, "Sent "+strconv.Itoa(sent)+" requests through ZAP")
+	if cmd.Scan {
+		for _, origin := range origins {
+			id, err := zap.Scan(origin)
+			if err != nil {
+				return err
+			}
+			fmt.Fprintln(os.Stderr, "Started active scan "+id+" of "+origin)
+		}
+	}
+	return nil
+}